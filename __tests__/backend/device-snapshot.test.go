@@ -0,0 +1,167 @@
+package services
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"mib-platform/models"
+)
+
+func TestDeviceSnapshots(t *testing.T) {
+	s := newTestMIBService(t, &models.DeviceSnapshot{}, &models.Device{}, &models.SNMPCredential{}, &models.SimulatedDevice{})
+	modules, err := s.compiler.CompileSource(testIfMIB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.createParsedMIB(&models.MIB{Filename: "if.mib", FilePath: "if.mib"}, modules); err != nil {
+		t.Fatal(err)
+	}
+
+	// 用模拟设备代替真实交换机
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+	conn.Close()
+	sim := NewSNMPSimulator(s.db, SimulatorConfig{Host: "127.0.0.1"})
+	defer sim.Stop()
+	if err := sim.CreateDevice(&models.SimulatedDevice{Name: "core-sw1", Port: port, Recording: `
+1.3.6.1.2.1.1.1.0|4|Switch firmware 1.0
+1.3.6.1.2.1.1.3.0|67|100
+1.3.6.1.2.1.2.2.1.1.1|2|1
+1.3.6.1.2.1.2.2.1.1.2|2|2
+1.3.6.1.2.1.2.2.1.8.1|2|1
+1.3.6.1.2.1.2.2.1.8.2|2|1
+1.3.6.1.2.1.2.2.1.10.1|65|1000
+1.3.6.1.2.1.2.2.1.10.2|65|2000
+1.3.6.1.4.1.99999.1.0|4x|00ff10
+`}, true); err != nil {
+		t.Fatal(err)
+	}
+	device := models.Device{Name: "core-sw1", IPAddress: "127.0.0.1", Port: port, Credentials: []models.SNMPCredential{{Version: "v2c", Community: "public"}}}
+	if err := s.db.Create(&device).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots := NewSnapshotService(s.db)
+	full, err := snapshots.TakeSnapshot(device.ID, "", "before upgrade")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if full.Records != 9 || full.Root != "1.3.6.1" || full.Source != "walk" || full.StoredSize == 0 || full.DeviceName != "core-sw1" {
+		t.Fatalf("snapshot = %+v", full)
+	}
+	before, err := snapshots.TakeSnapshot(device.ID, "ifTable", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before.Records != 6 || before.Root != "1.3.6.1.2.1.2.2" {
+		t.Fatalf("subtree snapshot = %+v", before)
+	}
+	if _, err := snapshots.TakeSnapshot(device.ID, "noSuchObject", ""); err == nil {
+		t.Fatal("expected unknown root to be rejected")
+	}
+
+	// 升级后: 接口 2 down, 计数器增长
+	client := &gosnmp.GoSNMP{Target: "127.0.0.1", Port: uint16(port), Version: gosnmp.Version2c, Community: "public", Timeout: time.Second}
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Conn.Close()
+	if _, err := client.Set([]gosnmp.SnmpPDU{
+		{Name: "1.3.6.1.2.1.2.2.1.8.2", Type: gosnmp.Integer, Value: 2},
+		{Name: "1.3.6.1.2.1.2.2.1.10.1", Type: gosnmp.Counter32, Value: uint32(1500)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	after, err := snapshots.TakeSnapshot(device.ID, "ifTable", "after upgrade")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := snapshots.DiffSnapshots(before.ID, after.ID, SnapshotDiffOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Summary != (SnapshotDiffSummary{Changed: 2, Unchanged: 4}) || len(diff.Added) != 0 || len(diff.Removed) != 0 {
+		t.Fatalf("diff = %+v", diff)
+	}
+	want := SnapshotChange{OID: "1.3.6.1.2.1.2.2.1.8.2", Name: "ifOperStatus.2", Module: "TEST-IF-MIB", OldType: "Integer", OldValue: "up(1)", NewType: "Integer", NewValue: "down(2)"}
+	if diff.Changed[0] != want {
+		t.Fatalf("changed[0] = %+v, want %+v", diff.Changed[0], want)
+	}
+	diff, err = snapshots.DiffSnapshots(before.ID, after.ID, SnapshotDiffOptions{IgnoreVolatile: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Summary != (SnapshotDiffSummary{Changed: 1, Unchanged: 4, Ignored: 1}) {
+		t.Fatalf("diff ignoring counters = %+v", diff.Summary)
+	}
+
+	// 导出再导入内容不变
+	for _, format := range []string{RecordingFormatSNMPWalk, RecordingFormatSNMPRec} {
+		_, text, err := snapshots.ExportSnapshot(full.ID, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		imported, err := snapshots.ImportSnapshot(device.ID, text, "", "imported", time.Time{})
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", format, err, text)
+		}
+		diff, err := snapshots.DiffSnapshots(full.ID, imported.ID, SnapshotDiffOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff.Summary != (SnapshotDiffSummary{Unchanged: 9}) {
+			t.Fatalf("%s round trip diff = %+v\n%s", format, diff.Summary, text)
+		}
+	}
+	_, walk, err := snapshots.ExportSnapshot(full.ID, RecordingFormatSNMPWalk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`.1.3.6.1.2.1.1.1.0 = STRING: "Switch firmware 1.0"`,
+		`.1.3.6.1.2.1.1.3.0 = Timeticks: (100) 0:00:01.00`,
+		`.1.3.6.1.2.1.2.2.1.10.2 = Counter32: 2000`,
+		`.1.3.6.1.4.1.99999.1.0 = Hex-STRING: 00 FF 10`,
+	} {
+		if !strings.Contains(walk, line+"\n") {
+			t.Errorf("export missing %q:\n%s", line, walk)
+		}
+	}
+
+	// 固件升级删除了一个对象并新增了一个对象
+	changed := strings.Replace(walk, ".1.3.6.1.4.1.99999.1.0 = Hex-STRING: 00 FF 10\n", "", 1) +
+		".1.3.6.1.2.1.1.5.0 = STRING: \"core-sw1\"\n"
+	upgraded, err := snapshots.ImportSnapshot(device.ID, changed, RecordingFormatSNMPWalk, "imported", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, err = snapshots.DiffSnapshots(full.ID, upgraded.ID, SnapshotDiffOptions{Ignore: []string{"1.3.6.1.2.1.1.3", "ifInOctets"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Summary.Added != 1 || diff.Summary.Removed != 1 || diff.Summary.Changed != 0 || diff.Summary.Ignored != 3 {
+		t.Fatalf("diff = %+v", diff.Summary)
+	}
+	if diff.Added[0].OID != "1.3.6.1.2.1.1.5.0" || diff.Added[0].NewValue != "core-sw1" || diff.Removed[0].OldValue != "00 FF 10" || diff.Removed[0].OldType != "OctetString" {
+		t.Fatalf("added = %+v, removed = %+v", diff.Added, diff.Removed)
+	}
+
+	list, total, err := snapshots.GetSnapshots(device.ID, 1, 2)
+	if err != nil || total != 6 || len(list) != 2 || list[0].ID != upgraded.ID || list[0].Data != nil {
+		t.Fatalf("GetSnapshots() = %+v, %d, %v", list, total, err)
+	}
+	if err := snapshots.DeleteSnapshot(upgraded.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := snapshots.GetSnapshot(upgraded.ID); err == nil {
+		t.Fatal("expected deleted snapshot to be gone")
+	}
+}
@@ -0,0 +1,827 @@
+package services
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"mib-platform/models"
+)
+
+const testIfMIB = `
+TEST-IF-MIB DEFINITIONS ::= BEGIN
+
+IMPORTS
+    MODULE-IDENTITY, OBJECT-TYPE, Counter32, Integer32,
+    mib-2                                   FROM SNMPv2-SMI
+    DisplayString                           FROM SNMPv2-TC;
+
+testIfMIB MODULE-IDENTITY
+    LAST-UPDATED "200006140000Z"
+    ORGANIZATION "IETF Interfaces MIB Working Group"
+    CONTACT-INFO "none"
+    DESCRIPTION
+            "The MIB module to describe generic objects for
+            network interface sub-layers."
+    REVISION     "200006140000Z"
+    DESCRIPTION  "Clarifications."
+    ::= { mib-2 31 }
+
+-------------------------------------------------------------------
+-- interfaces group
+-------------------------------------------------------------------
+
+ifTable OBJECT-TYPE
+    SYNTAX      SEQUENCE OF IfEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "A list of interface entries."
+    ::= { interfaces 2 }
+
+ifEntry OBJECT-TYPE
+    SYNTAX      IfEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "An entry containing management information."
+    INDEX   { ifIndex }
+    ::= { ifTable 1 }
+
+IfEntry ::=
+    SEQUENCE {
+        ifIndex      Integer32,
+        ifDescr      DisplayString,
+        ifOperStatus INTEGER,
+        ifInOctets   Counter32
+    }
+
+ifIndex OBJECT-TYPE
+    SYNTAX      Integer32 (1..2147483647)
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "A unique value, greater than zero, for each interface."
+    ::= { ifEntry 1 }
+
+ifOperStatus OBJECT-TYPE
+    SYNTAX  INTEGER {
+                up(1),        -- ready to pass packets
+                down(2),
+                testing(3)    -- in some test mode
+            }
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION
+            "The current operational state of the interface.
+            The ""testing"" state indicates that no operational
+            packets can be passed."
+    ::= { ifEntry 8 }
+
+ifInOctets OBJECT-TYPE
+    SYNTAX      Counter32
+    UNITS       "octets"
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "The total number of octets received on the interface."
+    ::= { ifEntry 10 }
+
+interfaces OBJECT IDENTIFIER ::= { mib-2 2 }
+
+END
+`
+
+func TestCompileMIBSource(t *testing.T) {
+	modules, err := NewMIBCompiler(nil, nil).CompileSource(testIfMIB)
+	if err != nil {
+		t.Fatalf("CompileSource() error = %v", err)
+	}
+	if len(modules) != 1 || modules[0].AST.Name != "TEST-IF-MIB" {
+		t.Fatalf("unexpected modules: %+v", modules)
+	}
+	if len(modules[0].Unresolved) != 0 {
+		t.Errorf("Unresolved = %v, want none", modules[0].Unresolved)
+	}
+
+	oids := make(map[string]string)
+	for _, oid := range modules[0].ModelOIDs() {
+		oids[oid.Name] = oid.OID
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"testIfMIB", "1.3.6.1.2.1.31"},
+		{"interfaces", "1.3.6.1.2.1.2"},
+		{"ifTable", "1.3.6.1.2.1.2.2"},
+		{"ifEntry", "1.3.6.1.2.1.2.2.1"},
+		{"ifOperStatus", "1.3.6.1.2.1.2.2.1.8"},
+		{"ifInOctets", "1.3.6.1.2.1.2.2.1.10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := oids[tt.name]; got != tt.want {
+				t.Errorf("OID(%s) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileMIBObjectClauses(t *testing.T) {
+	modules, err := NewMIBCompiler(nil, nil).CompileSource(testIfMIB)
+	if err != nil {
+		t.Fatalf("CompileSource() error = %v", err)
+	}
+
+	var found bool
+	for _, oid := range modules[0].ModelOIDs() {
+		switch oid.Name {
+		case "ifOperStatus":
+			found = true
+			if oid.Type != "INTEGER" || oid.Access != "read-only" || oid.Status != "current" {
+				t.Errorf("ifOperStatus = %+v", oid)
+			}
+			if oid.Syntax != "INTEGER { up(1), down(2), testing(3) }" {
+				t.Errorf("ifOperStatus syntax = %q", oid.Syntax)
+			}
+			want := "The current operational state of the interface.\nThe \"testing\" state indicates that no operational\npackets can be passed."
+			if oid.Description != want {
+				t.Errorf("ifOperStatus description = %q", oid.Description)
+			}
+		case "ifInOctets":
+			if oid.Units != "octets" {
+				t.Errorf("ifInOctets units = %q, want octets", oid.Units)
+			}
+		case "ifTable":
+			if oid.Type != "SEQUENCE OF" {
+				t.Errorf("ifTable type = %q", oid.Type)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("ifOperStatus not compiled")
+	}
+
+	if desc := modules[0].ModuleDescription(); desc != "The MIB module to describe generic objects for\nnetwork interface sub-layers." {
+		t.Errorf("ModuleDescription() = %q", desc)
+	}
+}
+
+func TestParseMIBSourceErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"空文件", ""},
+		{"缺少 END", "X-MIB DEFINITIONS ::= BEGIN\nfoo OBJECT IDENTIFIER ::= { iso 3 }\n"},
+		{"未闭合字符串", "X-MIB DEFINITIONS ::= BEGIN\nfoo OBJECT-TYPE DESCRIPTION \"abc\nEND\n"},
+		{"IMPORTS 缺少 FROM", "X-MIB DEFINITIONS ::= BEGIN\nIMPORTS a, b;\nEND\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseMIBSource(tt.src); err == nil {
+				t.Errorf("ParseMIBSource() expected error")
+			}
+		})
+	}
+}
+
+const testTCMIB = `
+TEST-TC-MIB DEFINITIONS ::= BEGIN
+
+IMPORTS
+    OBJECT-TYPE, Integer32, enterprises     FROM SNMPv2-SMI
+    TEXTUAL-CONVENTION, DisplayString,
+    MacAddress, DateAndTime                 FROM SNMPv2-TC;
+
+testTC OBJECT IDENTIFIER ::= { enterprises 99999 }
+
+TestPortState ::= TEXTUAL-CONVENTION
+    STATUS      current
+    DESCRIPTION "Port state."
+    SYNTAX      INTEGER { disabled(1), blocking(2), forwarding(5) }
+
+TestTemperature ::= TEXTUAL-CONVENTION
+    DISPLAY-HINT "d-1"
+    STATUS       current
+    DESCRIPTION  "Temperature in tenths of a degree."
+    SYNTAX       Integer32 (-400..1250)
+
+testName OBJECT-TYPE
+    SYNTAX      DisplayString (SIZE (0..32))
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "Name."
+    ::= { testTC 1 }
+
+testMac OBJECT-TYPE
+    SYNTAX      MacAddress
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "MAC."
+    ::= { testTC 2 }
+
+testState OBJECT-TYPE
+    SYNTAX      TestPortState
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "State."
+    ::= { testTC 3 }
+
+testTemp OBJECT-TYPE
+    SYNTAX      TestTemperature
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "Temperature."
+    ::= { testTC 4 }
+
+testClock OBJECT-TYPE
+    SYNTAX      DateAndTime
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "Clock."
+    ::= { testTC 5 }
+
+END
+`
+
+func TestCompileMIBTextualConventions(t *testing.T) {
+	modules, err := NewMIBCompiler(nil, nil).CompileSource(testTCMIB)
+	if err != nil {
+		t.Fatalf("CompileSource() error = %v", err)
+	}
+
+	oids := make(map[string]models.OID)
+	for _, oid := range modules[0].ModelOIDs() {
+		oids[oid.Name] = oid
+	}
+
+	tests := []struct {
+		name   string
+		base   string
+		tc     string
+		hint   string
+		enums  []models.OIDEnum
+		ranges []models.OIDRange
+		sizes  []models.OIDRange
+	}{
+		{"testName", "OCTET STRING", "DisplayString", "255a", nil, nil, []models.OIDRange{{Min: 0, Max: 32}}},
+		{"testMac", "OCTET STRING", "MacAddress", "1x:", nil, nil, []models.OIDRange{{Min: 6, Max: 6}}},
+		{"testState", "INTEGER", "TestPortState", "", []models.OIDEnum{{Name: "disabled", Value: 1}, {Name: "blocking", Value: 2}, {Name: "forwarding", Value: 5}}, nil, nil},
+		{"testTemp", "Integer32", "TestTemperature", "d-1", nil, []models.OIDRange{{Min: -400, Max: 1250}}, nil},
+		{"testClock", "OCTET STRING", "DateAndTime", "2d-1d-1d,1d:1d:1d.1d,1a1d:1d", nil, nil, []models.OIDRange{{Min: 8, Max: 8}, {Min: 11, Max: 11}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oid := oids[tt.name]
+			if oid.BaseType != tt.base || oid.TextualConvention != tt.tc || oid.DisplayHint != tt.hint {
+				t.Errorf("got base=%q tc=%q hint=%q", oid.BaseType, oid.TextualConvention, oid.DisplayHint)
+			}
+			if !reflect.DeepEqual(oid.Enums, tt.enums) {
+				t.Errorf("Enums = %+v, want %+v", oid.Enums, tt.enums)
+			}
+			if !reflect.DeepEqual(oid.Ranges, tt.ranges) {
+				t.Errorf("Ranges = %+v, want %+v", oid.Ranges, tt.ranges)
+			}
+			if !reflect.DeepEqual(oid.Sizes, tt.sizes) {
+				t.Errorf("Sizes = %+v, want %+v", oid.Sizes, tt.sizes)
+			}
+		})
+	}
+
+	if types := modules[0].ModelTypes(); len(types) != 2 || !types[0].IsTC {
+		t.Errorf("ModelTypes() = %+v", types)
+	}
+}
+
+const testTableMIB = `
+TEST-TABLE-MIB DEFINITIONS ::= BEGIN
+
+IMPORTS
+    OBJECT-TYPE, Integer32, Counter64, enterprises FROM SNMPv2-SMI
+    DisplayString, MacAddress                      FROM SNMPv2-TC;
+
+testTables OBJECT IDENTIFIER ::= { enterprises 99998 }
+
+testPortTable OBJECT-TYPE
+    SYNTAX      SEQUENCE OF TestPortEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "Ports."
+    ::= { testTables 1 }
+
+testPortEntry OBJECT-TYPE
+    SYNTAX      TestPortEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "A port."
+    INDEX       { testPortIndex, IMPLIED testPortName }
+    ::= { testPortTable 1 }
+
+TestPortEntry ::= SEQUENCE {
+    testPortIndex Integer32,
+    testPortName  DisplayString
+}
+
+testPortIndex OBJECT-TYPE
+    SYNTAX      Integer32 (1..65535)
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "Index."
+    ::= { testPortEntry 1 }
+
+testPortName OBJECT-TYPE
+    SYNTAX      DisplayString
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "Name."
+    ::= { testPortEntry 2 }
+
+testPortXTable OBJECT-TYPE
+    SYNTAX      SEQUENCE OF TestPortXEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "Port extensions."
+    ::= { testTables 2 }
+
+testPortXEntry OBJECT-TYPE
+    SYNTAX      TestPortXEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "A port extension."
+    AUGMENTS    { testPortEntry }
+    ::= { testPortXTable 1 }
+
+TestPortXEntry ::= SEQUENCE { testPortHCOctets Counter64 }
+
+testPortHCOctets OBJECT-TYPE
+    SYNTAX      Counter64
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "Octets."
+    ::= { testPortXEntry 1 }
+
+testMacTable OBJECT-TYPE
+    SYNTAX      SEQUENCE OF TestMacEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "MACs."
+    ::= { testTables 3 }
+
+testMacEntry OBJECT-TYPE
+    SYNTAX      TestMacEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "A MAC."
+    INDEX       { testMacAddress }
+    ::= { testMacTable 1 }
+
+TestMacEntry ::= SEQUENCE { testMacAddress MacAddress }
+
+testMacAddress OBJECT-TYPE
+    SYNTAX      MacAddress
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "MAC."
+    ::= { testMacEntry 1 }
+
+testUptime OBJECT-TYPE
+    SYNTAX      Integer32
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "Uptime."
+    ::= { testTables 4 }
+
+END
+`
+
+func TestCompileMIBTables(t *testing.T) {
+	modules, err := NewMIBCompiler(nil, nil).CompileSource(testTableMIB)
+	if err != nil {
+		t.Fatalf("CompileSource() error = %v", err)
+	}
+
+	oids := make(map[string]models.OID)
+	for _, oid := range modules[0].ModelOIDs() {
+		oids[oid.Name] = oid
+	}
+
+	portIndexes := []models.OIDIndex{
+		{Name: "testPortIndex", OID: "1.3.6.1.4.1.99998.1.1.1", Type: "Integer32"},
+		{Name: "testPortName", OID: "1.3.6.1.4.1.99998.1.1.2", Type: "OCTET STRING", TextualConvention: "DisplayString", Implied: true},
+	}
+	macIndexes := []models.OIDIndex{
+		{Name: "testMacAddress", OID: "1.3.6.1.4.1.99998.3.1.1", Type: "OCTET STRING", TextualConvention: "MacAddress", FixedSize: 6},
+	}
+
+	tests := []struct {
+		name     string
+		kind     string
+		table    string
+		augments string
+		indexes  []models.OIDIndex
+	}{
+		{"testPortTable", "table", "", "", portIndexes},
+		{"testPortEntry", "row", "testPortTable", "", portIndexes},
+		{"testPortName", "column", "testPortTable", "", portIndexes},
+		{"testPortXEntry", "row", "testPortXTable", "testPortEntry", portIndexes},
+		{"testPortHCOctets", "column", "testPortXTable", "", portIndexes},
+		{"testMacAddress", "column", "testMacTable", "", macIndexes},
+		{"testUptime", "scalar", "", "", nil},
+		{"testTables", "", "", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oid := oids[tt.name]
+			if oid.Kind != tt.kind || oid.Table != tt.table || oid.Augments != tt.augments {
+				t.Errorf("got kind=%q table=%q augments=%q", oid.Kind, oid.Table, oid.Augments)
+			}
+			if !reflect.DeepEqual(oid.Indexes, tt.indexes) {
+				t.Errorf("Indexes = %+v, want %+v", oid.Indexes, tt.indexes)
+			}
+		})
+	}
+}
+
+const testTrapMIB = `
+TEST-TRAP-MIB DEFINITIONS ::= BEGIN
+
+IMPORTS
+    NOTIFICATION-TYPE, OBJECT-TYPE, Integer32, enterprises, snmpModules FROM SNMPv2-SMI
+    TRAP-TYPE                                                         FROM RFC-1215;
+
+testTraps      OBJECT IDENTIFIER ::= { enterprises 99997 }
+testTrapPrefix OBJECT IDENTIFIER ::= { testTraps 0 }
+
+testIfIndex OBJECT-TYPE
+    SYNTAX      Integer32
+    MAX-ACCESS  accessible-for-notify
+    STATUS      current
+    DESCRIPTION "Interface."
+    ::= { testTraps 1 }
+
+testLinkDown NOTIFICATION-TYPE
+    OBJECTS     { testIfIndex }
+    STATUS      current
+    DESCRIPTION "Link down."
+    ::= { testTrapPrefix 3 }
+
+testColdStart NOTIFICATION-TYPE
+    STATUS      current
+    DESCRIPTION "Cold start."
+    ::= { testTraps 7 }
+
+testV1Trap TRAP-TYPE
+    ENTERPRISE  testTraps
+    VARIABLES   { testIfIndex }
+    DESCRIPTION "SMIv1 trap."
+    ::= 5
+
+END
+`
+
+func TestCompileMIBNotifications(t *testing.T) {
+	modules, err := NewMIBCompiler(nil, nil).CompileSource(testTrapMIB)
+	if err != nil {
+		t.Fatalf("CompileSource() error = %v", err)
+	}
+
+	notifications := make(map[string]models.MIBNotification)
+	for _, n := range modules[0].ModelNotifications() {
+		notifications[n.Name] = n
+	}
+	if len(notifications) != 3 {
+		t.Fatalf("ModelNotifications() returned %d notifications, want 3", len(notifications))
+	}
+
+	ifIndex := []models.NotificationObject{{Name: "testIfIndex", OID: "1.3.6.1.4.1.99997.1"}}
+	tests := []struct {
+		name       string
+		oid        string
+		macro      string
+		enterprise string
+		specific   uint32
+		objects    []models.NotificationObject
+	}{
+		{"testLinkDown", "1.3.6.1.4.1.99997.0.3", "NOTIFICATION-TYPE", "1.3.6.1.4.1.99997", 3, ifIndex},
+		{"testColdStart", "1.3.6.1.4.1.99997.7", "NOTIFICATION-TYPE", "1.3.6.1.4.1.99997", 7, []models.NotificationObject{}},
+		{"testV1Trap", "1.3.6.1.4.1.99997.0.5", "TRAP-TYPE", "1.3.6.1.4.1.99997", 5, ifIndex},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := notifications[tt.name]
+			if n.OID != tt.oid || n.Macro != tt.macro || n.Enterprise != tt.enterprise || n.SpecificTrap != tt.specific {
+				t.Errorf("got %+v", n)
+			}
+			if !reflect.DeepEqual(n.Objects, tt.objects) {
+				t.Errorf("Objects = %+v, want %+v", n.Objects, tt.objects)
+			}
+		})
+	}
+}
+
+func writeTestZip(t *testing.T, files map[string]string) string {
+	t.Helper()
+	archivePath := filepath.Join(t.TempDir(), "bundle.zip")
+	f, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return archivePath
+}
+
+func TestExtractMIBBundle(t *testing.T) {
+	archivePath := writeTestZip(t, map[string]string{
+		"vendor/TEST-IF-MIB.mib": testIfMIB,
+		"vendor/README.pdf":      "not a mib",
+		"__MACOSX/._TEST-IF-MIB": "resource fork",
+	})
+	names, err := extractMIBBundle(archivePath, filepath.Join(t.TempDir(), "files"))
+	if err != nil {
+		t.Fatalf("extractMIBBundle() error = %v", err)
+	}
+	if !reflect.DeepEqual(names, []string{"vendor/TEST-IF-MIB.mib"}) {
+		t.Errorf("names = %v", names)
+	}
+
+	for _, name := range []string{"../evil.mib", "/etc/evil.mib", "a/../../evil.mib"} {
+		archivePath := writeTestZip(t, map[string]string{name: testIfMIB})
+		if _, err := extractMIBBundle(archivePath, filepath.Join(t.TempDir(), "files")); err == nil {
+			t.Errorf("extractMIBBundle(%q) expected error", name)
+		}
+	}
+}
+
+func TestOrderMIBBundle(t *testing.T) {
+	parse := func(name, src string) *mibBundleFile {
+		modules, err := ParseMIBSource(src)
+		if err != nil {
+			t.Fatalf("ParseMIBSource(%s) error = %v", name, err)
+		}
+		return &mibBundleFile{name: name, modules: modules}
+	}
+	agent := parse("agent.mib", `
+TEST-AGENT-MIB DEFINITIONS ::= BEGIN
+IMPORTS testIfMIB FROM TEST-IF-MIB;
+testAgent OBJECT IDENTIFIER ::= { testIfMIB 9 }
+END
+`)
+	ifMIB := parse("if.mib", testIfMIB)
+	broken := &mibBundleFile{name: "broken.mib"}
+
+	var got []string
+	for _, f := range orderMIBBundle([]*mibBundleFile{agent, broken, ifMIB}) {
+		got = append(got, f.name)
+	}
+	want := []string{"if.mib", "agent.mib", "broken.mib"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("orderMIBBundle() = %v, want %v", got, want)
+	}
+}
+
+func TestMIBUploadJobFailures(t *testing.T) {
+	s := newTestMIBService(t, &models.MIBUploadJob{})
+
+	// 导入过程中 panic 时任务标记为失败, 不会一直停在 running (没有编译器的服务在编译时 panic)
+	job := &models.MIBUploadJob{Filename: "bundle.zip", Status: "pending"}
+	s.db.Create(job)
+	archivePath := writeTestZip(t, map[string]string{"TEST-IF-MIB.mib": testIfMIB})
+	(&MIBService{db: s.db}).processMIBBundle(job, archivePath, filepath.Join(t.TempDir(), "files"))
+	saved, err := s.GetMIBUploadJob(job.ID)
+	if err != nil || saved.Status != "failed" || !strings.Contains(saved.ErrorMsg, "panicked") || saved.CompletedAt == nil {
+		t.Errorf("panicked job = %+v, %v", saved, err)
+	}
+
+	// 重启前未完成的任务在启动时标记为失败
+	for _, status := range []string{"pending", "running", "completed"} {
+		s.db.Create(&models.MIBUploadJob{Filename: status + ".zip", Status: status})
+	}
+	if n, err := s.FailInterruptedUploadJobs(); err != nil || n != 2 {
+		t.Errorf("FailInterruptedUploadJobs() = %d, %v, want 2", n, err)
+	}
+	var jobs []models.MIBUploadJob
+	s.db.Order("id").Find(&jobs)
+	for _, job := range jobs[1:] {
+		want := "failed"
+		if job.Filename == "completed.zip" {
+			want = "completed"
+		}
+		if job.Status != want || want == "failed" && !strings.Contains(job.ErrorMsg, "restart") {
+			t.Errorf("job %s = %s %q", job.Filename, job.Status, job.ErrorMsg)
+		}
+	}
+}
+
+const testLintMIB = `
+TEST-LINT-MIB DEFINITIONS ::= BEGIN
+
+IMPORTS
+    MODULE-IDENTITY, OBJECT-TYPE, NOTIFICATION-TYPE, Gauge32,
+    enterprises, Bogus32                    FROM SNMPv2-SMI
+    Counter                                 FROM RFC1155-SMI;
+
+testLintMIB MODULE-IDENTITY
+    LAST-UPDATED "202401010000Z"
+    ORGANIZATION "none"
+    CONTACT-INFO "none"
+    DESCRIPTION  "Lint test module."
+    REVISION     "202001010000Z"
+    DESCRIPTION  "Older revision listed first."
+    REVISION     "202401010000Z"
+    DESCRIPTION  "Newest revision."
+    ::= { enterprises 99996 }
+
+testQueueDepth OBJECT-TYPE
+    SYNTAX      Gauge32
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "Queue depth."
+    ::= { testLintMIB 1 }
+
+test_index OBJECT-TYPE
+    SYNTAX      Counter
+    ACCESS      not-accessible
+    STATUS      current
+    DESCRIPTION "Index."
+    ::= { testLintMIB 2 }
+
+testAlias OBJECT-TYPE
+    SYNTAX      Gauge32
+    UNITS       "packets"
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "Same OID as testQueueDepth."
+    ::= { testLintMIB 1 }
+
+testEvent NOTIFICATION-TYPE
+    OBJECTS     { test_index }
+    STATUS      current
+    DESCRIPTION "Event."
+    ::= { testLintMIB 3 }
+
+END
+`
+
+func TestLintMIB(t *testing.T) {
+	report := NewMIBLinter(NewMIBCompiler(nil, nil), nil).Lint(testLintMIB)
+	if report.Valid {
+		t.Error("Valid = true, want false")
+	}
+
+	got := make(map[string]int)
+	for _, f := range report.Findings {
+		got[f.Rule+" "+f.Symbol]++
+		if f.Line == 0 {
+			t.Errorf("finding without line: %+v", f)
+		}
+	}
+	for _, want := range []string{
+		"import-undefined Bogus32",
+		"smi-mixing RFC1155-SMI",
+		"smi-mixing test_index",
+		"identifier-invalid test_index",
+		"duplicate-oid testAlias",
+		"gauge-units testQueueDepth",
+		"revision-order testLintMIB",
+		"notification-not-accessible test_index",
+	} {
+		if got[want] == 0 {
+			t.Errorf("missing finding %q in %+v", want, report.Findings)
+		}
+	}
+	if got["gauge-units testAlias"] != 0 {
+		t.Errorf("unexpected gauge-units finding for testAlias")
+	}
+
+	linter := NewMIBLinter(NewMIBCompiler(nil, nil), nil)
+	if err := linter.SetSeverity("duplicate-oid", MIBLintOff); err != nil {
+		t.Fatal(err)
+	}
+	if err := linter.SetMinimumSeverity(MIBLintWarning); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range linter.Lint(testLintMIB).Findings {
+		if f.Rule == "duplicate-oid" || f.Severity == MIBLintInfo {
+			t.Errorf("finding should be filtered: %+v", f)
+		}
+	}
+	if err := linter.SetSeverity("no-such-rule", MIBLintError); err == nil {
+		t.Error("SetSeverity() expected error for unknown rule")
+	}
+
+	syntax := NewMIBLinter(NewMIBCompiler(nil, nil), nil).Lint("BROKEN DEFINITIONS ::= BEGIN\nfoo OBJECT-TYPE\n")
+	if syntax.Valid || len(syntax.Findings) != 1 || syntax.Findings[0].Rule != "syntax" || syntax.Findings[0].Line == 0 {
+		t.Errorf("syntax findings = %+v", syntax.Findings)
+	}
+}
+
+func TestMIBRevisionsAndDiff(t *testing.T) {
+	compile := func(src string) *models.MIB {
+		modules, err := NewMIBCompiler(nil, nil).CompileSource(src)
+		if err != nil {
+			t.Fatalf("CompileSource() error = %v", err)
+		}
+		mib := &models.MIB{}
+		applyCompiledMIB(mib, modules)
+		return mib
+	}
+
+	old := compile(testIfMIB)
+	if old.Version != "200006140000Z" || old.LastUpdated == nil || old.LastUpdated.Year() != 2000 {
+		t.Errorf("Version = %q, LastUpdated = %v", old.Version, old.LastUpdated)
+	}
+	if len(old.Revisions) != 1 || old.Revisions[0].Description != "Clarifications." || old.Revisions[0].Date == nil {
+		t.Errorf("Revisions = %+v", old.Revisions)
+	}
+
+	newSrc := strings.Replace(testIfMIB, `LAST-UPDATED "200006140000Z"`, `LAST-UPDATED "201001010000Z"`, 1)
+	newSrc = strings.Replace(newSrc, `    REVISION     "200006140000Z"`, `    REVISION     "201001010000Z"
+    DESCRIPTION  "Firmware 2.0."
+    REVISION     "200006140000Z"`, 1)
+	newSrc = strings.Replace(newSrc, "SYNTAX      Counter32", "SYNTAX      Counter64", 1)
+	newSrc = strings.Replace(newSrc, `UNITS       "octets"`, `UNITS       "bytes"`, 1)
+	updated := compile(newSrc)
+
+	diff := DiffMIBVersions(old, updated)
+	if len(diff.NewRevisions) != 1 || diff.NewRevisions[0].Revision != "201001010000Z" {
+		t.Errorf("NewRevisions = %+v", diff.NewRevisions)
+	}
+	if len(diff.Added) != 0 || len(diff.Removed) != 0 {
+		t.Errorf("Added = %+v, Removed = %+v", diff.Added, diff.Removed)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].Name != "ifInOctets" || !diff.Changed[0].Breaking {
+		t.Fatalf("Changed = %+v", diff.Changed)
+	}
+	if len(diff.SyntaxChanges) != 1 || len(diff.SyntaxChanges[0].Changes) != 2 {
+		t.Errorf("SyntaxChanges = %+v", diff.SyntaxChanges)
+	}
+
+	reverse := DiffMIBVersions(updated, compile(testTrapMIB))
+	if !reverse.Breaking || len(reverse.Removed) == 0 || len(reverse.Added) == 0 {
+		t.Errorf("expected added and removed objects, got %+v", reverse)
+	}
+}
+
+func TestUploadFileName(t *testing.T) {
+	for in, want := range map[string]string{
+		"IF-MIB.mib":             "IF-MIB.mib",
+		"../../etc/cron.d/x.mib": "x.mib",
+		"..\\..\\evil.mib":       "evil.mib",
+		"/opt/monitoring/IF-MIB": "IF-MIB",
+		"vendor/bundle.tar.gz":   "bundle.tar.gz",
+		"":                       "",
+		"..":                     "",
+		"../..":                  "",
+		".hidden.mib":            "",
+		"mibs/.bashrc":           "",
+		"/":                      "",
+	} {
+		got, err := uploadFileName(in)
+		if got != want || (err != nil) != (want == "") {
+			t.Errorf("uploadFileName(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+}
+
+// newTestDB 创建内存 SQLite 数据库并迁移给定的表
+func newTestDB(t *testing.T, tables ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 每个连接都是独立的内存数据库
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(tables...); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// newTestMIBService 创建 MIBService, 迁移 MIB 库的表以及 extra 中功能自己的表
+func newTestMIBService(t *testing.T, extra ...interface{}) *MIBService {
+	t.Helper()
+	tables := []interface{}{&models.MIB{}, &models.OID{}, &models.MIBImport{}, &models.MIBType{}, &models.MIBNotification{}, &models.MIBGroup{}, &models.MIBCompliance{}, &models.MIBIngestEvent{}}
+	return NewMIBService(newTestDB(t, append(tables, extra...)...))
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"mib-platform/models"
+)
+
+const testDepBaseMIB = `
+TEST-DEP-BASE-MIB DEFINITIONS ::= BEGIN
+
+IMPORTS
+    OBJECT-TYPE, Integer32, enterprises FROM SNMPv2-SMI;
+
+testDepBase OBJECT IDENTIFIER ::= { enterprises 99980 }
+
+END
+`
+
+const testDepChildMIB = `
+TEST-DEP-CHILD-MIB DEFINITIONS ::= BEGIN
+
+IMPORTS
+    OBJECT-TYPE, Integer32 FROM SNMPv2-SMI
+    testDepBase            FROM TEST-DEP-BASE-MIB
+    testDepVendor          FROM TEST-DEP-VENDOR-MIB;
+
+depChildCount OBJECT-TYPE
+    SYNTAX      Integer32
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "Count."
+    ::= { testDepBase 1 }
+
+depVendorCount OBJECT-TYPE
+    SYNTAX      Integer32
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "Count under a module that is not loaded yet."
+    ::= { testDepVendor 1 }
+
+END
+`
+
+const testDepVendorMIB = `
+TEST-DEP-VENDOR-MIB DEFINITIONS ::= BEGIN
+
+IMPORTS
+    enterprises FROM SNMPv2-SMI;
+
+testDepVendor OBJECT IDENTIFIER ::= { enterprises 99981 }
+
+END
+`
+
+// testDepCycleMIB 两个互相导入的模块
+const testDepCycleMIB = `
+TEST-DEP-CYCLE-%s-MIB DEFINITIONS ::= BEGIN
+
+IMPORTS
+    enterprises FROM SNMPv2-SMI
+    cycle%s     FROM TEST-DEP-CYCLE-%s-MIB;
+
+cycle%s OBJECT IDENTIFIER ::= { enterprises %d }
+
+END
+`
+
+// createTestDependencyMIB 编译并保存 MIB, 导入的符号从 MIB 库中已有的模块解析; 源文件留给重新解析使用
+func createTestDependencyMIB(t *testing.T, s *MIBService, src string) *models.MIB {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.mib")
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modules, err := s.compiler.CompileFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mib := &models.MIB{Filename: modules[0].AST.Name + ".mib", FilePath: path}
+	if err := s.createParsedMIB(mib, modules); err != nil {
+		t.Fatal(err)
+	}
+	return mib
+}
+
+// depOID 返回模块中对象入库的 OID
+func depOID(t *testing.T, s *MIBService, module, name string) string {
+	t.Helper()
+	oid, _ := s.lookupImportedSymbol(module, name)
+	if oid == nil {
+		t.Fatalf("%s::%s not stored", module, name)
+	}
+	return oid.OID
+}
+
+func TestMIBDependencies(t *testing.T) {
+	s := newTestMIBService(t)
+	createTestDependencyMIB(t, s, testDepBaseMIB)
+	child := createTestDependencyMIB(t, s, testDepChildMIB)
+
+	// 从另一个已入库模块导入的符号跨模块解析
+	if oid := depOID(t, s, "TEST-DEP-CHILD-MIB", "depChildCount"); oid != "1.3.6.1.4.1.99980.1" {
+		t.Errorf("depChildCount = %q", oid)
+	}
+
+	report, err := s.GetMIBDependencies(child.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.LoadOrder, []string{"TEST-DEP-BASE-MIB", "TEST-DEP-CHILD-MIB"}) {
+		t.Errorf("LoadOrder = %v", report.LoadOrder)
+	}
+	if !reflect.DeepEqual(report.Missing, []string{"TEST-DEP-VENDOR-MIB"}) || report.Unresolved == "" {
+		t.Errorf("Missing = %v, Unresolved = %q", report.Missing, report.Unresolved)
+	}
+	statuses := make(map[string]string)
+	for _, dep := range report.Tree.Dependencies {
+		statuses[dep.Module] = dep.Status
+	}
+	if !reflect.DeepEqual(statuses, map[string]string{"SNMPv2-SMI": "builtin", "TEST-DEP-BASE-MIB": "loaded", "TEST-DEP-VENDOR-MIB": "missing"}) {
+		t.Errorf("dependency statuses = %v", statuses)
+	}
+
+	missing, err := s.GetMissingDependencies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 1 || missing[0].Module != "TEST-DEP-CHILD-MIB" || !reflect.DeepEqual(missing[0].Missing, []string{"TEST-DEP-VENDOR-MIB"}) {
+		t.Errorf("GetMissingDependencies() = %+v", missing)
+	}
+
+	// 缺失的模块入库后, 依赖它的 MIB 重新解析
+	createTestDependencyMIB(t, s, testDepVendorMIB)
+	if oid := depOID(t, s, "TEST-DEP-CHILD-MIB", "depVendorCount"); oid != "1.3.6.1.4.1.99981.1" {
+		t.Errorf("depVendorCount after loading its module = %q", oid)
+	}
+	if missing, err := s.GetMissingDependencies(); err != nil || len(missing) != 0 {
+		t.Errorf("GetMissingDependencies() after loading = %+v, %v", missing, err)
+	}
+}
+
+func TestMIBDependencyCycle(t *testing.T) {
+	s := newTestMIBService(t)
+	a := createTestDependencyMIB(t, s, fmt.Sprintf(testDepCycleMIB, "A", "B", "B", "A", 99982))
+	b := createTestDependencyMIB(t, s, fmt.Sprintf(testDepCycleMIB, "B", "A", "A", "B", 99983))
+
+	// 循环依赖在树中标记出来, 不会无限递归
+	report, err := s.GetMIBDependencies(a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var path []string
+	for node := report.Tree; node != nil; {
+		path = append(path, node.Module+"/"+node.Status)
+		var next *MIBDependencyNode
+		for _, dep := range node.Dependencies {
+			if dep.Module != "SNMPv2-SMI" {
+				next = dep
+			}
+		}
+		node = next
+	}
+	want := []string{"TEST-DEP-CYCLE-A-MIB/loaded", "TEST-DEP-CYCLE-B-MIB/loaded", "TEST-DEP-CYCLE-A-MIB/cycle"}
+	if !reflect.DeepEqual(path, want) {
+		t.Errorf("dependency path = %v, want %v", path, want)
+	}
+
+	// 加载顺序稳定: 每个模块只出现一次, 请求的模块在最后
+	for _, tt := range []struct {
+		mib  *models.MIB
+		want []string
+	}{
+		{a, []string{"TEST-DEP-CYCLE-B-MIB", "TEST-DEP-CYCLE-A-MIB"}},
+		{b, []string{"TEST-DEP-CYCLE-A-MIB", "TEST-DEP-CYCLE-B-MIB"}},
+	} {
+		for i := 0; i < 3; i++ {
+			report, err := s.GetMIBDependencies(tt.mib.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(report.LoadOrder, tt.want) || len(report.Missing) != 0 {
+				t.Errorf("%s: LoadOrder = %v, Missing = %v", tt.mib.Name, report.LoadOrder, report.Missing)
+			}
+		}
+	}
+}
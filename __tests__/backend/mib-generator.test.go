@@ -0,0 +1,113 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"mib-platform/models"
+)
+
+func TestExportGeneratorConfig(t *testing.T) {
+	s := newTestMIBService(t)
+	dir := t.TempDir()
+	for name, content := range map[string]string{"TEST-IF-MIB.mib": testIfMIB, "TEST-TRAP-MIB.mib": testTrapMIB} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		modules, err := s.compiler.CompileFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.createParsedMIB(&models.MIB{Filename: name, FilePath: path}, modules); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req := GeneratorConfig{
+		Auths: map[string]GeneratorAuth{
+			"public_v2": {Community: "public", Version: 2},
+			"secure_v3": {Version: 3, Username: "monitor", SecurityLevel: "authPriv", Password: "secret123",
+				AuthProtocol: "SHA", PrivProtocol: "AES", PrivPassword: "secret456"},
+		},
+		Modules: map[string]GeneratorModule{
+			"test_if": {
+				Walk: []string{"ifInOctets", "TEST-IF-MIB::ifTable", "ifOperStatus"},
+				Lookups: []GeneratorLookup{
+					{SourceIndexes: []string{"ifIndex"}, Lookup: "ifOperStatus"},
+				},
+				Overrides: map[string]GeneratorOverride{
+					"ifOperStatus": {Type: "EnumAsStateSet"},
+					"ifIndex": {RegexExtracts: map[string][]GeneratorRegex{
+						"Index": {{Regex: "^(\\d+)$", Value: "$1"}},
+					}},
+				},
+				MaxRepetitions: 25,
+			},
+		},
+	}
+	export, err := s.ExportGeneratorConfig(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var parsed GeneratorConfig
+	if err := yaml.Unmarshal([]byte(export.YAML), &parsed); err != nil {
+		t.Fatalf("generated YAML does not parse: %v\n%s", err, export.YAML)
+	}
+	module := parsed.Modules["test_if"]
+	if !reflect.DeepEqual(module.Walk, []string{"ifTable"}) {
+		t.Errorf("walk = %v, want [ifTable]", module.Walk)
+	}
+	if len(module.Lookups) != 1 || module.Lookups[0].Lookup != "ifOperStatus" || module.Overrides["ifOperStatus"].Type != "EnumAsStateSet" {
+		t.Errorf("lookups = %+v, overrides = %+v", module.Lookups, module.Overrides)
+	}
+	if parsed.Auths["secure_v3"].PrivProtocol != "AES" || module.MaxRepetitions != 25 {
+		t.Errorf("auths = %+v", parsed.Auths)
+	}
+	if len(export.MIBs) != 1 || export.MIBs[0].Module != "TEST-IF-MIB" {
+		t.Errorf("mibs = %+v", export.MIBs)
+	}
+
+	data, err := export.Archive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"generator.yml", "mibs/TEST-IF-MIB.mib"}) {
+		t.Errorf("archive files = %v", names)
+	}
+
+	bad := GeneratorConfig{
+		Auths: map[string]GeneratorAuth{"v3": {Version: 3, SecurityLevel: "authPriv", Password: "x"}},
+		Modules: map[string]GeneratorModule{
+			"bad": {
+				Walk:      []string{"noSuchObject"},
+				Overrides: map[string]GeneratorOverride{"ifIndex": {Type: "String"}},
+			},
+		},
+	}
+	_, err = s.ExportGeneratorConfig(bad)
+	if err == nil {
+		t.Fatal("invalid config accepted")
+	}
+	for _, want := range []string{"username is required", "priv_password is required", "noSuchObject", "unsupported type String"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
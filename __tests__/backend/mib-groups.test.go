@@ -0,0 +1,124 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"mib-platform/models"
+)
+
+const testConformanceMIB = `
+TEST-IF-CONF-MIB DEFINITIONS ::= BEGIN
+
+IMPORTS
+    NOTIFICATION-TYPE                          FROM SNMPv2-SMI
+    MODULE-COMPLIANCE, OBJECT-GROUP,
+    NOTIFICATION-GROUP                         FROM SNMPv2-CONF
+    testIfMIB, ifIndex, ifOperStatus,
+    ifInOctets                                 FROM TEST-IF-MIB;
+
+testIfNotifications OBJECT IDENTIFIER ::= { testIfMIB 0 }
+testIfConformance   OBJECT IDENTIFIER ::= { testIfMIB 2 }
+testIfGroups        OBJECT IDENTIFIER ::= { testIfConformance 1 }
+testIfCompliances   OBJECT IDENTIFIER ::= { testIfConformance 2 }
+
+testLinkDown NOTIFICATION-TYPE
+    OBJECTS     { ifIndex, ifOperStatus }
+    STATUS      current
+    DESCRIPTION "A link went down."
+    ::= { testIfNotifications 1 }
+
+testIfGeneralGroup OBJECT-GROUP
+    OBJECTS     { ifIndex, ifOperStatus }
+    STATUS      current
+    DESCRIPTION "General interface information."
+    ::= { testIfGroups 1 }
+
+testIfCounterGroup OBJECT-GROUP
+    OBJECTS     { ifInOctets }
+    STATUS      current
+    DESCRIPTION "Interface counters."
+    ::= { testIfGroups 2 }
+
+testIfNotificationGroup NOTIFICATION-GROUP
+    NOTIFICATIONS { testLinkDown }
+    STATUS      current
+    DESCRIPTION "Interface notifications."
+    ::= { testIfGroups 3 }
+
+testIfCompliance MODULE-COMPLIANCE
+    STATUS      current
+    DESCRIPTION "The compliance statement for interfaces."
+    MODULE  -- this module
+        MANDATORY-GROUPS { testIfGeneralGroup, testIfNotificationGroup }
+
+        GROUP       testIfCounterGroup
+        DESCRIPTION "Mandatory for interfaces with counters."
+
+        OBJECT      ifOperStatus
+        SYNTAX      INTEGER { up(1), down(2) }
+        MIN-ACCESS  not-accessible
+        DESCRIPTION "Need not be implemented."
+    ::= { testIfCompliances 1 }
+
+END
+`
+
+func TestMIBMetricSets(t *testing.T) {
+	s := newTestMIBService(t)
+	for _, src := range []string{testIfMIB, testConformanceMIB} {
+		modules, err := s.compiler.CompileSource(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.createParsedMIB(&models.MIB{Filename: "test.mib", FilePath: "test.mib"}, modules); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var compliance models.MIBCompliance
+	if err := s.db.Where("name = ?", "testIfCompliance").First(&compliance).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := []models.MIBComplianceModule{{
+		MandatoryGroups: []string{"testIfGeneralGroup", "testIfNotificationGroup"},
+		Groups:          []string{"testIfCounterGroup"},
+		Objects:         []models.MIBComplianceObject{{Name: "ifOperStatus", Syntax: "INTEGER { up(1), down(2) }", MinAccess: "not-accessible"}},
+	}}
+	if compliance.Status != "current" || !reflect.DeepEqual(compliance.Modules, want) {
+		t.Errorf("compliance = %+v", compliance)
+	}
+
+	sets, err := s.GetMetricSets("", "TEST-IF-CONF-MIB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[string]int)
+	for _, set := range sets {
+		counts[set.Kind+":"+set.Name] = set.ObjectCount
+	}
+	wantCounts := map[string]int{"compliance:testIfCompliance": 1, "group:testIfGeneralGroup": 2, "group:testIfCounterGroup": 1}
+	if !reflect.DeepEqual(counts, wantCounts) {
+		t.Errorf("metric sets = %v, want %v", counts, wantCounts)
+	}
+
+	expansion, err := s.ExpandMetricSets(MIBMetricSetRequest{Compliances: []string{"TEST-IF-CONF-MIB::testIfCompliance"}, IncludeOptional: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ifIndex, ifInOctets; ifOperStatus 的 MIN-ACCESS 为 not-accessible, testLinkDown 是通知
+	if !reflect.DeepEqual(expansion.OIDs, []string{"1.3.6.1.2.1.2.2.1.1", "1.3.6.1.2.1.2.2.1.10"}) {
+		t.Errorf("OIDs = %v", expansion.OIDs)
+	}
+	if len(expansion.Skipped) != 2 || len(expansion.Unresolved) != 0 {
+		t.Errorf("skipped = %+v, unresolved = %v", expansion.Skipped, expansion.Unresolved)
+	}
+
+	expansion, err = s.ExpandMetricSets(MIBMetricSetRequest{Groups: []string{"testIfGeneralGroup", "NO-MIB::noGroup"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expansion.OIDs) != 2 || !reflect.DeepEqual(expansion.Unresolved, []string{"NO-MIB::noGroup"}) {
+		t.Errorf("group expansion = %+v", expansion)
+	}
+}
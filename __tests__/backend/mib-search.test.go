@@ -0,0 +1,103 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"mib-platform/models"
+)
+
+func TestMIBSearchIndex(t *testing.T) {
+	s := newTestMIBService(t)
+
+	for _, src := range []string{testIfMIB, testTrapMIB} {
+		modules, err := s.compiler.CompileSource(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.createParsedMIB(&models.MIB{Filename: "test.mib", FilePath: "test.mib"}, modules); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := s.SearchOIDs(MIBSearchQuery{Query: "octets received"})
+	if err != nil {
+		t.Fatalf("SearchOIDs() error = %v", err)
+	}
+	if len(result.Hits) == 0 || result.Hits[0].Name != "ifInOctets" {
+		t.Fatalf("mode %s hits = %+v", result.Mode, result.Hits)
+	}
+	if len(result.Modules) != 1 || result.Modules[0].Value != "TEST-IF-MIB" || len(result.Vendors) != 1 || result.Vendors[0].Value != "standard" {
+		t.Errorf("facets = %+v %+v", result.Modules, result.Vendors)
+	}
+
+	prefix, err := s.SearchOIDs(MIBSearchQuery{Query: "1.3.6.1.4.1.99997", Vendor: "enterprise-99997"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prefix.Mode != "oid-prefix" || prefix.Total == 0 || prefix.Hits[0].OID != "1.3.6.1.4.1.99997" {
+		t.Errorf("prefix result = %+v", prefix)
+	}
+
+	// 删除后索引同步移除
+	if err := s.DeleteMIB(1); err != nil {
+		t.Fatal(err)
+	}
+	result, err = s.SearchOIDs(MIBSearchQuery{Query: "octets"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 0 {
+		t.Errorf("deleted MIB still searchable: %+v", result.Hits)
+	}
+}
+
+func TestMIBSearchLikeFallback(t *testing.T) {
+	s := newTestMIBService(t)
+	modules, err := s.compiler.CompileSource(testIfMIB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.createParsedMIB(&models.MIB{Filename: "test.mib", FilePath: "test.mib"}, modules); err != nil {
+		t.Fatal(err)
+	}
+
+	// 未使用 sqlite_fts5 构建时 FTS 表建不出来, 索引只做 LIKE 查询
+	idx := &MIBSearchIndex{db: s.db}
+	idx.once.Do(func() {})
+	if idx.Available() {
+		t.Fatal("Available() = true without the FTS table")
+	}
+	result, err := idx.Search(MIBSearchQuery{Query: "interface"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Mode != "like" || !strings.Contains(result.Warning, "sqlite_fts5") {
+		t.Errorf("mode = %q, warning = %q", result.Mode, result.Warning)
+	}
+	// 名称命中排在只有描述命中的对象之前
+	if result.Total < 3 || result.Hits[0].Name != "interfaces" {
+		t.Errorf("hits = %+v", result.Hits)
+	}
+
+	// 数字前缀查询不依赖 FTS5, 不带警告
+	prefix, err := idx.Search(MIBSearchQuery{Query: "1.3.6.1.2.1.2"})
+	if err != nil || prefix.Mode != "oid-prefix" || prefix.Warning != "" || prefix.Total == 0 {
+		t.Errorf("prefix result = %+v, %v", prefix, err)
+	}
+}
+
+func TestSplitMIBIdentifier(t *testing.T) {
+	tests := map[string]string{
+		"cOpticalRxPower":      "c optical rx power",
+		"ifHCInOctets":         "if hc in octets",
+		"hwEntityTemp2":        "hw entity temp 2",
+		"dot1dBasePortIfIndex": "dot 1 d base port if index",
+		"snmp-engine_id":       "snmp engine id",
+	}
+	for in, want := range tests {
+		if got := splitMIBIdentifier(in); got != want {
+			t.Errorf("splitMIBIdentifier(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
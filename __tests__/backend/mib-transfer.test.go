@@ -0,0 +1,185 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mib-platform/models"
+)
+
+func TestImportExportMIBs(t *testing.T) {
+	mibImportDir = t.TempDir()
+	src := newTestMIBService(t)
+	dir := t.TempDir()
+	for name, content := range map[string]string{"TEST-IF-MIB.mib": testIfMIB, "TEST-TRAP-MIB.mib": testTrapMIB} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		modules, err := src.compiler.CompileFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checksum, _ := fileChecksum(path)
+		if err := src.createParsedMIB(&models.MIB{Filename: name, FilePath: path, Checksum: checksum}, modules); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, filename, err := src.ExportMIBs(nil, "csv")
+	if err != nil || filename != "mibs_export.csv" {
+		t.Fatalf("ExportMIBs(csv) = %q, %v", filename, err)
+	}
+	var oidCount int64
+	src.db.Model(&models.OID{}).Count(&oidCount)
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil || int64(len(records)) != oidCount+1 {
+		t.Errorf("csv has %d records (%v), want %d", len(records), err, oidCount+1)
+	}
+
+	archive, _, err := src.ExportMIBs(nil, "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 每个 OID 一行的导出 CSV 不能再导入为 MIB
+	dst := newTestMIBService(t)
+	if report, err := dst.ImportMIBs(bytes.NewReader(data), "csv", false); err == nil || !strings.Contains(err.Error(), "archive") {
+		t.Errorf("ImportMIBs(exported csv) = %+v, %v, want error pointing to archive format", report, err)
+	}
+	var count int64
+	dst.db.Model(&models.MIB{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("exported csv import created %d MIBs", count)
+	}
+
+	report, err := dst.ImportMIBs(bytes.NewReader(archive), "archive", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst.db.Model(&models.MIB{}).Count(&count)
+	if report.Created != 2 || report.Committed || count != 0 {
+		t.Errorf("dry run: report = %+v, %d MIBs saved", report, count)
+	}
+
+	report, err = dst.ImportMIBs(bytes.NewReader(archive), "archive", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Created != 2 || !report.Committed {
+		t.Fatalf("import: report = %+v", report)
+	}
+	var imported int64
+	dst.db.Model(&models.OID{}).Count(&imported)
+	if imported != oidCount {
+		t.Errorf("imported %d OIDs, want %d", imported, oidCount)
+	}
+	var trap models.MIB
+	dst.db.Where("name = ?", "TEST-TRAP-MIB").First(&trap)
+	if trap.ErrorMsg != "" {
+		t.Errorf("cross-module import unresolved: %s", trap.ErrorMsg)
+	}
+
+	report, err = dst.ImportMIBs(bytes.NewReader(archive), "archive", false)
+	if err != nil || report.Skipped != 2 {
+		t.Errorf("re-import: report = %+v, %v", report, err)
+	}
+
+	csvData := "name,description,author\nTEST-IF-MIB,new description,ops\n,missing name,\n"
+	report, err = dst.ImportMIBs(strings.NewReader(csvData), "csv", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Committed || report.Updated != 1 || report.Errors != 1 || report.Rows[1].Action != "error" {
+		t.Errorf("csv import: report = %+v", report)
+	}
+	var mib models.MIB
+	dst.db.Where("name = ?", "TEST-IF-MIB").First(&mib)
+	if mib.Description == "new description" {
+		t.Error("failed import was not rolled back")
+	}
+
+	// 没有标题行时按列顺序读取, 第一行也是数据
+	headerless := "TEST-IF-MIB,,headerless description,ops\nNEW-MIB,new.mib,created,ops,1.0\n"
+	report, err = dst.ImportMIBs(strings.NewReader(headerless), "csv", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Rows) != 2 || report.Updated != 1 || report.Created != 1 || report.Rows[0].Name != "TEST-IF-MIB" {
+		t.Errorf("headerless csv import: report = %+v", report)
+	}
+
+	jsonData, _, err := src.ExportMIBs(nil, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err = newTestMIBService(t).ImportMIBs(bytes.NewReader(jsonData), "json", false)
+	if err != nil || report.Created != 2 || !report.Committed {
+		t.Errorf("json import: report = %+v, %v", report, err)
+	}
+}
+
+func TestImportMIBsDryRun(t *testing.T) {
+	mibImportDir = t.TempDir()
+	src := newTestMIBService(t)
+	path := filepath.Join(t.TempDir(), "TEST-IF-MIB.mib")
+	if err := os.WriteFile(path, []byte(testIfMIB), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modules, err := src.compiler.CompileFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checksum, _ := fileChecksum(path)
+	if err := src.createParsedMIB(&models.MIB{Filename: "TEST-IF-MIB.mib", FilePath: path, Checksum: checksum}, modules); err != nil {
+		t.Fatal(err)
+	}
+	archive, _, err := src.ExportMIBs(nil, "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonData, _, err := src.ExportMIBs(nil, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// dry run 返回与真实导入相同的报告, 但不写数据库也不保存源文件
+	dst := newTestMIBService(t)
+	for format, data := range map[string][]byte{
+		"archive": archive,
+		"json":    jsonData,
+		"csv":     []byte("name,filename,description\nNEW-MIB,new.mib,created\n"),
+	} {
+		report, err := dst.ImportMIBs(bytes.NewReader(data), format, true)
+		if err != nil || !report.DryRun || report.Committed || report.Created != 1 {
+			t.Errorf("ImportMIBs(%s, dry run) = %+v, %v", format, report, err)
+		}
+	}
+	for _, table := range []interface{}{&models.MIB{}, &models.OID{}, &models.MIBImport{}, &models.MIBType{}, &models.MIBNotification{}} {
+		var count int64
+		dst.db.Model(table).Count(&count)
+		if count != 0 {
+			t.Errorf("dry run wrote %d rows to %T", count, table)
+		}
+	}
+	if entries, err := os.ReadDir(mibImportDir); err != nil || len(entries) != 0 {
+		t.Errorf("dry run saved source files: %v, %v", entries, err)
+	}
+
+	// 更新已有 MIB 的 dry run 也不修改记录
+	var before models.MIB
+	src.db.Where("name = ?", "TEST-IF-MIB").First(&before)
+	report, err := src.ImportMIBs(strings.NewReader("name,description\nTEST-IF-MIB,changed\n"), "csv", true)
+	if err != nil || report.Updated != 1 || report.Committed {
+		t.Errorf("ImportMIBs(update, dry run) = %+v, %v", report, err)
+	}
+	var mib models.MIB
+	src.db.Where("name = ?", "TEST-IF-MIB").First(&mib)
+	if mib.Description != before.Description || !mib.UpdatedAt.Equal(before.UpdatedAt) {
+		t.Errorf("dry run updated description to %q", mib.Description)
+	}
+}
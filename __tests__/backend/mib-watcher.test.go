@@ -0,0 +1,106 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"mib-platform/models"
+)
+
+func TestMIBDirectoryWatcher(t *testing.T) {
+	s := newTestMIBService(t)
+	dir := t.TempDir()
+	w := NewMIBDirectoryWatcher(s, dir, 0)
+
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	scan := func(want MIBScanSummary) {
+		t.Helper()
+		got, err := w.Scan()
+		if err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		if got.Added != want.Added || got.Updated != want.Updated || got.Deleted != want.Deleted ||
+			got.Unchanged != want.Unchanged || got.Failed != want.Failed {
+			t.Errorf("Scan() = %+v, want %+v", got, want)
+		}
+	}
+
+	write("TEST-IF-MIB.mib", testIfMIB)
+	write("broken.mib", "NOT A MIB")
+	os.MkdirAll(filepath.Join(dir, "uploads"), 0755)
+	os.WriteFile(filepath.Join(dir, "uploads", "ignored.mib"), []byte(testTrapMIB), 0644)
+	scan(MIBScanSummary{Added: 1, Failed: 1})
+	scan(MIBScanSummary{Unchanged: 2})
+
+	write("TEST-IF-MIB.mib", strings.Replace(testIfMIB, "Clarifications.", "Updated.", 1))
+	scan(MIBScanSummary{Updated: 1, Unchanged: 1})
+
+	os.Remove(filepath.Join(dir, "TEST-IF-MIB.mib"))
+	scan(MIBScanSummary{Deleted: 1, Unchanged: 1})
+
+	var count int64
+	s.db.Model(&models.MIB{}).Count(&count)
+	if count != 0 {
+		t.Errorf("%d MIBs still active after delete", count)
+	}
+
+	events, total, err := w.GetEvents(1, 10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, e := range events {
+		actions = append(actions, e.Action)
+	}
+	if total != 4 || !reflect.DeepEqual(actions, []string{"deleted", "updated", "added", "error"}) &&
+		!reflect.DeepEqual(actions, []string{"deleted", "updated", "error", "added"}) {
+		t.Errorf("events = %v", actions)
+	}
+}
+
+func TestMIBDirectoryWatcherUnchangedChecksum(t *testing.T) {
+	s := newTestMIBService(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "TEST-IF-MIB.mib")
+	if err := os.WriteFile(path, []byte(testIfMIB), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w := NewMIBDirectoryWatcher(s, dir, 0)
+	if summary, err := w.Scan(); err != nil || summary.Added != 1 {
+		t.Fatalf("Scan() = %+v, %v", summary, err)
+	}
+	var mib models.MIB
+	if err := s.db.Where("file_path = ?", path).First(&mib).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 修改时间变化但内容相同: 只比较校验和, 不重新导入
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	// 重启后的监视器没有文件缓存, 同样按库中的校验和判断
+	for _, watcher := range []*MIBDirectoryWatcher{w, NewMIBDirectoryWatcher(s, dir, 0)} {
+		summary, err := watcher.Scan()
+		if err != nil || summary.Unchanged != 1 || summary.Added+summary.Updated+summary.Failed != 0 {
+			t.Errorf("Scan() = %+v, %v", summary, err)
+		}
+	}
+
+	var mibs []models.MIB
+	s.db.Find(&mibs)
+	if len(mibs) != 1 || mibs[0].ID != mib.ID || !mibs[0].UpdatedAt.Equal(mib.UpdatedAt) {
+		t.Errorf("MIBs after rescans = %+v", mibs)
+	}
+	if _, total, err := w.GetEvents(1, 10, ""); err != nil || total != 1 {
+		t.Errorf("GetEvents() total = %d, %v, want only the initial import", total, err)
+	}
+}
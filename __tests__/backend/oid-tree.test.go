@@ -0,0 +1,186 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"mib-platform/models"
+)
+
+const testTreeMIB = `
+TEST-TREE-MIB DEFINITIONS ::= BEGIN
+
+IMPORTS
+    OBJECT-TYPE, Integer32, enterprises FROM SNMPv2-SMI
+    DisplayString                       FROM SNMPv2-TC;
+
+testTree OBJECT IDENTIFIER ::= { enterprises 99990 }
+
+treeTable OBJECT-TYPE
+    SYNTAX      SEQUENCE OF TreeEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "Interfaces."
+    ::= { testTree 10 }
+
+treeEntry OBJECT-TYPE
+    SYNTAX      TreeEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "An interface."
+    INDEX   { treeIndex }
+    ::= { treeTable 1 }
+
+TreeEntry ::= SEQUENCE { treeIndex Integer32, ifDescr DisplayString }
+
+treeIndex OBJECT-TYPE
+    SYNTAX      Integer32
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "Index."
+    ::= { treeEntry 1 }
+
+ifDescr OBJECT-TYPE
+    SYNTAX      DisplayString
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "Description."
+    ::= { treeEntry 2 }
+
+treeCount OBJECT-TYPE
+    SYNTAX      Integer32
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "Count."
+    ::= { testTree 9 }
+
+treeName OBJECT-TYPE
+    SYNTAX      DisplayString
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "Name."
+    ::= { testTree 2 }
+
+END
+`
+
+// testTreeOtherMIB 在另一个模块中定义同名对象
+const testTreeOtherMIB = `
+TEST-TREE-OTHER-MIB DEFINITIONS ::= BEGIN
+
+IMPORTS
+    OBJECT-TYPE, enterprises FROM SNMPv2-SMI
+    DisplayString            FROM SNMPv2-TC;
+
+ifDescr OBJECT-TYPE
+    SYNTAX      DisplayString
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "Another description."
+    ::= { enterprises 99991 1 }
+
+END
+`
+
+func TestOIDTree(t *testing.T) {
+	s := newTestMIBService(t)
+	var ids []uint
+	for _, src := range []string{testTreeMIB, testTreeOtherMIB} {
+		modules, err := s.compiler.CompileSource(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mib := &models.MIB{Filename: "tree.mib", FilePath: "tree.mib"}
+		if err := s.createParsedMIB(mib, modules); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, mib.ID)
+	}
+	tree := s.tree
+	const testTree = "1.3.6.1.4.1.99990"
+
+	// 子节点按分量数值排序, 而不是按字符串
+	children, err := tree.Children(testTree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, child := range children {
+		names = append(names, child.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"treeName", "treeCount", "treeTable"}) {
+		t.Errorf("Children(testTree) = %v", names)
+	}
+	for a, b := range map[string]string{"1.3.6.1.2": "1.3.6.1.10", "1.3.6": "1.3.6.1", "1.2.9": "1.10"} {
+		if compareOIDs(a, b) >= 0 || compareOIDs(b, a) <= 0 {
+			t.Errorf("compareOIDs(%s, %s) not ordered", a, b)
+		}
+	}
+	if roots, err := tree.Children(""); err != nil || len(roots) != 3 || roots[0].Name != "ccitt" || roots[2].Name != "joint-iso-ccitt" {
+		t.Errorf("Children(\"\") = %+v, %v", roots, err)
+	}
+	if _, err := tree.Children("1.3.6.1.4.1.12345"); err == nil {
+		t.Error("Children() of an unknown OID succeeded")
+	}
+
+	// 祖先链从根节点开始, 缺失的中间节点也在链上
+	chain, err := tree.Ancestors(testTree + ".10.1.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var oids []string
+	for _, node := range chain {
+		oids = append(oids, node.OID)
+	}
+	want := []string{"1", "1.3", "1.3.6", "1.3.6.1", "1.3.6.1.4", "1.3.6.1.4.1", testTree, testTree + ".10", testTree + ".10.1", testTree + ".10.1.2"}
+	if !reflect.DeepEqual(oids, want) || chain[0].Name != "iso" || chain[len(chain)-1].Name != "ifDescr" {
+		t.Errorf("Ancestors() = %v", oids)
+	}
+
+	// MODULE::name 精确匹配, 单独的名称在多个模块中定义时有歧义
+	if nodes, err := tree.Lookup("TEST-TREE-OTHER-MIB::ifDescr"); err != nil || len(nodes) != 1 || nodes[0].OID != "1.3.6.1.4.1.99991.1" {
+		t.Errorf("Lookup(MODULE::name) = %+v, %v", nodes, err)
+	}
+	if nodes, err := tree.Lookup("ifDescr"); err != nil || len(nodes) != 2 {
+		t.Errorf("Lookup(name) = %+v, %v", nodes, err)
+	}
+	if _, err := tree.Lookup("TEST-TREE-MIB::treeMissing"); err == nil {
+		t.Error("Lookup() of an unknown symbol succeeded")
+	}
+	if _, err := tree.Resolve("ifDescr"); err == nil || !strings.Contains(err.Error(), "MODULE::name") {
+		t.Errorf("Resolve(ambiguous) error = %v", err)
+	}
+	for ref, oid := range map[string]string{"TEST-TREE-MIB::ifDescr": testTree + ".10.1.2", "treeCount": testTree + ".9", "." + testTree + ".2": testTree + ".2"} {
+		if node, err := tree.Resolve(ref); err != nil || node.OID != oid {
+			t.Errorf("Resolve(%q) = %+v, %v", ref, node, err)
+		}
+	}
+
+	// 实例 OID 匹配到列对象, 后缀为索引
+	match, err := tree.LongestPrefixMatch(testTree + ".10.1.2.3")
+	if err != nil || match.Node.Symbol() != "TEST-TREE-MIB::ifDescr" || match.Suffix != "3" {
+		t.Errorf("LongestPrefixMatch(ifDescr.3) = %+v, %v", match, err)
+	}
+	// 没有定义的子树匹配到最近的已命名祖先
+	if match, err := tree.LongestPrefixMatch("1.3.6.1.4.1.12345.1"); err != nil || match.Node.Name != "enterprises" || match.Suffix != "12345.1" {
+		t.Errorf("LongestPrefixMatch(unknown enterprise) = %+v, %v", match, err)
+	}
+	if _, err := tree.LongestPrefixMatch("5.1.2"); err == nil {
+		t.Error("LongestPrefixMatch() without a known prefix succeeded")
+	}
+
+	// 删除 MIB 后树重建, 其他模块的对象不受影响
+	if err := s.DeleteMIB(ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tree.Node(testTree + ".9"); err == nil {
+		t.Error("deleted object still in tree")
+	}
+	if node, err := tree.Resolve("ifDescr"); err != nil || node.Module != "TEST-TREE-OTHER-MIB" {
+		t.Errorf("Resolve(ifDescr) after delete = %+v, %v", node, err)
+	}
+	if match, err := tree.LongestPrefixMatch(testTree + ".10.1.2.3"); err != nil || match.Node.Name != "enterprises" {
+		t.Errorf("LongestPrefixMatch() after delete = %+v, %v", match, err)
+	}
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"mib-platform/models"
+)
+
+func TestPrometheusExporter(t *testing.T) {
+	s := newTestMIBService(t)
+	for _, src := range []string{testIfMIB, testTCMIB} {
+		modules, err := s.compiler.CompileSource(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.createParsedMIB(&models.MIB{Filename: "test.mib", FilePath: "test.mib"}, modules); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := NewSNMPPoller(s.db, PollerConfig{})
+	p.latest[1] = &pollerCollection{
+		Device: models.Device{ID: 1, Name: "core-sw1", IPAddress: "10.0.0.1", Template: &models.DeviceTemplate{
+			OIDs: []string{"ifTable", "testState", "TEST-TC-MIB::testMac", "1.3.6.1.4.1.12345.1.0"},
+		}},
+		PDUs: []gosnmp.SnmpPDU{
+			{Name: ".1.3.6.1.2.1.2.2.1.1.3", Type: gosnmp.Integer, Value: 3},
+			{Name: ".1.3.6.1.2.1.2.2.1.8.3", Type: gosnmp.Integer, Value: 2},
+			{Name: ".1.3.6.1.2.1.2.2.1.10.3", Type: gosnmp.Counter32, Value: uint(123456)},
+			{Name: ".1.3.6.1.4.1.99999.3.0", Type: gosnmp.Integer, Value: 5},
+			{Name: ".1.3.6.1.4.1.99999.2.0", Type: gosnmp.OctetString, Value: []byte{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e}},
+			{Name: ".1.3.6.1.4.1.12345.1.0", Type: gosnmp.Gauge32, Value: uint(7)},
+		},
+		At:       time.Unix(1700000000, 0),
+		Duration: 250 * time.Millisecond,
+	}
+
+	var buf bytes.Buffer
+	if err := NewPrometheusExporter(s.db, p).Metrics(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, line := range []string{
+		"# TYPE ifInOctets counter",
+		`ifInOctets{device="core-sw1",target="10.0.0.1",ifIndex="3"} 123456`,
+		"# TYPE ifOperStatus gauge",
+		`ifOperStatus{device="core-sw1",target="10.0.0.1",ifIndex="3",ifOperStatus="down"} 2`,
+		`testState{device="core-sw1",target="10.0.0.1",testState="forwarding"} 5`,
+		`testMac{device="core-sw1",target="10.0.0.1",testMac="00:1a:2b:3c:4d:5e"} 1`,
+		`snmp_1_3_6_1_4_1_12345_1_0{device="core-sw1",target="10.0.0.1"} 7`,
+		`snmp_poll_timestamp_seconds{device="core-sw1",target="10.0.0.1"} 1.7e+09`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, out)
+		}
+	}
+	if strings.Count(out, "# TYPE ifIndex ") != 1 || strings.Index(out, "# TYPE ifInOctets") > strings.Index(out, "# TYPE ifOperStatus") {
+		t.Errorf("families not grouped and sorted:\n%s", out)
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"mib-platform/models"
+)
+
+func TestSNMPBulkOperation(t *testing.T) {
+	db := newTestDB(t, &models.BulkOperation{}, &models.BulkOperationResult{})
+	snmp := NewSNMPService(db)
+
+	requests := make([]models.BulkSNMPRequest, 5)
+	for i := range requests {
+		// 不支持的版本在建立连接前失败, 不需要真实的 agent
+		requests[i] = models.BulkSNMPRequest{SNMPRequest: models.SNMPRequest{Target: "127.0.0.1", Version: "v9", Community: "secret", OID: "1.3.6.1.2.1.1.3.0"}}
+	}
+	if _, err := snmp.StartBulkOperation("set", requests, 2); err == nil {
+		t.Error("set without values accepted")
+	}
+	if _, err := snmp.StartBulkOperation("delete", requests, 2); err == nil {
+		t.Error("unknown type accepted")
+	}
+
+	started, err := snmp.StartBulkOperation("get", requests, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var operation *models.BulkOperation
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if operation, err = snmp.GetBulkOperation(started.ID); err != nil {
+			t.Fatal(err)
+		}
+		if operation.Status != "pending" && operation.Status != "running" {
+			break
+		}
+	}
+	if operation.Status != "completed" || operation.Completed != 5 || operation.Failed != 5 || operation.Progress != 100 || operation.EndTime == nil {
+		t.Fatalf("operation = %+v", operation)
+	}
+
+	results, total, err := snmp.GetBulkOperationResults(started.ID, 2, 2, "failed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 5 || len(results) != 2 || results[0].Seq != 2 || results[0].Error == "" {
+		t.Errorf("results = %d %+v", total, results)
+	}
+	if results[0].Request.Community != "" {
+		t.Error("community stored in bulk request")
+	}
+	if _, err := snmp.CancelBulkOperation(started.ID); err == nil {
+		t.Error("finished operation cancelled")
+	}
+
+	// 单个请求 panic 时只有该结果失败, 操作正常结束 (没有 resolver 的服务在 SET 校验时 panic)
+	broken := &SNMPService{db: db}
+	setRequests := []models.BulkSNMPRequest{{SNMPRequest: models.SNMPRequest{Target: "127.0.0.1", Version: "v2c", Community: "private", OID: "1.3.6.1.2.1.1.5.0"}, Value: "x", Type: "string"}}
+	if started, err = broken.StartBulkOperation("set", setRequests, 1); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if operation, err = snmp.GetBulkOperation(started.ID); err != nil {
+			t.Fatal(err)
+		}
+		if operation.Status != "pending" && operation.Status != "running" {
+			break
+		}
+	}
+	if operation.Status != "completed" || operation.Failed != 1 {
+		t.Errorf("panicking operation = %+v", operation)
+	}
+	if results, _, err = snmp.GetBulkOperationResults(started.ID, 1, 10, ""); err != nil || len(results) != 1 ||
+		results[0].Status != "failed" || !strings.Contains(results[0].Error, "panicked") {
+		t.Errorf("panicking results = %+v, %v", results, err)
+	}
+
+	// 数据库中残留的运行中操作 (服务重启) 视为中断
+	stale := &models.BulkOperation{Type: "walk", Status: "running", Total: 1}
+	if err := db.Create(stale).Error; err != nil {
+		t.Fatal(err)
+	}
+	if operation, err = snmp.GetBulkOperation(stale.ID); err != nil || operation.Status != "failed" {
+		t.Errorf("stale operation = %+v, %v", operation, err)
+	}
+}
+
+func TestBulkOperationResultsPaging(t *testing.T) {
+	db := newTestDB(t, &models.BulkOperation{}, &models.BulkOperationResult{})
+	snmp := NewSNMPService(db)
+	results := make([]models.BulkOperationResult, 1005)
+	for i := range results {
+		results[i] = models.BulkOperationResult{OperationID: 1, Seq: i, Status: "success"}
+	}
+	if err := db.CreateInBatches(results, 200).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 过大的 limit 限制为 1000, 非正数的页码从第一页开始
+	for _, tt := range []struct {
+		page, limit  int
+		count, first int
+	}{
+		{1, 1000000, 1000, 0},
+		{0, 10, 10, 0},
+		{-3, 0, 50, 0},
+		{2, 1000, 5, 1000},
+	} {
+		got, total, err := snmp.GetBulkOperationResults(1, tt.page, tt.limit, "")
+		if err != nil || total != 1005 || len(got) != tt.count || got[0].Seq != tt.first {
+			t.Errorf("GetBulkOperationResults(page %d, limit %d) = %d results, total %d, %v", tt.page, tt.limit, len(got), total, err)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"mib-platform/models"
+)
+
+func TestSNMPPollerSamples(t *testing.T) {
+	s := newTestMIBService(t, &models.Device{}, &models.SNMPCredential{}, &models.DeviceTemplate{}, &models.MetricSeries{}, &models.MetricSample{})
+	modules, err := s.compiler.CompileSource(testIfMIB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.createParsedMIB(&models.MIB{Filename: "if.mib", FilePath: "if.mib"}, modules); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		prev, cur uint64
+		bits      int
+		want      uint64
+		ok        bool
+	}{
+		{100, 150, 32, 50, true},
+		{math.MaxUint32 - 9, 10, 32, 20, true},
+		{1 << 40, 10, 64, 0, false},
+	} {
+		if got, ok := snmpCounterDelta(tt.prev, tt.cur, tt.bits); got != tt.want || ok != tt.ok {
+			t.Errorf("snmpCounterDelta(%d, %d, %d) = %d, %v", tt.prev, tt.cur, tt.bits, got, ok)
+		}
+	}
+
+	p := NewSNMPPoller(s.db, PollerConfig{RawRetention: time.Hour, RollupRetention: 24 * time.Hour})
+	t0 := time.Unix(1700000000, 0)
+	poll := func(at time.Time, octets uint, status int, restarted bool) (int, int) {
+		t.Helper()
+		pdus := []gosnmp.SnmpPDU{
+			{Name: ".1.3.6.1.2.1.2.2.1.10.1", Type: gosnmp.Counter32, Value: octets},
+			{Name: ".1.3.6.1.2.1.2.2.1.8.1", Type: gosnmp.Integer, Value: status},
+			{Name: ".1.3.6.1.2.1.2.2.1.2.1", Type: gosnmp.OctetString, Value: []byte("eth0")},
+		}
+		samples, skipped, err := p.record(1, pdus, at, restarted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return samples, skipped
+	}
+	// 第一次只取得计数器基线
+	if samples, skipped := poll(t0, math.MaxUint32-999, 1, false); samples != 1 || skipped != 2 {
+		t.Errorf("first poll = %d samples, %d skipped", samples, skipped)
+	}
+	if samples, _ := poll(t0.Add(10*time.Second), 1000, 2, false); samples != 2 {
+		t.Errorf("second poll = %d samples", samples)
+	}
+	if samples, _ := poll(t0.Add(20*time.Second), 5, 1, true); samples != 1 {
+		t.Errorf("poll after restart = %d samples", samples)
+	}
+	if samples, _ := poll(t0.Add(30*time.Second), 305, 1, false); samples != 2 {
+		t.Errorf("fourth poll = %d samples", samples)
+	}
+
+	series, err := p.GetSeries(1, "ifInOctets")
+	if err != nil || len(series) != 1 || series[0].Kind != "counter" || series[0].Index != "1" || series[0].Units != "octets" {
+		t.Fatalf("series = %+v, %v", series, err)
+	}
+	data, err := p.QuerySeries(MetricQuery{DeviceID: 1, OID: "ifInOctets", Start: t0, End: t0.Add(time.Minute)})
+	if err != nil || len(data) != 1 {
+		t.Fatalf("QuerySeries() = %+v, %v", data, err)
+	}
+	// 回绕: 2^32 - (2^32-1000) + 1000 = 2000 octets / 10s; 重启后重新取基线
+	want := []MetricPoint{{Time: t0.Add(10 * time.Second).Unix(), Value: 200}, {Time: t0.Add(30 * time.Second).Unix(), Value: 30}}
+	if !reflect.DeepEqual(data[0].Points, want) {
+		t.Errorf("points = %+v", data[0].Points)
+	}
+
+	data, err = p.QuerySeries(MetricQuery{DeviceID: 1, OID: "1.3.6.1.2.1.2.2.1.8", Start: t0, End: t0.Add(time.Minute), Step: time.Minute})
+	if err != nil || len(data) != 1 || len(data[0].Points) != 1 || data[0].Points[0].Value != 1.25 {
+		t.Errorf("stepped query = %+v, %v", data, err)
+	}
+
+	// 超过原始样本保留时长后降采样为 5 分钟桶
+	if err := p.Compact(t0.Add(2 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	var samples []models.MetricSample
+	s.db.Order("series_id, ts").Find(&samples)
+	if len(samples) != 2 || samples[0].Step != metricRollupStep || samples[0].Value != 115 || samples[0].Max != 200 || samples[1].Value != 1.25 || samples[1].Max != 2 {
+		t.Errorf("rollups = %+v", samples)
+	}
+	data, err = p.QuerySeries(MetricQuery{DeviceID: 1, OID: "ifInOctets", Start: t0.Add(-time.Hour), End: t0.Add(time.Hour)})
+	if err != nil || len(data) != 1 || len(data[0].Points) != 1 || data[0].Points[0].Time != t0.Unix()/metricRollupStep*metricRollupStep {
+		t.Errorf("query after compaction = %+v, %v", data, err)
+	}
+
+	if err := p.Compact(t0.Add(48 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	s.db.Model(&models.MetricSample{}).Count(&count)
+	if count != 0 {
+		t.Errorf("%d samples left after rollup retention", count)
+	}
+}
+
+func TestSNMPPollerDispatch(t *testing.T) {
+	s := newTestMIBService(t, &models.Device{}, &models.SNMPCredential{}, &models.DeviceTemplate{}, &models.MetricSeries{}, &models.MetricSample{})
+	p := NewSNMPPoller(s.db, PollerConfig{Interval: time.Minute, MaxConcurrent: 1})
+	now := time.Now()
+	intervals := map[uint]time.Duration{1: time.Minute, 2: time.Minute}
+	p.next[1] = now.Add(-time.Second)
+	p.next[2] = now.Add(-time.Second)
+
+	// 唯一的槽位被占用时, 到期设备不能被推迟一个间隔
+	p.sem <- struct{}{}
+	p.dispatch(now, intervals)
+	for id := range intervals {
+		if !p.next[id].Equal(now) || p.polling[id] {
+			t.Errorf("device %d after skipped dispatch: next = %v, polling = %v", id, p.next[id].Sub(now), p.polling[id])
+		}
+	}
+	<-p.sem
+
+	if due := p.due(now.Add(pollerTick), intervals); len(due) != 2 {
+		t.Errorf("due() after skip = %v, want both devices", due)
+	}
+}
+
+func TestSNMPPollerDeviceStatus(t *testing.T) {
+	s := newTestMIBService(t, &models.Device{}, &models.SNMPCredential{}, &models.DeviceTemplate{}, &models.MetricSeries{}, &models.MetricSample{})
+	p := NewSNMPPoller(s.db, PollerConfig{})
+
+	// 关闭的本地端口, 请求立即失败
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+	conn.Close()
+
+	template := models.DeviceTemplate{Name: "if", Type: "switch", OIDs: []string{"1.3.6.1.2.1.1.5.0"}}
+	s.db.Create(&template)
+	unconfigured := models.Device{Name: "unconfigured", IPAddress: "127.0.0.1", Port: port, Status: "online"}
+	unreachable := models.Device{
+		Name: "unreachable", IPAddress: "127.0.0.1", Port: port, Status: "online", TemplateID: &template.ID,
+		Credentials: []models.SNMPCredential{{Version: "v2c", Community: "public"}},
+	}
+	s.db.Create(&unconfigured)
+	s.db.Create(&unreachable)
+
+	for _, tt := range []struct {
+		device models.Device
+		want   string
+	}{
+		{unconfigured, "online"},
+		{models.Device{ID: 999}, ""},
+		{unreachable, "offline"},
+	} {
+		if _, err := p.PollDevice(tt.device.ID); err == nil {
+			t.Errorf("PollDevice(%s) succeeded", tt.device.Name)
+		}
+		var device models.Device
+		s.db.Unscoped().Where("id = ?", tt.device.ID).Find(&device)
+		if device.Status != tt.want {
+			t.Errorf("%s status = %q, want %q", tt.device.Name, device.Status, tt.want)
+		}
+	}
+}
+
+func TestSNMPPollerAgentErrors(t *testing.T) {
+	s := newTestMIBService(t, &models.Device{}, &models.SNMPCredential{}, &models.DeviceTemplate{}, &models.MetricSeries{}, &models.MetricSample{}, &models.SimulatedDevice{})
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+	conn.Close()
+	sim := NewSNMPSimulator(s.db, SimulatorConfig{Host: "127.0.0.1"})
+	defer sim.Stop()
+	if err := sim.CreateDevice(&models.SimulatedDevice{Name: "v1-agent", Port: port, Recording: `
+1.3.6.1.2.1.1.3.0|67|100
+1.3.6.1.4.1.99998.2.0|2|5
+`}, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// v1 agent 对不存在的 OID 整个请求返回 noSuchName
+	template := models.DeviceTemplate{Name: "v1", Type: "switch", OIDs: []string{"1.3.6.1.4.1.99998.9.0", "1.3.6.1.4.1.99998.2.0"}}
+	s.db.Create(&template)
+	device := models.Device{
+		Name: "v1-agent", IPAddress: "127.0.0.1", Port: port, Status: "offline", TemplateID: &template.ID,
+		Credentials: []models.SNMPCredential{{Version: "v1", Community: "public"}},
+	}
+	s.db.Create(&device)
+
+	p := NewSNMPPoller(s.db, PollerConfig{})
+	result, err := p.PollDevice(device.ID)
+	if err != nil {
+		t.Fatalf("PollDevice() error = %v", err)
+	}
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0], "1.3.6.1.4.1.99998.9.0") {
+		t.Errorf("errors = %v", result.Errors)
+	}
+	// 其余 OID 仍然取到, 设备可达, 标记为在线
+	var values []string
+	for _, pdu := range p.latestCollections()[0].PDUs {
+		values = append(values, fmt.Sprintf("%s=%v", strings.TrimPrefix(pdu.Name, "."), pdu.Value))
+	}
+	if !reflect.DeepEqual(values, []string{"1.3.6.1.4.1.99998.2.0=5"}) {
+		t.Errorf("values = %v", values)
+	}
+	s.db.First(&device, device.ID)
+	if device.Status != "online" {
+		t.Errorf("status = %q, want online", device.Status)
+	}
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"testing"
+
+	"mib-platform/models"
+)
+
+func TestSNMPResolver(t *testing.T) {
+	s := newTestMIBService(t)
+	for _, src := range []string{testIfMIB, testTCMIB} {
+		modules, err := s.compiler.CompileSource(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.createParsedMIB(&models.MIB{Filename: "test.mib", FilePath: "test.mib"}, modules); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oidOf := func(name string) string {
+		nodes, err := s.tree.Lookup(name)
+		if err != nil || len(nodes) == 0 {
+			t.Fatalf("Lookup(%s) = %v, %v", name, nodes, err)
+		}
+		return nodes[0].OID
+	}
+	results := []models.SNMPResult{
+		{OID: "." + oidOf("ifOperStatus") + ".3", Type: "Integer", Value: 1},
+		{OID: oidOf("testMac") + ".0", Type: "OctetString", Value: string([]byte{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e})},
+		{OID: oidOf("testClock") + ".0", Type: "OctetString", Value: string([]byte{0x07, 0xea, 10, 16, 13, 30, 15, 0, '+', 8, 0})},
+		{OID: oidOf("testTemp") + ".0", Type: "Integer", Value: -123},
+		{OID: oidOf("testName") + ".0", Type: "OctetString", Value: "core-sw1"},
+		{OID: "1.3.6.1.4.1.12345.1", Type: "Integer", Value: 7},
+	}
+	NewSNMPResolver(s.tree).Resolve(results)
+
+	want := []struct{ name, module, index, display string }{
+		{"ifOperStatus.3", "TEST-IF-MIB", "3", "up(1)"},
+		{"testMac.0", "TEST-TC-MIB", "0", "00:1a:2b:3c:4d:5e"},
+		{"testClock.0", "TEST-TC-MIB", "0", "2026-10-16,13:30:15.0,+8:0"},
+		{"testTemp.0", "TEST-TC-MIB", "0", "-12.3"},
+		{"testName.0", "TEST-TC-MIB", "0", "core-sw1"},
+	}
+	for i, w := range want {
+		got := results[i]
+		if got.Name != w.name || got.Module != w.module || got.Index != w.index || got.Display != w.display {
+			t.Errorf("result %d = %+v, want %+v", i, got, w)
+		}
+	}
+	if got := results[len(results)-1]; got.Name != "enterprises.12345.1" || got.Display != "" {
+		t.Errorf("unknown enterprise result = %+v", got)
+	}
+
+	if got := formatSNMPBits([]byte{0xa0}, []models.OIDEnum{{Name: "a", Value: 0}, {Name: "b", Value: 1}, {Name: "c", Value: 2}}); got != "a(0) c(2)" {
+		t.Errorf("formatSNMPBits() = %q", got)
+	}
+	if got, _ := applyOctetDisplayHint("1d.", []byte{10, 0, 0, 1}); got != "10.0.0.1" {
+		t.Errorf("applyOctetDisplayHint(1d.) = %q", got)
+	}
+	if got, _ := applyOctetDisplayHint("*1x:/", []byte{2, 0xaa, 0xbb, 1, 0xcc}); got != "aa:bb/cc" {
+		t.Errorf("applyOctetDisplayHint(*1x:/) = %q", got)
+	}
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"mib-platform/models"
+)
+
+const testSetMIB = `
+TEST-SET-MIB DEFINITIONS ::= BEGIN
+
+IMPORTS
+    OBJECT-TYPE, Integer32, TimeTicks, enterprises FROM SNMPv2-SMI
+    DisplayString                                  FROM SNMPv2-TC;
+
+testSet OBJECT IDENTIFIER ::= { enterprises 99998 }
+
+testSetName OBJECT-TYPE
+    SYNTAX      DisplayString (SIZE (0..16))
+    MAX-ACCESS  read-write
+    STATUS      current
+    DESCRIPTION "Name."
+    ::= { testSet 1 }
+
+testSetState OBJECT-TYPE
+    SYNTAX      INTEGER { disabled(1), blocking(2), forwarding(5) }
+    MAX-ACCESS  read-write
+    STATUS      current
+    DESCRIPTION "State."
+    ::= { testSet 2 }
+
+testSetMtu OBJECT-TYPE
+    SYNTAX      Integer32 (64..9216)
+    MAX-ACCESS  read-write
+    STATUS      current
+    DESCRIPTION "MTU."
+    ::= { testSet 3 }
+
+testSetUptime OBJECT-TYPE
+    SYNTAX      TimeTicks
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "Uptime."
+    ::= { testSet 4 }
+
+END
+`
+
+func TestSNMPSetWorkflow(t *testing.T) {
+	s := newTestMIBService(t, &models.SNMPSetOperation{}, &models.Device{}, &models.SNMPCredential{}, &models.SimulatedDevice{})
+	modules, err := s.compiler.CompileSource(testSetMIB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.createParsedMIB(&models.MIB{Filename: "set.mib", FilePath: "set.mib"}, modules); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+	conn.Close()
+	sim := NewSNMPSimulator(s.db, SimulatorConfig{Host: "127.0.0.1"})
+	defer sim.Stop()
+	if err := sim.CreateDevice(&models.SimulatedDevice{Name: "edge-1", Port: port, Recording: `
+1.3.6.1.4.1.99998.1.0|4|edge-1
+1.3.6.1.4.1.99998.2.0|2|5
+1.3.6.1.4.1.99998.3.0|2|1500
+1.3.6.1.4.1.99998.4.0|67|100
+`}, true); err != nil {
+		t.Fatal(err)
+	}
+	device := models.Device{Name: "edge-1", IPAddress: "127.0.0.1", Port: port, Credentials: []models.SNMPCredential{{Version: "v2c", Community: "public"}}}
+	if err := s.db.Create(&device).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	client := &gosnmp.GoSNMP{Target: "127.0.0.1", Port: uint16(port), Version: gosnmp.Version2c, Community: "public", Timeout: time.Second}
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Conn.Close()
+	current := func() string {
+		t.Helper()
+		result, err := client.Get([]string{"1.3.6.1.4.1.99998.1.0", "1.3.6.1.4.1.99998.2.0", "1.3.6.1.4.1.99998.3.0"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fmt.Sprintf("%s/%v/%v", result.Variables[0].Value, result.Variables[1].Value, result.Variables[2].Value)
+	}
+
+	svc := NewSNMPService(s.db)
+	change := []models.SNMPSetVarbind{
+		{OID: "testSetName.0", Value: "edge-1-new"},
+		{OID: "TEST-SET-MIB::testSetState.0", Value: "blocking"},
+		{OID: "1.3.6.1.4.1.99998.3.0", Value: float64(9000)},
+	}
+
+	// 演练: 读取当前值和校验, 不写入也不记录
+	dry, err := svc.ApplySet(&models.SNMPSetOperationRequest{DeviceID: device.ID, Varbinds: change, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dry.ID != 0 || dry.Status != SNMPSetStatusDryRun || len(dry.Changes) != 3 || current() != "edge-1/5/1500" {
+		t.Fatalf("dry run = %+v", dry)
+	}
+	want := models.SNMPSetChange{OID: "1.3.6.1.4.1.99998.2.0", Name: "testSetState.0", Module: "TEST-SET-MIB", Type: "Integer", OldType: "Integer", OldValue: "forwarding(5)", NewValue: "blocking(2)", OldRecord: "1.3.6.1.4.1.99998.2.0|2|5", NewRecord: "1.3.6.1.4.1.99998.2.0|2|2"}
+	if dry.Changes[1] != want {
+		t.Fatalf("change = %+v, want %+v", dry.Changes[1], want)
+	}
+
+	// 按 MIB 语法校验, 任何一个变量有问题都不写入
+	for _, tt := range []struct {
+		varbinds []models.SNMPSetVarbind
+		problems []string
+	}{
+		{
+			[]models.SNMPSetVarbind{{OID: "testSetState.0", Value: "blocking"}, {OID: "testSetState.0", Value: "disabled"}, {OID: "testSetUptime.0", Value: float64(1)}, {OID: "testSetMtu", Value: float64(1500)}},
+			[]string{"testSetState.0 is set more than once", "testSetUptime.0 is read-only", "testSetMtu is a scalar, set testSetMtu.0"},
+		},
+		{
+			[]models.SNMPSetVarbind{{OID: "testSetName.0", Value: "a-name-longer-than-16"}, {OID: "testSetState.0", Value: "bogus"}, {OID: "testSetMtu.0", Value: "1500", Type: "string"}},
+			[]string{"testSetName.0: length 21 is outside SIZE (0..16)", `testSetState.0: invalid INTEGER "bogus"`, "testSetMtu.0: type string does not match the MIB syntax"},
+		},
+		{
+			[]models.SNMPSetVarbind{{OID: "testSetState.0", Value: float64(3)}, {OID: "testSetMtu.0", Value: float64(10)}},
+			[]string{"3 is not one of disabled(1), blocking(2), forwarding(5)", "10 is outside the range (64..9216)"},
+		},
+	} {
+		_, err := svc.ApplySet(&models.SNMPSetOperationRequest{DeviceID: device.ID, Varbinds: tt.varbinds})
+		var invalid *SNMPSetValidationError
+		if !errors.As(err, &invalid) || len(invalid.Problems) != len(tt.problems) {
+			t.Fatalf("ApplySet(%+v) error = %v", tt.varbinds, err)
+		}
+		for i, want := range tt.problems {
+			if !strings.Contains(invalid.Problems[i], want) {
+				t.Errorf("problem %d = %q, want %q", i, invalid.Problems[i], want)
+			}
+		}
+	}
+	if current() != "edge-1/5/1500" {
+		t.Fatalf("device changed by rejected SETs: %s", current())
+	}
+
+	applied, err := svc.ApplySet(&models.SNMPSetOperationRequest{DeviceID: device.ID, Varbinds: change, Comment: "maintenance"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied.ID == 0 || applied.Status != SNMPSetStatusApplied || current() != "edge-1-new/2/9000" {
+		t.Fatalf("applied = %+v, device = %s", applied, current())
+	}
+
+	// 设备上的值被其他人修改后, 回滚需要 force
+	if _, err := client.Set([]gosnmp.SnmpPDU{{Name: "1.3.6.1.4.1.99998.3.0", Type: gosnmp.Integer, Value: 4000}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.RevertSetOperation(applied.ID, nil, false, ""); !errors.Is(err, ErrSNMPSetConflict) || !strings.Contains(err.Error(), "testSetMtu.0") {
+		t.Fatalf("RevertSetOperation() error = %v", err)
+	}
+	revert, err := svc.RevertSetOperation(applied.ID, nil, true, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revert.Status != SNMPSetStatusApplied || *revert.RevertOf != applied.ID || revert.Changes[2].OldValue != "4000" || revert.Changes[2].NewValue != "1500" || current() != "edge-1/5/1500" {
+		t.Fatalf("revert = %+v, device = %s", revert, current())
+	}
+	original, err := svc.GetSetOperation(applied.ID)
+	if err != nil || original.Status != SNMPSetStatusReverted || *original.RevertedBy != revert.ID {
+		t.Fatalf("original = %+v, %v", original, err)
+	}
+	var invalid *SNMPSetValidationError
+	if _, err := svc.RevertSetOperation(applied.ID, nil, true, ""); !errors.As(err, &invalid) {
+		t.Fatalf("second revert error = %v", err)
+	}
+
+	// 代理拒绝整个 PDU 时一个变量也不写入, 记录为 failed
+	failed, err := svc.ApplySet(&models.SNMPSetOperationRequest{DeviceID: device.ID, Varbinds: []models.SNMPSetVarbind{
+		{OID: "testSetMtu.0", Value: "2000"},
+		{OID: "1.3.6.1.4.1.99998.9.0", Value: "x", Type: "string"},
+	}})
+	if err == nil || failed == nil || failed.ID == 0 || failed.Status != SNMPSetStatusFailed || !strings.Contains(failed.Error, "NoCreation at testSet.9.0") || current() != "edge-1/5/1500" {
+		t.Fatalf("failed = %+v, %v, device = %s", failed, err, current())
+	}
+	if failed.Changes[1].OldRecord != "" {
+		t.Fatalf("missing instance should have no old value: %+v", failed.Changes[1])
+	}
+
+	// 原有的 /snmp/set 接口也经过校验和审计
+	connection := models.SNMPRequest{Target: "127.0.0.1", Port: port, Version: "v2c", Community: "public", OID: "1.3.6.1.4.1.99998.3.0"}
+	response, err := svc.SNMPSet(&models.SNMPSetRequest{SNMPRequest: connection, Value: float64(1), Type: "integer"})
+	if err != nil || response.Success || !strings.Contains(response.Message, "outside the range") {
+		t.Fatalf("SNMPSet() = %+v, %v", response, err)
+	}
+	response, err = svc.SNMPSet(&models.SNMPSetRequest{SNMPRequest: connection, Value: float64(1400), Type: "integer"})
+	if err != nil || !response.Success || response.Data[0].Value != 1400 || current() != "edge-1/5/1400" {
+		t.Fatalf("SNMPSet() = %+v, %v", response, err)
+	}
+	if _, err := svc.RevertSetOperation(4, nil, false, ""); !errors.As(err, &invalid) {
+		t.Fatalf("revert without device or connection error = %v", err)
+	}
+	revert, err = svc.RevertSetOperation(4, &connection, false, "")
+	if err != nil || revert.DeviceID != nil || current() != "edge-1/5/1500" {
+		t.Fatalf("revert = %+v, %v", revert, err)
+	}
+
+	operations, total, err := svc.GetSetOperations(SNMPSetOperationQuery{DeviceID: device.ID})
+	if err != nil || total != 3 || operations[0].ID != failed.ID {
+		t.Fatalf("GetSetOperations() = %+v, %d, %v", operations, total, err)
+	}
+	if _, total, _ := svc.GetSetOperations(SNMPSetOperationQuery{Status: SNMPSetStatusReverted}); total != 2 {
+		t.Fatalf("reverted operations = %d", total)
+	}
+}
+
+func TestSetOperationsPaging(t *testing.T) {
+	s := newTestMIBService(t, &models.SNMPSetOperation{})
+	operations := make([]models.SNMPSetOperation, 1005)
+	for i := range operations {
+		operations[i] = models.SNMPSetOperation{Target: "127.0.0.1", Status: "applied"}
+	}
+	if err := s.db.CreateInBatches(operations, 200).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewSNMPService(s.db)
+	for _, tt := range []struct {
+		query SNMPSetOperationQuery
+		count int
+	}{
+		{SNMPSetOperationQuery{Limit: 1000000}, 1000},
+		{SNMPSetOperationQuery{Page: -1}, 20},
+		{SNMPSetOperationQuery{Page: 2, Limit: 1000}, 5},
+	} {
+		got, total, err := svc.GetSetOperations(tt.query)
+		if err != nil || total != 1005 || len(got) != tt.count {
+			t.Errorf("GetSetOperations(%+v) = %d operations, total %d, %v", tt.query, len(got), total, err)
+		}
+	}
+}
+
+func TestExecuteSetNoRetry(t *testing.T) {
+	// 只收包不应答的 agent
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var received atomic.Int32
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			if _, _, err := conn.ReadFrom(buf); err != nil {
+				return
+			}
+			received.Add(1)
+		}
+	}()
+
+	snmp := &gosnmp.GoSNMP{Target: "127.0.0.1", Port: uint16(conn.LocalAddr().(*net.UDPAddr).Port), Version: gosnmp.Version2c, Community: "public", Timeout: 100 * time.Millisecond, Retries: 3}
+	if err := snmp.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer snmp.Conn.Close()
+	plans := []snmpSetPlan{{name: "testSetCount", record: snmpRecord{oid: "1.3.6.1.4.1.99998.2.0", typ: gosnmp.Integer, value: 5}}}
+	if err := executeSet(snmp, plans); err == nil || !strings.Contains(err.Error(), "unknown") {
+		t.Errorf("executeSet() error = %v", err)
+	}
+
+	// 超时的 SET 只发送一次, 连接上的重试次数恢复给之后的读取使用
+	time.Sleep(50 * time.Millisecond)
+	if n := received.Load(); n != 1 {
+		t.Errorf("agent received %d SET requests, want 1", n)
+	}
+	if snmp.Retries != 3 {
+		t.Errorf("Retries = %d after SET, want 3", snmp.Retries)
+	}
+}
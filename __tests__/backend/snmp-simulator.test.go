@@ -0,0 +1,341 @@
+package services
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"mib-platform/models"
+)
+
+func TestSNMPRecordingFormats(t *testing.T) {
+	walk := `.1.3.6.1.2.1.1.1.0 = STRING: "Cisco IOS Software,
+Catalyst L3 Switch"
+iso.3.6.1.2.1.1.3.0 = Timeticks: (123456) 0:20:34.56
+SNMPv2-MIB::sysName.0 = STRING: core-sw1
+.1.3.6.1.2.1.2.2.1.6.1 = Hex-STRING: 00 1E BD 00
+01 02
+.1.3.6.1.2.1.2.2.1.8.1 = INTEGER: up(1)
+.1.3.6.1.2.1.2.2.1.5.1 = Gauge32: 1000000000
+.1.3.6.1.2.1.2.2.1.18.1 = ""
+.1.3.6.1.2.1.1.2.0 = OID: .1.3.6.1.4.1.9.1.2494
+.1.3.6.1.2.1.4.20.1.1.10.0.0.1 = IpAddress: 10.0.0.1
+.1.3.6.1.2.1.31.1.1.1.6.1 = Counter64: 18446744073709551615
+.1.3.6.1.2.1.99.0 = No Such Object available on this agent at this OID
+`
+	if got := DetectRecordingFormat("", walk); got != RecordingFormatSNMPWalk {
+		t.Fatalf("DetectRecordingFormat() = %s", got)
+	}
+	records, err := parseSNMPRecording(walk, RecordingFormatSNMPWalk, nil)
+	if err == nil {
+		t.Fatal("expected SNMPv2-MIB::sysName to need a MIB")
+	}
+	records, err = parseSNMPRecording(strings.Replace(walk, "SNMPv2-MIB::sysName.0", "mib-2.1.5.0", 1), RecordingFormatSNMPWalk, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"1.3.6.1.2.1.1.1.0":             []byte("Cisco IOS Software,\nCatalyst L3 Switch"),
+		"1.3.6.1.2.1.1.2.0":             ".1.3.6.1.4.1.9.1.2494",
+		"1.3.6.1.2.1.1.3.0":             uint32(123456),
+		"1.3.6.1.2.1.1.5.0":             []byte("core-sw1"),
+		"1.3.6.1.2.1.2.2.1.5.1":         uint32(1000000000),
+		"1.3.6.1.2.1.2.2.1.6.1":         []byte{0x00, 0x1e, 0xbd, 0x00, 0x01, 0x02},
+		"1.3.6.1.2.1.2.2.1.8.1":         1,
+		"1.3.6.1.2.1.2.2.1.18.1":        []byte{},
+		"1.3.6.1.2.1.4.20.1.1.10.0.0.1": "10.0.0.1",
+		"1.3.6.1.2.1.31.1.1.1.6.1":      uint64(18446744073709551615),
+	}
+	if len(records) != len(want) {
+		t.Fatalf("records = %+v", records)
+	}
+	for i, record := range records {
+		if i > 0 && compareOIDs(records[i-1].oid, record.oid) >= 0 {
+			t.Fatalf("records not sorted: %s before %s", records[i-1].oid, record.oid)
+		}
+		if !reflect.DeepEqual(record.value, want[record.oid]) {
+			t.Errorf("%s = %#v, want %#v", record.oid, record.value, want[record.oid])
+		}
+	}
+
+	// 转成 snmprec 再解析应得到相同的值
+	rec := formatSNMPRec(records)
+	if got := DetectRecordingFormat("", rec); got != RecordingFormatSNMPRec {
+		t.Fatalf("DetectRecordingFormat() = %s", got)
+	}
+	again, err := parseSNMPRecording(rec, RecordingFormatSNMPRec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again, records) {
+		t.Fatalf("snmprec round trip:\n%s\n%+v", rec, again)
+	}
+
+	for _, bad := range []string{"1.3.6.1|2|abc", "1.3.6.1|99|1", "1.3.6.1|4:numeric|x", "1.3.6.1|4x|zz"} {
+		if _, err := parseSNMPRecording(bad, RecordingFormatSNMPRec, nil); err == nil {
+			t.Errorf("parseSNMPRecording(%q) expected error", bad)
+		}
+	}
+}
+
+func TestSNMPSimulator(t *testing.T) {
+	s := newTestMIBService(t, &models.SimulatedDevice{}, &models.Device{}, &models.SNMPCredential{})
+
+	// 选一段空闲端口
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := conn.LocalAddr().(*net.UDPAddr).Port
+	conn.Close()
+
+	sim := NewSNMPSimulator(s.db, SimulatorConfig{Host: "127.0.0.1", PortMin: base, PortMax: base + 50})
+	defer sim.Stop()
+
+	recording := `# recorded from core-sw1
+1.3.6.1.2.1.1.1.0|4|Simulated switch
+1.3.6.1.2.1.1.3.0|67|100
+1.3.6.1.2.1.1.4.0|4|noc@example.com
+1.3.6.1.2.1.1.5.0|4|core-sw1
+1.3.6.1.2.1.2.2.1.1.1|2|1
+1.3.6.1.2.1.2.2.1.1.2|2|2
+1.3.6.1.2.1.2.2.1.2.1|4|eth0
+1.3.6.1.2.1.2.2.1.2.2|4|eth1
+1.3.6.1.2.1.2.2.1.6.1|4x|001ebd000101
+1.3.6.1.2.1.2.2.1.6.2|4x|001ebd000102
+1.3.6.1.2.1.2.2.1.10.1|65|1000
+1.3.6.1.2.1.2.2.1.10.2|65|2000
+`
+	device := &models.SimulatedDevice{
+		Name:      "core-sw1",
+		Community: "public",
+		Username:  "simuser",
+		AuthProto: "SHA",
+		AuthKey:   "authpass1",
+		PrivProto: "AES",
+		PrivKey:   "privpass1",
+		Recording: recording,
+	}
+	if err := sim.CreateDevice(device, true); err != nil {
+		t.Fatal(err)
+	}
+	if device.Format != RecordingFormatSNMPRec || device.Records != 12 || device.Port != base || device.Status != "running" {
+		t.Fatalf("device = %+v", device)
+	}
+
+	client := func(c *gosnmp.GoSNMP) *gosnmp.GoSNMP {
+		t.Helper()
+		c.Target = "127.0.0.1"
+		c.Port = uint16(device.Port)
+		c.Timeout = time.Second
+		c.Retries = 0
+		c.MaxRepetitions = 3
+		if err := c.Connect(); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { c.Conn.Close() })
+		return c
+	}
+
+	v2 := client(&gosnmp.GoSNMP{Version: gosnmp.Version2c, Community: "public"})
+	result, err := v2.Get([]string{"1.3.6.1.2.1.1.5.0", "1.3.6.1.2.1.1.5.1", "1.3.6.1.2.1.99.0", "1.3.6.1.2.1.1.3.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := result.Variables; string(got[0].Value.([]byte)) != "core-sw1" || got[1].Type != gosnmp.NoSuchInstance || got[2].Type != gosnmp.NoSuchObject || got[3].Value.(uint32) < 100 {
+		t.Fatalf("Get() = %+v", got)
+	}
+
+	next, err := v2.GetNext([]string{"1.3.6.1.2.1.1.5.0", "1.3.6.1.2.1.2.2.1.10.2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Variables[0].Name != ".1.3.6.1.2.1.2.2.1.1.1" || next.Variables[1].Type != gosnmp.EndOfMibView {
+		t.Fatalf("GetNext() = %+v", next.Variables)
+	}
+
+	// BulkWalk 需要多次 GetBulk (每次 3 个)
+	var walked []string
+	if err := v2.BulkWalk("1.3.6.1.2.1.2.2", func(pdu gosnmp.SnmpPDU) error {
+		walked = append(walked, pdu.Name)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(walked) != 8 || walked[7] != ".1.3.6.1.2.1.2.2.1.10.2" {
+		t.Fatalf("BulkWalk() = %v", walked)
+	}
+
+	// Set 修改内存中的值, 类型不对时整个请求不生效
+	set, err := v2.Set([]gosnmp.SnmpPDU{{Name: "1.3.6.1.2.1.1.4.0", Type: gosnmp.OctetString, Value: "ops@example.com"}})
+	if err != nil || set.Error != gosnmp.NoError {
+		t.Fatalf("Set() = %+v, %v", set, err)
+	}
+	set, err = v2.Set([]gosnmp.SnmpPDU{
+		{Name: "1.3.6.1.2.1.1.5.0", Type: gosnmp.OctetString, Value: "renamed"},
+		{Name: "1.3.6.1.2.1.2.2.1.10.1", Type: gosnmp.Integer, Value: 5},
+	})
+	if err != nil || set.Error != gosnmp.WrongType || set.ErrorIndex != 2 {
+		t.Fatalf("Set() = %+v, %v", set, err)
+	}
+	set, err = v2.Set([]gosnmp.SnmpPDU{{Name: "1.3.6.1.2.1.1.9.0", Type: gosnmp.Integer, Value: 1}})
+	if err != nil || set.Error != gosnmp.NoCreation {
+		t.Fatalf("Set() = %+v, %v", set, err)
+	}
+	result, err = v2.Get([]string{"1.3.6.1.2.1.1.4.0", "1.3.6.1.2.1.1.5.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result.Variables[0].Value.([]byte)) != "ops@example.com" || string(result.Variables[1].Value.([]byte)) != "core-sw1" {
+		t.Fatalf("Get() after Set = %+v", result.Variables)
+	}
+	if rec, err := sim.GetRecording(device.ID); err != nil || !strings.Contains(rec, "1.3.6.1.2.1.1.4.0|4|ops@example.com\n") {
+		t.Fatalf("GetRecording() = %q, %v", rec, err)
+	}
+
+	// SNMPv1 不支持异常值, 缺失的实例返回 noSuchName
+	v1 := client(&gosnmp.GoSNMP{Version: gosnmp.Version1, Community: "public"})
+	result, err = v1.Get([]string{"1.3.6.1.2.1.1.5.0", "1.3.6.1.2.1.1.5.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != gosnmp.NoSuchName || result.ErrorIndex != 2 {
+		t.Fatalf("v1 Get() = %+v", result)
+	}
+
+	// SNMPv3 先做引擎发现, 再用 authPriv 请求
+	v3 := client(&gosnmp.GoSNMP{
+		Version:       gosnmp.Version3,
+		SecurityModel: gosnmp.UserSecurityModel,
+		MsgFlags:      gosnmp.AuthPriv,
+		SecurityParameters: &gosnmp.UsmSecurityParameters{
+			UserName:                 "simuser",
+			AuthenticationProtocol:   gosnmp.SHA,
+			AuthenticationPassphrase: "authpass1",
+			PrivacyProtocol:          gosnmp.AES,
+			PrivacyPassphrase:        "privpass1",
+		},
+	})
+	result, err = v3.Get([]string{"1.3.6.1.2.1.1.5.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result.Variables[0].Value.([]byte)) != "core-sw1" {
+		t.Fatalf("v3 Get() = %+v", result.Variables)
+	}
+
+	// 团体名错误时不应答
+	wrong := client(&gosnmp.GoSNMP{Version: gosnmp.Version2c, Community: "private"})
+	wrong.Timeout = 200 * time.Millisecond
+	if _, err := wrong.Get([]string{"1.3.6.1.2.1.1.5.0"}); err == nil {
+		t.Fatal("expected timeout with wrong community")
+	}
+	status, err := sim.GetDevice(device.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Listen == "" || status.Requests < 8 || status.Dropped != 1 || status.AuthKey != "" {
+		t.Fatalf("status = %+v", status)
+	}
+
+	// 停止后 Set 的值丢弃, 重新启动恢复录制内容
+	if status, err := sim.StopDevice(device.ID); err != nil || status.Status != "stopped" || status.Listen != "" {
+		t.Fatalf("StopDevice() = %+v, %v", status, err)
+	}
+	if status, err := sim.StartDevice(device.ID); err != nil || status.Status != "running" {
+		t.Fatalf("StartDevice() = %+v, %v", status, err)
+	}
+	v2 = client(&gosnmp.GoSNMP{Version: gosnmp.Version2c, Community: "public"})
+	if result, err := v2.Get([]string{"1.3.6.1.2.1.1.4.0"}); err != nil || string(result.Variables[0].Value.([]byte)) != "noc@example.com" {
+		t.Fatalf("Get() after restart = %+v, %v", result, err)
+	}
+
+	if err := sim.CreateDevice(&models.SimulatedDevice{Name: "broken", Recording: "1.3.6.1|2|abc"}, true); err == nil {
+		t.Fatal("expected invalid recording to be rejected")
+	}
+
+	// 厂商设备群: 设备发现按 sysDescr 识别厂商
+	fleet, err := sim.CreateFleet(SimulatorFleetRequest{Devices: map[string]int{"huawei": 2, "cisco": 1, "h3c": 1}, Interfaces: 4, Register: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fleet) != 4 || fleet[0].Name != "sim-cisco-01" || fleet[2].Name != "sim-huawei-01" || fleet[3].Name != "sim-huawei-02" {
+		t.Fatalf("fleet = %+v", fleet)
+	}
+	discovery := &DeviceDiscoveryService{}
+	for _, member := range fleet {
+		if member.Status != "running" || member.DeviceID == nil || member.Records != 8+4*16 {
+			t.Fatalf("member = %+v", member)
+		}
+		var registered models.Device
+		if err := s.db.Preload("Credentials").First(&registered, *member.DeviceID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if registered.Port != member.Port || registered.IPAddress != "127.0.0.1" || len(registered.Credentials) != 1 || registered.Credentials[0].Community != "public" {
+			t.Fatalf("registered = %+v", registered)
+		}
+
+		c := client(&gosnmp.GoSNMP{Version: gosnmp.Version2c, Community: "public"})
+		c.Port = uint16(member.Port)
+		if err := c.Connect(); err != nil {
+			t.Fatal(err)
+		}
+		result, err := c.Get([]string{"1.3.6.1.2.1.1.1.0", "1.3.6.1.2.1.1.2.0"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		vendor, _, _ := discovery.parseDeviceInfo(string(result.Variables[0].Value.([]byte)), result.Variables[1].Value.(string))
+		if vendor != member.Vendor {
+			t.Errorf("%s: vendor = %s, want %s", member.Name, vendor, member.Vendor)
+		}
+		names, err := c.BulkWalkAll("1.3.6.1.2.1.31.1.1.1.1")
+		if err != nil || len(names) != 4 {
+			t.Fatalf("%s: ifName = %+v, %v", member.Name, names, err)
+		}
+	}
+
+	// 再建一批时名称顺延
+	more, err := sim.CreateFleet(SimulatorFleetRequest{Devices: map[string]int{"cisco": 1}, Interfaces: 1})
+	if err != nil || len(more) != 1 || more[0].Name != "sim-cisco-02" || more[0].DeviceID != nil {
+		t.Fatalf("CreateFleet() = %+v, %v", more, err)
+	}
+	if _, err := sim.CreateFleet(SimulatorFleetRequest{Devices: map[string]int{"juniper": 1}}); err == nil {
+		t.Fatal("expected unsupported vendor to be rejected")
+	}
+
+	if err := sim.DeleteDevice(fleet[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	s.db.Model(&models.Device{}).Where("id = ?", *fleet[0].DeviceID).Count(&count)
+	if count != 0 {
+		t.Fatal("registered device was not deleted with the simulated device")
+	}
+	devices, err := sim.GetDevices()
+	if err != nil || len(devices) != 5 {
+		t.Fatalf("GetDevices() = %d, %v", len(devices), err)
+	}
+}
+
+func TestSNMPSimulatorHost(t *testing.T) {
+	s := newTestMIBService(t, &models.SimulatedDevice{}, &models.Device{}, &models.SNMPCredential{})
+	for _, tt := range []struct {
+		cfg  SimulatorConfig
+		want string
+	}{
+		{SimulatorConfig{}, "127.0.0.1"},
+		{SimulatorConfig{Host: "::1"}, "::1"},
+		// 非回环地址需要显式允许
+		{SimulatorConfig{Host: "0.0.0.0"}, "127.0.0.1"},
+		{SimulatorConfig{Host: "192.0.2.10"}, "127.0.0.1"},
+		{SimulatorConfig{Host: "0.0.0.0", AllowPublic: true}, "0.0.0.0"},
+	} {
+		if got := NewSNMPSimulator(s.db, tt.cfg).cfg.Host; got != tt.want {
+			t.Errorf("NewSNMPSimulator(%+v) host = %q, want %q", tt.cfg, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"mib-platform/models"
+)
+
+func TestSNMPStreamQuery(t *testing.T) {
+	s := newTestMIBService(t, &models.Device{}, &models.SNMPCredential{}, &models.SimulatedDevice{})
+	// 关联表由迁移脚本创建, 先迁移分组再补齐关联表的列
+	if err := s.db.AutoMigrate(&models.DeviceGroup{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.db.AutoMigrate(&models.DeviceGroupDevice{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 两台模拟设备和一个不应答的地址
+	sim := NewSNMPSimulator(s.db, SimulatorConfig{Host: "127.0.0.1"})
+	defer sim.Stop()
+	var devices []models.Device
+	for i, name := range []string{"core-1", "core-2"} {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		port := conn.LocalAddr().(*net.UDPAddr).Port
+		conn.Close()
+		recording := fmt.Sprintf("1.3.6.1.2.1.1.1.0|4|%s\n1.3.6.1.2.1.1.5.0|4|%s\n1.3.6.1.2.1.2.2.1.1.1|2|1\n1.3.6.1.2.1.2.2.1.1.2|2|2\n", "switch "+strconv.Itoa(i), name)
+		if err := sim.CreateDevice(&models.SimulatedDevice{Name: name, Port: port, Recording: recording}, true); err != nil {
+			t.Fatal(err)
+		}
+		device := models.Device{Name: name, IPAddress: "127.0.0.1", Port: port, Credentials: []models.SNMPCredential{{Version: "v2c", Community: "public"}}}
+		if err := s.db.Create(&device).Error; err != nil {
+			t.Fatal(err)
+		}
+		devices = append(devices, device)
+	}
+	silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+	blackhole := silent.LocalAddr().String()
+	noCreds := models.Device{Name: "legacy", IPAddress: "127.0.0.2", Port: 161}
+	if err := s.db.Create(&noCreds).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.db.Create(&models.DeviceGroup{ID: "group-core", Name: "core", Tags: models.JSON(`{"site":"dc1","role":"core"}`)}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.db.Create(&models.DeviceGroup{ID: "group-edge", Name: "edge", Tags: models.JSON(`{"site":"dc1","role":"edge"}`)}).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []uint{devices[0].ID, devices[1].ID, noCreds.ID} {
+		if err := s.db.Create(&models.DeviceGroupDevice{DeviceGroupID: "group-core", DeviceID: strconv.Itoa(int(id))}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	svc := NewSNMPService(s.db)
+	for _, tt := range []struct {
+		name string
+		req  models.SNMPStreamRequest
+		want string
+	}{
+		{"operation", models.SNMPStreamRequest{Operation: "set", OIDs: []string{"1.3.6.1.2.1.1.1.0"}, DeviceIDs: []uint{devices[0].ID}}, "unsupported operation"},
+		{"no targets", models.SNMPStreamRequest{Operation: "get", OIDs: []string{"1.3.6.1.2.1.1.1.0"}}, "no targets selected"},
+		{"no connection", models.SNMPStreamRequest{Operation: "get", OIDs: []string{"1.3.6.1.2.1.1.1.0"}, Targets: []string{"10.0.0.1"}}, "connection with version"},
+		{"unknown tags", models.SNMPStreamRequest{Operation: "get", OIDs: []string{"1.3.6.1.2.1.1.1.0"}, Tags: map[string]string{"site": "dc2"}}, "no device group matches"},
+		{"unknown oid", models.SNMPStreamRequest{Operation: "get", OIDs: []string{"noSuchThing.0"}, DeviceIDs: []uint{devices[0].ID}}, "unknown OID"},
+	} {
+		if _, err := svc.PrepareStreamQuery(&tt.req); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: PrepareStreamQuery() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+	if _, err := svc.PrepareStreamQuery(&models.SNMPStreamRequest{Operation: "get", OIDs: []string{"1.3.6.1.2.1.1.1.0"}, GroupID: "group-missing"}); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("unknown group error = %v", err)
+	}
+
+	// 标签选中的分组 + 显式地址, 重复的目标只查询一次
+	query, err := svc.PrepareStreamQuery(&models.SNMPStreamRequest{
+		Operation:  "get",
+		OIDs:       []string{"1.3.6.1.2.1.1.5.0", "1.3.6.1.2.1.1.1.0"},
+		Tags:       map[string]string{"site": "dc1", "role": "core"},
+		DeviceIDs:  []uint{devices[0].ID},
+		Targets:    []string{blackhole, fmt.Sprintf("127.0.0.1:%d", devices[1].Port)},
+		Connection: &models.SNMPRequest{Version: "v2c", Community: "public", Retries: 1},
+		Timeout:    1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query.Targets() != 4 {
+		t.Fatalf("Targets() = %d, want 4", query.Targets())
+	}
+	var results []SNMPStreamResult
+	summary := query.Run(context.Background(), func(result SNMPStreamResult) {
+		results = append(results, result)
+	})
+	if len(results) != 4 || summary.Total != 4 || summary.Succeeded != 2 || summary.Failed != 2 || summary.Cancelled != 0 {
+		t.Fatalf("summary = %+v, results = %+v", summary, results)
+	}
+	// 超时的目标最后完成
+	last := results[3]
+	if last.Seq != 4 || last.Target != blackhole || last.Success || last.Error != "timed out after 1s" || last.LatencyMs < 900 || last.LatencyMs > 3000 {
+		t.Fatalf("last result = %+v", last)
+	}
+	names := make(map[string]string)
+	for _, result := range results[:3] {
+		switch {
+		case result.Success:
+			names[result.Device] = fmt.Sprint(result.Data[0].Value)
+		case result.Device != "legacy" || !strings.Contains(result.Error, "no SNMP credentials"):
+			t.Fatalf("unexpected failure: %+v", result)
+		}
+	}
+	if names["core-1"] != "core-1" || names["core-2"] != "core-2" {
+		t.Fatalf("results by device = %v", names)
+	}
+	l := summary.Latency
+	if !(l.Min <= l.P50 && l.P50 <= l.P90 && l.P90 <= l.P95 && l.P95 <= l.P99 && l.P99 <= l.Max) || l.Max != last.LatencyMs {
+		t.Fatalf("latency = %+v", l)
+	}
+
+	// walk 和 MIB 翻译
+	query, err = svc.PrepareStreamQuery(&models.SNMPStreamRequest{Operation: "walk", OIDs: []string{"1.3.6.1.2.1.2.2"}, GroupID: "group-core", Resolve: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary = query.Run(context.Background(), func(result SNMPStreamResult) {
+		if result.Success && len(result.Data) != 2 {
+			t.Errorf("walk result = %+v", result)
+		}
+	})
+	if summary.Succeeded != 2 || summary.Failed != 1 {
+		t.Fatalf("walk summary = %+v", summary)
+	}
+
+	// 客户端断开后不再派发新目标
+	query, err = svc.PrepareStreamQuery(&models.SNMPStreamRequest{
+		Operation:   "get",
+		OIDs:        []string{"1.3.6.1.2.1.1.5.0"},
+		Targets:     []string{blackhole, "127.0.0.1:9", "127.0.0.1:10"},
+		Connection:  &models.SNMPRequest{Version: "v2c", Community: "public"},
+		Concurrency: 1,
+		Timeout:     1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	var cancelled []SNMPStreamResult
+	summary = query.Run(ctx, func(result SNMPStreamResult) {
+		cancelled = append(cancelled, result)
+	})
+	// 被断开中止的目标计为取消而不是失败
+	if summary.Failed != 0 || summary.Cancelled != 3 || len(cancelled) != 0 {
+		t.Fatalf("cancelled summary = %+v, results = %+v", summary, cancelled)
+	}
+
+	// 查询中的 panic 只让该目标失败 (没有 resolver 的服务在翻译结果时 panic)
+	query, err = svc.PrepareStreamQuery(&models.SNMPStreamRequest{Operation: "get", OIDs: []string{"1.3.6.1.2.1.1.5.0"}, DeviceIDs: []uint{devices[0].ID}, Resolve: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	query.service = &SNMPService{db: s.db}
+	var panicked []SNMPStreamResult
+	summary = query.Run(context.Background(), func(result SNMPStreamResult) {
+		panicked = append(panicked, result)
+	})
+	if summary.Failed != 1 || len(panicked) != 1 || !strings.Contains(panicked[0].Error, "panicked") {
+		t.Fatalf("panicked summary = %+v, results = %+v", summary, panicked)
+	}
+}
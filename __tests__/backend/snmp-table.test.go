@@ -0,0 +1,59 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"mib-platform/models"
+)
+
+func TestSNMPTableLayout(t *testing.T) {
+	s := newTestMIBService(t)
+	modules, err := s.compiler.CompileSource(testTableMIB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.createParsedMIB(&models.MIB{Filename: "test.mib", FilePath: "test.mib"}, modules); err != nil {
+		t.Fatal(err)
+	}
+	snmp := NewSNMPService(s.db)
+
+	layout, err := snmp.tableLayout("TEST-TABLE-MIB::testPortXTable", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if layout.row.Name != "testPortXEntry" || len(layout.columns) != 1 || layout.columns[0].Name != "testPortHCOctets" {
+		t.Errorf("layout = %+v", layout)
+	}
+	// AUGMENTS 行继承基础表的索引
+	key, err := decodeSNMPIndex("7.101.116.104.48", layout.row.Indexes, layout.hints)
+	if err != nil || !reflect.DeepEqual(key, map[string]interface{}{"testPortIndex": uint64(7), "testPortName": "eth0"}) {
+		t.Errorf("port key = %v, %v", key, err)
+	}
+
+	layout, err = snmp.tableLayout("testMacEntry", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err = decodeSNMPIndex("0.26.43.60.77.94", layout.row.Indexes, layout.hints)
+	if err != nil || key["testMacAddress"] != "00:1a:2b:3c:4d:5e" {
+		t.Errorf("mac key = %v, %v", key, err)
+	}
+	if _, err := decodeSNMPIndex("0.26.43", layout.row.Indexes, layout.hints); err == nil {
+		t.Error("short MAC index decoded")
+	}
+
+	if _, err := snmp.tableLayout("testPortTable", []string{"testPortIndex"}); err == nil {
+		t.Error("not-accessible column accepted")
+	}
+	if _, err := snmp.tableLayout("testPortName", nil); err == nil {
+		t.Error("column accepted as table")
+	}
+
+	// 非 IMPLIED 的字符串索引带长度前缀
+	indexes := []models.OIDIndex{{Name: "name", Type: "OCTET STRING"}, {Name: "addr", Type: "IpAddress"}}
+	key, err = decodeSNMPIndex("2.97.98.10.0.0.1", indexes, nil)
+	if err != nil || !reflect.DeepEqual(key, map[string]interface{}{"name": "ab", "addr": "10.0.0.1"}) {
+		t.Errorf("key = %v, %v", key, err)
+	}
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"mib-platform/models"
+)
+
+func TestSNMPTrapReceiver(t *testing.T) {
+	s := newTestMIBService(t, &models.SNMPTrap{}, &models.SNMPTrapUser{}, &models.Device{})
+	modules, err := s.compiler.CompileSource(testTrapMIB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.createParsedMIB(&models.MIB{Filename: "trap.mib", FilePath: "trap.mib"}, modules); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.db.Create(&models.Device{Name: "core-sw1", IPAddress: "127.0.0.1"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.db.Create(&models.SNMPTrapUser{Username: "informer", AuthProto: "SHA", AuthKey: "authpass1", PrivProto: "AES", PrivKey: "privpass1"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 选一个空闲端口
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+	conn.Close()
+
+	receiver := NewTrapReceiver(s.db, TrapReceiverConfig{ListenAddr: fmt.Sprintf("127.0.0.1:%d", port), Communities: []string{"public"}})
+	if err := receiver.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+	if status := receiver.Status(); !status.Running || status.Users != 1 || len(status.EngineID) < 10 {
+		t.Fatalf("status = %+v", status)
+	}
+
+	send := func(sender *gosnmp.GoSNMP, trap gosnmp.SnmpTrap) {
+		t.Helper()
+		sender.Target = "127.0.0.1"
+		sender.Port = uint16(port)
+		sender.Timeout = 2 * time.Second
+		sender.Retries = 1
+		if err := sender.Connect(); err != nil {
+			t.Fatal(err)
+		}
+		defer sender.Conn.Close()
+		if _, err := sender.SendTrap(trap); err != nil {
+			t.Fatalf("SendTrap() error = %v", err)
+		}
+	}
+	linkDown := []gosnmp.SnmpPDU{
+		{Name: "1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(4200)},
+		{Name: "1.3.6.1.6.3.1.1.4.1.0", Type: gosnmp.ObjectIdentifier, Value: "1.3.6.1.4.1.99997.0.3"},
+		{Name: "1.3.6.1.4.1.99997.1", Type: gosnmp.Integer, Value: 7},
+	}
+
+	send(&gosnmp.GoSNMP{Version: gosnmp.Version2c, Community: "public"}, gosnmp.SnmpTrap{Variables: linkDown})
+	send(&gosnmp.GoSNMP{Version: gosnmp.Version2c, Community: "wrong"}, gosnmp.SnmpTrap{Variables: linkDown})
+	// inform 必须收到响应才返回
+	send(&gosnmp.GoSNMP{Version: gosnmp.Version2c, Community: "public"}, gosnmp.SnmpTrap{Variables: linkDown, IsInform: true})
+	send(&gosnmp.GoSNMP{Version: gosnmp.Version1, Community: "public"}, gosnmp.SnmpTrap{
+		Enterprise:   "1.3.6.1.4.1.99997",
+		AgentAddress: "10.0.0.9",
+		GenericTrap:  6,
+		SpecificTrap: 5,
+		Timestamp:    300,
+		Variables:    []gosnmp.SnmpPDU{{Name: "1.3.6.1.4.1.99997.1", Type: gosnmp.Integer, Value: 2}},
+	})
+	send(&gosnmp.GoSNMP{
+		Version:       gosnmp.Version3,
+		SecurityModel: gosnmp.UserSecurityModel,
+		MsgFlags:      gosnmp.AuthPriv,
+		SecurityParameters: &gosnmp.UsmSecurityParameters{
+			UserName:                 "informer",
+			AuthenticationProtocol:   gosnmp.SHA,
+			AuthenticationPassphrase: "authpass1",
+			PrivacyProtocol:          gosnmp.AES,
+			PrivacyPassphrase:        "privpass1",
+		},
+	}, gosnmp.SnmpTrap{Variables: linkDown, IsInform: true})
+
+	var traps []models.SNMPTrap
+	var total int64
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if traps, total, err = receiver.GetTraps(TrapQuery{}); err != nil {
+			t.Fatal(err)
+		}
+		if total >= 4 {
+			break
+		}
+	}
+	if total != 4 {
+		t.Fatalf("received %d traps: %+v", total, traps)
+	}
+	if status := receiver.Status(); status.Received != 4 || status.Dropped != 1 {
+		t.Errorf("status = %+v", status)
+	}
+
+	seen := make(map[string]models.SNMPTrap)
+	for _, trap := range traps {
+		seen[trap.Version+"/"+trap.PDUType] = trap
+		if trap.DeviceName != "core-sw1" || trap.DeviceID == nil {
+			t.Errorf("trap %d device = %v %q", trap.ID, trap.DeviceID, trap.DeviceName)
+		}
+		if len(trap.Varbinds) != 1 || trap.Varbinds[0].Name != "testIfIndex" {
+			t.Errorf("trap %d varbinds = %+v", trap.ID, trap.Varbinds)
+		}
+	}
+	for _, key := range []string{"v2c/trap", "v2c/inform", "v3/inform"} {
+		trap := seen[key]
+		if trap.TrapName != "testLinkDown" || trap.Module != "TEST-TRAP-MIB" || trap.Uptime != 4200 {
+			t.Errorf("%s = %+v", key, trap)
+		}
+	}
+	if seen["v3/inform"].Username != "informer" {
+		t.Errorf("v3 username = %q", seen["v3/inform"].Username)
+	}
+	if v1 := seen["v1/trap"]; v1.TrapName != "testV1Trap" || v1.AgentAddress != "10.0.0.9" || v1.SpecificTrap != 5 || v1.Uptime != 300 {
+		t.Errorf("v1 = %+v", v1)
+	}
+
+	if traps, total, err = receiver.GetTraps(TrapQuery{Name: "testV1Trap"}); err != nil || total != 1 {
+		t.Errorf("GetTraps(name) = %d, %v", total, err)
+	}
+}
+
+func TestTrapReceiverAuthentication(t *testing.T) {
+	s := newTestMIBService(t, &models.SNMPTrap{}, &models.SNMPTrapUser{}, &models.Device{})
+	for addr, want := range map[string]bool{"127.0.0.1:1162": true, "[::1]:1162": true, "localhost:1162": true, "0.0.0.0:162": false, ":162": false, "10.0.0.1:162": false, "bad": false} {
+		if got := isLoopbackListenAddr(addr); got != want {
+			t.Errorf("isLoopbackListenAddr(%q) = %v, want %v", addr, got, want)
+		}
+	}
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+	conn.Close()
+	public := fmt.Sprintf("0.0.0.0:%d", port)
+
+	// 所有接口上不带认证的接收器会接受任何主机的 trap
+	receiver := NewTrapReceiver(s.db, TrapReceiverConfig{ListenAddr: public})
+	if err := receiver.Start(); err == nil || !strings.Contains(err.Error(), "without authentication") || receiver.Status().Running {
+		t.Errorf("unauthenticated public listener: %v", err)
+	}
+	receiver.Stop()
+
+	if err := s.db.Create(&models.SNMPTrapUser{Username: "informer", AuthProto: "SHA", AuthKey: "authpass1", PrivProto: "AES", PrivKey: "privpass1"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	v2c := func(community string) *gosnmp.GoSNMP {
+		return &gosnmp.GoSNMP{Version: gosnmp.Version2c, Community: community}
+	}
+	v3 := func(username string, flags gosnmp.SnmpV3MsgFlags) *gosnmp.GoSNMP {
+		usm := &gosnmp.UsmSecurityParameters{UserName: username, AuthoritativeEngineID: "\x80\x00\x1f\x88\x04test"}
+		if flags&gosnmp.AuthNoPriv != 0 {
+			usm.AuthenticationProtocol, usm.AuthenticationPassphrase = gosnmp.SHA, "authpass1"
+		}
+		if flags&gosnmp.AuthPriv == gosnmp.AuthPriv {
+			usm.PrivacyProtocol, usm.PrivacyPassphrase = gosnmp.AES, "privpass1"
+		}
+		return &gosnmp.GoSNMP{Version: gosnmp.Version3, SecurityModel: gosnmp.UserSecurityModel, MsgFlags: flags, SecurityParameters: usm}
+	}
+	coldStart := gosnmp.SnmpTrap{Variables: []gosnmp.SnmpPDU{
+		{Name: "1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(1)},
+		{Name: "1.3.6.1.6.3.1.1.4.1.0", Type: gosnmp.ObjectIdentifier, Value: "1.3.6.1.6.3.1.1.5.1"},
+	}}
+
+	for _, tt := range []struct {
+		name        string
+		communities []string
+		rejected    []*gosnmp.GoSNMP
+		accepted    []*gosnmp.GoSNMP
+	}{
+		{
+			name:        "communities",
+			communities: []string{"public"},
+			rejected: []*gosnmp.GoSNMP{
+				v2c("anything"),
+				v3("informer", gosnmp.NoAuthNoPriv),
+				v3("informer", gosnmp.AuthNoPriv),
+				v3("intruder", gosnmp.NoAuthNoPriv),
+			},
+			accepted: []*gosnmp.GoSNMP{v2c("public"), v3("informer", gosnmp.AuthPriv)},
+		},
+		{
+			// 非回环地址上没有团体名时不接受 v1/v2c
+			name:     "v3 only",
+			rejected: []*gosnmp.GoSNMP{v2c("public"), v2c("")},
+			accepted: []*gosnmp.GoSNMP{v3("informer", gosnmp.AuthPriv)},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s.db.Where("1 = 1").Delete(&models.SNMPTrap{})
+			receiver := NewTrapReceiver(s.db, TrapReceiverConfig{ListenAddr: public, Communities: tt.communities})
+			if err := receiver.Start(); err != nil {
+				t.Fatal(err)
+			}
+			defer receiver.Stop()
+
+			for _, sender := range append(tt.rejected, tt.accepted...) {
+				sender.Target, sender.Port, sender.Timeout, sender.Retries = "127.0.0.1", uint16(port), time.Second, 0
+				if err := sender.Connect(); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := sender.SendTrap(coldStart); err != nil {
+					t.Fatalf("SendTrap() error = %v", err)
+				}
+				sender.Conn.Close()
+			}
+
+			// 被拒绝的报文先发出, 等接受的报文入库后再留出时间让迟到的报文暴露出来
+			var traps []models.SNMPTrap
+			var total int64
+			for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline) && total < int64(len(tt.accepted)); time.Sleep(10 * time.Millisecond) {
+				if traps, total, err = receiver.GetTraps(TrapQuery{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			time.Sleep(200 * time.Millisecond)
+			if traps, total, err = receiver.GetTraps(TrapQuery{}); err != nil || total != int64(len(tt.accepted)) {
+				t.Fatalf("stored %d traps, want %d: %+v, %v", total, len(tt.accepted), traps, err)
+			}
+			for _, trap := range traps {
+				if trap.Version == "v3" && trap.Username != "informer" {
+					t.Errorf("stored v3 trap from %q", trap.Username)
+				}
+			}
+		})
+	}
+
+	// gosnmp 发不出空用户名的报文; 引擎发现请求能被解开, 但不能入库
+	s.db.Where("1 = 1").Delete(&models.SNMPTrap{})
+	receiver = NewTrapReceiver(s.db, TrapReceiverConfig{ListenAddr: public, Communities: []string{"public"}})
+	receiver.handle(&gosnmp.SnmpPacket{
+		Version:            gosnmp.Version3,
+		MsgFlags:           gosnmp.NoAuthNoPriv,
+		SecurityParameters: &gosnmp.UsmSecurityParameters{},
+		PDUType:            gosnmp.SNMPv2Trap,
+		Variables:          coldStart.Variables,
+	}, &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 162})
+	if _, total, err := receiver.GetTraps(TrapQuery{}); err != nil || total != 0 || receiver.Status().Dropped != 1 {
+		t.Errorf("empty username: stored %d traps, status = %+v, %v", total, receiver.Status(), err)
+	}
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+
+	"mib-platform/models"
+)
+
+func TestConfigureSNMPv3(t *testing.T) {
+	snmp := &gosnmp.GoSNMP{}
+	req := &models.SNMPRequest{
+		Username: "monitor", AuthProto: "sha-512", AuthKey: "authpass123",
+		PrivProto: "AES256C", PrivKey: "privpass123",
+		ContextName: "vlan-10", ContextEngineID: "0x80001f8880",
+	}
+	if err := configureSNMPv3(snmp, req); err != nil {
+		t.Fatal(err)
+	}
+	params := snmp.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	if snmp.MsgFlags != gosnmp.AuthPriv || params.AuthenticationProtocol != gosnmp.SHA512 || params.PrivacyProtocol != gosnmp.AES256C {
+		t.Errorf("flags = %v, params = %v", snmp.MsgFlags, params)
+	}
+	if snmp.ContextName != "vlan-10" || snmp.ContextEngineID != "\x80\x00\x1f\x88\x80" {
+		t.Errorf("context = %q %q", snmp.ContextName, snmp.ContextEngineID)
+	}
+
+	if err := configureSNMPv3(snmp, &models.SNMPRequest{Username: "monitor", AuthKey: "authpass123"}); err != nil || snmp.MsgFlags != gosnmp.AuthNoPriv {
+		t.Errorf("inferred level = %v, %v", snmp.MsgFlags, err)
+	}
+
+	invalid := []models.SNMPRequest{
+		{AuthKey: "authpass123"},
+		{Username: "u", SecurityLevel: "authpriv", AuthKey: "authpass123", PrivProto: "3DES", PrivKey: "privpass123"},
+		{Username: "u", AuthProto: "SHA3", AuthKey: "authpass123"},
+		{Username: "u", SecurityLevel: "authNoPriv", AuthKey: "short"},
+		{Username: "u", PrivKey: "privpass123"},
+		{Username: "u", SecurityLevel: "noAuthPriv"},
+		{Username: "u", ContextEngineID: "zz"},
+	}
+	for i := range invalid {
+		if err := configureSNMPv3(&gosnmp.GoSNMP{}, &invalid[i]); err == nil {
+			t.Errorf("configureSNMPv3(%+v) succeeded", invalid[i])
+		}
+	}
+}
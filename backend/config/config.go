@@ -9,6 +9,29 @@ type Config struct {
 	Port        string
 	JWTSecret   string
 	UploadPath  string
+
+	// MIB 目录监视
+	MIBWatchDir      string
+	MIBWatchInterval string // 轮询间隔, 如 "30s"; "0" 表示关闭
+
+	// SNMP trap 接收
+	TrapListenAddr  string // UDP 监听地址, 默认只监听本机非特权端口; "off" 表示关闭
+	TrapCommunities string // 逗号分隔, 为空时只在回环地址上接受 v1/v2c trap; 监听非回环地址时需要团体名或 v3 用户
+	TrapRetention   string // trap 保留时长, 如 "168h"; "0" 表示不清理
+	TrapEngineID    string // 接收 v3 inform 的本地 engine ID (十六进制), 为空时按主机名生成
+	TrapWebhookURL  string // 收到 trap 后 POST JSON 到该地址
+
+	// SNMP 定时轮询
+	PollInterval        string // 默认轮询间隔, 设备模板可用 poll_interval 覆盖; "0" 表示关闭
+	PollJitter          string // 每次轮询时间的随机偏移上限
+	PollRawRetention    string // 原始样本保留时长, 之后降采样为 5 分钟平均值; "0" 表示不降采样
+	PollRollupRetention string // 降采样数据保留时长; "0" 表示不清理
+	PollMaxConcurrent   string // 同时轮询的设备数
+
+	// SNMP 代理模拟器
+	SimulatorHost        string // 模拟设备的监听地址, 默认只监听本机
+	SimulatorPorts       string // 未指定端口时分配的 UDP 端口范围, 如 "16100-16199"
+	SimulatorAllowPublic string // "true" 时允许 SimulatorHost 为非回环地址
 }
 
 func Load() *Config {
@@ -17,6 +40,25 @@ func Load() *Config {
 		Port:        getEnv("SERVER_PORT", "17880"),
 		JWTSecret:   getEnv("JWT_SECRET", "your-secret-key"),
 		UploadPath:  getEnv("UPLOAD_PATH", "./uploads"),
+
+		MIBWatchDir:      getEnv("MIB_WATCH_DIR", "/opt/monitoring/mibs"),
+		MIBWatchInterval: getEnv("MIB_WATCH_INTERVAL", "30s"),
+
+		TrapListenAddr:  getEnv("TRAP_LISTEN_ADDR", "127.0.0.1:1162"),
+		TrapCommunities: getEnv("TRAP_COMMUNITIES", ""),
+		TrapRetention:   getEnv("TRAP_RETENTION", "168h"),
+		TrapEngineID:    getEnv("TRAP_ENGINE_ID", ""),
+		TrapWebhookURL:  getEnv("TRAP_WEBHOOK_URL", ""),
+
+		PollInterval:        getEnv("POLL_INTERVAL", "60s"),
+		PollJitter:          getEnv("POLL_JITTER", "5s"),
+		PollRawRetention:    getEnv("POLL_RAW_RETENTION", "48h"),
+		PollRollupRetention: getEnv("POLL_ROLLUP_RETENTION", "720h"),
+		PollMaxConcurrent:   getEnv("POLL_MAX_CONCURRENT", "8"),
+
+		SimulatorHost:        getEnv("SIMULATOR_HOST", "127.0.0.1"),
+		SimulatorPorts:       getEnv("SIMULATOR_PORTS", "16100-16199"),
+		SimulatorAllowPublic: getEnv("SIMULATOR_ALLOW_PUBLIC", "false"),
 	}
 }
 
@@ -63,8 +63,8 @@ func (c *ConfigController) GenerateConfig(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "config_name is required"})
 		return
 	}
-	if len(req.SelectedOIDs) == 0 {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "selected_oids is required"})
+	if len(req.SelectedOIDs) == 0 && req.MetricSets == nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "selected_oids or metric_sets is required"})
 		return
 	}
 
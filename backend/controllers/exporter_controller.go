@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gorm.io/gorm"
+
+	"mib-platform/services"
+)
+
+// prometheusContentType Prometheus 文本格式 0.0.4
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type ExporterController struct {
+	exporter *services.PrometheusExporter
+}
+
+func NewExporterController(exporter *services.PrometheusExporter) *ExporterController {
+	return &ExporterController{exporter: exporter}
+}
+
+// Metrics 输出轮询引擎最近一次采集到的所有设备的指标
+func (c *ExporterController) Metrics(ctx *gin.Context) {
+	var buf bytes.Buffer
+	if err := c.exporter.Metrics(&buf); err != nil {
+		ctx.String(http.StatusInternalServerError, "An error has occurred while serving metrics:\n\n%v\n", err)
+		return
+	}
+	ctx.Data(http.StatusOK, prometheusContentType, buf.Bytes())
+}
+
+// Probe 与 snmp_exporter 的 /probe 相同: 实时采集 target 指定的设备
+func (c *ExporterController) Probe(ctx *gin.Context) {
+	target := ctx.Query("target")
+	if target == "" {
+		ctx.String(http.StatusBadRequest, "'target' parameter must be specified\n")
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := c.exporter.Probe(target, &buf); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.String(http.StatusNotFound, "Unknown target %q\n", target)
+			return
+		}
+		ctx.String(http.StatusInternalServerError, "An error has occurred while serving metrics:\n\n%v\n", err)
+		return
+	}
+	ctx.Data(http.StatusOK, prometheusContentType, buf.Bytes())
+}
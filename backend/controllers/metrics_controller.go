@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"gorm.io/gorm"
+
+	"mib-platform/services"
+)
+
+type MetricsController struct {
+	poller *services.SNMPPoller
+}
+
+func NewMetricsController(poller *services.SNMPPoller) *MetricsController {
+	return &MetricsController{poller: poller}
+}
+
+func (c *MetricsController) GetPollerStatus(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"data": c.poller.Status()})
+}
+
+// GetSeries 列出已采集的序列, 可按设备和 OID (数字或对象名) 过滤
+func (c *MetricsController) GetSeries(ctx *gin.Context) {
+	deviceID, _ := strconv.ParseUint(ctx.Query("device_id"), 10, 32)
+
+	series, err := c.poller.GetSeries(uint(deviceID), ctx.Query("oid"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": series})
+}
+
+// QuerySeries 范围查询: start / end 为 RFC 3339 时间, 默认最近一小时; step 如 "5m"
+func (c *MetricsController) QuerySeries(ctx *gin.Context) {
+	deviceID, _ := strconv.ParseUint(ctx.Query("device_id"), 10, 32)
+	query := services.MetricQuery{
+		DeviceID: uint(deviceID),
+		OID:      ctx.Query("oid"),
+	}
+	if query.DeviceID == 0 && query.OID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "device_id or oid is required"})
+		return
+	}
+
+	for param, field := range map[string]*time.Time{"start": &query.Start, "end": &query.End} {
+		value := ctx.Query(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param + " time, expected RFC 3339"})
+			return
+		}
+		*field = t
+	}
+	if value := ctx.Query("step"); value != "" {
+		step, err := time.ParseDuration(value)
+		if err != nil || step < time.Second {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid step, expected a duration such as 5m"})
+			return
+		}
+		query.Step = step
+	}
+
+	data, err := c.poller.QuerySeries(query)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+// PollDevice 立即轮询设备, 不影响调度
+func (c *MetricsController) PollDevice(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+		return
+	}
+
+	result, err := c.poller.PollDevice(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+			return
+		}
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "data": result})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": result})
+}
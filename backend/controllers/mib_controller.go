@@ -20,13 +20,15 @@ import (
 type MIBController struct {
 	db      *gorm.DB
 	service *services.MIBService
+	watcher *services.MIBDirectoryWatcher
 }
 
-func NewMIBController(db *gorm.DB, ) *MIBController {
+func NewMIBController(db *gorm.DB, watcher *services.MIBDirectoryWatcher) *MIBController {
 	return &MIBController{
 		db:      db,
 		redis:   redis,
 		service: services.NewMIBService(db, redis),
+		watcher: watcher,
 	}
 }
 
@@ -90,10 +92,25 @@ func (c *MIBController) UploadMIB(ctx *gin.Context) {
 	}
 	defer file.Close()
 
+	// 厂商 MIB 压缩包: 后台导入, 返回可轮询的任务
+	if services.IsMIBBundle(header.Filename) {
+		job, err := c.service.StartMIBBundleUpload(file, header)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx.JSON(http.StatusAccepted, gin.H{
+			"message": "MIB bundle import started",
+			"data":    job,
+		})
+		return
+	}
+
 	// 验证文件类型
 	ext := strings.ToLower(filepath.Ext(header.Filename))
 	if ext != ".mib" && ext != ".txt" && ext != ".my" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file type. Only .mib, .txt, .my files are allowed"})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file type. Only .mib, .txt, .my, .zip, .tar.gz files are allowed"})
 		return
 	}
 
@@ -110,6 +127,23 @@ func (c *MIBController) UploadMIB(ctx *gin.Context) {
 	})
 }
 
+// 查询 MIB 压缩包导入任务及逐文件报告
+func (c *MIBController) GetMIBUploadJob(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	job, err := c.service.GetMIBUploadJob(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Upload job not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": job})
+}
+
 // 扫描 MIB 目录
 func (c *MIBController) ScanMIBDirectory(ctx *gin.Context) {
 	dirPath := ctx.Query("path")
@@ -201,7 +235,13 @@ func (c *MIBController) DeleteMIB(ctx *gin.Context) {
 
 
 func (c *MIBController) ParseMIB(ctx *gin.Context) {
-	result, err := c.service.ParseMIB(ctx.Param("id"))
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid MIB ID"})
+		return
+	}
+
+	result, err := c.service.ReparseMIB(uint(id))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -210,6 +250,8 @@ func (c *MIBController) ParseMIB(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"data": result})
 }
 
+// 检查 MIB 文件的 SMI 合规性
+// 可选表单字段: severity=rule=level (可重复或逗号分隔, level 为 error/warning/info/off), min_severity=level
 func (c *MIBController) ValidateMIB(ctx *gin.Context) {
 	file, header, err := ctx.Request.FormFile("file")
 	if err != nil {
@@ -218,6 +260,24 @@ func (c *MIBController) ValidateMIB(ctx *gin.Context) {
 	}
 	defer file.Close()
 
+	severities := make(map[string]services.MIBLintSeverity)
+	for _, value := range ctx.PostFormArray("severity") {
+		for _, item := range strings.Split(value, ",") {
+			rule, level, ok := strings.Cut(strings.TrimSpace(item), "=")
+			if !ok {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid severity %q, expected rule=level", item)})
+				return
+			}
+			severity, err := services.ParseMIBLintSeverity(level)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			severities[strings.TrimSpace(rule)] = severity
+		}
+	}
+	minimum := services.MIBLintSeverity(strings.ToLower(ctx.PostForm("min_severity")))
+
 	// 保存临时文件
 	tempPath := "/tmp/" + header.Filename
 	out, err := os.Create(tempPath)
@@ -234,9 +294,9 @@ func (c *MIBController) ValidateMIB(ctx *gin.Context) {
 		return
 	}
 
-	result, err := c.service.ValidateMIBFile(tempPath)
+	result, err := c.service.ValidateMIBFile(tempPath, severities, minimum)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -260,16 +320,22 @@ func (c *MIBController) GetMIBOIDs(ctx *gin.Context) {
 }
 
 func (c *MIBController) ImportMIBs(ctx *gin.Context) {
-	file, _, err := ctx.Request.FormFile("file")
+	file, header, err := ctx.Request.FormFile("file")
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
 		return
 	}
 	defer file.Close()
 
-	result, err := c.service.ImportMIBs(file, "json")
+	format := ctx.PostForm("format")
+	if format == "" {
+		format = services.DetectMIBTransferFormat(header.Filename)
+	}
+	dryRun, _ := strconv.ParseBool(ctx.DefaultPostForm("dry_run", ctx.Query("dry_run")))
+
+	result, err := c.service.ImportMIBs(file, format, dryRun)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -286,6 +352,346 @@ func (c *MIBController) ExportMIBs(ctx *gin.Context) {
 		return
 	}
 
+	contentType := "application/octet-stream"
+	switch format {
+	case "json":
+		contentType = "application/json"
+	case "csv":
+		contentType = "text/csv"
+	case "archive":
+		contentType = "application/zip"
+	}
 	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
-	ctx.Data(http.StatusOK, "application/octet-stream", data)
+	ctx.Data(http.StatusOK, contentType, data)
+}
+
+// 从选择的 MIB 对象生成 snmp_exporter generator.yml; format=archive 时打包所需的 MIB 文件
+func (c *MIBController) ExportGeneratorConfig(ctx *gin.Context) {
+	var req services.GeneratorConfig
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	export, err := c.service.ExportGeneratorConfig(req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch ctx.DefaultQuery("format", "json") {
+	case "yaml":
+		ctx.Header("Content-Disposition", "attachment; filename=generator.yml")
+		ctx.Data(http.StatusOK, "application/x-yaml", []byte(export.YAML))
+	case "archive":
+		data, err := export.Archive()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Header("Content-Disposition", "attachment; filename=generator.zip")
+		ctx.Data(http.StatusOK, "application/zip", data)
+	default:
+		ctx.JSON(http.StatusOK, gin.H{"data": export})
+	}
+}
+
+// 列出可一次选中的对象组和一致性声明
+func (c *MIBController) GetMetricSets(ctx *gin.Context) {
+	sets, err := c.service.GetMetricSets(ctx.Query("search"), ctx.Query("module"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":  sets,
+		"count": len(sets),
+	})
+}
+
+// 将对象组和一致性声明展开为 OID 列表
+func (c *MIBController) ExpandMetricSets(ctx *gin.Context) {
+	var req services.MIBMetricSetRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Groups) == 0 && len(req.Compliances) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "groups or compliances is required"})
+		return
+	}
+
+	expansion, err := c.service.ExpandMetricSets(req)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": expansion})
+}
+
+// 获取 MIB 的依赖树和加载顺序
+func (c *MIBController) GetMIBDependencies(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid MIB ID"})
+		return
+	}
+
+	report, err := c.service.GetMIBDependencies(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+// 列出缺少依赖的 MIB
+func (c *MIBController) GetMissingDependencies(ctx *gin.Context) {
+	results, err := c.service.GetMissingDependencies()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":  results,
+		"count": len(results),
+	})
+}
+
+// 浏览 OID 树: 列出子节点, oid 为空时返回根节点
+func (c *MIBController) GetOIDChildren(ctx *gin.Context) {
+	children, err := c.service.GetOIDChildren(ctx.Query("oid"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":  children,
+		"count": len(children),
+	})
+}
+
+// 获取 OID 的祖先链
+func (c *MIBController) GetOIDAncestors(ctx *gin.Context) {
+	chain, err := c.service.GetOIDAncestors(ctx.Query("oid"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": chain})
+}
+
+// 按 MODULE::name 查找 OID
+func (c *MIBController) LookupOID(ctx *gin.Context) {
+	nodes, err := c.service.LookupOIDSymbol(ctx.Query("symbol"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":  nodes,
+		"count": len(nodes),
+	})
+}
+
+// 全文搜索 OID: 名称、描述、模块、单位和枚举标签; 数字查询按 OID 前缀匹配
+func (c *MIBController) SearchOIDs(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	query := services.MIBSearchQuery{
+		Query:  ctx.Query("q"),
+		Module: ctx.Query("module"),
+		Vendor: ctx.Query("vendor"),
+		Page:   page,
+		Limit:  limit,
+	}
+	if strings.TrimSpace(query.Query) == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+
+	result, err := c.service.SearchOIDs(query)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"data":   result.Hits,
+		"total":  result.Total,
+		"page":   page,
+		"limit":  limit,
+		"mode":   result.Mode,
+		"facets": gin.H{"modules": result.Modules, "vendors": result.Vendors},
+	}
+	if result.Warning != "" {
+		response["warning"] = result.Warning
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// 对数字 OID 做最长前缀匹配
+func (c *MIBController) MatchOID(ctx *gin.Context) {
+	match, err := c.service.MatchOID(ctx.Query("oid"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": match})
+}
+
+// 列出通知目录 (NOTIFICATION-TYPE / TRAP-TYPE)
+func (c *MIBController) GetNotifications(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	search := ctx.Query("search")
+	module := ctx.Query("module")
+
+	notifications, total, err := c.service.GetNotifications(page, limit, search, module)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":  notifications,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// 按通知 OID 或 SNMPv1 enterprise/specific-trap 查找通知
+func (c *MIBController) SearchNotifications(ctx *gin.Context) {
+	oid := ctx.Query("oid")
+	enterprise := ctx.Query("enterprise")
+	if oid == "" && enterprise == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "oid or enterprise is required"})
+		return
+	}
+
+	var specific *uint32
+	if value := ctx.Query("specific_trap"); value != "" {
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid specific_trap"})
+			return
+		}
+		v := uint32(n)
+		specific = &v
+	}
+
+	notifications, err := c.service.SearchNotifications(oid, enterprise, specific)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":  notifications,
+		"count": len(notifications),
+	})
+}
+
+// 获取 MIB 中定义的通知
+func (c *MIBController) GetMIBNotifications(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid MIB ID"})
+		return
+	}
+
+	notifications, err := c.service.GetMIBNotifications(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": notifications})
+}
+
+// 列出同一模块在 MIB 库中的所有版本
+func (c *MIBController) GetMIBRevisions(ctx *gin.Context) {
+	module := ctx.Query("module")
+	if module == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "module is required"})
+		return
+	}
+
+	revisions, err := c.service.GetMIBRevisions(module)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":  revisions,
+		"count": len(revisions),
+	})
+}
+
+// 比较两个 MIB 版本: 新增、删除和变化的对象
+func (c *MIBController) DiffMIBs(ctx *gin.Context) {
+	from, err := strconv.ParseUint(ctx.Query("from"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from MIB ID"})
+		return
+	}
+	to, err := strconv.ParseUint(ctx.Query("to"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to MIB ID"})
+		return
+	}
+
+	diff, err := c.service.DiffMIBs(uint(from), uint(to))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": diff})
+}
+
+// 获取 MIB 目录监视器状态
+func (c *MIBController) GetWatcherStatus(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"data": c.watcher.Status()})
+}
+
+// 立即扫描一次 MIB 目录
+func (c *MIBController) TriggerWatcherScan(ctx *gin.Context) {
+	summary, err := c.watcher.Scan()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": summary})
+}
+
+// 列出 MIB 目录导入事件
+func (c *MIBController) GetWatcherEvents(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "50"))
+	action := ctx.Query("action")
+
+	events, total, err := c.watcher.GetEvents(page, limit, action)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":  events,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
 }
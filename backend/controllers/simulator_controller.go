@@ -0,0 +1,228 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"gorm.io/gorm"
+
+	"mib-platform/models"
+	"mib-platform/services"
+)
+
+// 录制文件上限
+const simulatorMaxRecording = 32 << 20
+
+type SimulatorController struct {
+	simulator *services.SNMPSimulator
+}
+
+func NewSimulatorController(simulator *services.SNMPSimulator) *SimulatorController {
+	return &SimulatorController{simulator: simulator}
+}
+
+// simulatedDeviceRequest 创建模拟设备的 JSON 请求, recording 为录制文件内容
+type simulatedDeviceRequest struct {
+	models.SimulatedDevice
+	Recording string `json:"recording"`
+	Start     *bool  `json:"start"` // 默认立即启动
+}
+
+func (c *SimulatorController) GetDevices(ctx *gin.Context) {
+	devices, err := c.simulator.GetDevices()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": devices})
+}
+
+func (c *SimulatorController) GetDevice(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid simulated device ID"})
+		return
+	}
+
+	device, err := c.simulator.GetDevice(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Simulated device not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": device})
+}
+
+// GetRecording 以 .snmprec 格式下载模拟设备当前的值
+func (c *SimulatorController) GetRecording(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid simulated device ID"})
+		return
+	}
+
+	recording, err := c.simulator.GetRecording(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Simulated device not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(recording))
+}
+
+// CreateDevice 创建模拟设备: multipart 上传 file (.snmprec / .snmpwalk) 和表单字段,
+// 或 JSON 请求体中的 recording
+func (c *SimulatorController) CreateDevice(ctx *gin.Context) {
+	var device models.SimulatedDevice
+	start := true
+
+	if strings.HasPrefix(ctx.ContentType(), "multipart/") {
+		file, header, err := ctx.Request.FormFile("file")
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+			return
+		}
+		defer file.Close()
+		data, err := io.ReadAll(io.LimitReader(file, simulatorMaxRecording+1))
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(data) > simulatorMaxRecording {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Recording is too large"})
+			return
+		}
+
+		device = models.SimulatedDevice{
+			Name:        ctx.PostForm("name"),
+			Vendor:      ctx.PostForm("vendor"),
+			Description: ctx.PostForm("description"),
+			Community:   ctx.PostForm("community"),
+			Username:    ctx.PostForm("username"),
+			AuthProto:   ctx.PostForm("auth_proto"),
+			AuthKey:     ctx.PostForm("auth_key"),
+			PrivProto:   ctx.PostForm("priv_proto"),
+			PrivKey:     ctx.PostForm("priv_key"),
+			EngineID:    ctx.PostForm("engine_id"),
+			Format:      ctx.PostForm("format"),
+			Recording:   string(data),
+		}
+		if device.Name == "" {
+			device.Name = strings.TrimSuffix(header.Filename, filepath.Ext(header.Filename))
+		}
+		if device.Format == "" {
+			device.Format = services.DetectRecordingFormat(header.Filename, device.Recording)
+		}
+		if value := ctx.PostForm("port"); value != "" {
+			if device.Port, err = strconv.Atoi(value); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid port"})
+				return
+			}
+		}
+		start = ctx.DefaultPostForm("start", "true") != "false"
+	} else {
+		var req simulatedDeviceRequest
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		device = req.SimulatedDevice
+		device.Recording = req.Recording
+		if req.Start != nil {
+			start = *req.Start
+		}
+	}
+
+	if err := c.simulator.CreateDevice(&device, start); err != nil {
+		// 已保存但端口无法监听时仍返回设备, 状态为 error
+		if device.ID != 0 {
+			status, _ := c.simulator.GetDevice(device.ID)
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error(), "data": status})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, err := c.simulator.GetDevice(device.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"data": status})
+}
+
+func (c *SimulatorController) StartDevice(ctx *gin.Context) {
+	c.toggle(ctx, c.simulator.StartDevice)
+}
+
+func (c *SimulatorController) StopDevice(ctx *gin.Context) {
+	c.toggle(ctx, c.simulator.StopDevice)
+}
+
+func (c *SimulatorController) toggle(ctx *gin.Context, action func(uint) (*services.SimulatedDeviceStatus, error)) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid simulated device ID"})
+		return
+	}
+
+	status, err := action(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Simulated device not found"})
+			return
+		}
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error(), "data": status})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": status})
+}
+
+func (c *SimulatorController) DeleteDevice(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid simulated device ID"})
+		return
+	}
+
+	if err := c.simulator.DeleteDevice(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Simulated device not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Simulated device deleted successfully"})
+}
+
+// CreateFleet 用内置录制批量启动 Cisco / Huawei / H3C 模拟设备, 如 {"devices": {"cisco": 2, "huawei": 2, "h3c": 1}, "register": true}
+func (c *SimulatorController) CreateFleet(ctx *gin.Context) {
+	var req services.SimulatorFleetRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	devices, err := c.simulator.CreateFleet(req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "data": devices})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"data": devices})
+}
@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"gorm.io/gorm"
+
+	"mib-platform/services"
+)
+
+// 导入的录制文件上限
+const snapshotMaxImport = 64 << 20
+
+type SnapshotController struct {
+	snapshots *services.SnapshotService
+}
+
+func NewSnapshotController(snapshots *services.SnapshotService) *SnapshotController {
+	return &SnapshotController{snapshots: snapshots}
+}
+
+// takeSnapshotRequest root 为空时 walk 整个设备
+type takeSnapshotRequest struct {
+	DeviceID uint   `json:"device_id" binding:"required"`
+	Root     string `json:"root"`
+	Label    string `json:"label"`
+}
+
+func (c *SnapshotController) GetSnapshots(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	deviceID, _ := strconv.ParseUint(ctx.Query("device_id"), 10, 32)
+
+	snapshots, total, err := c.snapshots.GetSnapshots(uint(deviceID), page, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":  snapshots,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+func (c *SnapshotController) GetSnapshot(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid snapshot ID"})
+		return
+	}
+
+	snapshot, err := c.snapshots.GetSnapshot(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Snapshot not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": snapshot})
+}
+
+// TakeSnapshot 立即 walk 设备并保存快照
+func (c *SnapshotController) TakeSnapshot(ctx *gin.Context) {
+	var req takeSnapshotRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	snapshot, err := c.snapshots.TakeSnapshot(req.DeviceID, req.Root, req.Label)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+			return
+		}
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"data": snapshot})
+}
+
+// ImportSnapshot multipart 上传 file (snmpwalk / snmprec) 以及 device_id、label、format、taken_at (RFC3339)
+func (c *SnapshotController) ImportSnapshot(ctx *gin.Context) {
+	deviceID, err := strconv.ParseUint(ctx.PostForm("device_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+		return
+	}
+	var takenAt time.Time
+	if value := ctx.PostForm("taken_at"); value != "" {
+		if takenAt, err = time.Parse(time.RFC3339, value); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid taken_at, expected RFC3339"})
+			return
+		}
+	}
+
+	file, header, err := ctx.Request.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, snapshotMaxImport+1))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(data) > snapshotMaxImport {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Recording is too large"})
+		return
+	}
+
+	format := ctx.PostForm("format")
+	if format == "" {
+		format = services.DetectRecordingFormat(header.Filename, string(data))
+	}
+	label := ctx.DefaultPostForm("label", header.Filename)
+
+	snapshot, err := c.snapshots.ImportSnapshot(uint(deviceID), string(data), format, label, takenAt)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"data": snapshot})
+}
+
+// ExportSnapshot 下载快照, format 为 snmpwalk (默认) 或 snmprec
+func (c *SnapshotController) ExportSnapshot(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid snapshot ID"})
+		return
+	}
+
+	format := ctx.DefaultQuery("format", services.RecordingFormatSNMPWalk)
+	snapshot, text, err := c.snapshots.ExportSnapshot(uint(id), format)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Snapshot not found"})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("%s-%s.%s", snapshot.DeviceName, snapshot.TakenAt.Format("20060102-150405"), format)
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	ctx.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(text))
+}
+
+// DiffSnapshots 比较 from 和 to 两个快照, ignore 为逗号分隔的对象名或 OID
+func (c *SnapshotController) DiffSnapshots(ctx *gin.Context) {
+	from, err := strconv.ParseUint(ctx.Query("from"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'from' snapshot ID"})
+		return
+	}
+	to, err := strconv.ParseUint(ctx.Query("to"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'to' snapshot ID"})
+		return
+	}
+
+	opts := services.SnapshotDiffOptions{IgnoreVolatile: ctx.Query("ignore_volatile") == "true"}
+	if value := ctx.Query("ignore"); value != "" {
+		opts.Ignore = strings.Split(value, ",")
+	}
+
+	diff, err := c.snapshots.DiffSnapshots(uint(from), uint(to), opts)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Snapshot not found"})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": diff})
+}
+
+func (c *SnapshotController) DeleteSnapshot(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid snapshot ID"})
+		return
+	}
+
+	if err := c.snapshots.DeleteSnapshot(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Snapshot not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Snapshot deleted successfully"})
+}
@@ -1,7 +1,9 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 
@@ -72,6 +74,23 @@ func (c *SNMPController) SNMPWalk(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, response)
 }
 
+// SNMPTable 获取概念表, 行按 INDEX 解码, 列名来自 MIB 库
+func (c *SNMPController) SNMPTable(ctx *gin.Context) {
+	var req models.SNMPTableRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := c.service.SNMPTable(&req)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
 func (c *SNMPController) SNMPSet(ctx *gin.Context) {
 	var req models.SNMPSetRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -110,29 +129,214 @@ func (c *SNMPController) BulkOperations(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Operation type is required"})
 		return
 	}
+	concurrency, _ := strconv.Atoi(ctx.Query("concurrency"))
 
-	var requests []models.SNMPRequest
+	var requests []models.BulkSNMPRequest
 	if err := ctx.ShouldBindJSON(&requests); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	operation, err := c.service.StartBulkOperation(operationType, requests)
+	operation, err := c.service.StartBulkOperation(operationType, requests, concurrency)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	ctx.JSON(http.StatusAccepted, gin.H{"data": operation})
 }
 
+// GetBulkOperation 查询批量操作状态, 并分页返回各请求的结果
 func (c *SNMPController) GetBulkOperation(ctx *gin.Context) {
-	id := ctx.Param("id")
-	operation, err := c.service.GetBulkOperation(id)
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid operation ID"})
+		return
+	}
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "50"))
+	status := ctx.Query("status")
+
+	operation, err := c.service.GetBulkOperation(uint(id))
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
 		return
 	}
 
+	results, total, err := c.service.GetBulkOperationResults(uint(id), page, limit, status)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":    operation,
+		"results": results,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// CancelBulkOperation 取消正在执行的批量操作
+func (c *SNMPController) CancelBulkOperation(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid operation ID"})
+		return
+	}
+
+	operation, err := c.service.CancelBulkOperation(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+			return
+		}
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{"data": operation})
 }
+
+// revertSetRequest 没有关联设备的操作需要重新提供连接参数
+type revertSetRequest struct {
+	Connection *models.SNMPRequest `json:"connection" binding:"-"`
+	Force      bool                `json:"force"` // 设备上的值已被修改时仍然回滚
+	Comment    string              `json:"comment"`
+}
+
+// ApplySet 预读当前值、按 MIB 校验后原子写入多个变量; dry_run 时只返回将要进行的修改
+func (c *SNMPController) ApplySet(ctx *gin.Context) {
+	var req models.SNMPSetOperationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	operation, err := c.service.ApplySet(&req)
+	if err != nil {
+		c.setError(ctx, operation, err)
+		return
+	}
+
+	if req.DryRun {
+		ctx.JSON(http.StatusOK, gin.H{"data": operation})
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"data": operation})
+}
+
+func (c *SNMPController) GetSetOperations(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	deviceID, _ := strconv.ParseUint(ctx.Query("device_id"), 10, 32)
+
+	operations, total, err := c.service.GetSetOperations(services.SNMPSetOperationQuery{
+		DeviceID: uint(deviceID),
+		Target:   ctx.Query("target"),
+		Status:   ctx.Query("status"),
+		Page:     page,
+		Limit:    limit,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":  operations,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+func (c *SNMPController) GetSetOperation(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid operation ID"})
+		return
+	}
+
+	operation, err := c.service.GetSetOperation(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": operation})
+}
+
+// RevertSetOperation 把操作涉及的变量写回原值
+func (c *SNMPController) RevertSetOperation(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid operation ID"})
+		return
+	}
+
+	var req revertSetRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	operation, err := c.service.RevertSetOperation(uint(id), req.Connection, req.Force, req.Comment)
+	if err != nil {
+		c.setError(ctx, operation, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"data": operation})
+}
+
+// setError 校验失败为 400, 值已被修改为 409, 设备拒绝或无响应为 502 (已记录的操作一并返回)
+func (c *SNMPController) setError(ctx *gin.Context, operation *models.SNMPSetOperation, err error) {
+	var invalid *services.SNMPSetValidationError
+	switch {
+	case errors.As(err, &invalid):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "problems": invalid.Problems})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Operation or device not found"})
+	case errors.Is(err, services.ErrSNMPSetConflict):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "data": operation})
+	}
+}
+
+// StreamQuery 对多个目标执行同一查询, 以 Server-Sent Events 推送: start 事件给出目标数,
+// 每个目标完成后推送一个 result 事件, 最后推送 summary 事件
+func (c *SNMPController) StreamQuery(ctx *gin.Context) {
+	var req models.SNMPStreamRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query, err := c.service.PrepareStreamQuery(&req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Device group not found"})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no") // 关闭 nginx 缓冲, 结果才能逐个到达
+	ctx.SSEvent("start", gin.H{"operation": req.Operation, "total": query.Targets()})
+	ctx.Writer.Flush()
+
+	summary := query.Run(ctx.Request.Context(), func(result services.SNMPStreamResult) {
+		ctx.SSEvent("result", result)
+		ctx.Writer.Flush()
+	})
+	ctx.SSEvent("summary", summary)
+	ctx.Writer.Flush()
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"gorm.io/gorm"
+
+	"mib-platform/models"
+	"mib-platform/services"
+)
+
+type TrapController struct {
+	receiver *services.TrapReceiver
+}
+
+func NewTrapController(receiver *services.TrapReceiver) *TrapController {
+	return &TrapController{receiver: receiver}
+}
+
+// GetTraps 分页查询 trap, 支持按来源、设备、trap OID / 名称和时间范围 (RFC 3339) 过滤
+func (c *TrapController) GetTraps(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "50"))
+	deviceID, _ := strconv.ParseUint(ctx.Query("device_id"), 10, 32)
+
+	query := services.TrapQuery{
+		Page:     page,
+		Limit:    limit,
+		Source:   ctx.Query("source"),
+		DeviceID: uint(deviceID),
+		TrapOID:  ctx.Query("oid"),
+		Name:     ctx.Query("name"),
+	}
+	for param, field := range map[string]**time.Time{"since": &query.Since, "until": &query.Until} {
+		value := ctx.Query(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param + " time, expected RFC 3339"})
+			return
+		}
+		*field = &t
+	}
+
+	traps, total, err := c.receiver.GetTraps(query)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":  traps,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+func (c *TrapController) GetTrap(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trap ID"})
+		return
+	}
+
+	trap, err := c.receiver.GetTrap(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Trap not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": trap})
+}
+
+func (c *TrapController) GetReceiverStatus(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"data": c.receiver.Status()})
+}
+
+func (c *TrapController) GetUsers(ctx *gin.Context) {
+	users, err := c.receiver.GetUsers()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": users})
+}
+
+// CreateUser 添加接收 v3 inform 的 USM 用户, 监听随之重启
+func (c *TrapController) CreateUser(ctx *gin.Context) {
+	var user models.SNMPTrapUser
+	if err := ctx.ShouldBindJSON(&user); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.receiver.CreateUser(&user); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user.AuthKey = ""
+	user.PrivKey = ""
+	ctx.JSON(http.StatusCreated, gin.H{"data": user})
+}
+
+func (c *TrapController) DeleteUser(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := c.receiver.DeleteUser(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+}
@@ -24,12 +24,28 @@ func Initialize() (*gorm.DB, error) {
 	err = db.AutoMigrate(
 		&models.MIB{},
 		&models.OID{},
+		&models.MIBImport{},
+		&models.MIBType{},
+		&models.MIBNotification{},
+		&models.MIBGroup{},
+		&models.MIBCompliance{},
+		&models.MIBUploadJob{},
+		&models.MIBIngestEvent{},
 		&models.Device{},
 		&models.DeviceTemplate{},
 		&models.Config{},
 		&models.ConfigTemplate{},
 		&models.ConfigVersion{},
 		&models.SNMPCredential{},
+		&models.BulkOperation{},
+		&models.BulkOperationResult{},
+		&models.SNMPSetOperation{},
+		&models.SNMPTrap{},
+		&models.SNMPTrapUser{},
+		&models.MetricSeries{},
+		&models.MetricSample{},
+		&models.SimulatedDevice{},
+		&models.DeviceSnapshot{},
 		&models.Setting{},
 		&models.Host{},
 		&models.HostComponent{},
@@ -5,9 +5,12 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -80,9 +83,93 @@ func main() {
 	deploymentService := services.NewDeploymentService(db, hostService)
 	configDeploymentService := services.NewConfigDeploymentService(db, hostService)
 
+	// Bundle uploads still pending or running belonged to the previous process
+	if n, err := services.NewMIBService(db).FailInterruptedUploadJobs(); err != nil {
+		log.Print(err)
+	} else if n > 0 {
+		log.Printf("Marked %d interrupted MIB upload jobs as failed", n)
+	}
+
+	// Without the sqlite_fts5 build tag OID search falls back to LIKE matching
+	if !services.NewMIBService(db).FullTextSearchAvailable() {
+		log.Printf("WARNING: SQLite FTS5 is unavailable, OID search uses LIKE matching; build with -tags sqlite_fts5 for full-text search")
+	}
+
+	// Watch the MIB directory and ingest new or changed files
+	mibWatchInterval, err := time.ParseDuration(cfg.MIBWatchInterval)
+	if err != nil {
+		log.Printf("Invalid MIB_WATCH_INTERVAL %q, directory watching disabled: %v", cfg.MIBWatchInterval, err)
+		mibWatchInterval = 0
+	}
+	mibWatcher := services.NewMIBDirectoryWatcher(services.NewMIBService(db), cfg.MIBWatchDir, mibWatchInterval)
+	mibWatcher.Start()
+
+	// Receive traps and informs unless TRAP_LISTEN_ADDR is "off"
+	trapRetention, err := time.ParseDuration(cfg.TrapRetention)
+	if err != nil {
+		log.Printf("Invalid TRAP_RETENTION %q, old traps will not be pruned: %v", cfg.TrapRetention, err)
+		trapRetention = 0
+	}
+	trapConfig := services.TrapReceiverConfig{
+		Retention:  trapRetention,
+		EngineID:   cfg.TrapEngineID,
+		WebhookURL: cfg.TrapWebhookURL,
+	}
+	if cfg.TrapListenAddr != "off" {
+		trapConfig.ListenAddr = cfg.TrapListenAddr
+	}
+	for _, community := range strings.Split(cfg.TrapCommunities, ",") {
+		if community = strings.TrimSpace(community); community != "" {
+			trapConfig.Communities = append(trapConfig.Communities, community)
+		}
+	}
+	trapReceiver := services.NewTrapReceiver(db, trapConfig)
+	if err := trapReceiver.Start(); err != nil {
+		log.Printf("Trap receiver not started: %v", err)
+	}
+
+	// Poll devices with template OIDs into the local time-series tables
+	pollerConfig := services.PollerConfig{}
+	for _, d := range []struct {
+		name  string
+		value string
+		field *time.Duration
+	}{
+		{"POLL_INTERVAL", cfg.PollInterval, &pollerConfig.Interval},
+		{"POLL_JITTER", cfg.PollJitter, &pollerConfig.Jitter},
+		{"POLL_RAW_RETENTION", cfg.PollRawRetention, &pollerConfig.RawRetention},
+		{"POLL_ROLLUP_RETENTION", cfg.PollRollupRetention, &pollerConfig.RollupRetention},
+	} {
+		if *d.field, err = time.ParseDuration(d.value); err != nil {
+			log.Printf("Invalid %s %q, using 0: %v", d.name, d.value, err)
+		}
+	}
+	if pollerConfig.MaxConcurrent, err = strconv.Atoi(cfg.PollMaxConcurrent); err != nil {
+		log.Printf("Invalid POLL_MAX_CONCURRENT %q, using the default: %v", cfg.PollMaxConcurrent, err)
+	}
+	poller := services.NewSNMPPoller(db, pollerConfig)
+	poller.Start()
+
+	// Simulated SNMP agents for QA and demos, listening on local UDP ports
+	simulatorConfig := services.SimulatorConfig{Host: cfg.SimulatorHost, AllowPublic: cfg.SimulatorAllowPublic == "true"}
+	if _, err := fmt.Sscanf(cfg.SimulatorPorts, "%d-%d", &simulatorConfig.PortMin, &simulatorConfig.PortMax); err != nil {
+		log.Printf("Invalid SIMULATOR_PORTS %q, simulated devices need an explicit port: %v", cfg.SimulatorPorts, err)
+	}
+	simulator := services.NewSNMPSimulator(db, simulatorConfig)
+	simulator.Start()
+
+	// Serve polled values in Prometheus format at /metrics and live probes at /probe
+	exporterController := controllers.NewExporterController(services.NewPrometheusExporter(db, poller))
+	router.GET("/metrics", exporterController.Metrics)
+	router.GET("/probe", exporterController.Probe)
+
 	// Initialize controllers
-	mibController := controllers.NewMIBController(db)
+	mibController := controllers.NewMIBController(db, mibWatcher)
 	snmpController := controllers.NewSNMPController(db)
+	trapController := controllers.NewTrapController(trapReceiver)
+	metricsController := controllers.NewMetricsController(poller)
+	simulatorController := controllers.NewSimulatorController(simulator)
+	snapshotController := controllers.NewSnapshotController(services.NewSnapshotService(db))
 	configController := controllers.NewConfigController(db)
 	deviceController := controllers.NewDeviceController(db)
 	alertRulesController := controllers.NewAlertRulesController(alertRulesService, deviceService)
@@ -107,25 +194,102 @@ func main() {
 			mibs.PUT("/:id", mibController.UpdateMIB)
 			mibs.DELETE("/:id", mibController.DeleteMIB)
 			mibs.POST("/upload", mibController.UploadMIB)
+			mibs.GET("/upload/jobs/:id", mibController.GetMIBUploadJob)
 			mibs.POST("/:id/parse", mibController.ParseMIB)
 			mibs.POST("/validate", mibController.ValidateMIB)
 			mibs.GET("/:id/oids", mibController.GetMIBOIDs)
+			mibs.GET("/:id/dependencies", mibController.GetMIBDependencies)
+			mibs.GET("/dependencies", mibController.GetMissingDependencies)
+			mibs.GET("/revisions", mibController.GetMIBRevisions)
+			mibs.GET("/diff", mibController.DiffMIBs)
+			mibs.GET("/:id/notifications", mibController.GetMIBNotifications)
+			mibs.GET("/notifications", mibController.GetNotifications)
+			mibs.GET("/notifications/search", mibController.SearchNotifications)
+			mibs.GET("/metric-sets", mibController.GetMetricSets)
+			mibs.POST("/metric-sets/expand", mibController.ExpandMetricSets)
 			mibs.POST("/import", mibController.ImportMIBs)
 			mibs.GET("/export", mibController.ExportMIBs)
+			mibs.POST("/export/generator", mibController.ExportGeneratorConfig)
 			// 新增的 API 端点
 			mibs.GET("/scan", mibController.ScanMIBDirectory)
+			mibs.GET("/watcher", mibController.GetWatcherStatus)
+			mibs.POST("/watcher/scan", mibController.TriggerWatcherScan)
+			mibs.GET("/watcher/events", mibController.GetWatcherEvents)
 			mibs.POST("/parse-file", mibController.ParseMIBFile)
 		}
 
+		// OID tree routes
+		oids := api.Group("/oids")
+		{
+			oids.GET("/children", mibController.GetOIDChildren)
+			oids.GET("/ancestors", mibController.GetOIDAncestors)
+			oids.GET("/lookup", mibController.LookupOID)
+			oids.GET("/match", mibController.MatchOID)
+			oids.GET("/search", mibController.SearchOIDs)
+		}
+
 		// SNMP routes
 		snmp := api.Group("/snmp")
 		{
 			snmp.GET("", snmpController.GetSNMPConfig)
 			snmp.POST("/get", snmpController.SNMPGet)
 			snmp.POST("/walk", snmpController.SNMPWalk)
+			snmp.POST("/table", snmpController.SNMPTable)
 			snmp.POST("/set", snmpController.SNMPSet)
+			snmp.GET("/set-operations", snmpController.GetSetOperations)
+			snmp.POST("/set-operations", snmpController.ApplySet)
+			snmp.GET("/set-operations/:id", snmpController.GetSetOperation)
+			snmp.POST("/set-operations/:id/revert", snmpController.RevertSetOperation)
 			snmp.POST("/test", snmpController.TestConnection)
 			snmp.POST("/bulk", snmpController.BulkOperations)
+			snmp.GET("/bulk/:id", snmpController.GetBulkOperation)
+			snmp.POST("/bulk/:id/cancel", snmpController.CancelBulkOperation)
+			snmp.POST("/stream", snmpController.StreamQuery)
+		}
+
+		// SNMP trap routes
+		traps := api.Group("/traps")
+		{
+			traps.GET("", trapController.GetTraps)
+			traps.GET("/:id", trapController.GetTrap)
+			traps.GET("/receiver", trapController.GetReceiverStatus)
+			traps.GET("/users", trapController.GetUsers)
+			traps.POST("/users", trapController.CreateUser)
+			traps.DELETE("/users/:id", trapController.DeleteUser)
+		}
+
+		// Polled metric routes
+		metrics := api.Group("/metrics")
+		{
+			metrics.GET("/poller", metricsController.GetPollerStatus)
+			metrics.GET("/series", metricsController.GetSeries)
+			metrics.GET("/query", metricsController.QuerySeries)
+			metrics.POST("/poll/:id", metricsController.PollDevice)
+		}
+
+		// SNMP agent simulator routes
+		simulated := api.Group("/simulator")
+		{
+			simulated.GET("/devices", simulatorController.GetDevices)
+			simulated.POST("/devices", simulatorController.CreateDevice)
+			simulated.GET("/devices/:id", simulatorController.GetDevice)
+			simulated.DELETE("/devices/:id", simulatorController.DeleteDevice)
+			simulated.GET("/devices/:id/recording", simulatorController.GetRecording)
+			simulated.POST("/devices/:id/start", simulatorController.StartDevice)
+			simulated.POST("/devices/:id/stop", simulatorController.StopDevice)
+			simulated.POST("/fleet", simulatorController.CreateFleet)
+		}
+
+		// Device walk snapshot routes
+		snapshots := api.Group("/snapshots")
+		{
+			snapshots.GET("", snapshotController.GetSnapshots)
+			snapshots.POST("", snapshotController.TakeSnapshot)
+			snapshots.POST("/import", snapshotController.ImportSnapshot)
+			snapshots.GET("/diff", snapshotController.DiffSnapshots)
+			snapshots.GET("/:id", snapshotController.GetSnapshot)
+			snapshots.GET("/:id/export", snapshotController.ExportSnapshot)
+			snapshots.DELETE("/:id", snapshotController.DeleteSnapshot)
 		}
 
 		// Configuration routes
@@ -34,24 +34,27 @@ type DeviceTemplate struct {
 	Vendor      string         `json:"vendor"`
 	Description string         `json:"description"`
 	MIBs        []MIB          `json:"mibs" gorm:"many2many:device_template_mibs;"`
-	OIDs        []string       `json:"oids" gorm:"type:text[]"`
-	Config      map[string]interface{} `json:"config" gorm:"type:jsonb"`
+	OIDs        []string       `json:"oids" gorm:"type:text;serializer:json"`
+	Config      map[string]interface{} `json:"config" gorm:"type:text;serializer:json"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 }
 
 type SNMPCredential struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	DeviceID  uint           `json:"device_id" gorm:"not null"`
-	Version   string         `json:"version" gorm:"not null"` // v1, v2c, v3
-	Community string         `json:"community"`               // for v1, v2c
-	Username  string         `json:"username"`                // for v3
-	AuthProto string         `json:"auth_proto"`              // MD5, SHA
-	AuthKey   string         `json:"auth_key"`
-	PrivProto string         `json:"priv_proto"`              // DES, AES
-	PrivKey   string         `json:"priv_key"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	DeviceID        uint           `json:"device_id" gorm:"not null"`
+	Version         string         `json:"version" gorm:"not null"` // v1, v2c, v3
+	Community       string         `json:"community"`               // for v1, v2c
+	Username        string         `json:"username"`                // for v3
+	SecurityLevel   string         `json:"security_level"`          // noAuthNoPriv, authNoPriv, authPriv; 为空时按密钥推断
+	AuthProto       string         `json:"auth_proto"`              // MD5, SHA, SHA224, SHA256, SHA384, SHA512
+	AuthKey         string         `json:"auth_key"`
+	PrivProto       string         `json:"priv_proto"` // DES, AES, AES192, AES256, AES192C, AES256C
+	PrivKey         string         `json:"priv_key"`
+	ContextName     string         `json:"context_name"`
+	ContextEngineID string         `json:"context_engine_id"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 }
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// MetricSeries 轮询产生的时间序列, 每个设备上的每个 OID 实例一条
+type MetricSeries struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	DeviceID  uint      `json:"device_id" gorm:"not null;uniqueIndex:idx_metric_series_device_oid"`
+	OID       string    `json:"oid" gorm:"not null;uniqueIndex:idx_metric_series_device_oid"`
+	Name      string    `json:"name" gorm:"index"` // MIB 对象名, 未在 MIB 库中找到时为空
+	Module    string    `json:"module,omitempty"`
+	Index     string    `json:"index,omitempty"` // 实例后缀, 标量为 "0"
+	Type      string    `json:"type"`            // SNMP 类型, 如 Counter64
+	Kind      string    `json:"kind"`            // gauge; counter 存储的是每秒速率
+	Units     string    `json:"units,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MetricSample 时间序列的样本; Step 为 0 表示原始样本, 否则为降采样的桶宽 (秒)
+type MetricSample struct {
+	SeriesID uint    `json:"series_id" gorm:"primaryKey;autoIncrement:false"`
+	Step     int     `json:"step" gorm:"primaryKey;autoIncrement:false"`
+	Time     int64   `json:"time" gorm:"column:ts;primaryKey;autoIncrement:false"` // Unix 秒, 降采样时为桶起点
+	Value    float64 `json:"value"`                                                // 降采样时为平均值
+	Max      float64 `json:"max"`
+}
@@ -7,40 +7,237 @@ import (
 )
 
 type MIB struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	Name        string         `json:"name" gorm:"not null"`
-	Filename    string         `json:"filename" gorm:"not null"`
-	FilePath    string         `json:"file_path" gorm:"not null"`
-	Version     string         `json:"version"`
-	Description string         `json:"description"`
-	Author      string         `json:"author"`
-	Status      string         `json:"status" gorm:"default:'uploaded'"` // uploaded, parsed, error
-	ParsedAt    *time.Time     `json:"parsed_at"`
-	ErrorMsg    string         `json:"error_msg"`
-	FileSize    int64          `json:"file_size"`
-	Size        int64          `json:"size"`
-	Checksum    string         `json:"checksum"`
-	UploadedAt  time.Time      `json:"uploaded_at"`
-	OIDs        []OID          `json:"oids" gorm:"foreignKey:MIBID"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	ID            uint              `json:"id" gorm:"primaryKey"`
+	Name          string            `json:"name" gorm:"not null"`
+	Filename      string            `json:"filename" gorm:"not null"`
+	FilePath      string            `json:"file_path" gorm:"not null"`
+	Version       string            `json:"version"` // MODULE-IDENTITY 的 LAST-UPDATED
+	Description   string            `json:"description"`
+	Author        string            `json:"author"`
+	Status        string            `json:"status" gorm:"default:'uploaded'"` // uploaded, parsed, error
+	ParsedAt      *time.Time        `json:"parsed_at"`
+	ErrorMsg      string            `json:"error_msg"`
+	FileSize      int64             `json:"file_size"`
+	Size          int64             `json:"size"`
+	Checksum      string            `json:"checksum"`
+	UploadedAt    time.Time         `json:"uploaded_at"`
+	LastUpdated   *time.Time        `json:"last_updated" gorm:"index"`
+	Revisions     []MIBRevision     `json:"revisions" gorm:"type:text;serializer:json"` // 按时间倒序
+	OIDs          []OID             `json:"oids" gorm:"foreignKey:MIBID"`
+	Imports       []MIBImport       `json:"imports" gorm:"foreignKey:MIBID"`
+	Types         []MIBType         `json:"types" gorm:"foreignKey:MIBID"`
+	Notifications []MIBNotification `json:"notifications" gorm:"foreignKey:MIBID"`
+	Groups        []MIBGroup        `json:"groups" gorm:"foreignKey:MIBID"`
+	Compliances   []MIBCompliance   `json:"compliances" gorm:"foreignKey:MIBID"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt    `json:"deleted_at" gorm:"index"`
 }
 
 type OID struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	MIBID       uint   `json:"mib_id" gorm:"not null"`
+	Name        string `json:"name" gorm:"not null"`
+	OID         string `json:"oid" gorm:"not null"`
+	OIDString   string `json:"oid_string" gorm:"not null"`
+	Type        string `json:"type"`   // INTEGER, OCTET STRING, etc.
+	Access      string `json:"access"` // read-only, read-write, etc.
+	Status      string `json:"status"` // current, deprecated, etc.
+	Description string `json:"description"`
+	Syntax      string `json:"syntax"`
+	Units       string `json:"units"`
+	ParentOID   string `json:"parent_oid"`
+
+	// TEXTUAL-CONVENTION 展开后的类型信息
+	BaseType          string     `json:"base_type"`
+	TextualConvention string     `json:"textual_convention"`
+	DisplayHint       string     `json:"display_hint"`
+	Enums             []OIDEnum  `json:"enums" gorm:"type:text;serializer:json"`
+	Ranges            []OIDRange `json:"ranges" gorm:"type:text;serializer:json"`
+	Sizes             []OIDRange `json:"sizes" gorm:"type:text;serializer:json"`
+
+	// 概念表结构
+	Kind     string     `json:"kind"`     // table, row, column, scalar; 其他宏为空
+	Table    string     `json:"table"`    // row / column 所属表的名称
+	Augments string     `json:"augments"` // row 的 AUGMENTS 目标
+	Indexes  []OIDIndex `json:"indexes" gorm:"type:text;serializer:json"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+}
+
+// MIBImport MIB 模块的 IMPORTS 记录, 每个被导入的模块一行
+type MIBImport struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	MIBID     uint           `json:"mib_id" gorm:"not null;index"`
+	Module    string         `json:"module" gorm:"not null;index"`
+	Symbols   string         `json:"symbols" gorm:"type:text"` // 逗号分隔的符号列表
+	CreatedAt time.Time      `json:"created_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+}
+
+// MIBRevision MODULE-IDENTITY 中的一条 REVISION
+type MIBRevision struct {
+	Revision    string     `json:"revision"` // 原始 ExtUTCTime 文本
+	Date        *time.Time `json:"date"`     // 格式错误时为空
+	Description string     `json:"description"`
+}
+
+// OIDEnum INTEGER 枚举值或 BITS 命名位
+type OIDEnum struct {
+	Name  string `json:"name"`
+	Value int64  `json:"value"`
+}
+
+// OIDRange 取值范围或 SIZE 约束, 单个值时 Min == Max
+type OIDRange struct {
+	Min int64 `json:"min"`
+	Max int64 `json:"max"`
+}
+
+// OIDIndex 表行 INDEX 中的一个索引对象
+type OIDIndex struct {
+	Name              string `json:"name"`
+	OID               string `json:"oid,omitempty"`
+	Type              string `json:"type,omitempty"` // 基础类型
+	TextualConvention string `json:"textual_convention,omitempty"`
+	FixedSize         int    `json:"fixed_size,omitempty"` // 定长 OCTET STRING 的长度
+	Implied           bool   `json:"implied,omitempty"`
+}
+
+// MIBType MIB 模块中定义的类型 (TEXTUAL-CONVENTION 或类型赋值), 供导入它的模块解析
+type MIBType struct {
 	ID          uint           `json:"id" gorm:"primaryKey"`
-	MIBID       uint           `json:"mib_id" gorm:"not null"`
-	Name        string         `json:"name" gorm:"not null"`
-	OID         string         `json:"oid" gorm:"not null"`
-	OIDString   string         `json:"oid_string" gorm:"not null"`
-	Type        string         `json:"type"`        // INTEGER, OCTET STRING, etc.
-	Access      string         `json:"access"`      // read-only, read-write, etc.
-	Status      string         `json:"status"`      // current, deprecated, etc.
-	Description string         `json:"description"`
+	MIBID       uint           `json:"mib_id" gorm:"not null;index"`
+	Name        string         `json:"name" gorm:"not null;index"`
+	IsTC        bool           `json:"is_tc"`
+	BaseType    string         `json:"base_type"`
+	DisplayHint string         `json:"display_hint"`
 	Syntax      string         `json:"syntax"`
-	Units       string         `json:"units"`
-	ParentOID   string         `json:"parent_oid"`
+	Status      string         `json:"status"`
+	Description string         `json:"description"`
+	Enums       []OIDEnum      `json:"enums" gorm:"type:text;serializer:json"`
+	Ranges      []OIDRange     `json:"ranges" gorm:"type:text;serializer:json"`
+	Sizes       []OIDRange     `json:"sizes" gorm:"type:text;serializer:json"`
 	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 }
+
+// MIBNotification MIB 中定义的通知 (SMIv2 NOTIFICATION-TYPE 或 SMIv1 TRAP-TYPE)
+type MIBNotification struct {
+	ID           uint                 `json:"id" gorm:"primaryKey"`
+	MIBID        uint                 `json:"mib_id" gorm:"not null;index"`
+	Module       string               `json:"module" gorm:"index"`
+	Name         string               `json:"name" gorm:"not null;index"`
+	OID          string               `json:"oid" gorm:"not null;index"`
+	Macro        string               `json:"macro"` // NOTIFICATION-TYPE, TRAP-TYPE
+	Status       string               `json:"status"`
+	Description  string               `json:"description"`
+	Objects      []NotificationObject `json:"objects" gorm:"type:text;serializer:json"` // OBJECTS / VARIABLES
+	Enterprise   string               `json:"enterprise"`                               // SNMPv1 enterprise OID (RFC 3584 映射)
+	SpecificTrap uint32               `json:"specific_trap"`                            // SNMPv1 specific-trap
+	CreatedAt    time.Time            `json:"created_at"`
+	UpdatedAt    time.Time            `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt       `json:"deleted_at" gorm:"index"`
+}
+
+// NotificationObject 通知携带的一个变量绑定对象
+type NotificationObject struct {
+	Name string `json:"name"`
+	OID  string `json:"oid,omitempty"`
+}
+
+// MIBGroup MIB 中定义的对象组 (OBJECT-GROUP 或 NOTIFICATION-GROUP)
+type MIBGroup struct {
+	ID          uint             `json:"id" gorm:"primaryKey"`
+	MIBID       uint             `json:"mib_id" gorm:"not null;index"`
+	Module      string           `json:"module" gorm:"index"`
+	Name        string           `json:"name" gorm:"not null;index"`
+	OID         string           `json:"oid" gorm:"not null"`
+	Macro       string           `json:"macro"` // OBJECT-GROUP, NOTIFICATION-GROUP
+	Status      string           `json:"status"`
+	Description string           `json:"description"`
+	Members     []MIBGroupMember `json:"members" gorm:"type:text;serializer:json"` // OBJECTS / NOTIFICATIONS
+	CreatedAt   time.Time        `json:"created_at"`
+	DeletedAt   gorm.DeletedAt   `json:"deleted_at" gorm:"index"`
+}
+
+// MIBGroupMember 组中的一个对象或通知, 跨模块引用未解析时 OID 为空
+type MIBGroupMember struct {
+	Name string `json:"name"`
+	OID  string `json:"oid,omitempty"`
+}
+
+// MIBCompliance MODULE-COMPLIANCE 一致性声明
+type MIBCompliance struct {
+	ID          uint                  `json:"id" gorm:"primaryKey"`
+	MIBID       uint                  `json:"mib_id" gorm:"not null;index"`
+	Module      string                `json:"module" gorm:"index"`
+	Name        string                `json:"name" gorm:"not null;index"`
+	OID         string                `json:"oid" gorm:"not null"`
+	Status      string                `json:"status"`
+	Description string                `json:"description"`
+	Modules     []MIBComplianceModule `json:"modules" gorm:"type:text;serializer:json"`
+	CreatedAt   time.Time             `json:"created_at"`
+	DeletedAt   gorm.DeletedAt        `json:"deleted_at" gorm:"index"`
+}
+
+// MIBComplianceModule 一致性声明中的一个 MODULE 子句
+type MIBComplianceModule struct {
+	Module          string                `json:"module"` // 为空表示声明所在的模块
+	MandatoryGroups []string              `json:"mandatory_groups"`
+	Groups          []string              `json:"groups"`  // 条件性的 GROUP
+	Objects         []MIBComplianceObject `json:"objects"` // OBJECT 细化
+}
+
+// MIBComplianceObject OBJECT 子句对单个对象的细化
+type MIBComplianceObject struct {
+	Name      string `json:"name"`
+	Syntax    string `json:"syntax,omitempty"`
+	MinAccess string `json:"min_access,omitempty"`
+}
+
+// MIBUploadJob MIB 压缩包导入任务
+type MIBUploadJob struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Filename string `json:"filename" gorm:"not null"`
+
+	// 任务状态
+	Status   string `json:"status" gorm:"size:20;default:'pending'"` // pending, running, completed, failed
+	Progress int    `json:"progress" gorm:"default:0"`               // 0-100
+	ErrorMsg string `json:"error_msg"`
+
+	// 结果统计
+	TotalFiles     int                   `json:"total_files"`
+	ParsedFiles    int                   `json:"parsed_files"`
+	DuplicateFiles int                   `json:"duplicate_files"`
+	FailedFiles    int                   `json:"failed_files"`
+	Report         []MIBUploadFileResult `json:"report" gorm:"type:text;serializer:json"`
+
+	StartedAt   *time.Time `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// MIBUploadFileResult 压缩包中单个文件的导入结果
+type MIBUploadFileResult struct {
+	File   string `json:"file"`
+	Module string `json:"module,omitempty"`
+	Status string `json:"status"` // parsed, duplicate, failed
+	MIBID  uint   `json:"mib_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// MIBIngestEvent MIB 目录监视器的导入事件
+type MIBIngestEvent struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Path      string    `json:"path" gorm:"not null;index"`
+	Action    string    `json:"action" gorm:"size:20;index"` // added, updated, deleted, error
+	MIBID     uint      `json:"mib_id,omitempty"`
+	Module    string    `json:"module,omitempty"`
+	Checksum  string    `json:"checksum,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
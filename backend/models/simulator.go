@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// SimulatedDevice 内置 SNMP 代理模拟器中的一台设备, 按录制的 walk 结果应答
+type SimulatedDevice struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"not null;uniqueIndex"`
+	Vendor      string    `json:"vendor"`
+	Description string    `json:"description"`
+	Port        int       `json:"port" gorm:"not null;uniqueIndex"` // 本机 UDP 端口
+	Community   string    `json:"community"`                        // v1 / v2c 团体名, 为空时只接受 v3
+	Username    string    `json:"username"`                         // v3 USM 用户, 为空时不接受 v3
+	AuthProto   string    `json:"auth_proto"`
+	AuthKey     string    `json:"auth_key,omitempty"`
+	PrivProto   string    `json:"priv_proto"`
+	PrivKey     string    `json:"priv_key,omitempty"`
+	EngineID    string    `json:"engine_id"` // 十六进制
+	Format      string    `json:"format"`    // snmprec, snmpwalk
+	Recording   string    `json:"-" gorm:"type:text"`
+	Records     int       `json:"records"`
+	DeviceID    *uint     `json:"device_id"`                       // 在设备管理中登记的设备
+	Status      string    `json:"status" gorm:"default:'stopped'"` // running, stopped, error
+	LastError   string    `json:"last_error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
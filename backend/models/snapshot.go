@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// DeviceSnapshot 设备某一时刻的 SNMP walk 快照, 内容为 gzip 压缩的 snmprec 文本
+type DeviceSnapshot struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	DeviceID   uint      `json:"device_id" gorm:"not null;index"`
+	DeviceName string    `json:"device_name"`
+	Root       string    `json:"root"`   // walk 的子树, 导入的快照为空
+	Label      string    `json:"label"`  // 如 "before upgrade"
+	Source     string    `json:"source"` // walk, import
+	Records    int       `json:"records"`
+	Size       int       `json:"size"`        // 未压缩的字节数
+	StoredSize int       `json:"stored_size"` // 压缩后的字节数
+	Duration   string    `json:"duration,omitempty"`
+	TakenAt    time.Time `json:"taken_at" gorm:"not null;index"`
+	Data       []byte    `json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+}
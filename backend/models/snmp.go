@@ -3,20 +3,26 @@ package models
 import "time"
 
 type SNMPRequest struct {
-	Target    string            `json:"target" binding:"required"`
-	Port      int               `json:"port"`
-	Version   string            `json:"version" binding:"required"`
-	Community string            `json:"community"`
-	Username  string            `json:"username"`
-	AuthProto string            `json:"auth_proto"`
-	AuthKey   string            `json:"auth_key"`
-	PrivProto string            `json:"priv_proto"`
-	PrivKey   string            `json:"priv_key"`
-	OID       string            `json:"oid" binding:"required"`
-	Timeout   int               `json:"timeout"`
-	Retries   int               `json:"retries"`
-	MaxOIDs   int               `json:"max_oids"`
-	Context   map[string]string `json:"context"`
+	Target          string            `json:"target" binding:"required"`
+	Port            int               `json:"port"`
+	Version         string            `json:"version" binding:"required"`
+	Community       string            `json:"community"`
+	Username        string            `json:"username"`
+	SecurityLevel   string            `json:"security_level"` // noAuthNoPriv, authNoPriv, authPriv; 为空时按密钥推断
+	AuthProto       string            `json:"auth_proto"`     // MD5, SHA, SHA224, SHA256, SHA384, SHA512
+	AuthKey         string            `json:"auth_key"`
+	PrivProto       string            `json:"priv_proto"` // DES, AES, AES192, AES256, AES192C, AES256C
+	PrivKey         string            `json:"priv_key"`
+	ContextName     string            `json:"context_name"`
+	ContextEngineID string            `json:"context_engine_id"` // 十六进制
+	OID             string            `json:"oid" binding:"required"`
+	Timeout         int               `json:"timeout"`
+	Retries         int               `json:"retries"`
+	MaxOIDs         int               `json:"max_oids"`
+	MaxRepetitions  uint32            `json:"max_repetitions"` // GetBulk 每次返回的行数, v2c / v3 有效
+	NonRepeaters    []string          `json:"non_repeaters"`   // 随第一个 GetBulk 各取一次的标量 OID
+	Context         map[string]string `json:"context"`
+	Resolve         bool              `json:"resolve"` // 用 MIB 库翻译 OID 名称和值, 默认返回原始结果
 }
 
 type SNMPResponse struct {
@@ -29,10 +35,40 @@ type SNMPResponse struct {
 }
 
 type SNMPResult struct {
-	OID   string      `json:"oid"`
-	Type  string      `json:"type"`
-	Value interface{} `json:"value"`
-	Name  string      `json:"name,omitempty"`
+	OID     string      `json:"oid"`
+	Type    string      `json:"type"`
+	Value   interface{} `json:"value"`
+	Name    string      `json:"name,omitempty"`
+	Module  string      `json:"module,omitempty"`
+	Index   string      `json:"index,omitempty"`
+	Display string      `json:"display,omitempty"`
+}
+
+// SNMPTableRequest 表获取请求, OID 为表的名称、MODULE::name 或数字 OID
+type SNMPTableRequest struct {
+	SNMPRequest
+	Columns []string `json:"columns"` // 只取这些列, 为空时取所有可读列
+}
+
+type SNMPTableResponse struct {
+	Success   bool                   `json:"success"`
+	Message   string                 `json:"message"`
+	Table     string                 `json:"table"`
+	Module    string                 `json:"module"`
+	OID       string                 `json:"oid"`
+	Indexes   []string               `json:"indexes"`
+	Columns   []string               `json:"columns"`
+	Rows      []SNMPTableRow         `json:"rows"`
+	Timestamp time.Time              `json:"timestamp"`
+	Duration  string                 `json:"duration"`
+	Stats     map[string]interface{} `json:"stats"`
+}
+
+// SNMPTableRow 表中的一行, Key 是按 INDEX 对象解码的索引值
+type SNMPTableRow struct {
+	Index  string                 `json:"index"`
+	Key    map[string]interface{} `json:"key"`
+	Values map[string]SNMPResult  `json:"values"`
 }
 
 type SNMPSetRequest struct {
@@ -41,15 +77,103 @@ type SNMPSetRequest struct {
 	Type  string      `json:"type" binding:"required"`
 }
 
+// BulkOperation 批量 SNMP 操作, 请求列表和每个请求的结果保存在 BulkOperationResult 中
 type BulkOperation struct {
-	ID        string                 `json:"id"`
-	Type      string                 `json:"type"` // walk, get, set
-	Status    string                 `json:"status"`
-	Progress  int                    `json:"progress"`
-	Total     int                    `json:"total"`
-	Results   []SNMPResponse         `json:"results"`
-	Errors    []string               `json:"errors"`
-	StartTime time.Time              `json:"start_time"`
-	EndTime   *time.Time             `json:"end_time"`
-	Config    map[string]interface{} `json:"config"`
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	Type        string `json:"type" gorm:"size:20;not null"`                  // get, walk, set
+	Status      string `json:"status" gorm:"size:20;default:'pending';index"` // pending, running, completed, failed, cancelled
+	Progress    int    `json:"progress"`                                      // 0-100
+	Total       int    `json:"total"`
+	Completed   int    `json:"completed"`
+	Succeeded   int    `json:"succeeded"`
+	Failed      int    `json:"failed"`
+	Concurrency int    `json:"concurrency"`
+	ErrorMsg    string `json:"error_msg"`
+
+	StartTime *time.Time `json:"start_time"`
+	EndTime   *time.Time `json:"end_time"`
+	Duration  string     `json:"duration"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// BulkOperationResult 批量操作中单个请求及其结果
+type BulkOperationResult struct {
+	ID          uint            `json:"id" gorm:"primaryKey"`
+	OperationID uint            `json:"operation_id" gorm:"not null;index"`
+	Seq         int             `json:"seq"` // 请求在列表中的位置
+	Request     BulkSNMPRequest `json:"request" gorm:"type:text;serializer:json"`
+	Status      string          `json:"status" gorm:"size:20;default:'pending';index"` // pending, success, failed, cancelled
+	Response    *SNMPResponse   `json:"response" gorm:"type:text;serializer:json"`
+	Error       string          `json:"error,omitempty"`
+	StartedAt   *time.Time      `json:"started_at"`
+	CompletedAt *time.Time      `json:"completed_at"`
+}
+
+// BulkSNMPRequest 批量操作中的单个请求, Value / Type 只用于 set
+type BulkSNMPRequest struct {
+	SNMPRequest
+	Value interface{} `json:"value,omitempty"`
+	Type  string      `json:"type,omitempty"`
+}
+
+// SNMPSetVarbind SET 工作流中要写入的一个变量; type 为空时按 MIB 语法推断, 其次沿用设备上当前值的类型
+type SNMPSetVarbind struct {
+	OID   string      `json:"oid" binding:"required"` // 数字 OID、name.index 或 MODULE::name.index
+	Value interface{} `json:"value"`
+	Type  string      `json:"type"` // integer, string, hex, oid, ipaddress, counter32, counter64, gauge32, unsigned32, timeticks
+}
+
+// SNMPSetOperationRequest 安全 SET: 先读取当前值并按 MIB 语法校验, 所有变量在一个 PDU 中原子写入
+type SNMPSetOperationRequest struct {
+	DeviceID   uint             `json:"device_id"`              // 使用设备保存的第一个凭据
+	Connection *SNMPRequest     `json:"connection" binding:"-"` // 未指定设备时使用, 其中的 oid 忽略
+	Varbinds   []SNMPSetVarbind `json:"varbinds" binding:"required,min=1,dive"`
+	DryRun     bool             `json:"dry_run"` // 只读取和校验, 不写入也不记录
+	Comment    string           `json:"comment"`
+}
+
+// SNMPSetOperation SET 审计记录, 保存每个变量写入前后的值, 用于回滚
+type SNMPSetOperation struct {
+	ID         uint            `json:"id" gorm:"primaryKey"`
+	DeviceID   *uint           `json:"device_id" gorm:"index"`
+	Target     string          `json:"target" gorm:"index"`
+	Port       int             `json:"port"`
+	Version    string          `json:"version"`
+	Status     string          `json:"status" gorm:"size:20;index"` // dry-run, applied, failed, reverted
+	Error      string          `json:"error,omitempty"`
+	Comment    string          `json:"comment"`
+	Changes    []SNMPSetChange `json:"changes" gorm:"type:text;serializer:json"`
+	RevertOf   *uint           `json:"revert_of,omitempty"`   // 回滚操作撤销的原操作
+	RevertedBy *uint           `json:"reverted_by,omitempty"` // 撤销本操作的回滚操作
+	Duration   string          `json:"duration"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// SNMPSetChange 一个变量写入前后的值; 两个 record 为 snmprec 格式 (OID|TAG|VALUE), 回滚时按原样写回
+type SNMPSetChange struct {
+	OID       string `json:"oid"`
+	Name      string `json:"name,omitempty"`
+	Module    string `json:"module,omitempty"`
+	Type      string `json:"type"`
+	OldType   string `json:"old_type,omitempty"` // 写入前实例不存在时为空
+	OldValue  string `json:"old_value"`
+	NewValue  string `json:"new_value"`
+	OldRecord string `json:"old_record,omitempty"`
+	NewRecord string `json:"new_record"`
+}
+
+// SNMPStreamRequest 多目标查询: 对 targets、device_ids、group_id 和 tags 选中的所有目标执行同一操作, 结果逐个以 SSE 推送
+type SNMPStreamRequest struct {
+	Operation   string            `json:"operation" binding:"required"` // get, walk
+	OIDs        []string          `json:"oids" binding:"required,min=1"`
+	Targets     []string          `json:"targets"`                // IP 或 IP:port, 使用 connection 中的版本和凭据
+	Connection  *SNMPRequest      `json:"connection" binding:"-"` // targets 使用的连接参数, 其中的 target 和 oid 忽略
+	DeviceIDs   []uint            `json:"device_ids"`             // 使用设备保存的第一个凭据
+	GroupID     string            `json:"group_id"`               // 设备分组中的所有设备
+	Tags        map[string]string `json:"tags"`                   // 标签全部匹配的设备分组中的所有设备
+	Concurrency int               `json:"concurrency"`
+	Timeout     int               `json:"timeout"` // 每个目标的总超时秒数, 包括重试和 walk 的所有请求
+	Resolve     bool              `json:"resolve"`
 }
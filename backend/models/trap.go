@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// SNMPTrap 接收到的 trap 或 inform, 变量绑定已按 MIB 库翻译
+type SNMPTrap struct {
+	ID           uint         `json:"id" gorm:"primaryKey"`
+	ReceivedAt   time.Time    `json:"received_at" gorm:"not null;index"`
+	SourceIP     string       `json:"source_ip" gorm:"index"`
+	SourcePort   int          `json:"source_port"`
+	DeviceID     *uint        `json:"device_id" gorm:"index"` // 源地址匹配到的设备
+	DeviceName   string       `json:"device_name,omitempty"`
+	Version      string       `json:"version"`  // v1, v2c, v3
+	PDUType      string       `json:"pdu_type"` // trap, inform
+	Username     string       `json:"username,omitempty"`
+	TrapOID      string       `json:"trap_oid" gorm:"index"`
+	TrapName     string       `json:"trap_name" gorm:"index"` // 未在通知目录中找到时为空
+	Module       string       `json:"module,omitempty"`
+	Uptime       uint32       `json:"uptime"`               // sysUpTime, 单位 1/100 秒
+	Enterprise   string       `json:"enterprise,omitempty"` // SNMPv1 头部
+	AgentAddress string       `json:"agent_address,omitempty"`
+	GenericTrap  int          `json:"generic_trap,omitempty"`
+	SpecificTrap int          `json:"specific_trap,omitempty"`
+	Varbinds     []SNMPResult `json:"varbinds" gorm:"type:text;serializer:json"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+// SNMPTrapUser 接收 SNMPv3 inform 的 USM 用户
+type SNMPTrapUser struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Username  string    `json:"username" gorm:"not null;uniqueIndex"`
+	AuthProto string    `json:"auth_proto"` // MD5, SHA, SHA224, SHA256, SHA384, SHA512
+	AuthKey   string    `json:"auth_key,omitempty"`
+	PrivProto string    `json:"priv_proto"` // DES, AES, AES192, AES256, AES192C, AES256C
+	PrivKey   string    `json:"priv_key,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
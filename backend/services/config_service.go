@@ -86,6 +86,7 @@ type ConfigGenerationRequest struct {
 	ConfigName   string                 `json:"config_name"`
 	DeviceInfo   DeviceInfo            `json:"device_info"`
 	SelectedOIDs []string              `json:"selected_oids"`
+	MetricSets   *MIBMetricSetRequest   `json:"metric_sets,omitempty"` // 展开后追加到 SelectedOIDs
 	Template     string                 `json:"template"`
 	Options      map[string]interface{} `json:"options"`
 }
@@ -124,6 +125,8 @@ type SNMPMetric struct {
 type SNMPIndex struct {
 	LabelName string `yaml:"labelname"`
 	Type      string `yaml:"type"`
+	FixedSize int    `yaml:"fixed_size,omitempty"`
+	Implied   bool   `yaml:"implied,omitempty"`
 }
 
 type SNMPLookup struct {
@@ -167,6 +170,12 @@ func (s *ConfigService) GenerateConfig(req ConfigGenerationRequest) (*models.Con
 	var configContent string
 	var err error
 
+	if req.MetricSets != nil {
+		if req.SelectedOIDs, err = s.expandMetricSets(req.SelectedOIDs, *req.MetricSets); err != nil {
+			return nil, err
+		}
+	}
+
 	// 根据配置类型生成不同格式的配置
 	switch req.ConfigType {
 	case "snmp_exporter":
@@ -199,6 +208,29 @@ func (s *ConfigService) GenerateConfig(req ConfigGenerationRequest) (*models.Con
 	return config, nil
 }
 
+// 将对象组和一致性声明展开为 OID 并追加到已选 OID 之后
+func (s *ConfigService) expandMetricSets(selected []string, sets MIBMetricSetRequest) ([]string, error) {
+	expansion, err := NewMIBService(s.db).ExpandMetricSets(sets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand metric sets: %v", err)
+	}
+	if len(expansion.Unresolved) > 0 {
+		return nil, fmt.Errorf("unknown metric set objects: %s", strings.Join(expansion.Unresolved, ", "))
+	}
+
+	seen := make(map[string]bool, len(selected))
+	for _, oid := range selected {
+		seen[oid] = true
+	}
+	for _, oid := range expansion.OIDs {
+		if !seen[oid] {
+			seen[oid] = true
+			selected = append(selected, oid)
+		}
+	}
+	return selected, nil
+}
+
 // 生成 SNMP Exporter 配置
 func (s *ConfigService) generateSNMPExporterConfig(req ConfigGenerationRequest) (string, error) {
 	// 获取 OID 信息
@@ -288,7 +320,7 @@ func (s *ConfigService) getOIDMetrics(oids []string) ([]SNMPMetric, error) {
 
 	for _, oid := range oids {
 		var oidModel models.OID
-		if err := s.db.Where("oid = ?", oid).First(&oidModel).Error; err != nil {
+		if err := s.db.Where("o_id = ?", oid).First(&oidModel).Error; err != nil {
 			// 如果数据库中没有找到，使用默认值
 			metrics = append(metrics, SNMPMetric{
 				Name: strings.ReplaceAll(oid, ".", "_"),
@@ -302,21 +334,56 @@ func (s *ConfigService) getOIDMetrics(oids []string) ([]SNMPMetric, error) {
 		// 根据 SNMP 类型确定 Prometheus 类型
 		promType := s.getPrometheusType(oidModel.Type)
 		
-		metrics = append(metrics, SNMPMetric{
+		metric := SNMPMetric{
 			Name: oidModel.Name,
 			OID:  oid,
 			Type: promType,
 			Help: oidModel.Description,
-		})
+		}
+
+		// 表的列需要按行索引生成标签
+		if oidModel.Kind == MIBKindColumn {
+			for _, index := range oidModel.Indexes {
+				metric.Indexes = append(metric.Indexes, SNMPIndex{
+					LabelName: index.Name,
+					Type:      s.getSNMPIndexType(index),
+					FixedSize: index.FixedSize,
+					Implied:   index.Implied,
+				})
+			}
+		}
+
+		metrics = append(metrics, metric)
 	}
 
 	return metrics, nil
 }
 
+// 转换为 snmp_exporter 的索引类型
+func (s *ConfigService) getSNMPIndexType(index models.OIDIndex) string {
+	switch index.TextualConvention {
+	case "PhysAddress", "MacAddress":
+		return "PhysAddress48"
+	case "DisplayString", "SnmpAdminString":
+		return "DisplayString"
+	case "InetAddress", "InetAddressIPv4", "InetAddressIPv6":
+		return index.TextualConvention
+	}
+
+	switch index.Type {
+	case "IpAddress", "NetworkAddress":
+		return "IpAddr"
+	case "OCTET STRING":
+		return "OctetString"
+	default:
+		return "gauge"
+	}
+}
+
 // 获取 OID 名称
 func (s *ConfigService) getOIDName(oid string) (string, error) {
 	var oidModel models.OID
-	if err := s.db.Where("oid = ?", oid).First(&oidModel).Error; err != nil {
+	if err := s.db.Where("o_id = ?", oid).First(&oidModel).Error; err != nil {
 		return "", err
 	}
 	return oidModel.Name, nil
@@ -56,6 +56,11 @@ func (s *DeviceService) GetDevice(id uint) (*models.Device, error) {
 }
 
 func (s *DeviceService) CreateDevice(device *models.Device) error {
+	for _, cred := range device.Credentials {
+		if err := validateSNMPCredential(cred); err != nil {
+			return err
+		}
+	}
 	return s.db.Create(device).Error
 }
 
@@ -94,20 +99,9 @@ func (s *DeviceService) TestDevice(id uint) (map[string]interface{}, error) {
 	cred := device.Credentials[0]
 
 	// Create SNMP test request
-	snmpReq := &models.SNMPRequest{
-		Target:    device.IPAddress,
-		Port:      device.Port,
-		Version:   cred.Version,
-		Community: cred.Community,
-		Username:  cred.Username,
-		AuthProto: cred.AuthProto,
-		AuthKey:   cred.AuthKey,
-		PrivProto: cred.PrivProto,
-		PrivKey:   cred.PrivKey,
-		OID:       "1.3.6.1.2.1.1.3.0", // sysUpTime
-		Timeout:   5,
-		Retries:   3,
-	}
+	snmpReq := snmpCredentialRequest(device.IPAddress, device.Port, cred, "1.3.6.1.2.1.1.3.0") // sysUpTime
+	snmpReq.Timeout = 5
+	snmpReq.Retries = 3
 
 	// Create SNMP service for testing
 	snmpService := NewSNMPService(s.db)
@@ -0,0 +1,413 @@
+package services
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"mib-platform/models"
+)
+
+const (
+	mibBundleDir          = "/opt/monitoring/mibs/uploads/bundles"
+	mibBundleMaxFiles     = 5000
+	mibBundleMaxFileSize  = 16 << 20  // 单个 MIB 文件解压后的上限
+	mibBundleMaxTotalSize = 256 << 20 // 压缩包及解压后总大小的上限
+)
+
+// IsMIBBundle 判断上传的文件是否为 MIB 压缩包 (zip / tar.gz)
+func IsMIBBundle(filename string) bool {
+	name := strings.ToLower(filename)
+	return strings.HasSuffix(name, ".zip") || strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz")
+}
+
+// StartMIBBundleUpload 保存压缩包并在后台导入其中的 MIB, 返回可轮询的任务
+func (s *MIBService) StartMIBBundleUpload(file multipart.File, header *multipart.FileHeader) (*models.MIBUploadJob, error) {
+	filename, err := uploadFileName(header.Filename)
+	if err != nil {
+		return nil, err
+	}
+	job := &models.MIBUploadJob{
+		Filename: filename,
+		Status:   "pending",
+	}
+	if err := s.db.Create(job).Error; err != nil {
+		return nil, fmt.Errorf("failed to create upload job: %v", err)
+	}
+
+	jobDir := filepath.Join(mibBundleDir, strconv.FormatUint(uint64(job.ID), 10))
+	if err := os.MkdirAll(jobDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %v", err)
+	}
+
+	archivePath := filepath.Join(jobDir, filename)
+	dst, err := os.Create(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %v", err)
+	}
+	n, err := io.Copy(dst, io.LimitReader(file, mibBundleMaxTotalSize+1))
+	dst.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to save file: %v", err)
+	}
+	if n > mibBundleMaxTotalSize {
+		os.RemoveAll(jobDir)
+		s.db.Delete(job)
+		return nil, fmt.Errorf("archive exceeds %d bytes", mibBundleMaxTotalSize)
+	}
+
+	// 异步执行导入; 后台任务更新自己的副本, 返回给调用方的 job 不会被并发修改
+	worker := *job
+	go s.processMIBBundle(&worker, archivePath, filepath.Join(jobDir, "files"))
+
+	return job, nil
+}
+
+// GetMIBUploadJob 查询压缩包导入任务
+func (s *MIBService) GetMIBUploadJob(id uint) (*models.MIBUploadJob, error) {
+	var job models.MIBUploadJob
+	if err := s.db.First(&job, id).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// FailInterruptedUploadJobs 启动时把上次进程遗留的未完成任务标记为失败, 它们不会再继续执行
+func (s *MIBService) FailInterruptedUploadJobs() (int64, error) {
+	now := time.Now()
+	result := s.db.Model(&models.MIBUploadJob{}).
+		Where("status IN ?", []string{"pending", "running"}).
+		Updates(map[string]interface{}{
+			"status":       "failed",
+			"error_msg":    "upload job was interrupted by a service restart",
+			"completed_at": &now,
+		})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to mark interrupted upload jobs: %v", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// mibBundleFile 压缩包中解压出的一个 MIB 文件
+type mibBundleFile struct {
+	name    string // 压缩包内的相对路径
+	path    string
+	modules []*MIBModuleAST
+	err     error
+}
+
+func (s *MIBService) processMIBBundle(job *models.MIBUploadJob, archivePath, destDir string) {
+	now := time.Now()
+	job.Status = "running"
+	job.StartedAt = &now
+	s.db.Save(job)
+
+	defer func() {
+		if r := recover(); r != nil {
+			job.Status = "failed"
+			job.ErrorMsg = fmt.Sprintf("bundle import panicked: %v", r)
+		}
+
+		now := time.Now()
+		job.CompletedAt = &now
+		if job.Status == "running" {
+			job.Status = "completed"
+			job.Progress = 100
+		}
+		s.db.Save(job)
+	}()
+
+	names, err := extractMIBBundle(archivePath, destDir)
+	if err != nil {
+		job.Status = "failed"
+		job.ErrorMsg = err.Error()
+		return
+	}
+
+	files := make([]*mibBundleFile, 0, len(names))
+	for _, name := range names {
+		f := &mibBundleFile{name: name, path: filepath.Join(destDir, filepath.FromSlash(name))}
+		content, err := os.ReadFile(f.path)
+		if err == nil {
+			f.modules, err = ParseMIBSource(string(content))
+		}
+		f.err = err
+		files = append(files, f)
+	}
+
+	job.TotalFiles = len(files)
+	job.Report = make([]models.MIBUploadFileResult, 0, len(files))
+	s.db.Save(job)
+
+	// 按依赖顺序导入, 被导入的模块先入库, 后面的文件才能解析跨模块引用
+	checksums := make(map[string]uint)
+	for i, f := range orderMIBBundle(files) {
+		result := s.importMIBBundleFile(f, checksums)
+		switch result.Status {
+		case "parsed":
+			job.ParsedFiles++
+		case "duplicate":
+			job.DuplicateFiles++
+		default:
+			job.FailedFiles++
+		}
+		job.Report = append(job.Report, result)
+		job.Progress = (i + 1) * 100 / len(files)
+		s.db.Save(job)
+	}
+}
+
+func (s *MIBService) importMIBBundleFile(f *mibBundleFile, checksums map[string]uint) models.MIBUploadFileResult {
+	result := models.MIBUploadFileResult{File: f.name}
+	if len(f.modules) > 0 {
+		result.Module = f.modules[0].Name
+	}
+	fail := func(err error) models.MIBUploadFileResult {
+		result.Status = "failed"
+		result.Error = err.Error()
+		return result
+	}
+
+	if f.err != nil {
+		return fail(f.err)
+	}
+
+	checksum, err := fileChecksum(f.path)
+	if err != nil {
+		return fail(err)
+	}
+
+	// 与 MIB 库或同一压缩包中已有的文件内容相同
+	if id, ok := checksums[checksum]; ok {
+		result.Status = "duplicate"
+		result.MIBID = id
+		return result
+	}
+	var existing []models.MIB
+	if err := s.db.Where("checksum = ?", checksum).Limit(1).Find(&existing).Error; err != nil {
+		return fail(err)
+	}
+	if len(existing) > 0 {
+		checksums[checksum] = existing[0].ID
+		result.Status = "duplicate"
+		result.MIBID = existing[0].ID
+		return result
+	}
+
+	modules, err := s.compiler.CompileFile(f.path)
+	if err != nil {
+		return fail(err)
+	}
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return fail(err)
+	}
+	mib := &models.MIB{
+		Filename:   path.Base(f.name),
+		FilePath:   f.path,
+		Size:       info.Size(),
+		Checksum:   checksum,
+		Status:     "active",
+		UploadedAt: time.Now(),
+	}
+	if err := s.createParsedMIB(mib, modules); err != nil {
+		return fail(err)
+	}
+
+	checksums[checksum] = mib.ID
+	result.Status = "parsed"
+	result.MIBID = mib.ID
+	result.Module = mib.Name
+	// 缺少依赖时仍然入库, 依赖上传后会自动重新解析
+	result.Error = mib.ErrorMsg
+	return result
+}
+
+// orderMIBBundle 按 IMPORTS 对文件做拓扑排序, 依赖在前; 循环依赖保持原顺序
+func orderMIBBundle(files []*mibBundleFile) []*mibBundleFile {
+	definedIn := make(map[string]*mibBundleFile)
+	for _, f := range files {
+		for _, module := range f.modules {
+			if _, ok := definedIn[module.Name]; !ok {
+				definedIn[module.Name] = f
+			}
+		}
+	}
+
+	ordered := make([]*mibBundleFile, 0, len(files))
+	visited := make(map[*mibBundleFile]bool)
+	var visit func(f *mibBundleFile)
+	visit = func(f *mibBundleFile) {
+		if visited[f] {
+			return
+		}
+		visited[f] = true
+		for _, module := range f.modules {
+			for _, imp := range module.Imports {
+				if dep, ok := definedIn[imp.Module]; ok && dep != f {
+					visit(dep)
+				}
+			}
+		}
+		ordered = append(ordered, f)
+	}
+
+	for _, f := range files {
+		visit(f)
+	}
+	return ordered
+}
+
+// extractMIBBundle 将压缩包中的 MIB 文件解压到 destDir, 返回解压出的相对路径
+func extractMIBBundle(archivePath, destDir string) ([]string, error) {
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create extract directory: %v", err)
+	}
+
+	x := &mibBundleExtractor{destDir: destDir}
+	name := strings.ToLower(archivePath)
+	var err error
+	if strings.HasSuffix(name, ".zip") {
+		err = x.extractZip(archivePath)
+	} else {
+		err = x.extractTarGz(archivePath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return x.names, nil
+}
+
+type mibBundleExtractor struct {
+	destDir string
+	names   []string
+	total   int64
+}
+
+func (x *mibBundleExtractor) extractZip(archivePath string) error {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("invalid zip archive: %v", err)
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", f.Name, err)
+		}
+		err = x.write(f.Name, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *mibBundleExtractor) extractTarGz(archivePath string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %v", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("invalid gzip archive: %v", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid tar archive: %v", err)
+		}
+		// 只解压普通文件, 忽略目录、符号链接和设备文件
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := x.write(header.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// write 校验路径和大小后写出一个文件, 非 MIB 文件直接跳过
+func (x *mibBundleExtractor) write(name string, r io.Reader) error {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(name, "/") || filepath.IsAbs(name) {
+		return fmt.Errorf("unsafe path in archive: %s", name)
+	}
+	clean := path.Clean(name)
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("unsafe path in archive: %s", name)
+	}
+	if !isMIBBundleEntry(clean) {
+		return nil
+	}
+
+	if len(x.names) >= mibBundleMaxFiles {
+		return fmt.Errorf("archive contains more than %d MIB files", mibBundleMaxFiles)
+	}
+
+	target := filepath.Join(x.destDir, filepath.FromSlash(clean))
+	if !strings.HasPrefix(target, filepath.Clean(x.destDir)+string(os.PathSeparator)) {
+		return fmt.Errorf("unsafe path in archive: %s", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	out, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %v", err)
+	}
+	defer out.Close()
+
+	n, err := io.Copy(out, io.LimitReader(r, mibBundleMaxFileSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %v", name, err)
+	}
+	if n > mibBundleMaxFileSize {
+		return fmt.Errorf("%s exceeds %d bytes", name, mibBundleMaxFileSize)
+	}
+	x.total += n
+	if x.total > mibBundleMaxTotalSize {
+		return fmt.Errorf("extracted size exceeds %d bytes", mibBundleMaxTotalSize)
+	}
+
+	x.names = append(x.names, clean)
+	return nil
+}
+
+// isMIBBundleEntry 判断压缩包中的文件是否按 MIB 处理; 厂商 MIB 经常没有扩展名
+func isMIBBundleEntry(name string) bool {
+	base := path.Base(name)
+	if strings.HasPrefix(base, ".") || strings.HasPrefix(name, "__MACOSX/") || strings.Contains(name, "/__MACOSX/") {
+		return false
+	}
+	switch strings.ToLower(path.Ext(base)) {
+	case ".mib", ".txt", ".my", "":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,345 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"mib-platform/models"
+)
+
+// mibWellKnownOIDs SMI 基础模块 (SNMPv2-SMI / RFC1155-SMI) 中定义的根节点
+var mibWellKnownOIDs = map[string]string{
+	"ccitt":           "0",
+	"zeroDotZero":     "0.0",
+	"iso":             "1",
+	"org":             "1.3",
+	"dod":             "1.3.6",
+	"internet":        "1.3.6.1",
+	"directory":       "1.3.6.1.1",
+	"mgmt":            "1.3.6.1.2",
+	"mib-2":           "1.3.6.1.2.1",
+	"transmission":    "1.3.6.1.2.1.10",
+	"experimental":    "1.3.6.1.3",
+	"private":         "1.3.6.1.4",
+	"enterprises":     "1.3.6.1.4.1",
+	"security":        "1.3.6.1.5",
+	"snmpV2":          "1.3.6.1.6",
+	"snmpDomains":     "1.3.6.1.6.1",
+	"snmpProxys":      "1.3.6.1.6.2",
+	"snmpModules":     "1.3.6.1.6.3",
+	"joint-iso-ccitt": "2",
+}
+
+// mibBaseModules 内置的 SMI 基础模块, 导入它们不需要 MIB 库中存在对应文件
+var mibBaseModules = map[string]bool{
+	"SNMPv2-SMI":  true,
+	"SNMPv2-TC":   true,
+	"SNMPv2-CONF": true,
+	"RFC1155-SMI": true,
+	"RFC1065-SMI": true,
+	"RFC-1212":    true,
+	"RFC-1215":    true,
+}
+
+// IsBaseMIBModule 判断模块是否为内置的 SMI 基础模块
+func IsBaseMIBModule(module string) bool {
+	return mibBaseModules[module]
+}
+
+// MIBImportResolver 解析从其他模块导入的符号, 返回该符号的 OID 记录
+type MIBImportResolver func(module, symbol string) (*models.OID, bool)
+
+// MIBCompiler 原生 SMIv1/SMIv2 MIB 编译器
+type MIBCompiler struct {
+	resolveImport MIBImportResolver
+	resolveType   MIBTypeResolver
+	builtin       bool // 正在编译内置的 SNMPv2-TC
+}
+
+// NewMIBCompiler 创建 MIB 编译器, 两个解析函数都可以为 nil
+func NewMIBCompiler(resolveImport MIBImportResolver, resolveType MIBTypeResolver) *MIBCompiler {
+	return &MIBCompiler{resolveImport: resolveImport, resolveType: resolveType}
+}
+
+// CompiledMIBModule 编译后的模块, 所有可解析的节点都带有数字 OID
+type CompiledMIBModule struct {
+	AST        *MIBModuleAST
+	Objects    []*CompiledMIBObject
+	Types      []*CompiledMIBType
+	Unresolved []string
+}
+
+// CompiledMIBObject 编译后的 OID 节点, Type 和表结构仅对 OBJECT-TYPE 有值
+type CompiledMIBObject struct {
+	Node     *MIBNode
+	OID      string
+	Type     *MIBTypeInfo
+	Kind     string
+	Table    string
+	Augments string
+	Indexes  []models.OIDIndex
+	Varbinds []models.NotificationObject // NOTIFICATION-TYPE / TRAP-TYPE 的 OBJECTS
+	Members  []models.MIBGroupMember     // OBJECT-GROUP / NOTIFICATION-GROUP 的成员
+}
+
+// CompileFile 读取并编译一个 MIB 文件
+func (c *MIBCompiler) CompileFile(filePath string) ([]*CompiledMIBModule, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read MIB file: %v", err)
+	}
+	return c.CompileSource(string(content))
+}
+
+// CompileSource 编译 MIB 源文本
+func (c *MIBCompiler) CompileSource(src string) ([]*CompiledMIBModule, error) {
+	asts, err := ParseMIBSource(src)
+	if err != nil {
+		return nil, err
+	}
+
+	// 同一文件中先出现的模块可以被后面的模块导入
+	local := make(map[string]*CompiledMIBModule)
+	var modules []*CompiledMIBModule
+	for _, ast := range asts {
+		module := c.compileModule(ast, local)
+		local[ast.Name] = module
+		modules = append(modules, module)
+	}
+	return modules, nil
+}
+
+func (c *MIBCompiler) compileModule(ast *MIBModuleAST, local map[string]*CompiledMIBModule) *CompiledMIBModule {
+	importedFrom := make(map[string]string)
+	for _, imp := range ast.Imports {
+		for _, symbol := range imp.Symbols {
+			importedFrom[symbol] = imp.Module
+		}
+	}
+
+	resolved := make(map[string]string)
+	lookup := func(name string) (string, bool) {
+		if oid, ok := resolved[name]; ok {
+			return oid, true
+		}
+		if module, ok := importedFrom[name]; ok {
+			if oid, ok := c.lookupImport(module, name, local); ok {
+				return oid.OID, true
+			}
+		}
+		oid, ok := mibWellKnownOIDs[name]
+		return oid, ok
+	}
+
+	compiled := &CompiledMIBModule{AST: ast}
+	oids := make(map[*MIBNode]string)
+
+	// 节点可以引用在其后定义的父节点, 迭代直到不再有新节点被解析
+	pending := ast.Nodes
+	for len(pending) > 0 {
+		var rest []*MIBNode
+		for _, node := range pending {
+			oid, ok := resolveMIBNodeOID(node, resolved, lookup)
+			if !ok {
+				rest = append(rest, node)
+				continue
+			}
+			oids[node] = oid
+			if node.Macro != "TRAP-TYPE" {
+				resolved[node.Name] = oid
+			}
+		}
+		if len(rest) == len(pending) {
+			break
+		}
+		pending = rest
+	}
+
+	for _, node := range pending {
+		compiled.Unresolved = append(compiled.Unresolved, node.Name)
+	}
+
+	scope := c.newTypeScope(ast, importedFrom, local)
+	for _, def := range ast.Types {
+		info := scope.resolveName(def.Name)
+		compiled.Types = append(compiled.Types, &CompiledMIBType{Def: def, Info: &info})
+	}
+	for _, node := range ast.Nodes {
+		oid, ok := oids[node]
+		if !ok {
+			continue
+		}
+		obj := &CompiledMIBObject{Node: node, OID: oid}
+		if syntax := node.Clause("SYNTAX"); syntax != nil && node.Macro == "OBJECT-TYPE" {
+			info := scope.resolveSyntax(syntax.Syntax)
+			obj.Type = &info
+		}
+		compiled.Objects = append(compiled.Objects, obj)
+	}
+
+	c.buildTables(compiled, importedFrom, local)
+	c.buildNotifications(compiled, importedFrom, local)
+	c.buildGroups(compiled, importedFrom, local)
+	return compiled
+}
+
+func (c *MIBCompiler) lookupImport(module, symbol string, local map[string]*CompiledMIBModule) (*models.OID, bool) {
+	if compiled, ok := local[module]; ok {
+		if obj := compiled.Object(symbol); obj != nil {
+			oid := obj.ModelOID()
+			return &oid, true
+		}
+	}
+	if c.resolveImport != nil {
+		return c.resolveImport(module, symbol)
+	}
+	return nil, false
+}
+
+// Object 按名称查找编译后的节点
+func (m *CompiledMIBModule) Object(name string) *CompiledMIBObject {
+	for _, obj := range m.Objects {
+		if obj.Node.Name == name {
+			return obj
+		}
+	}
+	return nil
+}
+
+// resolveMIBNodeOID 将 OID 值解析为数字形式, 并登记值中出现的命名分量 (如 org(3))
+func resolveMIBNodeOID(node *MIBNode, resolved map[string]string, lookup func(string) (string, bool)) (string, bool) {
+	// SMIv1 TRAP-TYPE 按 RFC 3584 映射为 enterprise.0.specific-trap
+	if node.Macro == "TRAP-TYPE" {
+		enterprise, ok := lookup(node.ClauseText("ENTERPRISE"))
+		if !ok || len(node.Value) != 1 {
+			return "", false
+		}
+		return fmt.Sprintf("%s.0.%d", enterprise, node.Value[0].Number), true
+	}
+
+	var parts []string
+	for i, comp := range node.Value {
+		if i == 0 {
+			if comp.Name != "" {
+				if base, ok := lookup(comp.Name); ok {
+					parts = append(parts, base)
+					continue
+				}
+				if !comp.HasNumber {
+					return "", false
+				}
+			}
+			parts = append(parts, strconv.FormatUint(uint64(comp.Number), 10))
+			continue
+		}
+
+		if !comp.HasNumber {
+			return "", false
+		}
+		parts = append(parts, strconv.FormatUint(uint64(comp.Number), 10))
+		if comp.Name != "" {
+			if _, exists := resolved[comp.Name]; !exists {
+				resolved[comp.Name] = strings.Join(parts, ".")
+			}
+		}
+	}
+	return strings.Join(parts, "."), true
+}
+
+// formatMIBOIDValue 将 OID 值还原为 "ifEntry.8" 形式的文本
+func formatMIBOIDValue(value []MIBOIDComponent) string {
+	parts := make([]string, 0, len(value))
+	for _, comp := range value {
+		switch {
+		case comp.Name != "":
+			parts = append(parts, comp.Name)
+		default:
+			parts = append(parts, strconv.FormatUint(uint64(comp.Number), 10))
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// cleanMIBDescription 去掉多行描述的公共缩进
+func cleanMIBDescription(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if i > 0 && indent > 0 && len(line) >= indent {
+			line = line[indent:]
+		}
+		lines[i] = line
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// ModuleDescription 返回 MODULE-IDENTITY 的 DESCRIPTION
+func (m *CompiledMIBModule) ModuleDescription() string {
+	for _, node := range m.AST.Nodes {
+		if node.Macro == "MODULE-IDENTITY" {
+			return cleanMIBDescription(node.ClauseText("DESCRIPTION"))
+		}
+	}
+	return ""
+}
+
+// ModelOIDs 将编译结果转换为 models.OID
+func (m *CompiledMIBModule) ModelOIDs() []models.OID {
+	oids := make([]models.OID, 0, len(m.Objects))
+	for _, obj := range m.Objects {
+		oids = append(oids, obj.ModelOID())
+	}
+	return oids
+}
+
+// ModelOID 将编译后的节点转换为 models.OID
+func (obj *CompiledMIBObject) ModelOID() models.OID {
+	node := obj.Node
+	oid := models.OID{
+		Name:        node.Name,
+		OID:         obj.OID,
+		OIDString:   formatMIBOIDValue(node.Value),
+		Type:        node.Macro,
+		Status:      node.ClauseText("STATUS"),
+		Description: cleanMIBDescription(node.ClauseText("DESCRIPTION")),
+		Units:       node.ClauseText("UNITS"),
+		ParentOID:   mibParentOID(obj.OID),
+		Kind:        obj.Kind,
+		Table:       obj.Table,
+		Augments:    obj.Augments,
+		Indexes:     obj.Indexes,
+	}
+	// MODULE-COMPLIANCE / AGENT-CAPABILITIES 中的 SYNTAX、ACCESS 是对其他对象的细化, 不属于该节点
+	if node.Macro == "OBJECT-TYPE" {
+		if syntax := node.Clause("SYNTAX"); syntax != nil {
+			oid.Type = syntax.Syntax.Type
+			oid.Syntax = syntax.Syntax.Text()
+		}
+		if info := obj.Type; info != nil {
+			oid.BaseType = info.BaseType
+			oid.TextualConvention = info.Name
+			oid.DisplayHint = info.DisplayHint
+			oid.Enums = info.Enums
+			oid.Ranges = info.Ranges
+			oid.Sizes = info.Sizes
+		}
+		if access := node.ClauseText("MAX-ACCESS"); access != "" {
+			oid.Access = access
+		} else {
+			oid.Access = node.ClauseText("ACCESS")
+		}
+	}
+	return oid
+}
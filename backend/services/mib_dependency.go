@@ -0,0 +1,250 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"mib-platform/models"
+)
+
+// MIBDependencyNode 依赖树中的一个模块
+type MIBDependencyNode struct {
+	Module       string               `json:"module"`
+	MIBID        uint                 `json:"mib_id,omitempty"`
+	Status       string               `json:"status"` // loaded, builtin, missing, cycle
+	Symbols      []string             `json:"symbols,omitempty"`
+	Dependencies []*MIBDependencyNode `json:"dependencies,omitempty"`
+}
+
+// MIBDependencyReport 单个 MIB 的依赖报告
+type MIBDependencyReport struct {
+	MIBID      uint               `json:"mib_id"`
+	Module     string             `json:"module"`
+	Tree       *MIBDependencyNode `json:"tree"`
+	LoadOrder  []string           `json:"load_order"`
+	Missing    []string           `json:"missing"`
+	Unresolved string             `json:"unresolved,omitempty"`
+}
+
+// MIBMissingDependencies 缺少依赖的 MIB
+type MIBMissingDependencies struct {
+	MIBID      uint     `json:"mib_id"`
+	Module     string   `json:"module"`
+	Missing    []string `json:"missing"`
+	Unresolved string   `json:"unresolved,omitempty"`
+}
+
+// mibDependencyGraph 基于 MIB 库中所有模块的 IMPORTS 构建的依赖图
+type mibDependencyGraph struct {
+	modules map[string]*models.MIB
+}
+
+func (s *MIBService) loadDependencyGraph() (*mibDependencyGraph, error) {
+	var mibs []models.MIB
+	if err := s.db.Preload("Imports").Order("id").Find(&mibs).Error; err != nil {
+		return nil, err
+	}
+
+	// 同名模块存在多份时以最后加载的为准
+	graph := &mibDependencyGraph{modules: make(map[string]*models.MIB)}
+	for i := range mibs {
+		graph.modules[mibs[i].Name] = &mibs[i]
+	}
+	return graph, nil
+}
+
+func (g *mibDependencyGraph) status(module string) string {
+	if _, ok := g.modules[module]; ok {
+		return "loaded"
+	}
+	if IsBaseMIBModule(module) {
+		return "builtin"
+	}
+	return "missing"
+}
+
+// tree 构建以 module 为根的依赖树, path 用于检测循环依赖
+func (g *mibDependencyGraph) tree(module string, symbols []string, path map[string]bool) *MIBDependencyNode {
+	node := &MIBDependencyNode{Module: module, Symbols: symbols, Status: g.status(module)}
+	mib, ok := g.modules[module]
+	if !ok {
+		return node
+	}
+	node.MIBID = mib.ID
+	if path[module] {
+		node.Status = "cycle"
+		return node
+	}
+
+	path[module] = true
+	for _, imp := range mib.Imports {
+		node.Dependencies = append(node.Dependencies, g.tree(imp.Module, splitMIBSymbols(imp.Symbols), path))
+	}
+	delete(path, module)
+	return node
+}
+
+// loadOrder 返回加载 module 所需的模块顺序 (依赖在前), 只包含 MIB 库中存在的模块
+func (g *mibDependencyGraph) loadOrder(module string) []string {
+	var order []string
+	visited := make(map[string]bool)
+
+	var visit func(name string)
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+		mib, ok := g.modules[name]
+		if !ok {
+			return
+		}
+		for _, imp := range mib.Imports {
+			visit(imp.Module)
+		}
+		order = append(order, name)
+	}
+
+	visit(module)
+	return order
+}
+
+// missing 返回 module 直接或间接依赖但 MIB 库中不存在的模块
+func (g *mibDependencyGraph) missing(module string) []string {
+	found := make(map[string]bool)
+	for _, name := range g.loadOrder(module) {
+		for _, imp := range g.modules[name].Imports {
+			if g.status(imp.Module) == "missing" {
+				found[imp.Module] = true
+			}
+		}
+	}
+
+	missing := make([]string, 0, len(found))
+	for name := range found {
+		missing = append(missing, name)
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+func splitMIBSymbols(symbols string) []string {
+	if symbols == "" {
+		return nil
+	}
+	return strings.Split(symbols, ",")
+}
+
+// GetMIBDependencies 返回 MIB 的依赖树、加载顺序和缺失的依赖
+func (s *MIBService) GetMIBDependencies(id uint) (*MIBDependencyReport, error) {
+	var mib models.MIB
+	if err := s.db.Preload("Imports").First(&mib, id).Error; err != nil {
+		return nil, err
+	}
+
+	graph, err := s.loadDependencyGraph()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load MIB dependency graph: %v", err)
+	}
+	// 报告请求的具体记录, 而不是同名模块中最后加载的一份
+	graph.modules[mib.Name] = &mib
+
+	return &MIBDependencyReport{
+		MIBID:      mib.ID,
+		Module:     mib.Name,
+		Tree:       graph.tree(mib.Name, nil, make(map[string]bool)),
+		LoadOrder:  graph.loadOrder(mib.Name),
+		Missing:    graph.missing(mib.Name),
+		Unresolved: mib.ErrorMsg,
+	}, nil
+}
+
+// GetMissingDependencies 列出所有存在缺失依赖或未解析节点的 MIB
+func (s *MIBService) GetMissingDependencies() ([]MIBMissingDependencies, error) {
+	graph, err := s.loadDependencyGraph()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load MIB dependency graph: %v", err)
+	}
+
+	names := make([]string, 0, len(graph.modules))
+	for name := range graph.modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := []MIBMissingDependencies{}
+	for _, name := range names {
+		mib := graph.modules[name]
+		missing := graph.missing(name)
+		if len(missing) == 0 && mib.ErrorMsg == "" {
+			continue
+		}
+		results = append(results, MIBMissingDependencies{
+			MIBID:      mib.ID,
+			Module:     name,
+			Missing:    missing,
+			Unresolved: mib.ErrorMsg,
+		})
+	}
+	return results, nil
+}
+
+// lookupImportedSymbol 在 MIB 库中查找其他模块定义的符号
+func (s *MIBService) lookupImportedSymbol(module, symbol string) (*models.OID, bool) {
+	// 用 Find 而不是 First, 未找到符号是正常情况, 不需要记录 record not found
+	var oids []models.OID
+	err := s.db.Joins("JOIN mibs ON mibs.id = o_ids.mib_id AND mibs.deleted_at IS NULL").
+		Where("mibs.name = ? AND o_ids.name = ?", module, symbol).
+		Order("mibs.id DESC").
+		Limit(1).
+		Find(&oids).Error
+	if err != nil || len(oids) == 0 {
+		return nil, false
+	}
+	return &oids[0], true
+}
+
+// lookupImportedType 在 MIB 库中查找其他模块定义的类型
+func (s *MIBService) lookupImportedType(module, name string) (*MIBTypeInfo, bool) {
+	var types []models.MIBType
+	err := s.db.Joins("JOIN mibs ON mibs.id = mib_types.mib_id AND mibs.deleted_at IS NULL").
+		Where("mibs.name = ? AND mib_types.name = ?", module, name).
+		Order("mibs.id DESC").
+		Limit(1).
+		Find(&types).Error
+	if err != nil || len(types) == 0 {
+		return nil, false
+	}
+
+	t := types[0]
+	return &MIBTypeInfo{
+		Name:        t.Name,
+		BaseType:    t.BaseType,
+		DisplayHint: t.DisplayHint,
+		Enums:       t.Enums,
+		Ranges:      t.Ranges,
+		Sizes:       t.Sizes,
+	}, true
+}
+
+// reparseDependents 重新解析导入了 mib 且存在未解析节点的其他 MIB
+func (s *MIBService) reparseDependents(mib *models.MIB) {
+	var ids []uint
+	err := s.db.Model(&models.MIBImport{}).
+		Joins("JOIN mibs ON mibs.id = mib_imports.mib_id AND mibs.deleted_at IS NULL").
+		Where("mib_imports.module = ? AND mibs.id <> ? AND mibs.error_msg <> ''", mib.Name, mib.ID).
+		Distinct("mib_imports.mib_id").
+		Pluck("mib_imports.mib_id", &ids).Error
+	if err != nil {
+		log.Printf("查找依赖 %s 的 MIB 失败: %v", mib.Name, err)
+		return
+	}
+
+	for _, id := range ids {
+		if _, err := s.ReparseMIB(id); err != nil {
+			log.Printf("重新解析 MIB %d 失败: %v", id, err)
+		}
+	}
+}
@@ -0,0 +1,384 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// generatorOverrideTypes snmp_exporter generator 支持的 overrides.type
+var generatorOverrideTypes = map[string]bool{
+	"gauge": true, "counter": true, "OctetString": true, "DisplayString": true, "PhysAddress48": true,
+	"Float": true, "Double": true, "DateAndTime": true, "ParseDateAndTime": true, "NTPTimeStamp": true,
+	"EnumAsInfo": true, "EnumAsStateSet": true, "Bits": true, "IpAddr": true,
+	"InetAddress": true, "InetAddressIPv4": true, "InetAddressIPv6": true,
+}
+
+var (
+	generatorSecurityLevels = []string{"noAuthNoPriv", "authNoPriv", "authPriv"}
+	generatorAuthProtocols  = []string{"MD5", "SHA", "SHA224", "SHA256", "SHA384", "SHA512"}
+	generatorPrivProtocols  = []string{"DES", "AES", "AES192", "AES256", "AES192C", "AES256C"}
+)
+
+// GeneratorConfig snmp_exporter generator.yml
+type GeneratorConfig struct {
+	Auths   map[string]GeneratorAuth   `json:"auths,omitempty" yaml:"auths,omitempty"`
+	Modules map[string]GeneratorModule `json:"modules" yaml:"modules"`
+}
+
+// GeneratorAuth generator.yml 中的认证配置
+type GeneratorAuth struct {
+	Community     string `json:"community,omitempty" yaml:"community,omitempty"`
+	Version       int    `json:"version,omitempty" yaml:"version,omitempty"` // 1, 2, 3; 为空时 generator 默认 2
+	SecurityLevel string `json:"security_level,omitempty" yaml:"security_level,omitempty"`
+	Username      string `json:"username,omitempty" yaml:"username,omitempty"`
+	Password      string `json:"password,omitempty" yaml:"password,omitempty"`
+	AuthProtocol  string `json:"auth_protocol,omitempty" yaml:"auth_protocol,omitempty"`
+	PrivProtocol  string `json:"priv_protocol,omitempty" yaml:"priv_protocol,omitempty"`
+	PrivPassword  string `json:"priv_password,omitempty" yaml:"priv_password,omitempty"`
+	ContextName   string `json:"context_name,omitempty" yaml:"context_name,omitempty"`
+}
+
+// GeneratorModule generator.yml 中的一个采集模块; 对象可以写 OID、名称或 MODULE::name
+type GeneratorModule struct {
+	Walk           []string                     `json:"walk" yaml:"walk"`
+	Lookups        []GeneratorLookup            `json:"lookups,omitempty" yaml:"lookups,omitempty"`
+	Overrides      map[string]GeneratorOverride `json:"overrides,omitempty" yaml:"overrides,omitempty"`
+	MaxRepetitions int                          `json:"max_repetitions,omitempty" yaml:"max_repetitions,omitempty"`
+	Retries        int                          `json:"retries,omitempty" yaml:"retries,omitempty"`
+	Timeout        string                       `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// GeneratorLookup 用另一列的值替换表索引标签
+type GeneratorLookup struct {
+	SourceIndexes     []string `json:"source_indexes" yaml:"source_indexes"`
+	Lookup            string   `json:"lookup" yaml:"lookup"`
+	DropSourceIndexes bool     `json:"drop_source_indexes,omitempty" yaml:"drop_source_indexes,omitempty"`
+}
+
+// GeneratorOverride 单个对象的覆盖配置
+type GeneratorOverride struct {
+	Ignore        bool                        `json:"ignore,omitempty" yaml:"ignore,omitempty"`
+	Type          string                      `json:"type,omitempty" yaml:"type,omitempty"`
+	RegexExtracts map[string][]GeneratorRegex `json:"regex_extracts,omitempty" yaml:"regex_extracts,omitempty"`
+}
+
+// GeneratorRegex regex_extracts 中的一条规则
+type GeneratorRegex struct {
+	Regex string `json:"regex" yaml:"regex"`
+	Value string `json:"value" yaml:"value"`
+}
+
+// GeneratorMIBFile generator 需要加载的 MIB 文件
+type GeneratorMIBFile struct {
+	Module   string `json:"module"`
+	MIBID    uint   `json:"mib_id"`
+	Filename string `json:"filename"`
+	filePath string
+}
+
+// GeneratorExport 导出结果
+type GeneratorExport struct {
+	YAML     string             `json:"yaml"`
+	MIBs     []GeneratorMIBFile `json:"mibs"`    // 按依赖顺序排列
+	Missing  []string           `json:"missing"` // MIB 库中缺少的依赖模块
+	Warnings []string           `json:"warnings"`
+}
+
+// ExportGeneratorConfig 根据选择的 MIB 对象生成 generator.yml, 并收集 generator 需要的 MIB 文件
+func (s *MIBService) ExportGeneratorConfig(req GeneratorConfig) (*GeneratorExport, error) {
+	if len(req.Modules) == 0 {
+		return nil, fmt.Errorf("at least one module is required")
+	}
+
+	var problems []string
+	for name, auth := range req.Auths {
+		for _, p := range validateGeneratorAuth(auth) {
+			problems = append(problems, fmt.Sprintf("auth %s: %s", name, p))
+		}
+	}
+
+	modules := make(map[string]bool)
+	config := GeneratorConfig{Auths: req.Auths, Modules: make(map[string]GeneratorModule, len(req.Modules))}
+	for name, module := range req.Modules {
+		out, used, errs := s.resolveGeneratorModule(module)
+		for _, p := range errs {
+			problems = append(problems, fmt.Sprintf("module %s: %s", name, p))
+		}
+		for _, m := range used {
+			modules[m] = true
+		}
+		config.Modules[name] = out
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, fmt.Errorf("invalid generator config: %s", strings.Join(problems, "; "))
+	}
+
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal YAML: %v", err)
+	}
+
+	export := &GeneratorExport{YAML: string(data), MIBs: []GeneratorMIBFile{}, Missing: []string{}, Warnings: []string{}}
+	if err := s.collectGeneratorMIBs(export, modules); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// resolveGeneratorModule 将模块中的对象解析为 MIB 库中的节点, 返回规范化后的模块和用到的 MIB 模块
+func (s *MIBService) resolveGeneratorModule(module GeneratorModule) (GeneratorModule, []string, []string) {
+	var problems, used []string
+	resolve := func(symbol string) (*OIDTreeNode, string, bool) {
+		node, name, err := s.resolveGeneratorObject(symbol)
+		if err != nil {
+			problems = append(problems, err.Error())
+			return nil, "", false
+		}
+		used = append(used, node.Module)
+		return node, name, true
+	}
+	// 索引和覆盖项按标签名匹配, 只能使用有名称的对象
+	resolveName := func(symbol string) (string, bool) {
+		node, _, ok := resolve(symbol)
+		if !ok {
+			return "", false
+		}
+		if node.Name == "" {
+			problems = append(problems, fmt.Sprintf("%s has no object name", symbol))
+			return "", false
+		}
+		return node.Name, true
+	}
+
+	if len(module.Walk) == 0 {
+		problems = append(problems, "walk is empty")
+	}
+	walkNames := make(map[string]string)
+	var walkOIDs []string
+	for _, symbol := range module.Walk {
+		node, name, ok := resolve(symbol)
+		if !ok {
+			continue
+		}
+		if _, dup := walkNames[node.OID]; !dup {
+			walkOIDs = append(walkOIDs, node.OID)
+		}
+		walkNames[node.OID] = name
+	}
+	// 已被祖先节点覆盖的子树不重复 walk
+	sort.Slice(walkOIDs, func(i, j int) bool { return compareOIDs(walkOIDs[i], walkOIDs[j]) < 0 })
+	var walk []string
+	last := ""
+	for _, oid := range walkOIDs {
+		if last != "" && strings.HasPrefix(oid, last+".") {
+			continue
+		}
+		last = oid
+		walk = append(walk, walkNames[oid])
+	}
+
+	out := GeneratorModule{
+		Walk:           walk,
+		MaxRepetitions: module.MaxRepetitions,
+		Retries:        module.Retries,
+		Timeout:        module.Timeout,
+	}
+
+	for _, lookup := range module.Lookups {
+		if len(lookup.SourceIndexes) == 0 || lookup.Lookup == "" {
+			problems = append(problems, "lookup requires source_indexes and lookup")
+			continue
+		}
+		resolved := GeneratorLookup{DropSourceIndexes: lookup.DropSourceIndexes}
+		ok := true
+		for _, index := range lookup.SourceIndexes {
+			name, found := resolveName(index)
+			ok = ok && found
+			resolved.SourceIndexes = append(resolved.SourceIndexes, name)
+		}
+		_, name, found := resolve(lookup.Lookup)
+		if ok && found {
+			resolved.Lookup = name
+			out.Lookups = append(out.Lookups, resolved)
+		}
+	}
+
+	if len(module.Overrides) > 0 {
+		out.Overrides = make(map[string]GeneratorOverride, len(module.Overrides))
+	}
+	for symbol, override := range module.Overrides {
+		name, ok := resolveName(symbol)
+		if override.Type != "" && !generatorOverrideTypes[override.Type] {
+			problems = append(problems, fmt.Sprintf("override %s: unsupported type %s", symbol, override.Type))
+			ok = false
+		}
+		for label, extracts := range override.RegexExtracts {
+			for _, extract := range extracts {
+				if _, err := regexp.Compile(extract.Regex); err != nil {
+					problems = append(problems, fmt.Sprintf("override %s: invalid regex for %s: %v", symbol, label, err))
+					ok = false
+				}
+			}
+		}
+		if ok {
+			out.Overrides[name] = override
+		}
+	}
+	return out, used, problems
+}
+
+// resolveGeneratorObject 返回对象节点和写入 generator.yml 的名称; 名称不唯一时使用数字 OID
+func (s *MIBService) resolveGeneratorObject(symbol string) (*OIDTreeNode, string, error) {
+	node, err := s.tree.Resolve(symbol)
+	if err != nil {
+		return nil, "", err
+	}
+	if node.Name == "" {
+		return node, node.OID, nil
+	}
+	if nodes, err := s.tree.Lookup(node.Name); err == nil && len(nodes) == 1 {
+		return node, node.Name, nil
+	}
+	return node, node.OID, nil
+}
+
+// collectGeneratorMIBs 按依赖顺序列出生成配置所需的 MIB 文件
+func (s *MIBService) collectGeneratorMIBs(export *GeneratorExport, modules map[string]bool) error {
+	graph, err := s.loadDependencyGraph()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(modules))
+	for name := range modules {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	seen := make(map[string]bool)
+	missing := make(map[string]bool)
+	for _, name := range names {
+		for _, m := range graph.missing(name) {
+			missing[m] = true
+		}
+		for _, module := range graph.loadOrder(name) {
+			if seen[module] {
+				continue
+			}
+			seen[module] = true
+			mib := graph.modules[module]
+			if _, err := os.Stat(mib.FilePath); err != nil {
+				export.Warnings = append(export.Warnings, fmt.Sprintf("source file of %s is not available: %s", module, mib.FilePath))
+				continue
+			}
+			filename := mib.Filename
+			if filename == "" {
+				filename = filepath.Base(mib.FilePath)
+			}
+			export.MIBs = append(export.MIBs, GeneratorMIBFile{
+				Module:   module,
+				MIBID:    mib.ID,
+				Filename: filename,
+				filePath: mib.FilePath,
+			})
+		}
+	}
+
+	for name := range missing {
+		export.Missing = append(export.Missing, name)
+	}
+	sort.Strings(export.Missing)
+	return nil
+}
+
+// Archive 打包 generator.yml 和 mibs/ 目录, 可直接执行 generator generate -m mibs
+func (e *GeneratorExport) Archive() ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	w, err := zw.Create("generator.yml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write([]byte(e.YAML)); err != nil {
+		return nil, err
+	}
+
+	used := make(map[string]bool)
+	for _, mib := range e.MIBs {
+		content, err := os.ReadFile(mib.filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read MIB file: %v", err)
+		}
+		// 不同模块的文件同名时以模块名命名, generator 按内容而不是文件名加载
+		name := mib.Filename
+		if used[name] {
+			name = mib.Module + ".mib"
+		}
+		used[name] = true
+		w, err := zw.Create(path.Join("mibs", name))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// validateGeneratorAuth 检查认证配置, 返回问题列表
+func validateGeneratorAuth(auth GeneratorAuth) []string {
+	var problems []string
+	switch auth.Version {
+	case 0, 1, 2:
+		return nil
+	case 3:
+	default:
+		return []string{fmt.Sprintf("unsupported version %d", auth.Version)}
+	}
+
+	if auth.Username == "" {
+		problems = append(problems, "username is required for version 3")
+	}
+	level := auth.SecurityLevel
+	if level == "" {
+		level = "noAuthNoPriv"
+	}
+	if !containsString(generatorSecurityLevels, level) {
+		problems = append(problems, fmt.Sprintf("unsupported security_level %s", level))
+	}
+	if level == "authNoPriv" || level == "authPriv" {
+		if auth.Password == "" {
+			problems = append(problems, "password is required for "+level)
+		}
+		if auth.AuthProtocol != "" && !containsString(generatorAuthProtocols, auth.AuthProtocol) {
+			problems = append(problems, fmt.Sprintf("unsupported auth_protocol %s", auth.AuthProtocol))
+		}
+	}
+	if level == "authPriv" {
+		if auth.PrivPassword == "" {
+			problems = append(problems, "priv_password is required for authPriv")
+		}
+		if auth.PrivProtocol != "" && !containsString(generatorPrivProtocols, auth.PrivProtocol) {
+			problems = append(problems, fmt.Sprintf("unsupported priv_protocol %s", auth.PrivProtocol))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,409 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"mib-platform/models"
+)
+
+// mibPollableAccess 可以通过 GET/WALK 采集的访问级别, SMIv1 的 ACCESS 与 SMIv2 的 MAX-ACCESS 共用
+var mibPollableAccess = map[string]bool{
+	"read-only":   true,
+	"read-write":  true,
+	"read-create": true,
+}
+
+// MIBMetricSet 可以一次选中的一组对象: 对象组或一致性声明
+type MIBMetricSet struct {
+	Kind        string `json:"kind"`   // group, compliance
+	Symbol      string `json:"symbol"` // MODULE::name, 可直接用于展开
+	Module      string `json:"module"`
+	Name        string `json:"name"`
+	MIBID       uint   `json:"mib_id"`
+	Status      string `json:"status"`
+	Description string `json:"description"`
+	ObjectCount int    `json:"object_count"` // 展开后可采集的对象数, 一致性声明只计算必选组
+}
+
+// MIBMetricSetRequest 要展开的对象组和一致性声明, 均使用 MODULE::name 或 name
+type MIBMetricSetRequest struct {
+	Groups          []string `json:"groups"`
+	Compliances     []string `json:"compliances"`
+	IncludeOptional bool     `json:"include_optional"` // 同时展开一致性声明中的条件性 GROUP
+}
+
+// MIBMetricSetObject 展开得到的一个对象
+type MIBMetricSetObject struct {
+	Name   string `json:"name"`
+	OID    string `json:"oid"`
+	Module string `json:"module"`
+	Group  string `json:"group"` // 来源对象组 MODULE::name
+	Access string `json:"access,omitempty"`
+	Reason string `json:"reason,omitempty"` // 被跳过的原因
+}
+
+// MIBMetricSetExpansion 展开结果, OIDs 可直接作为 ConfigGenerationRequest.SelectedOIDs
+type MIBMetricSetExpansion struct {
+	OIDs       []string             `json:"oids"`
+	Objects    []MIBMetricSetObject `json:"objects"`
+	Skipped    []MIBMetricSetObject `json:"skipped"`
+	Unresolved []string             `json:"unresolved"`
+}
+
+// buildGroups 解析 OBJECT-GROUP 的 OBJECTS 和 NOTIFICATION-GROUP 的 NOTIFICATIONS
+func (c *MIBCompiler) buildGroups(m *CompiledMIBModule, importedFrom map[string]string, local map[string]*CompiledMIBModule) {
+	for _, obj := range m.Objects {
+		var clause *MIBClause
+		switch obj.Node.Macro {
+		case "OBJECT-GROUP":
+			clause = obj.Node.Clause("OBJECTS")
+		case "NOTIFICATION-GROUP":
+			clause = obj.Node.Clause("NOTIFICATIONS")
+		default:
+			continue
+		}
+
+		obj.Members = []models.MIBGroupMember{}
+		if clause == nil {
+			continue
+		}
+		for _, name := range clause.Identifiers() {
+			member := models.MIBGroupMember{Name: name}
+			if target := m.Object(name); target != nil {
+				member.OID = target.OID
+			} else if module, ok := importedFrom[name]; ok {
+				if target, ok := c.lookupImport(module, name, local); ok {
+					member.OID = target.OID
+				}
+			}
+			obj.Members = append(obj.Members, member)
+		}
+	}
+}
+
+// ModelGroups 将模块中的对象组转换为 models.MIBGroup
+func (m *CompiledMIBModule) ModelGroups() []models.MIBGroup {
+	var groups []models.MIBGroup
+	for _, obj := range m.Objects {
+		if obj.Members == nil {
+			continue
+		}
+		groups = append(groups, models.MIBGroup{
+			Module:      m.AST.Name,
+			Name:        obj.Node.Name,
+			OID:         obj.OID,
+			Macro:       obj.Node.Macro,
+			Status:      obj.Node.ClauseText("STATUS"),
+			Description: cleanMIBDescription(obj.Node.ClauseText("DESCRIPTION")),
+			Members:     obj.Members,
+		})
+	}
+	return groups
+}
+
+// ModelCompliances 将模块中的 MODULE-COMPLIANCE 转换为 models.MIBCompliance
+func (m *CompiledMIBModule) ModelCompliances() []models.MIBCompliance {
+	var compliances []models.MIBCompliance
+	for _, obj := range m.Objects {
+		if obj.Node.Macro != "MODULE-COMPLIANCE" {
+			continue
+		}
+		compliance := models.MIBCompliance{
+			Module:  m.AST.Name,
+			Name:    obj.Node.Name,
+			OID:     obj.OID,
+			Modules: []models.MIBComplianceModule{},
+		}
+
+		// 子句按出现顺序归属: MODULE 开始一个新模块, OBJECT 之后的 SYNTAX / MIN-ACCESS 属于该对象
+		var module *models.MIBComplianceModule
+		var object *models.MIBComplianceObject
+		for i := range obj.Node.Clauses {
+			c := &obj.Node.Clauses[i]
+			switch c.Keyword {
+			case "STATUS":
+				if module == nil {
+					compliance.Status = c.Text()
+				}
+			case "DESCRIPTION":
+				if module == nil {
+					compliance.Description = cleanMIBDescription(c.Text())
+				}
+			case "MODULE":
+				compliance.Modules = append(compliance.Modules, models.MIBComplianceModule{
+					MandatoryGroups: []string{},
+					Groups:          []string{},
+					Objects:         []models.MIBComplianceObject{},
+				})
+				module = &compliance.Modules[len(compliance.Modules)-1]
+				object = nil
+				if idents := c.Identifiers(); len(idents) > 0 {
+					module.Module = idents[0]
+				}
+			case "MANDATORY-GROUPS":
+				if module != nil {
+					module.MandatoryGroups = append(module.MandatoryGroups, c.Identifiers()...)
+				}
+			case "GROUP":
+				object = nil
+				if idents := c.Identifiers(); module != nil && len(idents) > 0 {
+					module.Groups = append(module.Groups, idents[0])
+				}
+			case "OBJECT":
+				object = nil
+				if idents := c.Identifiers(); module != nil && len(idents) > 0 {
+					module.Objects = append(module.Objects, models.MIBComplianceObject{Name: idents[0]})
+					object = &module.Objects[len(module.Objects)-1]
+				}
+			case "SYNTAX":
+				if object != nil && c.Syntax != nil {
+					object.Syntax = c.Syntax.Text()
+				}
+			case "MIN-ACCESS":
+				if object != nil {
+					object.MinAccess = c.Text()
+				}
+			}
+		}
+		compliances = append(compliances, compliance)
+	}
+	return compliances
+}
+
+// splitMIBSymbol 拆分 "MODULE::name", 没有模块时 module 为空
+func splitMIBSymbol(symbol string) (string, string) {
+	symbol = strings.TrimSpace(symbol)
+	if i := strings.Index(symbol, "::"); i >= 0 {
+		return symbol[:i], symbol[i+2:]
+	}
+	return "", symbol
+}
+
+// GetMetricSets 列出可一次选中的对象组和一致性声明, 支持按名称/描述搜索和按模块过滤
+func (s *MIBService) GetMetricSets(search, module string) ([]MIBMetricSet, error) {
+	filter := func(table string) (string, []interface{}) {
+		where := []string{}
+		var args []interface{}
+		if search != "" {
+			like := "%" + search + "%"
+			where = append(where, fmt.Sprintf("(%[1]s.name LIKE ? OR %[1]s.description LIKE ?)", table))
+			args = append(args, like, like)
+		}
+		if module != "" {
+			where = append(where, table+".module = ?")
+			args = append(args, module)
+		}
+		if len(where) == 0 {
+			return "1 = 1", nil
+		}
+		return strings.Join(where, " AND "), args
+	}
+
+	var groups []models.MIBGroup
+	where, args := filter("mib_groups")
+	err := s.db.Joins("JOIN mibs ON mibs.id = mib_groups.mib_id AND mibs.deleted_at IS NULL").
+		Where("mib_groups.macro = ?", "OBJECT-GROUP").
+		Where(where, args...).
+		Order("mib_groups.module, mib_groups.name").
+		Find(&groups).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var compliances []models.MIBCompliance
+	where, args = filter("mib_compliances")
+	err = s.db.Joins("JOIN mibs ON mibs.id = mib_compliances.mib_id AND mibs.deleted_at IS NULL").
+		Where(where, args...).
+		Order("mib_compliances.module, mib_compliances.name").
+		Find(&compliances).Error
+	if err != nil {
+		return nil, err
+	}
+
+	sets := make([]MIBMetricSet, 0, len(compliances)+len(groups))
+	for i := range compliances {
+		c := &compliances[i]
+		expansion := s.newMetricSetExpansion()
+		s.expandCompliance(expansion, c, false)
+		sets = append(sets, MIBMetricSet{
+			Kind:        "compliance",
+			Symbol:      c.Module + "::" + c.Name,
+			Module:      c.Module,
+			Name:        c.Name,
+			MIBID:       c.MIBID,
+			Status:      c.Status,
+			Description: c.Description,
+			ObjectCount: len(expansion.OIDs),
+		})
+	}
+	for i := range groups {
+		g := &groups[i]
+		expansion := s.newMetricSetExpansion()
+		s.expandGroup(expansion, g, nil)
+		sets = append(sets, MIBMetricSet{
+			Kind:        "group",
+			Symbol:      g.Module + "::" + g.Name,
+			Module:      g.Module,
+			Name:        g.Name,
+			MIBID:       g.MIBID,
+			Status:      g.Status,
+			Description: g.Description,
+			ObjectCount: len(expansion.OIDs),
+		})
+	}
+	return sets, nil
+}
+
+// ExpandMetricSets 将对象组和一致性声明展开为可采集对象的 OID 列表
+func (s *MIBService) ExpandMetricSets(req MIBMetricSetRequest) (*MIBMetricSetExpansion, error) {
+	expansion := s.newMetricSetExpansion()
+	for _, symbol := range req.Groups {
+		module, name := splitMIBSymbol(symbol)
+		group, err := s.findMIBGroup(module, name)
+		if err != nil {
+			return nil, err
+		}
+		if group == nil {
+			expansion.Unresolved = append(expansion.Unresolved, symbol)
+			continue
+		}
+		s.expandGroup(expansion, group, nil)
+	}
+
+	for _, symbol := range req.Compliances {
+		module, name := splitMIBSymbol(symbol)
+		query := s.db.Joins("JOIN mibs ON mibs.id = mib_compliances.mib_id AND mibs.deleted_at IS NULL").
+			Where("mib_compliances.name = ?", name)
+		if module != "" {
+			query = query.Where("mib_compliances.module = ?", module)
+		}
+		var compliances []models.MIBCompliance
+		if err := query.Order("mib_compliances.mib_id DESC").Limit(1).Find(&compliances).Error; err != nil {
+			return nil, err
+		}
+		if len(compliances) == 0 {
+			expansion.Unresolved = append(expansion.Unresolved, symbol)
+			continue
+		}
+		s.expandCompliance(expansion, &compliances[0], req.IncludeOptional)
+	}
+	return expansion.result(), nil
+}
+
+// mibMetricSetBuilder 展开过程中的状态, 同一对象只保留第一次出现
+type mibMetricSetBuilder struct {
+	MIBMetricSetExpansion
+	seen map[string]bool
+}
+
+func (s *MIBService) newMetricSetExpansion() *mibMetricSetBuilder {
+	return &mibMetricSetBuilder{
+		MIBMetricSetExpansion: MIBMetricSetExpansion{
+			OIDs:       []string{},
+			Objects:    []MIBMetricSetObject{},
+			Skipped:    []MIBMetricSetObject{},
+			Unresolved: []string{},
+		},
+		seen: make(map[string]bool),
+	}
+}
+
+func (b *mibMetricSetBuilder) result() *MIBMetricSetExpansion {
+	return &b.MIBMetricSetExpansion
+}
+
+// findMIBGroup 查找对象组, 同名模块存在多个版本时使用最后加载的
+func (s *MIBService) findMIBGroup(module, name string) (*models.MIBGroup, error) {
+	query := s.db.Joins("JOIN mibs ON mibs.id = mib_groups.mib_id AND mibs.deleted_at IS NULL").
+		Where("mib_groups.name = ?", name)
+	if module != "" {
+		query = query.Where("mib_groups.module = ?", module)
+	}
+	var groups []models.MIBGroup
+	if err := query.Order("mib_groups.mib_id DESC").Limit(1).Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, nil
+	}
+	return &groups[0], nil
+}
+
+// expandCompliance 展开一致性声明的必选组, includeOptional 时包括条件性 GROUP;
+// MIN-ACCESS 为 not-accessible 的对象不要求实现, 不加入结果
+func (s *MIBService) expandCompliance(b *mibMetricSetBuilder, c *models.MIBCompliance, includeOptional bool) {
+	for _, cm := range c.Modules {
+		module := cm.Module
+		if module == "" {
+			module = c.Module
+		}
+		excluded := make(map[string]bool)
+		for _, obj := range cm.Objects {
+			if obj.MinAccess == "not-accessible" {
+				excluded[obj.Name] = true
+			}
+		}
+
+		names := cm.MandatoryGroups
+		if includeOptional {
+			names = append(append([]string{}, cm.MandatoryGroups...), cm.Groups...)
+		}
+		for _, name := range names {
+			group, err := s.findMIBGroup(module, name)
+			if err != nil || group == nil {
+				b.Unresolved = append(b.Unresolved, module+"::"+name)
+				continue
+			}
+			s.expandGroup(b, group, excluded)
+		}
+	}
+}
+
+// expandGroup 将对象组的成员加入结果; 通知和不可读的对象记入 Skipped
+func (s *MIBService) expandGroup(b *mibMetricSetBuilder, g *models.MIBGroup, excluded map[string]bool) {
+	symbol := g.Module + "::" + g.Name
+	for _, member := range g.Members {
+		node := s.metricSetNode(g.Module, member)
+		if node == nil {
+			b.Unresolved = append(b.Unresolved, g.Module+"::"+member.Name)
+			continue
+		}
+		obj := MIBMetricSetObject{Name: member.Name, OID: node.OID, Module: node.Module, Group: symbol, Access: node.Access}
+		if b.seen[obj.OID] {
+			continue
+		}
+		b.seen[obj.OID] = true
+
+		switch {
+		case g.Macro == "NOTIFICATION-GROUP":
+			obj.Reason = "notification"
+		case excluded[member.Name]:
+			obj.Reason = "MIN-ACCESS not-accessible in compliance"
+		case obj.Access != "" && !mibPollableAccess[obj.Access]:
+			obj.Reason = "not readable (" + obj.Access + ")"
+		}
+		if obj.Reason != "" {
+			b.Skipped = append(b.Skipped, obj)
+			continue
+		}
+		b.Objects = append(b.Objects, obj)
+		b.OIDs = append(b.OIDs, obj.OID)
+	}
+}
+
+// metricSetNode 在 OID 树中查找组成员; 编译时未解析的跨模块成员按名称查找
+func (s *MIBService) metricSetNode(module string, member models.MIBGroupMember) *OIDTreeNode {
+	if member.OID != "" {
+		if node, err := s.tree.Node(member.OID); err == nil {
+			return node
+		}
+	}
+	if nodes, err := s.tree.Lookup(module + "::" + member.Name); err == nil {
+		return &nodes[0]
+	}
+	if nodes, err := s.tree.Lookup(member.Name); err == nil && len(nodes) == 1 {
+		return &nodes[0]
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// mibTokenKind MIB 词法单元类型
+type mibTokenKind int
+
+const (
+	mibTokEOF mibTokenKind = iota
+	mibTokIdent
+	mibTokNumber
+	mibTokString
+	mibTokBinString
+	mibTokHexString
+	mibTokPunct
+)
+
+// mibToken MIB 词法单元
+type mibToken struct {
+	Kind mibTokenKind
+	Text string
+	Line int
+}
+
+func (t mibToken) is(kind mibTokenKind, text string) bool {
+	return t.Kind == kind && t.Text == text
+}
+
+func (t mibToken) isPunct(text string) bool {
+	return t.is(mibTokPunct, text)
+}
+
+func (t mibToken) isIdent(text string) bool {
+	return t.is(mibTokIdent, text)
+}
+
+// tokenizeMIB 将 ASN.1 / SMI 源文本切分为词法单元
+func tokenizeMIB(src string) ([]mibToken, error) {
+	var tokens []mibToken
+	line := 1
+	i := 0
+	n := len(src)
+
+	for i < n {
+		ch := src[i]
+
+		switch {
+		case ch == '\n':
+			line++
+			i++
+			continue
+		case ch == ' ' || ch == '\t' || ch == '\r' || ch == '\f' || ch == '\v':
+			i++
+			continue
+		}
+
+		// 注释: "--" 到行尾 (大量 MIB 使用 "-----" 分隔线, 不按 ASN.1 的成对 "--" 结束注释)
+		if ch == '-' && i+1 < n && src[i+1] == '-' {
+			for i < n && src[i] != '\n' {
+				i++
+			}
+			continue
+		}
+
+		start := i
+		startLine := line
+
+		switch {
+		case isMIBLetter(ch):
+			i++
+			for i < n {
+				c := src[i]
+				if isMIBLetter(c) || isMIBDigit(c) || c == '_' {
+					i++
+					continue
+				}
+				// 标识符中允许单个连字符, 但 "--" 表示注释开始
+				if c == '-' && i+1 < n && (isMIBLetter(src[i+1]) || isMIBDigit(src[i+1])) {
+					i++
+					continue
+				}
+				break
+			}
+			tokens = append(tokens, mibToken{Kind: mibTokIdent, Text: src[start:i], Line: startLine})
+
+		case isMIBDigit(ch):
+			for i < n && isMIBDigit(src[i]) {
+				i++
+			}
+			tokens = append(tokens, mibToken{Kind: mibTokNumber, Text: src[start:i], Line: startLine})
+
+		case ch == '"':
+			i++
+			var sb strings.Builder
+			closed := false
+			for i < n {
+				c := src[i]
+				if c == '"' {
+					// ASN.1 中 "" 表示字符串内的双引号
+					if i+1 < n && src[i+1] == '"' {
+						sb.WriteByte('"')
+						i += 2
+						continue
+					}
+					i++
+					closed = true
+					break
+				}
+				if c == '\n' {
+					line++
+				}
+				sb.WriteByte(c)
+				i++
+			}
+			if !closed {
+				return nil, fmt.Errorf("line %d: unterminated string literal", startLine)
+			}
+			tokens = append(tokens, mibToken{Kind: mibTokString, Text: sb.String(), Line: startLine})
+
+		case ch == '\'':
+			i++
+			for i < n && src[i] != '\'' {
+				if src[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			if i+1 >= n {
+				return nil, fmt.Errorf("line %d: unterminated binary/hex string", startLine)
+			}
+			body := src[start+1 : i]
+			i++
+			suffix := src[i]
+			i++
+			switch suffix {
+			case 'H', 'h':
+				tokens = append(tokens, mibToken{Kind: mibTokHexString, Text: body, Line: startLine})
+			case 'B', 'b':
+				tokens = append(tokens, mibToken{Kind: mibTokBinString, Text: body, Line: startLine})
+			default:
+				return nil, fmt.Errorf("line %d: invalid quoted literal suffix %q", startLine, suffix)
+			}
+
+		case ch == ':' && strings.HasPrefix(src[i:], "::="):
+			i += 3
+			tokens = append(tokens, mibToken{Kind: mibTokPunct, Text: "::=", Line: startLine})
+
+		case ch == '.' && i+1 < n && src[i+1] == '.':
+			i += 2
+			tokens = append(tokens, mibToken{Kind: mibTokPunct, Text: "..", Line: startLine})
+
+		case strings.ContainsRune("{}()[],;|.-<>", rune(ch)):
+			i++
+			tokens = append(tokens, mibToken{Kind: mibTokPunct, Text: string(ch), Line: startLine})
+
+		default:
+			return nil, fmt.Errorf("line %d: unexpected character %q", startLine, ch)
+		}
+	}
+
+	tokens = append(tokens, mibToken{Kind: mibTokEOF, Line: line})
+	return tokens, nil
+}
+
+func isMIBLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isMIBDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// joinMIBTokens 将词法单元还原为规范化的文本
+func joinMIBTokens(tokens []mibToken) string {
+	var sb strings.Builder
+	var prev *mibToken
+	for idx := range tokens {
+		tok := tokens[idx]
+		if prev != nil && needsMIBSpace(*prev, tok) {
+			sb.WriteByte(' ')
+		}
+		switch tok.Kind {
+		case mibTokString:
+			sb.WriteString(`"` + tok.Text + `"`)
+		case mibTokHexString:
+			sb.WriteString("'" + tok.Text + "'H")
+		case mibTokBinString:
+			sb.WriteString("'" + tok.Text + "'B")
+		default:
+			sb.WriteString(tok.Text)
+		}
+		prev = &tokens[idx]
+	}
+	return sb.String()
+}
+
+func needsMIBSpace(prev, cur mibToken) bool {
+	if prev.isPunct("(") || prev.isPunct("[") || prev.isPunct("..") || prev.isPunct("-") {
+		return false
+	}
+	if cur.isPunct(")") || cur.isPunct("]") || cur.isPunct(",") || cur.isPunct("..") {
+		return false
+	}
+	if cur.isPunct("(") && prev.Kind == mibTokIdent && !isMIBUpperIdent(prev.Text) {
+		// 命名数字: up(1)
+		return false
+	}
+	return true
+}
+
+func isMIBUpperIdent(s string) bool {
+	return s != "" && s[0] >= 'A' && s[0] <= 'Z'
+}
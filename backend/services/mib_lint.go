@@ -0,0 +1,544 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MIBLintSeverity 检查结果的严重级别
+type MIBLintSeverity string
+
+const (
+	MIBLintError   MIBLintSeverity = "error"
+	MIBLintWarning MIBLintSeverity = "warning"
+	MIBLintInfo    MIBLintSeverity = "info"
+	MIBLintOff     MIBLintSeverity = "off" // 关闭该规则
+)
+
+// mibLintRules 检查规则及其默认级别
+var mibLintRules = map[string]MIBLintSeverity{
+	"syntax":                      MIBLintError,   // 无法解析的语法错误
+	"import-undefined":            MIBLintError,   // 导入的符号在来源模块中不存在
+	"import-module-missing":       MIBLintWarning, // 来源模块不在 MIB 库中, 无法检查导入的符号
+	"oid-unresolved":              MIBLintWarning, // OID 父节点无法解析
+	"duplicate-oid":               MIBLintError,   // 多个定义分配了相同的 OID
+	"smi-mixing":                  MIBLintWarning, // SMIv1 模块使用 SMIv2 结构, 或反之
+	"identifier-invalid":          MIBLintError,   // 标识符大小写或字符不合法
+	"identifier-hyphen":           MIBLintWarning, // SMIv2 标识符中包含连字符
+	"identifier-length":           MIBLintWarning, // 标识符超过 64 个字符
+	"gauge-units":                 MIBLintInfo,    // Gauge 类型的对象缺少 UNITS
+	"date-format":                 MIBLintError,   // LAST-UPDATED / REVISION 日期格式错误
+	"revision-order":              MIBLintWarning, // REVISION 不是按时间倒序, 或与 LAST-UPDATED 不一致
+	"notification-not-accessible": MIBLintError,   // 通知中引用了 not-accessible 的对象
+}
+
+var mibLintSeverityRank = map[MIBLintSeverity]int{
+	MIBLintError:   3,
+	MIBLintWarning: 2,
+	MIBLintInfo:    1,
+}
+
+// mibRFC1155Symbols RFC1155-SMI (及其前身 RFC1065-SMI) 导出的符号
+var mibRFC1155Symbols = []string{
+	"OBJECT-TYPE", "ObjectName", "ObjectSyntax", "SimpleSyntax", "ApplicationSyntax", "NetworkAddress",
+	"IpAddress", "Counter", "Gauge", "TimeTicks", "Opaque",
+	"internet", "directory", "mgmt", "experimental", "private", "enterprises",
+}
+
+// mibBaseModuleSymbols SMI 基础模块导出的符号
+var mibBaseModuleSymbols = map[string][]string{
+	"SNMPv2-SMI": {
+		"MODULE-IDENTITY", "OBJECT-IDENTITY", "OBJECT-TYPE", "NOTIFICATION-TYPE",
+		"Integer32", "Unsigned32", "Counter32", "Counter64", "Gauge32", "TimeTicks", "IpAddress", "Opaque",
+		"ObjectName", "NotificationName", "ObjectSyntax", "SimpleSyntax", "ApplicationSyntax", "ExtUTCTime",
+		"org", "dod", "internet", "directory", "mgmt", "mib-2", "transmission", "experimental", "private",
+		"enterprises", "security", "snmpV2", "snmpDomains", "snmpProxys", "snmpModules", "zeroDotZero",
+	},
+	"SNMPv2-TC": {
+		"TEXTUAL-CONVENTION", "DisplayString", "PhysAddress", "MacAddress", "TruthValue", "TestAndIncr",
+		"AutonomousType", "InstancePointer", "VariablePointer", "RowPointer", "RowStatus", "TimeStamp",
+		"TimeInterval", "DateAndTime", "StorageType", "TDomain", "TAddress",
+	},
+	"SNMPv2-CONF": {
+		"MODULE-COMPLIANCE", "OBJECT-GROUP", "NOTIFICATION-GROUP", "AGENT-CAPABILITIES",
+	},
+	"RFC1155-SMI": mibRFC1155Symbols,
+	"RFC1065-SMI": mibRFC1155Symbols,
+	"RFC-1212":    {"OBJECT-TYPE"},
+	"RFC-1215":    {"TRAP-TYPE"},
+}
+
+// mibSMIv1Modules 只在 SMIv1 模块中使用的基础模块
+var mibSMIv1Modules = map[string]bool{
+	"RFC1155-SMI": true,
+	"RFC1065-SMI": true,
+	"RFC-1212":    true,
+	"RFC-1215":    true,
+}
+
+// mibSMIv2Macros 只在 SMIv2 中存在的宏
+var mibSMIv2Macros = map[string]bool{
+	"MODULE-IDENTITY":    true,
+	"OBJECT-IDENTITY":    true,
+	"NOTIFICATION-TYPE":  true,
+	"OBJECT-GROUP":       true,
+	"NOTIFICATION-GROUP": true,
+	"MODULE-COMPLIANCE":  true,
+	"AGENT-CAPABILITIES": true,
+}
+
+// mibSMIv1Types 在 SMIv2 中已被替换的 SMIv1 类型
+var mibSMIv1Types = map[string]string{
+	"Counter":        "Counter32",
+	"Gauge":          "Gauge32",
+	"NetworkAddress": "IpAddress",
+}
+
+var mibParseErrorLine = regexp.MustCompile(`^line (\d+): `)
+
+// MIBLintFinding 一条检查结果
+type MIBLintFinding struct {
+	Module   string          `json:"module,omitempty"`
+	Line     int             `json:"line"`
+	Rule     string          `json:"rule"`
+	Severity MIBLintSeverity `json:"severity"`
+	Symbol   string          `json:"symbol,omitempty"`
+	Message  string          `json:"message"`
+}
+
+// MIBLintReport MIB 检查报告
+type MIBLintReport struct {
+	Valid    bool             `json:"valid"` // 没有 error 级别的结果
+	Modules  []string         `json:"modules"`
+	OIDCount int              `json:"oid_count"`
+	Errors   int              `json:"errors"`
+	Warnings int              `json:"warnings"`
+	Infos    int              `json:"infos"`
+	Findings []MIBLintFinding `json:"findings"`
+}
+
+// MIBLinter 基于语法树的 SMI 合规检查器, 类似 smilint
+type MIBLinter struct {
+	compiler   *MIBCompiler
+	hasModule  func(module string) bool
+	severities map[string]MIBLintSeverity
+	minimum    MIBLintSeverity
+}
+
+// NewMIBLinter 创建检查器; hasModule 判断模块是否在 MIB 库中, 可以为 nil
+func NewMIBLinter(compiler *MIBCompiler, hasModule func(module string) bool) *MIBLinter {
+	severities := make(map[string]MIBLintSeverity, len(mibLintRules))
+	for rule, severity := range mibLintRules {
+		severities[rule] = severity
+	}
+	return &MIBLinter{compiler: compiler, hasModule: hasModule, severities: severities, minimum: MIBLintInfo}
+}
+
+// MIBLintRules 返回所有规则及其默认级别
+func MIBLintRules() map[string]MIBLintSeverity {
+	rules := make(map[string]MIBLintSeverity, len(mibLintRules))
+	for rule, severity := range mibLintRules {
+		rules[rule] = severity
+	}
+	return rules
+}
+
+// ParseMIBLintSeverity 校验级别名称
+func ParseMIBLintSeverity(value string) (MIBLintSeverity, error) {
+	severity := MIBLintSeverity(strings.ToLower(strings.TrimSpace(value)))
+	if _, ok := mibLintSeverityRank[severity]; !ok && severity != MIBLintOff {
+		return "", fmt.Errorf("invalid severity %q, expected error, warning, info or off", value)
+	}
+	return severity, nil
+}
+
+// SetSeverity 调整规则的级别
+func (l *MIBLinter) SetSeverity(rule string, severity MIBLintSeverity) error {
+	if _, ok := mibLintRules[rule]; !ok {
+		return fmt.Errorf("unknown lint rule %q", rule)
+	}
+	if _, err := ParseMIBLintSeverity(string(severity)); err != nil {
+		return err
+	}
+	l.severities[rule] = severity
+	return nil
+}
+
+// SetMinimumSeverity 只报告不低于该级别的结果
+func (l *MIBLinter) SetMinimumSeverity(severity MIBLintSeverity) error {
+	if _, ok := mibLintSeverityRank[severity]; !ok {
+		return fmt.Errorf("invalid minimum severity %q, expected error, warning or info", severity)
+	}
+	l.minimum = severity
+	return nil
+}
+
+// Lint 检查 MIB 源文本
+func (l *MIBLinter) Lint(src string) *MIBLintReport {
+	report := &MIBLintReport{Modules: []string{}, Findings: []MIBLintFinding{}}
+
+	modules, err := l.compiler.CompileSource(src)
+	if err != nil {
+		line := 0
+		msg := err.Error()
+		if m := mibParseErrorLine.FindStringSubmatch(msg); m != nil {
+			line, _ = strconv.Atoi(m[1])
+			msg = strings.TrimPrefix(msg, m[0])
+		}
+		l.add(report, MIBLintFinding{Line: line, Rule: "syntax", Message: msg})
+		l.finish(report)
+		return report
+	}
+
+	local := make(map[string]*CompiledMIBModule)
+	for _, module := range modules {
+		local[module.AST.Name] = module
+	}
+	for _, module := range modules {
+		report.Modules = append(report.Modules, module.AST.Name)
+		for _, node := range module.AST.Nodes {
+			if node.Macro == "OBJECT-TYPE" {
+				report.OIDCount++
+			}
+		}
+
+		ctx := &mibLintModule{linter: l, report: report, module: module, local: local, smiv2: isSMIv2Module(module.AST)}
+		ctx.checkImports()
+		ctx.checkUnresolved()
+		ctx.checkDuplicateOIDs()
+		ctx.checkSMIMixing()
+		ctx.checkIdentifiers()
+		ctx.checkGaugeUnits()
+		ctx.checkRevisions()
+		ctx.checkNotificationObjects()
+	}
+
+	l.finish(report)
+	return report
+}
+
+func (l *MIBLinter) add(report *MIBLintReport, finding MIBLintFinding) {
+	severity := l.severities[finding.Rule]
+	if severity == MIBLintOff || mibLintSeverityRank[severity] < mibLintSeverityRank[l.minimum] {
+		return
+	}
+	finding.Severity = severity
+	report.Findings = append(report.Findings, finding)
+}
+
+func (l *MIBLinter) finish(report *MIBLintReport) {
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		return report.Findings[i].Line < report.Findings[j].Line
+	})
+	for _, f := range report.Findings {
+		switch f.Severity {
+		case MIBLintError:
+			report.Errors++
+		case MIBLintWarning:
+			report.Warnings++
+		default:
+			report.Infos++
+		}
+	}
+	report.Valid = report.Errors == 0
+}
+
+// isSMIv2Module 有 MODULE-IDENTITY 或从 SNMPv2-SMI 导入的模块按 SMIv2 处理
+func isSMIv2Module(ast *MIBModuleAST) bool {
+	for _, node := range ast.Nodes {
+		if node.Macro == "MODULE-IDENTITY" {
+			return true
+		}
+	}
+	for _, imp := range ast.Imports {
+		if imp.Module == "SNMPv2-SMI" {
+			return true
+		}
+	}
+	return false
+}
+
+// mibLintModule 单个模块的检查上下文
+type mibLintModule struct {
+	linter *MIBLinter
+	report *MIBLintReport
+	module *CompiledMIBModule
+	local  map[string]*CompiledMIBModule
+	smiv2  bool
+}
+
+func (m *mibLintModule) addf(line int, rule, symbol, format string, args ...interface{}) {
+	m.linter.add(m.report, MIBLintFinding{
+		Module:  m.module.AST.Name,
+		Line:    line,
+		Rule:    rule,
+		Symbol:  symbol,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (m *mibLintModule) checkImports() {
+	for _, imp := range m.module.AST.Imports {
+		if base, ok := mibBaseModuleSymbols[imp.Module]; ok {
+			for _, symbol := range imp.Symbols {
+				if !containsString(base, symbol) {
+					m.addf(imp.Line, "import-undefined", symbol, "%s is not defined in %s", symbol, imp.Module)
+				}
+			}
+			continue
+		}
+
+		compiled, isLocal := m.local[imp.Module]
+		if !isLocal && (m.linter.hasModule == nil || !m.linter.hasModule(imp.Module)) {
+			m.addf(imp.Line, "import-module-missing", imp.Module, "module %s is not in the MIB library, imports cannot be checked", imp.Module)
+			continue
+		}
+		for _, symbol := range imp.Symbols {
+			var found bool
+			if isLocal {
+				found = compiled.Object(symbol) != nil || compiled.Type(symbol) != nil
+			} else {
+				_, found = m.linter.compiler.lookupImport(imp.Module, symbol, m.local)
+				if !found {
+					_, found = m.linter.compiler.lookupType(imp.Module, symbol, m.local)
+				}
+			}
+			if !found {
+				m.addf(imp.Line, "import-undefined", symbol, "%s is not defined in %s", symbol, imp.Module)
+			}
+		}
+	}
+}
+
+func (m *mibLintModule) checkUnresolved() {
+	for _, name := range m.module.Unresolved {
+		line := 0
+		if node := m.node(name); node != nil {
+			line = node.Line
+		}
+		m.addf(line, "oid-unresolved", name, "OID of %s cannot be resolved", name)
+	}
+}
+
+func (m *mibLintModule) checkDuplicateOIDs() {
+	first := make(map[string]*CompiledMIBObject)
+	for _, obj := range m.module.Objects {
+		if prev, ok := first[obj.OID]; ok {
+			m.addf(obj.Node.Line, "duplicate-oid", obj.Node.Name, "%s has the same OID %s as %s (line %d)", obj.Node.Name, obj.OID, prev.Node.Name, prev.Node.Line)
+			continue
+		}
+		first[obj.OID] = obj
+	}
+}
+
+func (m *mibLintModule) checkSMIMixing() {
+	ast := m.module.AST
+	if !m.smiv2 {
+		for _, node := range ast.Nodes {
+			if mibSMIv2Macros[node.Macro] {
+				m.addf(node.Line, "smi-mixing", node.Name, "SMIv1 module uses SMIv2 macro %s", node.Macro)
+			}
+			if node.Macro == "OBJECT-TYPE" {
+				if c := node.Clause("MAX-ACCESS"); c != nil {
+					m.addf(c.Line, "smi-mixing", node.Name, "SMIv1 module uses MAX-ACCESS, expected ACCESS")
+				}
+			}
+		}
+		for _, def := range ast.Types {
+			if def.IsTC {
+				m.addf(def.Line, "smi-mixing", def.Name, "SMIv1 module uses TEXTUAL-CONVENTION")
+			}
+		}
+		return
+	}
+
+	for _, imp := range ast.Imports {
+		if mibSMIv1Modules[imp.Module] {
+			m.addf(imp.Line, "smi-mixing", imp.Module, "SMIv2 module imports from SMIv1 module %s", imp.Module)
+		}
+	}
+	for _, node := range ast.Nodes {
+		switch node.Macro {
+		case "TRAP-TYPE":
+			m.addf(node.Line, "smi-mixing", node.Name, "SMIv2 module uses TRAP-TYPE, expected NOTIFICATION-TYPE")
+		case "OBJECT-TYPE":
+			if c := node.Clause("ACCESS"); c != nil {
+				m.addf(c.Line, "smi-mixing", node.Name, "SMIv2 module uses ACCESS, expected MAX-ACCESS")
+			}
+			if c := node.Clause("SYNTAX"); c != nil {
+				if v2, ok := mibSMIv1Types[c.Syntax.Type]; ok {
+					m.addf(c.Line, "smi-mixing", node.Name, "SMIv2 module uses SMIv1 type %s, expected %s", c.Syntax.Type, v2)
+				}
+			}
+		}
+	}
+}
+
+func (m *mibLintModule) checkIdentifiers() {
+	ast := m.module.AST
+	m.checkIdentifier(ast.Line, ast.Name, true, false)
+	for _, def := range ast.Types {
+		m.checkIdentifier(def.Line, def.Name, true, m.smiv2)
+	}
+	for _, node := range ast.Nodes {
+		m.checkIdentifier(node.Line, node.Name, false, m.smiv2)
+	}
+}
+
+// checkIdentifier 按 RFC 2578 3.1 检查标识符; 模块和类型名以大写字母开头, 其他以小写字母开头
+func (m *mibLintModule) checkIdentifier(line int, name string, upper, noHyphen bool) {
+	if name == "" {
+		return
+	}
+	switch {
+	case upper && !(name[0] >= 'A' && name[0] <= 'Z'):
+		m.addf(line, "identifier-invalid", name, "%s must start with an uppercase letter", name)
+	case !upper && !(name[0] >= 'a' && name[0] <= 'z'):
+		m.addf(line, "identifier-invalid", name, "%s must start with a lowercase letter", name)
+	}
+	if strings.Contains(name, "_") {
+		m.addf(line, "identifier-invalid", name, "%s must not contain underscores", name)
+	}
+	if strings.HasSuffix(name, "-") {
+		m.addf(line, "identifier-invalid", name, "%s must not end with a hyphen", name)
+	}
+	if noHyphen && strings.Contains(name, "-") {
+		m.addf(line, "identifier-hyphen", name, "SMIv2 identifier %s should not contain hyphens", name)
+	}
+	if len(name) > 64 {
+		m.addf(line, "identifier-length", name, "%s is longer than 64 characters", name)
+	}
+}
+
+func (m *mibLintModule) checkGaugeUnits() {
+	// SMIv1 没有 UNITS 子句
+	if !m.smiv2 {
+		return
+	}
+	for _, obj := range m.module.Objects {
+		if obj.Type == nil || obj.Node.Clause("UNITS") != nil {
+			continue
+		}
+		if obj.Type.BaseType == "Gauge32" || obj.Type.BaseType == "Gauge" {
+			m.addf(obj.Node.Line, "gauge-units", obj.Node.Name, "gauge %s has no UNITS clause", obj.Node.Name)
+		}
+	}
+}
+
+func (m *mibLintModule) checkRevisions() {
+	for _, node := range m.module.AST.Nodes {
+		if node.Macro != "MODULE-IDENTITY" {
+			continue
+		}
+
+		var lastUpdated time.Time
+		if c := node.Clause("LAST-UPDATED"); c != nil {
+			t, err := parseMIBDate(c.Text())
+			if err != nil {
+				m.addf(c.Line, "date-format", node.Name, "LAST-UPDATED %s", err)
+			}
+			lastUpdated = t
+		}
+
+		var prev time.Time
+		var newest time.Time
+		for _, c := range node.Clauses {
+			if c.Keyword != "REVISION" {
+				continue
+			}
+			t, err := parseMIBDate(c.Text())
+			if err != nil {
+				m.addf(c.Line, "date-format", node.Name, "REVISION %s", err)
+				continue
+			}
+			if newest.IsZero() {
+				newest = t
+			}
+			if !prev.IsZero() && !t.Before(prev) {
+				m.addf(c.Line, "revision-order", node.Name, "REVISION %q is not older than the preceding revision", c.Text())
+			}
+			prev = t
+		}
+
+		if !newest.IsZero() && !lastUpdated.IsZero() && !newest.Equal(lastUpdated) {
+			m.addf(node.Clause("LAST-UPDATED").Line, "revision-order", node.Name, "LAST-UPDATED does not match the most recent REVISION")
+		}
+	}
+}
+
+// parseMIBDate 解析 ExtUTCTime, 格式为 "YYMMDDHHMMZ" 或 "YYYYMMDDHHMMZ"
+func parseMIBDate(value string) (time.Time, error) {
+	switch len(value) {
+	case 11:
+		value = "19" + value
+	case 13:
+	default:
+		return time.Time{}, fmt.Errorf("%q is not in YYYYMMDDHHMMZ format", value)
+	}
+	t, err := time.Parse("200601021504Z", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a valid date", value)
+	}
+	return t, nil
+}
+
+func (m *mibLintModule) checkNotificationObjects() {
+	importedFrom := make(map[string]string)
+	for _, imp := range m.module.AST.Imports {
+		for _, symbol := range imp.Symbols {
+			importedFrom[symbol] = imp.Module
+		}
+	}
+
+	for _, node := range m.module.AST.Nodes {
+		var clause *MIBClause
+		switch node.Macro {
+		case "NOTIFICATION-TYPE":
+			clause = node.Clause("OBJECTS")
+		case "TRAP-TYPE":
+			clause = node.Clause("VARIABLES")
+		}
+		if clause == nil {
+			continue
+		}
+
+		for _, name := range clause.Identifiers() {
+			var access string
+			if target := m.node(name); target != nil {
+				access = target.ClauseText("MAX-ACCESS")
+				if access == "" {
+					access = target.ClauseText("ACCESS")
+				}
+			} else if module, ok := importedFrom[name]; ok {
+				if oid, ok := m.linter.compiler.lookupImport(module, name, m.local); ok {
+					access = oid.Access
+				}
+			}
+			if access == "not-accessible" {
+				m.addf(clause.Line, "notification-not-accessible", name, "%s is not-accessible and cannot be used in %s", name, node.Name)
+			}
+		}
+	}
+}
+
+func (m *mibLintModule) node(name string) *MIBNode {
+	for _, node := range m.module.AST.Nodes {
+		if node.Name == name {
+			return node
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
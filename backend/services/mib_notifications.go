@@ -0,0 +1,144 @@
+package services
+
+import (
+	"strconv"
+	"strings"
+
+	"mib-platform/models"
+)
+
+// buildNotifications 解析 NOTIFICATION-TYPE 的 OBJECTS 和 TRAP-TYPE 的 VARIABLES
+func (c *MIBCompiler) buildNotifications(m *CompiledMIBModule, importedFrom map[string]string, local map[string]*CompiledMIBModule) {
+	for _, obj := range m.Objects {
+		var clause *MIBClause
+		switch obj.Node.Macro {
+		case "NOTIFICATION-TYPE":
+			clause = obj.Node.Clause("OBJECTS")
+		case "TRAP-TYPE":
+			clause = obj.Node.Clause("VARIABLES")
+		default:
+			continue
+		}
+
+		obj.Varbinds = []models.NotificationObject{}
+		if clause == nil {
+			continue
+		}
+		for _, name := range clause.Identifiers() {
+			varbind := models.NotificationObject{Name: name}
+			if target := m.Object(name); target != nil {
+				varbind.OID = target.OID
+			} else if module, ok := importedFrom[name]; ok {
+				if target, ok := c.lookupImport(module, name, local); ok {
+					varbind.OID = target.OID
+				}
+			}
+			obj.Varbinds = append(obj.Varbinds, varbind)
+		}
+	}
+}
+
+// ModelNotifications 将模块中的通知转换为 models.MIBNotification
+func (m *CompiledMIBModule) ModelNotifications() []models.MIBNotification {
+	var notifications []models.MIBNotification
+	for _, obj := range m.Objects {
+		if obj.Varbinds == nil {
+			continue
+		}
+		enterprise, specific := notificationV1Mapping(obj.OID)
+		notifications = append(notifications, models.MIBNotification{
+			Module:       m.AST.Name,
+			Name:         obj.Node.Name,
+			OID:          obj.OID,
+			Macro:        obj.Node.Macro,
+			Status:       obj.Node.ClauseText("STATUS"),
+			Description:  cleanMIBDescription(obj.Node.ClauseText("DESCRIPTION")),
+			Objects:      obj.Varbinds,
+			Enterprise:   enterprise,
+			SpecificTrap: specific,
+		})
+	}
+	return notifications
+}
+
+// notificationV1Mapping 按 RFC 3584 将通知 OID 映射为 SNMPv1 的 enterprise 和 specific-trap:
+// 倒数第二个分量为 0 时 enterprise 为其之前的部分, 否则为父 OID
+func notificationV1Mapping(oid string) (string, uint32) {
+	arcs := strings.Split(oid, ".")
+	if len(arcs) < 2 {
+		return "", 0
+	}
+
+	specific, _ := strconv.ParseUint(arcs[len(arcs)-1], 10, 32)
+	if len(arcs) > 2 && arcs[len(arcs)-2] == "0" {
+		return strings.Join(arcs[:len(arcs)-2], "."), uint32(specific)
+	}
+	return strings.Join(arcs[:len(arcs)-1], "."), uint32(specific)
+}
+
+// GetNotifications 分页列出通知目录, 支持按名称/描述/OID 搜索和按模块过滤
+func (s *MIBService) GetNotifications(page, limit int, search, module string) ([]models.MIBNotification, int64, error) {
+	var notifications []models.MIBNotification
+	var total int64
+
+	query := s.db.Model(&models.MIBNotification{}).
+		Joins("JOIN mibs ON mibs.id = mib_notifications.mib_id AND mibs.deleted_at IS NULL")
+
+	if search != "" {
+		like := "%" + search + "%"
+		query = query.Where("mib_notifications.name LIKE ? OR mib_notifications.description LIKE ? OR mib_notifications.o_id LIKE ?", like, like, search+"%")
+	}
+
+	if module != "" {
+		query = query.Where("mib_notifications.module = ?", module)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	if err := query.Order("mib_notifications.module, mib_notifications.name").Offset(offset).Limit(limit).Find(&notifications).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return notifications, total, nil
+}
+
+// SearchNotifications 按通知 OID 或 SNMPv1 的 enterprise + specific-trap 查找通知
+func (s *MIBService) SearchNotifications(oid, enterprise string, specific *uint32) ([]models.MIBNotification, error) {
+	notifications := []models.MIBNotification{}
+	query := s.db.Joins("JOIN mibs ON mibs.id = mib_notifications.mib_id AND mibs.deleted_at IS NULL")
+
+	switch {
+	case oid != "":
+		normalized, err := normalizeOID(oid)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("mib_notifications.o_id = ?", normalized)
+	case enterprise != "":
+		normalized, err := normalizeOID(enterprise)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("mib_notifications.enterprise = ?", normalized)
+		if specific != nil {
+			query = query.Where("mib_notifications.specific_trap = ?", *specific)
+		}
+	}
+
+	if err := query.Order("mib_notifications.mib_id DESC").Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// GetMIBNotifications 返回单个 MIB 中定义的通知
+func (s *MIBService) GetMIBNotifications(id uint) ([]models.MIBNotification, error) {
+	var notifications []models.MIBNotification
+	if err := s.db.Where("mib_id = ?", id).Order("o_id").Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
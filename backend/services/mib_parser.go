@@ -0,0 +1,812 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"mib-platform/models"
+)
+
+// MIBModuleAST 单个 MIB 模块的语法树
+type MIBModuleAST struct {
+	Name    string        `json:"name"`
+	Line    int           `json:"line"`
+	Imports []MIBImport   `json:"imports"`
+	Nodes   []*MIBNode    `json:"nodes"`
+	Types   []*MIBTypeDef `json:"types"`
+}
+
+// MIBImport IMPORTS 子句中来自同一模块的一组符号
+type MIBImport struct {
+	Module  string   `json:"module"`
+	Symbols []string `json:"symbols"`
+	Line    int      `json:"line"`
+}
+
+// MIBNode 带 OID 值的定义 (OBJECT-TYPE, OBJECT IDENTIFIER 等)
+type MIBNode struct {
+	Name    string            `json:"name"`
+	Macro   string            `json:"macro"`
+	Clauses []MIBClause       `json:"clauses"`
+	Value   []MIBOIDComponent `json:"value"`
+	Line    int               `json:"line"`
+}
+
+// MIBClause 宏定义中的一个子句, 如 SYNTAX / MAX-ACCESS / DESCRIPTION
+type MIBClause struct {
+	Keyword string     `json:"keyword"`
+	Syntax  *MIBSyntax `json:"syntax,omitempty"`
+	Line    int        `json:"line"`
+	tokens  []mibToken
+}
+
+// MIBOIDComponent OID 值中的一个分量, 如 "ifEntry"、"8" 或 "org(3)"
+type MIBOIDComponent struct {
+	Name      string `json:"name,omitempty"`
+	Number    uint32 `json:"number"`
+	HasNumber bool   `json:"has_number"`
+}
+
+// MIBTypeDef 类型赋值, 包括 TEXTUAL-CONVENTION
+type MIBTypeDef struct {
+	Name    string      `json:"name"`
+	IsTC    bool        `json:"is_tc"`
+	Clauses []MIBClause `json:"clauses"`
+	Syntax  *MIBSyntax  `json:"syntax"`
+	Line    int         `json:"line"`
+}
+
+// MIBSyntax 类型表达式
+type MIBSyntax struct {
+	Type         string             `json:"type"`
+	SequenceOf   string             `json:"sequence_of,omitempty"`
+	Fields       []MIBSequenceField `json:"fields,omitempty"`
+	NamedNumbers []models.OIDEnum   `json:"named_numbers,omitempty"` // INTEGER 枚举或 BITS 命名位
+	Ranges       []models.OIDRange  `json:"ranges,omitempty"`
+	Sizes        []models.OIDRange  `json:"sizes,omitempty"`
+	Line         int                `json:"line"`
+	tokens       []mibToken
+}
+
+// MIBSequenceField SEQUENCE { ... } 中的一列
+type MIBSequenceField struct {
+	Name   string     `json:"name"`
+	Syntax *MIBSyntax `json:"syntax"`
+}
+
+// mibMacros 产生 OID 值的 SMI 宏
+var mibMacros = map[string]bool{
+	"OBJECT-TYPE":        true,
+	"OBJECT-IDENTITY":    true,
+	"MODULE-IDENTITY":    true,
+	"NOTIFICATION-TYPE":  true,
+	"TRAP-TYPE":          true,
+	"OBJECT-GROUP":       true,
+	"NOTIFICATION-GROUP": true,
+	"MODULE-COMPLIANCE":  true,
+	"AGENT-CAPABILITIES": true,
+}
+
+// mibClauseKeywords 宏子句关键字
+var mibClauseKeywords = map[string]bool{
+	"SYNTAX":            true,
+	"WRITE-SYNTAX":      true,
+	"UNITS":             true,
+	"MAX-ACCESS":        true,
+	"ACCESS":            true,
+	"MIN-ACCESS":        true,
+	"STATUS":            true,
+	"DESCRIPTION":       true,
+	"REFERENCE":         true,
+	"INDEX":             true,
+	"AUGMENTS":          true,
+	"DEFVAL":            true,
+	"OBJECTS":           true,
+	"NOTIFICATIONS":     true,
+	"LAST-UPDATED":      true,
+	"ORGANIZATION":      true,
+	"CONTACT-INFO":      true,
+	"REVISION":          true,
+	"ENTERPRISE":        true,
+	"VARIABLES":         true,
+	"MODULE":            true,
+	"MANDATORY-GROUPS":  true,
+	"GROUP":             true,
+	"OBJECT":            true,
+	"DISPLAY-HINT":      true,
+	"PRODUCT-RELEASE":   true,
+	"SUPPORTS":          true,
+	"INCLUDES":          true,
+	"VARIATION":         true,
+	"CREATION-REQUIRES": true,
+}
+
+// Clause 返回第一个匹配关键字的子句
+func (n *MIBNode) Clause(keyword string) *MIBClause {
+	for i := range n.Clauses {
+		if n.Clauses[i].Keyword == keyword {
+			return &n.Clauses[i]
+		}
+	}
+	return nil
+}
+
+// ClauseText 返回子句的规范化文本, 不存在时返回空字符串
+func (n *MIBNode) ClauseText(keyword string) string {
+	if c := n.Clause(keyword); c != nil {
+		return c.Text()
+	}
+	return ""
+}
+
+// Clause 返回第一个匹配关键字的子句
+func (t *MIBTypeDef) Clause(keyword string) *MIBClause {
+	for i := range t.Clauses {
+		if t.Clauses[i].Keyword == keyword {
+			return &t.Clauses[i]
+		}
+	}
+	return nil
+}
+
+// Text 子句值的规范化文本; 字符串值返回其内容
+func (c *MIBClause) Text() string {
+	if len(c.tokens) == 1 && c.tokens[0].Kind == mibTokString {
+		return c.tokens[0].Text
+	}
+	return joinMIBTokens(c.tokens)
+}
+
+// Identifiers 子句值中出现的所有标识符 (如 INDEX { ifIndex } 或 OBJECTS { a, b })
+func (c *MIBClause) Identifiers() []string {
+	var idents []string
+	for _, tok := range c.tokens {
+		if tok.Kind == mibTokIdent {
+			idents = append(idents, tok.Text)
+		}
+	}
+	return idents
+}
+
+// Text 类型表达式的规范化文本
+func (s *MIBSyntax) Text() string {
+	return joinMIBTokens(s.tokens)
+}
+
+// ParseMIBSource 解析 MIB 源文本, 一个文件可以包含多个模块
+func ParseMIBSource(src string) ([]*MIBModuleAST, error) {
+	tokens, err := tokenizeMIB(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &mibParser{tokens: tokens}
+	var modules []*MIBModuleAST
+	for p.peek().Kind != mibTokEOF {
+		module, err := p.parseModule()
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, module)
+	}
+
+	if len(modules) == 0 {
+		return nil, fmt.Errorf("no MIB module definition found")
+	}
+	return modules, nil
+}
+
+type mibParser struct {
+	tokens []mibToken
+	pos    int
+}
+
+func (p *mibParser) peek() mibToken {
+	return p.peekAt(0)
+}
+
+func (p *mibParser) peekAt(offset int) mibToken {
+	if p.pos+offset >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *mibParser) next() mibToken {
+	tok := p.peek()
+	if tok.Kind != mibTokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *mibParser) errorf(tok mibToken, format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", tok.Line, fmt.Sprintf(format, args...))
+}
+
+func describeMIBToken(tok mibToken) string {
+	if tok.Kind == mibTokEOF {
+		return "end of file"
+	}
+	return fmt.Sprintf("%q", tok.Text)
+}
+
+func (p *mibParser) expectPunct(text string) (mibToken, error) {
+	tok := p.next()
+	if !tok.isPunct(text) {
+		return tok, p.errorf(tok, "expected %q, found %s", text, describeMIBToken(tok))
+	}
+	return tok, nil
+}
+
+func (p *mibParser) expectKeyword(text string) (mibToken, error) {
+	tok := p.next()
+	if !tok.isIdent(text) {
+		return tok, p.errorf(tok, "expected %s, found %s", text, describeMIBToken(tok))
+	}
+	return tok, nil
+}
+
+func (p *mibParser) expectIdent() (mibToken, error) {
+	tok := p.next()
+	if tok.Kind != mibTokIdent {
+		return tok, p.errorf(tok, "expected identifier, found %s", describeMIBToken(tok))
+	}
+	return tok, nil
+}
+
+// skipGroup 跳过一个成对的括号组, 返回组内 (含括号) 的词法单元
+func (p *mibParser) skipGroup() ([]mibToken, error) {
+	open := p.next()
+	var closeText string
+	switch open.Text {
+	case "{":
+		closeText = "}"
+	case "(":
+		closeText = ")"
+	case "[":
+		closeText = "]"
+	default:
+		return nil, p.errorf(open, "expected bracket, found %s", describeMIBToken(open))
+	}
+
+	start := p.pos - 1
+	depth := 1
+	for depth > 0 {
+		tok := p.next()
+		switch {
+		case tok.Kind == mibTokEOF:
+			return nil, p.errorf(open, "unbalanced %q", open.Text)
+		case tok.isPunct(open.Text):
+			depth++
+		case tok.isPunct(closeText):
+			depth--
+		}
+	}
+	return p.tokens[start:p.pos], nil
+}
+
+func (p *mibParser) parseModule() (*MIBModuleAST, error) {
+	nameTok, err := p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+	module := &MIBModuleAST{Name: nameTok.Text, Line: nameTok.Line}
+
+	if p.peek().isPunct("{") {
+		if _, err := p.skipGroup(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.expectKeyword("DEFINITIONS"); err != nil {
+		return nil, err
+	}
+	// 可选的 tag default, 如 "IMPLICIT TAGS"
+	for p.peek().Kind == mibTokIdent {
+		p.next()
+	}
+	if _, err := p.expectPunct("::="); err != nil {
+		return nil, err
+	}
+	if _, err := p.expectKeyword("BEGIN"); err != nil {
+		return nil, err
+	}
+
+	for {
+		tok := p.peek()
+		switch {
+		case tok.Kind == mibTokEOF:
+			return nil, p.errorf(tok, "module %s: missing END", module.Name)
+		case tok.isIdent("END"):
+			p.next()
+			return module, nil
+		case tok.isIdent("EXPORTS"):
+			for !p.peek().isPunct(";") {
+				if p.next().Kind == mibTokEOF {
+					return nil, p.errorf(tok, "unterminated EXPORTS")
+				}
+			}
+			p.next()
+		case tok.isIdent("IMPORTS"):
+			p.next()
+			imports, err := p.parseImports()
+			if err != nil {
+				return nil, err
+			}
+			module.Imports = append(module.Imports, imports...)
+		default:
+			if err := p.parseAssignment(module); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (p *mibParser) parseImports() ([]MIBImport, error) {
+	var imports []MIBImport
+	var symbols []string
+	line := p.peek().Line
+
+	for {
+		tok := p.next()
+		switch {
+		case tok.isPunct(";"):
+			if len(symbols) > 0 {
+				return nil, p.errorf(tok, "IMPORTS: symbols %s without FROM", strings.Join(symbols, ", "))
+			}
+			return imports, nil
+		case tok.isPunct(","):
+			continue
+		case tok.isIdent("FROM"):
+			moduleTok, err := p.expectIdent()
+			if err != nil {
+				return nil, err
+			}
+			imports = append(imports, MIBImport{Module: moduleTok.Text, Symbols: symbols, Line: line})
+			symbols = nil
+			if p.peek().isPunct("{") {
+				if _, err := p.skipGroup(); err != nil {
+					return nil, err
+				}
+			}
+			line = p.peek().Line
+		case tok.Kind == mibTokIdent:
+			symbols = append(symbols, tok.Text)
+		default:
+			return nil, p.errorf(tok, "unexpected %s in IMPORTS", describeMIBToken(tok))
+		}
+	}
+}
+
+func (p *mibParser) parseAssignment(module *MIBModuleAST) error {
+	nameTok, err := p.expectIdent()
+	if err != nil {
+		return err
+	}
+
+	// 宏定义 (如 SNMPv2-SMI 中的 "OBJECT-TYPE MACRO ::= BEGIN ... END"), 整体跳过
+	if p.peek().isIdent("MACRO") {
+		for !p.peek().isIdent("END") {
+			if p.next().Kind == mibTokEOF {
+				return p.errorf(nameTok, "unterminated MACRO %s", nameTok.Text)
+			}
+		}
+		p.next()
+		return nil
+	}
+
+	// 类型赋值
+	if p.peek().isPunct("::=") {
+		p.next()
+		typeDef := &MIBTypeDef{Name: nameTok.Text, Line: nameTok.Line}
+		if p.peek().isIdent("TEXTUAL-CONVENTION") {
+			p.next()
+			typeDef.IsTC = true
+			clauses, syntax, err := p.parseTCClauses()
+			if err != nil {
+				return err
+			}
+			typeDef.Clauses = clauses
+			typeDef.Syntax = syntax
+		} else {
+			syntax, err := p.parseSyntax()
+			if err != nil {
+				return err
+			}
+			typeDef.Syntax = syntax
+		}
+		module.Types = append(module.Types, typeDef)
+		return nil
+	}
+
+	node := &MIBNode{Name: nameTok.Text, Line: nameTok.Line}
+	tok := p.peek()
+	switch {
+	case tok.isIdent("OBJECT") && p.peekAt(1).isIdent("IDENTIFIER"):
+		p.pos += 2
+		node.Macro = "OBJECT IDENTIFIER"
+	case tok.Kind == mibTokIdent && mibMacros[tok.Text]:
+		p.next()
+		node.Macro = tok.Text
+		clauses, err := p.parseClauses()
+		if err != nil {
+			return err
+		}
+		node.Clauses = clauses
+	default:
+		// 其他类型的值赋值 (如 "x INTEGER ::= 5"), 不产生 OID 节点
+		if _, err := p.parseSyntax(); err != nil {
+			return err
+		}
+		if _, err := p.expectPunct("::="); err != nil {
+			return err
+		}
+		if p.peek().isPunct("{") {
+			_, err = p.skipGroup()
+			return err
+		}
+		p.next()
+		return nil
+	}
+
+	if _, err := p.expectPunct("::="); err != nil {
+		return err
+	}
+
+	if node.Macro == "TRAP-TYPE" {
+		numTok := p.next()
+		if numTok.Kind != mibTokNumber {
+			return p.errorf(numTok, "%s: expected trap number, found %s", node.Name, describeMIBToken(numTok))
+		}
+		num, err := strconv.ParseUint(numTok.Text, 10, 32)
+		if err != nil {
+			return p.errorf(numTok, "%s: invalid trap number %s", node.Name, numTok.Text)
+		}
+		node.Value = []MIBOIDComponent{{Number: uint32(num), HasNumber: true}}
+	} else {
+		value, err := p.parseOIDValue()
+		if err != nil {
+			return err
+		}
+		node.Value = value
+	}
+
+	module.Nodes = append(module.Nodes, node)
+	return nil
+}
+
+func (p *mibParser) isClauseStart(offset int) bool {
+	tok := p.peekAt(offset)
+	if tok.Kind != mibTokIdent || !mibClauseKeywords[tok.Text] {
+		return false
+	}
+	// "OBJECT IDENTIFIER" 是类型而不是 MODULE-COMPLIANCE 中的 OBJECT 子句
+	if tok.Text == "OBJECT" && p.peekAt(offset+1).isIdent("IDENTIFIER") {
+		return false
+	}
+	return true
+}
+
+// parseClause 解析一个子句; 子句值一直延伸到下一个关键字或 "::="
+func (p *mibParser) parseClause() (MIBClause, error) {
+	kwTok := p.next()
+	if kwTok.Kind != mibTokIdent || !mibClauseKeywords[kwTok.Text] {
+		return MIBClause{}, p.errorf(kwTok, "unexpected %s, expected a clause keyword", describeMIBToken(kwTok))
+	}
+	clause := MIBClause{Keyword: kwTok.Text, Line: kwTok.Line}
+
+	if kwTok.Text == "SYNTAX" || kwTok.Text == "WRITE-SYNTAX" {
+		syntax, err := p.parseSyntax()
+		if err != nil {
+			return clause, err
+		}
+		clause.Syntax = syntax
+		clause.tokens = syntax.tokens
+		return clause, nil
+	}
+
+	start := p.pos
+	for {
+		tok := p.peek()
+		if tok.Kind == mibTokEOF || tok.isPunct("::=") || p.isClauseStart(0) {
+			break
+		}
+		if tok.isPunct("{") || tok.isPunct("(") {
+			if _, err := p.skipGroup(); err != nil {
+				return clause, err
+			}
+			continue
+		}
+		p.next()
+	}
+	clause.tokens = p.tokens[start:p.pos]
+	return clause, nil
+}
+
+func (p *mibParser) parseClauses() ([]MIBClause, error) {
+	var clauses []MIBClause
+	for !p.peek().isPunct("::=") {
+		if p.peek().Kind == mibTokEOF {
+			return nil, p.errorf(p.peek(), "unexpected end of file, expected \"::=\"")
+		}
+		clause, err := p.parseClause()
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, clause)
+	}
+	return clauses, nil
+}
+
+// parseTCClauses 解析 TEXTUAL-CONVENTION, SYNTAX 子句总是最后一个
+func (p *mibParser) parseTCClauses() ([]MIBClause, *MIBSyntax, error) {
+	var clauses []MIBClause
+	for {
+		clause, err := p.parseClause()
+		if err != nil {
+			return nil, nil, err
+		}
+		clauses = append(clauses, clause)
+		if clause.Keyword == "SYNTAX" {
+			return clauses, clause.Syntax, nil
+		}
+	}
+}
+
+// parseSyntax 解析类型表达式, 如 "INTEGER { up(1), down(2) }"、"OCTET STRING (SIZE (0..255))"、"SEQUENCE OF IfEntry"
+func (p *mibParser) parseSyntax() (*MIBSyntax, error) {
+	start := p.pos
+	syntax := &MIBSyntax{Line: p.peek().Line}
+
+	// [APPLICATION n] IMPLICIT
+	if p.peek().isPunct("[") {
+		if _, err := p.skipGroup(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek().isIdent("IMPLICIT") || p.peek().isIdent("EXPLICIT") {
+		p.next()
+	}
+
+	typeTok, err := p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+
+	switch typeTok.Text {
+	case "OCTET":
+		if _, err := p.expectKeyword("STRING"); err != nil {
+			return nil, err
+		}
+		syntax.Type = "OCTET STRING"
+	case "OBJECT":
+		if _, err := p.expectKeyword("IDENTIFIER"); err != nil {
+			return nil, err
+		}
+		syntax.Type = "OBJECT IDENTIFIER"
+	case "SEQUENCE":
+		if p.peek().isIdent("OF") {
+			p.next()
+			elemTok, err := p.expectIdent()
+			if err != nil {
+				return nil, err
+			}
+			syntax.Type = "SEQUENCE OF"
+			syntax.SequenceOf = elemTok.Text
+		} else {
+			fields, err := p.parseSequenceFields()
+			if err != nil {
+				return nil, err
+			}
+			syntax.Type = "SEQUENCE"
+			syntax.Fields = fields
+		}
+	case "CHOICE":
+		if _, err := p.skipGroup(); err != nil {
+			return nil, err
+		}
+		syntax.Type = "CHOICE"
+	default:
+		syntax.Type = typeTok.Text
+	}
+
+	// 枚举 / BITS 命名位 / 取值范围 / SIZE 约束
+	if syntax.Type != "SEQUENCE" && syntax.Type != "SEQUENCE OF" && syntax.Type != "CHOICE" {
+		for p.peek().isPunct("{") || p.peek().isPunct("(") {
+			group, err := p.skipGroup()
+			if err != nil {
+				return nil, err
+			}
+			if group[0].isPunct("{") {
+				syntax.NamedNumbers = parseMIBNamedNumbers(group)
+			} else if inner := group[1 : len(group)-1]; len(inner) > 0 && inner[0].isIdent("SIZE") {
+				syntax.Sizes = parseMIBRanges(inner[1:])
+			} else {
+				syntax.Ranges = parseMIBRanges(group)
+			}
+		}
+	}
+
+	syntax.tokens = p.tokens[start:p.pos]
+	return syntax, nil
+}
+
+// parseMIBNamedNumbers 解析 "{ up(1), down(2) }" 形式的命名数值
+func parseMIBNamedNumbers(group []mibToken) []models.OIDEnum {
+	var enums []models.OIDEnum
+	for i := 1; i+3 < len(group); i++ {
+		if group[i].Kind != mibTokIdent || !group[i+1].isPunct("(") {
+			continue
+		}
+		end := i + 2
+		for end < len(group) && !group[end].isPunct(")") {
+			end++
+		}
+		if value, ok := parseMIBValue(group[i+2 : end]); ok {
+			enums = append(enums, models.OIDEnum{Name: group[i].Text, Value: value})
+		}
+		i = end
+	}
+	return enums
+}
+
+// parseMIBRanges 解析 "(0..255 | 1024)" 形式的取值范围列表
+func parseMIBRanges(group []mibToken) []models.OIDRange {
+	if len(group) >= 2 && group[0].isPunct("(") && group[len(group)-1].isPunct(")") {
+		group = group[1 : len(group)-1]
+	}
+
+	var ranges []models.OIDRange
+	start := 0
+	for i := 0; i <= len(group); i++ {
+		if i < len(group) && !group[i].isPunct("|") {
+			continue
+		}
+		item := group[start:i]
+		start = i + 1
+
+		sep := len(item)
+		for j, tok := range item {
+			if tok.isPunct("..") {
+				sep = j
+				break
+			}
+		}
+		min, ok := parseMIBValue(item[:sep])
+		if !ok {
+			continue
+		}
+		max := min
+		if sep < len(item) {
+			if max, ok = parseMIBValue(item[sep+1:]); !ok {
+				continue
+			}
+		}
+		ranges = append(ranges, models.OIDRange{Min: min, Max: max})
+	}
+	return ranges
+}
+
+// parseMIBValue 解析十进制 (可带负号)、'..'H 或 '..'B 形式的数值, 超出 int64 时截断
+func parseMIBValue(tokens []mibToken) (int64, bool) {
+	negative := false
+	if len(tokens) == 2 && tokens[0].isPunct("-") {
+		negative = true
+		tokens = tokens[1:]
+	}
+	if len(tokens) != 1 {
+		return 0, false
+	}
+
+	base := 10
+	switch tokens[0].Kind {
+	case mibTokNumber:
+	case mibTokHexString:
+		base = 16
+	case mibTokBinString:
+		base = 2
+	default:
+		return 0, false
+	}
+	if tokens[0].Text == "" {
+		return 0, false
+	}
+
+	value, err := strconv.ParseUint(tokens[0].Text, base, 64)
+	if err != nil {
+		if !errors.Is(err, strconv.ErrRange) {
+			return 0, false
+		}
+		value = math.MaxInt64
+	}
+	if value > math.MaxInt64 {
+		value = math.MaxInt64
+	}
+	if negative {
+		return -int64(value), true
+	}
+	return int64(value), true
+}
+
+func (p *mibParser) parseSequenceFields() ([]MIBSequenceField, error) {
+	if _, err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+	var fields []MIBSequenceField
+	for {
+		if p.peek().isPunct("}") {
+			p.next()
+			return fields, nil
+		}
+		nameTok, err := p.expectIdent()
+		if err != nil {
+			return nil, err
+		}
+		syntax, err := p.parseSyntax()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, MIBSequenceField{Name: nameTok.Text, Syntax: syntax})
+
+		tok := p.next()
+		switch {
+		case tok.isPunct(","):
+		case tok.isPunct("}"):
+			return fields, nil
+		default:
+			return nil, p.errorf(tok, "expected \",\" or \"}\" in SEQUENCE, found %s", describeMIBToken(tok))
+		}
+	}
+}
+
+// parseOIDValue 解析 "{ parent 1 }"、"{ iso org(3) dod(6) 1 }" 形式的 OID 值
+func (p *mibParser) parseOIDValue() ([]MIBOIDComponent, error) {
+	open, err := p.expectPunct("{")
+	if err != nil {
+		return nil, err
+	}
+
+	var components []MIBOIDComponent
+	for {
+		tok := p.next()
+		switch {
+		case tok.isPunct("}"):
+			if len(components) == 0 {
+				return nil, p.errorf(open, "empty OID value")
+			}
+			return components, nil
+		case tok.Kind == mibTokNumber:
+			num, err := strconv.ParseUint(tok.Text, 10, 32)
+			if err != nil {
+				return nil, p.errorf(tok, "OID sub-identifier %s out of range", tok.Text)
+			}
+			components = append(components, MIBOIDComponent{Number: uint32(num), HasNumber: true})
+		case tok.Kind == mibTokIdent:
+			component := MIBOIDComponent{Name: tok.Text}
+			// 形如 Module.name 的外部引用只保留名称
+			if p.peek().isPunct(".") && p.peekAt(1).Kind == mibTokIdent {
+				p.next()
+				component.Name = p.next().Text
+			}
+			if p.peek().isPunct("(") {
+				p.next()
+				numTok := p.next()
+				num, err := strconv.ParseUint(numTok.Text, 10, 32)
+				if numTok.Kind != mibTokNumber || err != nil {
+					return nil, p.errorf(numTok, "invalid named number in OID value")
+				}
+				if _, err := p.expectPunct(")"); err != nil {
+					return nil, err
+				}
+				component.Number = uint32(num)
+				component.HasNumber = true
+			}
+			components = append(components, component)
+		default:
+			return nil, p.errorf(tok, "unexpected %s in OID value", describeMIBToken(tok))
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"mib-platform/models"
+)
+
+// MIBRevisionInfo 同一模块的一个已加载版本
+type MIBRevisionInfo struct {
+	MIBID       uint                 `json:"mib_id"`
+	Module      string               `json:"module"`
+	Filename    string               `json:"filename"`
+	Version     string               `json:"version"`
+	LastUpdated *time.Time           `json:"last_updated"`
+	Revisions   []models.MIBRevision `json:"revisions"`
+	UploadedAt  time.Time            `json:"uploaded_at"`
+}
+
+// MIBDiffSide 参与比较的一个版本
+type MIBDiffSide struct {
+	MIBID       uint       `json:"mib_id"`
+	Module      string     `json:"module"`
+	Version     string     `json:"version"`
+	LastUpdated *time.Time `json:"last_updated"`
+}
+
+// MIBDiffObject 新增或删除的对象
+type MIBDiffObject struct {
+	Name   string `json:"name"`
+	OID    string `json:"oid"`
+	Type   string `json:"type"`
+	Syntax string `json:"syntax,omitempty"`
+}
+
+// MIBFieldChange 对象的一个字段变化
+type MIBFieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// MIBObjectChange 两个版本中都存在但定义不同的对象
+type MIBObjectChange struct {
+	Name     string           `json:"name"`
+	OID      string           `json:"oid"`
+	Changes  []MIBFieldChange `json:"changes"`
+	Breaking bool             `json:"breaking"` // 可能导致已生成的采集配置失效
+}
+
+// MIBDiff 两个 MIB 版本之间的差异
+type MIBDiff struct {
+	From          MIBDiffSide          `json:"from"`
+	To            MIBDiffSide          `json:"to"`
+	NewRevisions  []models.MIBRevision `json:"new_revisions"` // to 中比 from 的 LAST-UPDATED 更新的 REVISION
+	Added         []MIBDiffObject      `json:"added"`
+	Removed       []MIBDiffObject      `json:"removed"`
+	Changed       []MIBObjectChange    `json:"changed"`
+	SyntaxChanges []MIBObjectChange    `json:"syntax_changes"` // Changed 中涉及类型的部分
+	Breaking      bool                 `json:"breaking"`
+}
+
+// mibSyntaxFields 与对象类型相关的字段
+var mibSyntaxFields = map[string]bool{
+	"syntax":             true,
+	"base_type":          true,
+	"textual_convention": true,
+	"enums":              true,
+	"ranges":             true,
+	"sizes":              true,
+}
+
+// mibBreakingFields 变化后已有的采集配置需要重新生成的字段
+var mibBreakingFields = map[string]bool{
+	"oid":       true,
+	"base_type": true,
+	"kind":      true,
+	"table":     true,
+	"augments":  true,
+	"indexes":   true,
+}
+
+// ModelRevisions 返回 MODULE-IDENTITY 的 LAST-UPDATED 和 REVISION 列表
+func (m *CompiledMIBModule) ModelRevisions() (string, []models.MIBRevision) {
+	for _, node := range m.AST.Nodes {
+		if node.Macro != "MODULE-IDENTITY" {
+			continue
+		}
+
+		revisions := []models.MIBRevision{}
+		var current *models.MIBRevision
+		for i := range node.Clauses {
+			c := &node.Clauses[i]
+			switch {
+			case c.Keyword == "REVISION":
+				revisions = append(revisions, models.MIBRevision{Revision: c.Text()})
+				current = &revisions[len(revisions)-1]
+				if t, err := parseMIBDate(current.Revision); err == nil {
+					current.Date = &t
+				}
+			case c.Keyword == "DESCRIPTION" && current != nil:
+				current.Description = cleanMIBDescription(c.Text())
+				current = nil
+			}
+		}
+		return node.ClauseText("LAST-UPDATED"), revisions
+	}
+	return "", nil
+}
+
+// applyMIBRevisions 将版本信息写入 MIB 记录
+func applyMIBRevisions(mib *models.MIB, module *CompiledMIBModule) {
+	lastUpdated, revisions := module.ModelRevisions()
+	mib.Revisions = revisions
+	mib.LastUpdated = nil
+	if lastUpdated == "" {
+		return
+	}
+	mib.Version = lastUpdated
+	if t, err := parseMIBDate(lastUpdated); err == nil {
+		mib.LastUpdated = &t
+	}
+}
+
+// GetMIBRevisions 列出 MIB 库中同一模块的所有版本, 最新的在前
+func (s *MIBService) GetMIBRevisions(module string) ([]MIBRevisionInfo, error) {
+	var mibs []models.MIB
+	if err := s.db.Where("name = ?", module).Order("id DESC").Find(&mibs).Error; err != nil {
+		return nil, err
+	}
+
+	infos := make([]MIBRevisionInfo, 0, len(mibs))
+	for _, mib := range mibs {
+		infos = append(infos, MIBRevisionInfo{
+			MIBID:       mib.ID,
+			Module:      mib.Name,
+			Filename:    mib.Filename,
+			Version:     mib.Version,
+			LastUpdated: mib.LastUpdated,
+			Revisions:   mib.Revisions,
+			UploadedAt:  mib.UploadedAt,
+		})
+	}
+	// 没有 LAST-UPDATED 的 (SMIv1) 版本按上传顺序排在最后
+	sort.SliceStable(infos, func(i, j int) bool {
+		a, b := infos[i].LastUpdated, infos[j].LastUpdated
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return a.After(*b)
+	})
+	return infos, nil
+}
+
+// DiffMIBs 比较两个 MIB 版本中的对象
+func (s *MIBService) DiffMIBs(fromID, toID uint) (*MIBDiff, error) {
+	var from, to models.MIB
+	if err := s.db.Preload("OIDs").First(&from, fromID).Error; err != nil {
+		return nil, fmt.Errorf("MIB %d not found", fromID)
+	}
+	if err := s.db.Preload("OIDs").First(&to, toID).Error; err != nil {
+		return nil, fmt.Errorf("MIB %d not found", toID)
+	}
+	return DiffMIBVersions(&from, &to), nil
+}
+
+// DiffMIBVersions 按对象名称比较两个 MIB 记录
+func DiffMIBVersions(from, to *models.MIB) *MIBDiff {
+	diff := &MIBDiff{
+		From:          mibDiffSide(from),
+		To:            mibDiffSide(to),
+		NewRevisions:  []models.MIBRevision{},
+		Added:         []MIBDiffObject{},
+		Removed:       []MIBDiffObject{},
+		Changed:       []MIBObjectChange{},
+		SyntaxChanges: []MIBObjectChange{},
+	}
+
+	for _, rev := range to.Revisions {
+		if rev.Date != nil && (from.LastUpdated == nil || rev.Date.After(*from.LastUpdated)) {
+			diff.NewRevisions = append(diff.NewRevisions, rev)
+		}
+	}
+
+	old := make(map[string]*models.OID, len(from.OIDs))
+	for i := range from.OIDs {
+		old[from.OIDs[i].Name] = &from.OIDs[i]
+	}
+	seen := make(map[string]bool, len(to.OIDs))
+	for i := range to.OIDs {
+		oid := &to.OIDs[i]
+		seen[oid.Name] = true
+		prev, ok := old[oid.Name]
+		if !ok {
+			diff.Added = append(diff.Added, mibDiffObject(oid))
+			continue
+		}
+
+		change := MIBObjectChange{Name: oid.Name, OID: oid.OID}
+		var syntax []MIBFieldChange
+		for _, fc := range compareMIBObjects(prev, oid) {
+			change.Changes = append(change.Changes, fc)
+			if mibBreakingFields[fc.Field] {
+				change.Breaking = true
+			}
+			if mibSyntaxFields[fc.Field] {
+				syntax = append(syntax, fc)
+			}
+		}
+		// 删除或修改已有的枚举值也会让已生成的 enum 映射失效
+		if !change.Breaking && mibEnumsNarrowed(prev.Enums, oid.Enums) {
+			change.Breaking = true
+		}
+		if len(change.Changes) == 0 {
+			continue
+		}
+		diff.Changed = append(diff.Changed, change)
+		if len(syntax) > 0 {
+			diff.SyntaxChanges = append(diff.SyntaxChanges, MIBObjectChange{
+				Name:     change.Name,
+				OID:      change.OID,
+				Changes:  syntax,
+				Breaking: change.Breaking,
+			})
+		}
+		if change.Breaking {
+			diff.Breaking = true
+		}
+	}
+	for i := range from.OIDs {
+		if !seen[from.OIDs[i].Name] {
+			diff.Removed = append(diff.Removed, mibDiffObject(&from.OIDs[i]))
+			diff.Breaking = true
+		}
+	}
+
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].Name < diff.Added[j].Name })
+	sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].Name < diff.Removed[j].Name })
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].Name < diff.Changed[j].Name })
+	sort.Slice(diff.SyntaxChanges, func(i, j int) bool { return diff.SyntaxChanges[i].Name < diff.SyntaxChanges[j].Name })
+	return diff
+}
+
+func mibDiffSide(mib *models.MIB) MIBDiffSide {
+	return MIBDiffSide{MIBID: mib.ID, Module: mib.Name, Version: mib.Version, LastUpdated: mib.LastUpdated}
+}
+
+func mibDiffObject(oid *models.OID) MIBDiffObject {
+	return MIBDiffObject{Name: oid.Name, OID: oid.OID, Type: oid.Type, Syntax: oid.Syntax}
+}
+
+// compareMIBObjects 列出两个对象定义中不同的字段
+func compareMIBObjects(a, b *models.OID) []MIBFieldChange {
+	fields := []struct {
+		name string
+		a, b interface{}
+	}{
+		{"oid", a.OID, b.OID},
+		{"type", a.Type, b.Type},
+		{"syntax", a.Syntax, b.Syntax},
+		{"base_type", a.BaseType, b.BaseType},
+		{"textual_convention", a.TextualConvention, b.TextualConvention},
+		{"display_hint", a.DisplayHint, b.DisplayHint},
+		{"enums", a.Enums, b.Enums},
+		{"ranges", a.Ranges, b.Ranges},
+		{"sizes", a.Sizes, b.Sizes},
+		{"access", a.Access, b.Access},
+		{"status", a.Status, b.Status},
+		{"units", a.Units, b.Units},
+		{"kind", a.Kind, b.Kind},
+		{"table", a.Table, b.Table},
+		{"augments", a.Augments, b.Augments},
+		{"indexes", a.Indexes, b.Indexes},
+	}
+
+	var changes []MIBFieldChange
+	for _, f := range fields {
+		from, to := formatMIBDiffValue(f.a), formatMIBDiffValue(f.b)
+		if from != to {
+			changes = append(changes, MIBFieldChange{Field: f.name, From: from, To: to})
+		}
+	}
+	return changes
+}
+
+// formatMIBDiffValue 将字段值格式化为可比较的文本, 空列表与 nil 等价
+func formatMIBDiffValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "null" || string(data) == "[]" {
+		return ""
+	}
+	return string(data)
+}
+
+// mibEnumsNarrowed 判断新版本是否删除或改变了已有的枚举值
+func mibEnumsNarrowed(from, to []models.OIDEnum) bool {
+	values := make(map[string]int64, len(to))
+	for _, e := range to {
+		values[e.Name] = e.Value
+	}
+	for _, e := range from {
+		if v, ok := values[e.Name]; !ok || v != e.Value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,533 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"gorm.io/gorm"
+
+	"mib-platform/models"
+)
+
+// mibEnterpriseVendors 常见厂商的 IANA 企业号, 用于搜索结果的厂商分面
+var mibEnterpriseVendors = map[string]string{
+	"9":     "Cisco",
+	"11":    "HP",
+	"43":    "3Com",
+	"171":   "D-Link",
+	"232":   "HP",
+	"311":   "Microsoft",
+	"318":   "APC",
+	"534":   "Eaton",
+	"674":   "Dell",
+	"1588":  "Brocade",
+	"1916":  "Extreme",
+	"1991":  "Foundry",
+	"2011":  "Huawei",
+	"2021":  "UCD-SNMP",
+	"2620":  "Check Point",
+	"2636":  "Juniper",
+	"3375":  "F5",
+	"3902":  "ZTE",
+	"4526":  "Netgear",
+	"4881":  "Ruijie",
+	"6027":  "Dell",
+	"6486":  "Alcatel-Lucent",
+	"6527":  "Nokia",
+	"6876":  "VMware",
+	"8072":  "Net-SNMP",
+	"12356": "Fortinet",
+	"14988": "MikroTik",
+	"25461": "Palo Alto",
+	"25506": "H3C",
+	"30065": "Arista",
+	"41112": "Ubiquiti",
+}
+
+var mibOIDPrefixPattern = regexp.MustCompile(`^\.?[0-9]+(\.[0-9]+)*\.?$`)
+
+// mibVendor 根据 OID 推断厂商; 非企业私有节点归为 standard
+func mibVendor(oid string) string {
+	const enterprises = "1.3.6.1.4.1."
+	if !strings.HasPrefix(oid, enterprises) {
+		return "standard"
+	}
+	number := strings.SplitN(strings.TrimPrefix(oid, enterprises), ".", 2)[0]
+	if vendor, ok := mibEnterpriseVendors[number]; ok {
+		return vendor
+	}
+	return "enterprise-" + number
+}
+
+// splitMIBIdentifier 将 "cOpticalRxPower" 拆成 "c optical rx power", 供按词搜索
+func splitMIBIdentifier(name string) string {
+	var words []string
+	var word []rune
+	runes := []rune(name)
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+	for i, r := range runes {
+		switch {
+		case r == '-' || r == '_':
+			flush()
+			continue
+		case unicode.IsUpper(r):
+			// 连续大写视为缩写, 缩写的最后一个字母属于下一个单词 (如 "ifHCInOctets" -> "if hc in octets")
+			if i > 0 && (!unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				flush()
+			}
+		case unicode.IsDigit(r):
+			if i > 0 && !unicode.IsDigit(runes[i-1]) {
+				flush()
+			}
+		default:
+			if i > 0 && unicode.IsDigit(runes[i-1]) {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+	return strings.Join(words, " ")
+}
+
+// MIBSearchQuery OID 搜索条件
+type MIBSearchQuery struct {
+	Query  string `json:"query"`
+	Module string `json:"module,omitempty"`
+	Vendor string `json:"vendor,omitempty"`
+	Page   int    `json:"page"`
+	Limit  int    `json:"limit"`
+}
+
+// MIBSearchHit 一条搜索结果
+type MIBSearchHit struct {
+	OIDID       uint    `json:"oid_id"`
+	MIBID       uint    `json:"mib_id"`
+	Module      string  `json:"module"`
+	Vendor      string  `json:"vendor"`
+	Name        string  `json:"name"`
+	OID         string  `json:"oid"`
+	Type        string  `json:"type"`
+	Syntax      string  `json:"syntax,omitempty"`
+	Units       string  `json:"units,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Snippet     string  `json:"snippet,omitempty"` // 描述中命中的片段, 命中词以 [] 标出
+	Score       float64 `json:"score"`             // 越小越相关 (bm25)
+}
+
+// MIBSearchFacet 分面统计
+type MIBSearchFacet struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// MIBSearchResult 搜索结果及按模块、厂商的分面
+type MIBSearchResult struct {
+	Hits    []MIBSearchHit   `json:"hits"`
+	Total   int64            `json:"total"`
+	Modules []MIBSearchFacet `json:"modules"`
+	Vendors []MIBSearchFacet `json:"vendors"`
+	Mode    string           `json:"mode"`              // fts, like, oid-prefix
+	Warning string           `json:"warning,omitempty"` // 全文搜索不可用时说明原因
+}
+
+// MIBSearchIndex 基于 SQLite FTS5 的 OID 全文索引; FTS5 不可用时退化为 LIKE 查询
+type MIBSearchIndex struct {
+	db    *gorm.DB
+	once  sync.Once
+	mu    sync.Mutex
+	fts   bool
+	ready error
+}
+
+// 同一数据库的所有 MIBService 共享一个索引
+var mibSearchIndexes sync.Map
+
+func sharedMIBSearchIndex(db *gorm.DB) *MIBSearchIndex {
+	index, _ := mibSearchIndexes.LoadOrStore(db, &MIBSearchIndex{db: db})
+	return index.(*MIBSearchIndex)
+}
+
+// mibSearchRow 写入 FTS 表的一行
+type mibSearchRow struct {
+	OIDID       uint `gorm:"column:oid_id"`
+	MIBID       uint
+	Module      string
+	Name        string
+	OID         string
+	Description string
+	Units       string
+	Enums       []models.OIDEnum `gorm:"serializer:json"`
+}
+
+// ensure 创建 FTS 表, 首次使用时从现有数据建立索引
+func (idx *MIBSearchIndex) ensure() error {
+	idx.once.Do(func() {
+		err := idx.db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS oid_search USING fts5(
+			name, terms, description, module, units, enums,
+			oid UNINDEXED, oid_id UNINDEXED, mib_id UNINDEXED, vendor UNINDEXED,
+			tokenize = 'unicode61'
+		)`).Error
+		if err != nil {
+			// 未使用 sqlite_fts5 构建标签编译时没有 FTS5 模块
+			log.Printf("FTS5 unavailable, falling back to LIKE search: %v", err)
+			return
+		}
+		idx.fts = true
+
+		var indexed, total int64
+		idx.db.Raw("SELECT count(*) FROM oid_search").Scan(&indexed)
+		idx.db.Model(&models.OID{}).Count(&total)
+		if indexed == 0 && total > 0 {
+			idx.ready = idx.Rebuild()
+		}
+	})
+	return idx.ready
+}
+
+// Rebuild 从 o_ids 表重建整个索引
+func (idx *MIBSearchIndex) Rebuild() error {
+	if !idx.fts {
+		return nil
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	return idx.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM oid_search").Error; err != nil {
+			return err
+		}
+		return idx.insert(tx, "")
+	})
+}
+
+// IndexMIB 重新索引单个 MIB 的对象, MIB 已删除时只移除旧条目
+func (idx *MIBSearchIndex) IndexMIB(mibID uint) {
+	if err := idx.ensure(); err != nil || !idx.fts {
+		return
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	err := idx.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM oid_search WHERE mib_id = ?", mibID).Error; err != nil {
+			return err
+		}
+		return idx.insert(tx, "o_ids.mib_id = ?", mibID)
+	})
+	if err != nil {
+		log.Printf("Failed to index MIB %d for search: %v", mibID, err)
+	}
+}
+
+// RemoveMIB 从索引中移除 MIB 的所有对象
+func (idx *MIBSearchIndex) RemoveMIB(mibID uint) {
+	if err := idx.ensure(); err != nil || !idx.fts {
+		return
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if err := idx.db.Exec("DELETE FROM oid_search WHERE mib_id = ?", mibID).Error; err != nil {
+		log.Printf("Failed to remove MIB %d from search index: %v", mibID, err)
+	}
+}
+
+func (idx *MIBSearchIndex) insert(tx *gorm.DB, where string, args ...interface{}) error {
+	query := tx.Model(&models.OID{}).
+		Select("o_ids.id AS oid_id, o_ids.mib_id, mibs.name AS module, o_ids.name, o_ids.o_id AS oid, o_ids.description, o_ids.units, o_ids.enums").
+		Joins("JOIN mibs ON mibs.id = o_ids.mib_id AND mibs.deleted_at IS NULL").
+		Where("o_ids.deleted_at IS NULL")
+	if where != "" {
+		query = query.Where(where, args...)
+	}
+
+	var rows []mibSearchRow
+	if err := query.Scan(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		labels := make([]string, 0, len(row.Enums))
+		for _, e := range row.Enums {
+			labels = append(labels, e.Name+" "+splitMIBIdentifier(e.Name))
+		}
+		err := tx.Exec(`INSERT INTO oid_search (name, terms, description, module, units, enums, oid, oid_id, mib_id, vendor)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			row.Name, splitMIBIdentifier(row.Name), row.Description, row.Module, row.Units,
+			strings.Join(labels, " "), row.OID, row.OIDID, row.MIBID, mibVendor(row.OID)).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildMIBFTSQuery 将用户输入转换为 FTS5 查询: 每个词做前缀匹配, 词之间为 AND
+func buildMIBFTSQuery(input string) string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(input, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		terms = append(terms, `"`+word+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// mibSearchFTSWarning 没有 FTS5 时附在搜索结果中的说明
+const mibSearchFTSWarning = "full-text search is unavailable because the server was built without the sqlite_fts5 tag; results use LIKE matching"
+
+// Available 返回 FTS5 全文索引是否可用
+func (idx *MIBSearchIndex) Available() bool {
+	idx.ensure()
+	return idx.fts
+}
+
+// Search 按关键词或数字 OID 前缀搜索
+func (idx *MIBSearchIndex) Search(q MIBSearchQuery) (*MIBSearchResult, error) {
+	if err := idx.ensure(); err != nil {
+		return nil, fmt.Errorf("failed to build search index: %v", err)
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.Limit < 1 || q.Limit > 200 {
+		q.Limit = 20
+	}
+	text := strings.TrimSpace(q.Query)
+	if text == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+
+	switch {
+	case mibOIDPrefixPattern.MatchString(text):
+		return idx.searchOIDPrefix(strings.Trim(text, "."), q)
+	case idx.fts:
+		return idx.searchFTS(text, q)
+	default:
+		return idx.searchLike(text, q)
+	}
+}
+
+func (idx *MIBSearchIndex) searchFTS(text string, q MIBSearchQuery) (*MIBSearchResult, error) {
+	match := buildMIBFTSQuery(text)
+	if match == "" {
+		return nil, fmt.Errorf("query contains no searchable terms")
+	}
+
+	filter := func() *gorm.DB {
+		query := idx.db.Table("oid_search").Where("oid_search MATCH ?", match)
+		if q.Module != "" {
+			query = query.Where("module = ?", q.Module)
+		}
+		if q.Vendor != "" {
+			query = query.Where("vendor = ?", q.Vendor)
+		}
+		return query
+	}
+
+	result := &MIBSearchResult{Mode: "fts"}
+	if err := filter().Count(&result.Total).Error; err != nil {
+		return nil, fmt.Errorf("invalid search query: %v", err)
+	}
+
+	// 名称命中的权重远高于描述
+	var rows []struct {
+		OIDID   uint `gorm:"column:oid_id"`
+		MIBID   uint
+		Module  string
+		Vendor  string
+		Snippet string
+		Score   float64
+	}
+	err := filter().
+		Select("oid_id, mib_id, module, vendor, snippet(oid_search, 2, '[', ']', '...', 16) AS snippet, bm25(oid_search, 10.0, 8.0, 1.0, 3.0, 2.0, 2.0) AS score").
+		Order("score").
+		Offset((q.Page - 1) * q.Limit).
+		Limit(q.Limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %v", err)
+	}
+
+	ids := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.OIDID)
+	}
+	oids := make(map[uint]models.OID)
+	if len(ids) > 0 {
+		var list []models.OID
+		if err := idx.db.Where("id IN ?", ids).Find(&list).Error; err != nil {
+			return nil, err
+		}
+		for _, oid := range list {
+			oids[oid.ID] = oid
+		}
+	}
+
+	result.Hits = make([]MIBSearchHit, 0, len(rows))
+	for _, row := range rows {
+		oid := oids[row.OIDID]
+		result.Hits = append(result.Hits, MIBSearchHit{
+			OIDID:       row.OIDID,
+			MIBID:       row.MIBID,
+			Module:      row.Module,
+			Vendor:      row.Vendor,
+			Name:        oid.Name,
+			OID:         oid.OID,
+			Type:        oid.Type,
+			Syntax:      oid.Syntax,
+			Units:       oid.Units,
+			Description: oid.Description,
+			Snippet:     row.Snippet,
+			Score:       row.Score,
+		})
+	}
+
+	// 分面统计不受分页影响
+	if result.Modules, err = mibSearchFacets(filter(), "module"); err != nil {
+		return nil, err
+	}
+	if result.Vendors, err = mibSearchFacets(filter(), "vendor"); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func mibSearchFacets(query *gorm.DB, column string) ([]MIBSearchFacet, error) {
+	facets := []MIBSearchFacet{}
+	err := query.Select(column + " AS value, count(*) AS count").
+		Group(column).
+		Order("count DESC, value").
+		Limit(50).
+		Scan(&facets).Error
+	return facets, err
+}
+
+// searchOIDPrefix 数字 OID 前缀搜索, 结果按 OID 排序
+func (idx *MIBSearchIndex) searchOIDPrefix(prefix string, q MIBSearchQuery) (*MIBSearchResult, error) {
+	query := idx.oidQuery().Where("o_ids.o_id = ? OR o_ids.o_id LIKE ?", prefix, prefix+".%")
+	result, err := idx.searchOIDTable(query, q, func(hits []MIBSearchHit) {
+		sort.Slice(hits, func(i, j int) bool { return compareOIDs(hits[i].OID, hits[j].OID) < 0 })
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Mode = "oid-prefix"
+	return result, nil
+}
+
+// searchLike 没有 FTS5 时的回退实现, 名称命中排在描述命中之前
+func (idx *MIBSearchIndex) searchLike(text string, q MIBSearchQuery) (*MIBSearchResult, error) {
+	words := strings.Fields(strings.ToLower(text))
+	query := idx.oidQuery()
+	for _, word := range words {
+		pattern := "%" + word + "%"
+		query = query.Where("(o_ids.name LIKE ? OR o_ids.description LIKE ? OR o_ids.units LIKE ? OR mibs.name LIKE ? OR o_ids.enums LIKE ?)",
+			pattern, pattern, pattern, pattern, pattern)
+	}
+
+	result, err := idx.searchOIDTable(query, q, func(hits []MIBSearchHit) {
+		rank := func(hit MIBSearchHit) int {
+			name := strings.ToLower(hit.Name)
+			n := 0
+			for _, word := range words {
+				if strings.Contains(name, word) {
+					n++
+				}
+			}
+			return n
+		}
+		sort.SliceStable(hits, func(i, j int) bool { return rank(hits[i]) > rank(hits[j]) })
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Mode = "like"
+	result.Warning = mibSearchFTSWarning
+	return result, nil
+}
+
+func (idx *MIBSearchIndex) oidQuery() *gorm.DB {
+	return idx.db.Model(&models.OID{}).
+		Select("o_ids.*, mibs.name AS module").
+		Joins("JOIN mibs ON mibs.id = o_ids.mib_id AND mibs.deleted_at IS NULL")
+}
+
+// searchOIDTable 直接查询 o_ids 表; 厂商由 OID 推断, 过滤、分面和分页在内存中完成
+func (idx *MIBSearchIndex) searchOIDTable(query *gorm.DB, q MIBSearchQuery, order func([]MIBSearchHit)) (*MIBSearchResult, error) {
+	var rows []struct {
+		models.OID
+		Module string
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("search failed: %v", err)
+	}
+
+	modules := make(map[string]int64)
+	vendors := make(map[string]int64)
+	hits := make([]MIBSearchHit, 0, len(rows))
+	for _, row := range rows {
+		vendor := mibVendor(row.OID.OID)
+		if (q.Module != "" && row.Module != q.Module) || (q.Vendor != "" && vendor != q.Vendor) {
+			continue
+		}
+		modules[row.Module]++
+		vendors[vendor]++
+		hits = append(hits, MIBSearchHit{
+			OIDID:       row.ID,
+			MIBID:       row.MIBID,
+			Module:      row.Module,
+			Vendor:      vendor,
+			Name:        row.Name,
+			OID:         row.OID.OID,
+			Type:        row.Type,
+			Syntax:      row.Syntax,
+			Units:       row.Units,
+			Description: row.Description,
+		})
+	}
+	order(hits)
+
+	result := &MIBSearchResult{
+		Hits:    []MIBSearchHit{},
+		Total:   int64(len(hits)),
+		Modules: sortMIBSearchFacets(modules),
+		Vendors: sortMIBSearchFacets(vendors),
+	}
+	start := (q.Page - 1) * q.Limit
+	if start < len(hits) {
+		end := start + q.Limit
+		if end > len(hits) {
+			end = len(hits)
+		}
+		result.Hits = hits[start:end]
+	}
+	return result, nil
+}
+
+func sortMIBSearchFacets(counts map[string]int64) []MIBSearchFacet {
+	facets := make([]MIBSearchFacet, 0, len(counts))
+	for value, count := range counts {
+		facets = append(facets, MIBSearchFacet{Value: value, Count: count})
+	}
+	sort.Slice(facets, func(i, j int) bool {
+		if facets[i].Count != facets[j].Count {
+			return facets[i].Count > facets[j].Count
+		}
+		return facets[i].Value < facets[j].Value
+	})
+	if len(facets) > 50 {
+		facets = facets[:50]
+	}
+	return facets
+}
@@ -1,16 +1,13 @@
 package services
 
 import (
-	"bufio"
-	"encoding/csv"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -21,15 +18,21 @@ import (
 )
 
 type MIBService struct {
-	db    *gorm.DB
-	
+	db       *gorm.DB
+	compiler *MIBCompiler
+	tree     *OIDTree
+	search   *MIBSearchIndex
+	pending  map[uint]bool // 事务内修改的 MIB, 提交后再更新搜索索引
 }
 
 func NewMIBService(db *gorm.DB, ) *MIBService {
-	return &MIBService{
-		db:    db,
-		
+	s := &MIBService{
+		db:       db,
+		tree:     sharedOIDTree(db),
+		search:   sharedMIBSearchIndex(db),
 	}
+	s.compiler = NewMIBCompiler(s.lookupImportedSymbol, s.lookupImportedType)
+	return s
 }
 
 func (s *MIBService) GetMIBs(page, limit int, search, status string) ([]models.MIB, int64, error) {
@@ -39,7 +42,7 @@ func (s *MIBService) GetMIBs(page, limit int, search, status string) ([]models.M
 	query := s.db.Model(&models.MIB{})
 
 	if search != "" {
-		query = query.Where("name ILIKE ? OR description ILIKE ?", "%"+search+"%", "%"+search+"%")
+		query = query.Where("name LIKE ? OR description LIKE ?", "%"+search+"%", "%"+search+"%")
 	}
 
 	if status != "" {
@@ -67,7 +70,12 @@ func (s *MIBService) GetMIB(id uint) (*models.MIB, error) {
 }
 
 func (s *MIBService) CreateMIB(mib *models.MIB) error {
-	return s.db.Create(mib).Error
+	if err := s.db.Create(mib).Error; err != nil {
+		return err
+	}
+	s.tree.Invalidate()
+	s.indexMIB(mib.ID)
+	return nil
 }
 
 func (s *MIBService) UpdateMIB(id uint, updates *models.MIB) (*models.MIB, error) {
@@ -79,23 +87,19 @@ func (s *MIBService) UpdateMIB(id uint, updates *models.MIB) (*models.MIB, error
 	if err := s.db.Model(&mib).Updates(updates).Error; err != nil {
 		return nil, err
 	}
+	s.tree.Invalidate()
+	s.indexMIB(mib.ID)
 
 	return &mib, nil
 }
 
 func (s *MIBService) DeleteMIB(id uint) error {
-	return s.db.Delete(&models.MIB{}, id).Error
-}
-
-// MIB 解析结构
-type MIBParseResult struct {
-	OID         string `json:"oid"`
-	Name        string `json:"name"`
-	Type        string `json:"type"`
-	Access      string `json:"access"`
-	Description string `json:"description"`
-	Status      string `json:"status"`
-	Module      string `json:"module"`
+	if err := s.db.Delete(&models.MIB{}, id).Error; err != nil {
+		return err
+	}
+	s.tree.Invalidate()
+	s.search.RemoveMIB(id)
+	return nil
 }
 
 // 扫描指定目录中的 MIB 文件
@@ -132,174 +136,30 @@ func (s *MIBService) ScanMIBDirectory(dirPath string) ([]string, error) {
 	return mibFiles, err
 }
 
-// 使用 snmptranslate 解析 MIB 文件
-func (s *MIBService) ParseMIBWithSNMPTranslate(filePath string) ([]MIBParseResult, error) {
-	var results []MIBParseResult
-
-	// 首先尝试使用 snmptranslate 获取所有 OID
-	cmd := exec.Command("snmptranslate", "-Td", "-OS", "-m", filePath)
-	output, err := cmd.Output()
-	if err != nil {
-		// 如果 snmptranslate 不可用，使用备用解析方法
-		return s.parseMIBContentFallback(filePath)
-	}
-
-	// 解析 snmptranslate 输出
-	scanner := bufio.NewScanner(strings.NewReader(string(output)))
-	var currentOID MIBParseResult
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		
-		if strings.Contains(line, "::") {
-			// 新的 OID 定义开始
-			if currentOID.Name != "" {
-				results = append(results, currentOID)
-			}
-			currentOID = MIBParseResult{}
-			
-			// 解析 OID 名称和数字
-			parts := strings.Split(line, "::")
-			if len(parts) >= 2 {
-				currentOID.Name = strings.TrimSpace(parts[0])
-				oidPart := strings.TrimSpace(parts[1])
-				// 提取 OID 数字
-				re := regexp.MustCompile(`\d+(\.\d+)*`)
-				if match := re.FindString(oidPart); match != "" {
-					currentOID.OID = match
-				}
-			}
-		} else if strings.HasPrefix(line, "SYNTAX") {
-			currentOID.Type = strings.TrimSpace(strings.TrimPrefix(line, "SYNTAX"))
-		} else if strings.HasPrefix(line, "ACCESS") {
-			currentOID.Access = strings.TrimSpace(strings.TrimPrefix(line, "ACCESS"))
-		} else if strings.HasPrefix(line, "STATUS") {
-			currentOID.Status = strings.TrimSpace(strings.TrimPrefix(line, "STATUS"))
-		} else if strings.HasPrefix(line, "DESCRIPTION") {
-			// 描述可能跨多行
-			desc := strings.TrimSpace(strings.TrimPrefix(line, "DESCRIPTION"))
-			currentOID.Description = strings.Trim(desc, "\"")
-		}
-	}
-
-	// 添加最后一个 OID
-	if currentOID.Name != "" {
-		results = append(results, currentOID)
-	}
-
-	return results, nil
-}
-
-// 备用 MIB 解析方法（当 snmptranslate 不可用时）
-func (s *MIBService) parseMIBContentFallback(filePath string) ([]MIBParseResult, error) {
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read MIB file: %v", err)
-	}
-
-	var results []MIBParseResult
-	lines := strings.Split(string(content), "\n")
-
-	// 简单的 MIB 解析逻辑
-	oidRegex := regexp.MustCompile(`(\w+)\s+OBJECT\s+IDENTIFIER\s*::=\s*\{\s*([^}]+)\s*\}`)
-	objectRegex := regexp.MustCompile(`(\w+)\s+OBJECT-TYPE`)
-	syntaxRegex := regexp.MustCompile(`SYNTAX\s+(.+)`)
-	accessRegex := regexp.MustCompile(`ACCESS\s+(.+)`)
-	statusRegex := regexp.MustCompile(`STATUS\s+(.+)`)
-	descRegex := regexp.MustCompile(`DESCRIPTION\s+"([^"]*)"`)
-
-	var currentOID MIBParseResult
-	inObjectDef := false
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		
-		// 检查 OBJECT IDENTIFIER 定义
-		if matches := oidRegex.FindStringSubmatch(line); len(matches) > 2 {
-			if currentOID.Name != "" {
-				results = append(results, currentOID)
-			}
-			currentOID = MIBParseResult{
-				Name: matches[1],
-				OID:  s.parseOIDPath(matches[2]),
-			}
-			inObjectDef = false
-		}
-
-		// 检查 OBJECT-TYPE 定义
-		if matches := objectRegex.FindStringSubmatch(line); len(matches) > 1 {
-			if currentOID.Name != "" {
-				results = append(results, currentOID)
-			}
-			currentOID = MIBParseResult{
-				Name: matches[1],
-			}
-			inObjectDef = true
-		}
-
-		if inObjectDef {
-			if matches := syntaxRegex.FindStringSubmatch(line); len(matches) > 1 {
-				currentOID.Type = strings.TrimSpace(matches[1])
-			}
-			if matches := accessRegex.FindStringSubmatch(line); len(matches) > 1 {
-				currentOID.Access = strings.TrimSpace(matches[1])
-			}
-			if matches := statusRegex.FindStringSubmatch(line); len(matches) > 1 {
-				currentOID.Status = strings.TrimSpace(matches[1])
-			}
-			if matches := descRegex.FindStringSubmatch(line); len(matches) > 1 {
-				currentOID.Description = matches[1]
-			}
-		}
-	}
-
-	// 添加最后一个 OID
-	if currentOID.Name != "" {
-		results = append(results, currentOID)
-	}
-
-	return results, nil
-}
-
-// 解析 OID 路径
-func (s *MIBService) parseOIDPath(oidPath string) string {
-	// 简单的 OID 路径解析
-	parts := strings.Fields(oidPath)
-	var oidNumbers []string
-	
-	for _, part := range parts {
-		if num, err := strconv.Atoi(part); err == nil {
-			oidNumbers = append(oidNumbers, strconv.Itoa(num))
-		}
-	}
-	
-	return strings.Join(oidNumbers, ".")
-}
-
+// 使用内置 MIB 编译器解析 MIB 文件
 func (s *MIBService) ParseMIB(filePath string) ([]models.OID, error) {
-	// 使用新的解析方法
-	parseResults, err := s.ParseMIBWithSNMPTranslate(filePath)
+	modules, err := s.compiler.CompileFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse MIB content: %v", err)
 	}
 
-	// 转换为 models.OID 结构
 	var oids []models.OID
-	for _, result := range parseResults {
-		oid := models.OID{
-			OID:         result.OID,
-			Name:        result.Name,
-			Type:        result.Type,
-			Access:      result.Access,
-			Description: result.Description,
-			Status:      result.Status,
-		}
-		oids = append(oids, oid)
+	for _, module := range modules {
+		oids = append(oids, module.ModelOIDs()...)
 	}
 
 	return oids, nil
 }
 
+// uploadFileName 只保留上传文件名的最后一段, 拒绝空名称和以 "." 开头的名称 (包括 "..")
+func uploadFileName(name string) (string, error) {
+	base := filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if base == "/" || strings.HasPrefix(base, ".") {
+		return "", fmt.Errorf("invalid file name: %q", name)
+	}
+	return base, nil
+}
+
 // 上传并解析 MIB 文件
 func (s *MIBService) UploadAndParseMIB(file multipart.File, header *multipart.FileHeader) (*models.MIB, error) {
 	// 创建上传目录
@@ -308,8 +168,20 @@ func (s *MIBService) UploadAndParseMIB(file multipart.File, header *multipart.Fi
 		return nil, fmt.Errorf("failed to create upload directory: %v", err)
 	}
 
-	// 保存文件
-	filePath := filepath.Join(uploadDir, header.Filename)
+	filename, err := uploadFileName(header.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	// 保存文件; 同名文件可能是同一模块的其他版本, 不覆盖
+	filePath := filepath.Join(uploadDir, filename)
+	if _, err := os.Stat(filePath); err == nil {
+		revisionDir := filepath.Join(uploadDir, "revisions", strconv.FormatInt(time.Now().UnixNano(), 10))
+		if err := os.MkdirAll(revisionDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create upload directory: %v", err)
+		}
+		filePath = filepath.Join(revisionDir, filename)
+	}
 	dst, err := os.Create(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create file: %v", err)
@@ -321,239 +193,206 @@ func (s *MIBService) UploadAndParseMIB(file multipart.File, header *multipart.Fi
 	}
 
 	// 解析 MIB 文件
-	oids, err := s.ParseMIB(filePath)
+	modules, err := s.compiler.CompileFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse MIB file: %v", err)
 	}
 
+	checksum, err := fileChecksum(filePath)
+	if err != nil {
+		return nil, err
+	}
+
 	// 创建 MIB 记录
 	mib := &models.MIB{
-		Name:        strings.TrimSuffix(header.Filename, filepath.Ext(header.Filename)),
-		Filename:    header.Filename,
+		Filename:    filename,
 		FilePath:    filePath,
 		Size:        header.Size,
+		Checksum:    checksum,
 		Status:      "active",
 		UploadedAt:  time.Now(),
-		OIDs:        oids,
 	}
+	if err := s.createParsedMIB(mib, modules); err != nil {
+		return nil, err
+	}
+
+	return mib, nil
+}
+
+// createParsedMIB 保存新解析的 MIB 记录
+func (s *MIBService) createParsedMIB(mib *models.MIB, modules []*CompiledMIBModule) error {
+	applyCompiledMIB(mib, modules)
 
 	// 保存到数据库
 	if err := s.db.Create(mib).Error; err != nil {
-		return nil, fmt.Errorf("failed to save MIB to database: %v", err)
+		return fmt.Errorf("failed to save MIB to database: %v", err)
 	}
+	s.tree.Invalidate()
+	s.indexMIB(mib.ID)
 
-	return mib, nil
+	// 新模块可能补全了其他 MIB 缺失的依赖
+	s.reparseDependents(mib)
+
+	return nil
 }
 
-func (s *MIBService) parseMIBContent(content string) ([]models.OID, error) {
-	var oids []models.OID
-	lines := strings.Split(content, "\n")
-	
-	for i, line := range lines {
-		line = strings.TrimSpace(line)
-		
-		// Skip comments and empty lines
-		if strings.HasPrefix(line, "--") || line == "" {
-			continue
-		}
-		
-		// Look for OID definitions (simplified parsing)
-		if strings.Contains(line, "OBJECT-TYPE") {
-			oid, err := s.parseOIDDefinition(lines, i)
-			if err != nil {
-				continue // Skip malformed definitions
-			}
-			oids = append(oids, *oid)
-		}
+// fileChecksum 计算文件的 SHA-256 校验和
+func fileChecksum(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %v", err)
 	}
-	
-	return oids, nil
-}
+	defer f.Close()
 
-func (s *MIBService) parseOIDDefinition(lines []string, startIndex int) (*models.OID, error) {
-	if startIndex >= len(lines) {
-		return nil, fmt.Errorf("invalid start index")
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
 	}
-	
-	// Extract OID name from the line before OBJECT-TYPE
-	var name string
-	if startIndex > 0 {
-		prevLine := strings.TrimSpace(lines[startIndex-1])
-		fields := strings.Fields(prevLine)
-		if len(fields) > 0 {
-			name = fields[0]
-		}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// 重新解析已保存的 MIB, 使用当前 MIB 库解析跨模块导入
+func (s *MIBService) ReparseMIB(id uint) (*models.MIB, error) {
+	var mib models.MIB
+	if err := s.db.First(&mib, id).Error; err != nil {
+		return nil, err
 	}
-	
-	oid := &models.OID{
-		Name:   name,
-		Type:   "UNKNOWN",
-		Access: "not-accessible",
-		Status: "current",
+
+	modules, err := s.compiler.CompileFile(mib.FilePath)
+	if err != nil {
+		s.db.Model(&mib).Updates(map[string]interface{}{"status": "error", "error_msg": err.Error()})
+		return nil, fmt.Errorf("failed to parse MIB file: %v", err)
 	}
-	
-	// Parse the OBJECT-TYPE definition
-	for i := startIndex; i < len(lines) && i < startIndex+20; i++ {
-		line := strings.TrimSpace(lines[i])
-		
-		if strings.HasPrefix(line, "SYNTAX") {
-			parts := strings.Fields(line)
-			if len(parts) > 1 {
-				oid.Type = parts[1]
-			}
-		} else if strings.HasPrefix(line, "ACCESS") || strings.HasPrefix(line, "MAX-ACCESS") {
-			parts := strings.Fields(line)
-			if len(parts) > 1 {
-				oid.Access = parts[1]
-			}
-		} else if strings.HasPrefix(line, "STATUS") {
-			parts := strings.Fields(line)
-			if len(parts) > 1 {
-				oid.Status = parts[1]
-			}
-		} else if strings.HasPrefix(line, "DESCRIPTION") {
-			// Extract description (may span multiple lines)
-			desc := s.extractDescription(lines, i)
-			oid.Description = desc
-		} else if strings.Contains(line, "::=") {
-			// Extract OID value
-			oidValue := s.extractOIDValue(line)
-			if oidValue != "" {
-				oid.OIDString = oidValue
-			}
-			break
-		}
+	if err := s.replaceParsedMIB(&mib, modules); err != nil {
+		return nil, err
 	}
-	
-	return oid, nil
+	return &mib, nil
 }
 
-func (s *MIBService) extractDescription(lines []string, startIndex int) string {
-	var description strings.Builder
-	for i := startIndex; i < len(lines); i++ {
-		line := strings.TrimSpace(lines[i])
-		if strings.HasPrefix(line, "DESCRIPTION") {
-			// Remove DESCRIPTION keyword and quotes
-			line = strings.TrimPrefix(line, "DESCRIPTION")
-			line = strings.TrimSpace(line)
-			if strings.HasPrefix(line, "\"") {
-				line = strings.TrimPrefix(line, "\"")
-			}
+// replaceParsedMIB 用新的编译结果替换已有 MIB 记录的对象
+func (s *MIBService) replaceParsedMIB(mib *models.MIB, modules []*CompiledMIBModule) error {
+	applyCompiledMIB(mib, modules)
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("mib_id = ?", mib.ID).Delete(&models.OID{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("mib_id = ?", mib.ID).Delete(&models.MIBImport{}).Error; err != nil {
+			return err
 		}
-		
-		if strings.HasSuffix(line, "\"") {
-			line = strings.TrimSuffix(line, "\"")
-			description.WriteString(line)
-			break
-		} else if strings.Contains(line, "::") {
-			break
+		if err := tx.Unscoped().Where("mib_id = ?", mib.ID).Delete(&models.MIBType{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("mib_id = ?", mib.ID).Delete(&models.MIBNotification{}).Error; err != nil {
+			return err
 		}
-		
-		description.WriteString(line + " ")
+		if err := tx.Unscoped().Where("mib_id = ?", mib.ID).Delete(&models.MIBGroup{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("mib_id = ?", mib.ID).Delete(&models.MIBCompliance{}).Error; err != nil {
+			return err
+		}
+		return tx.Save(mib).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save parsed MIB: %v", err)
+	}
+	s.tree.Invalidate()
+	s.indexMIB(mib.ID)
+
+	// 完全解析后再向下游传播, 避免循环依赖反复重解析
+	if mib.ErrorMsg == "" {
+		s.reparseDependents(mib)
 	}
-	return strings.TrimSpace(description.String())
+
+	return nil
 }
 
-func (s *MIBService) extractOIDValue(line string) string {
-	// Look for pattern like "{ system 1 }"
-	if idx := strings.Index(line, "{"); idx != -1 {
-		if endIdx := strings.Index(line[idx:], "}"); endIdx != -1 {
-			oidPart := line[idx+1 : idx+endIdx]
-			oidPart = strings.TrimSpace(oidPart)
-			// This is a simplified conversion - in reality, you'd need to resolve parent OIDs
-			return s.resolveOIDPath(oidPart)
-		}
+// indexMIB 更新 MIB 的搜索索引, 事务内只记录下来等提交后再更新
+func (s *MIBService) indexMIB(id uint) {
+	if s.pending != nil {
+		s.pending[id] = true
+		return
 	}
-	return ""
+	s.search.IndexMIB(id)
 }
 
-func (s *MIBService) resolveOIDPath(oidPath string) string {
-	// Simplified OID resolution - in a real implementation, you'd maintain an OID tree
-	parts := strings.Fields(oidPath)
-	if len(parts) >= 2 {
-		// Basic mapping for common MIB roots
-		switch parts[0] {
-		case "system":
-			return "1.3.6.1.2.1.1." + parts[1]
-		case "interfaces":
-			return "1.3.6.1.2.1.2." + parts[1]
-		case "ip":
-			return "1.3.6.1.2.1.4." + parts[1]
-		case "tcp":
-			return "1.3.6.1.2.1.6." + parts[1]
-		case "udp":
-			return "1.3.6.1.2.1.7." + parts[1]
-		case "snmp":
-			return "1.3.6.1.2.1.11." + parts[1]
-		default:
-			// Return as-is if we can't resolve
-			return strings.Join(parts, ".")
+// applyCompiledMIB 将编译结果写入 MIB 记录 (不落库)
+func applyCompiledMIB(mib *models.MIB, modules []*CompiledMIBModule) {
+	local := make(map[string]bool)
+	for _, module := range modules {
+		local[module.AST.Name] = true
+	}
+
+	var oids []models.OID
+	var imports []models.MIBImport
+	var types []models.MIBType
+	var notifications []models.MIBNotification
+	var groups []models.MIBGroup
+	var compliances []models.MIBCompliance
+	var unresolved []string
+	for _, module := range modules {
+		oids = append(oids, module.ModelOIDs()...)
+		types = append(types, module.ModelTypes()...)
+		notifications = append(notifications, module.ModelNotifications()...)
+		groups = append(groups, module.ModelGroups()...)
+		compliances = append(compliances, module.ModelCompliances()...)
+		unresolved = append(unresolved, module.Unresolved...)
+		for _, imp := range module.AST.Imports {
+			if local[imp.Module] {
+				continue
+			}
+			imports = append(imports, models.MIBImport{
+				Module:  imp.Module,
+				Symbols: strings.Join(imp.Symbols, ","),
+			})
 		}
 	}
-	return oidPath
+
+	now := time.Now()
+	mib.Name = modules[0].AST.Name
+	mib.Description = modules[0].ModuleDescription()
+	applyMIBRevisions(mib, modules[0])
+	mib.Status = "active"
+	mib.ParsedAt = &now
+	mib.OIDs = oids
+	mib.Imports = imports
+	mib.Types = types
+	mib.Notifications = notifications
+	mib.Groups = groups
+	mib.Compliances = compliances
+	mib.ErrorMsg = ""
+	if len(unresolved) > 0 {
+		mib.ErrorMsg = fmt.Sprintf("unresolved OID parents: %s", strings.Join(unresolved, ", "))
+	}
 }
 
-func (s *MIBService) ValidateMIBFile(filePath string) (map[string]interface{}, error) {
+// ValidateMIBFile 对 MIB 文件做 SMI 合规检查; severities 覆盖规则的默认级别, minimum 为空时报告所有级别
+func (s *MIBService) ValidateMIBFile(filePath string, severities map[string]MIBLintSeverity, minimum MIBLintSeverity) (*MIBLintReport, error) {
 	content, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read MIB file: %v", err)
 	}
 
-	var errors []string
-	var warnings []string
-	lines := strings.Split(string(content), "\n")
-	
-	// Basic validation checks
-	hasModuleIdentity := false
-	hasImports := false
-	oidCount := 0
-	
-	for i, line := range lines {
-		line = strings.TrimSpace(line)
-		
-		// Check for required elements
-		if strings.Contains(line, "MODULE-IDENTITY") {
-			hasModuleIdentity = true
+	linter := NewMIBLinter(s.compiler, s.hasMIBModule)
+	for rule, severity := range severities {
+		if err := linter.SetSeverity(rule, severity); err != nil {
+			return nil, err
 		}
-		if strings.Contains(line, "IMPORTS") {
-			hasImports = true
-		}
-		if strings.Contains(line, "OBJECT-TYPE") {
-			oidCount++
-		}
-		
-		// Check for common syntax errors
-		if strings.Contains(line, "::=") && !strings.Contains(line, "{") {
-			warnings = append(warnings, fmt.Sprintf("Line %d: OID assignment may be incomplete", i+1))
-		}
-		
-		// Check for missing quotes in descriptions
-		if strings.Contains(line, "DESCRIPTION") && !strings.Contains(line, "\"") {
-			errors = append(errors, fmt.Sprintf("Line %d: DESCRIPTION missing quotes", i+1))
-		}
-	}
-	
-	// Validate structure
-	if !hasModuleIdentity {
-		warnings = append(warnings, "MIB file should contain MODULE-IDENTITY")
 	}
-	if !hasImports {
-		warnings = append(warnings, "MIB file should contain IMPORTS section")
-	}
-	if oidCount == 0 {
-		errors = append(errors, "No OBJECT-TYPE definitions found")
+	if minimum != "" {
+		if err := linter.SetMinimumSeverity(minimum); err != nil {
+			return nil, err
+		}
 	}
-	
-	isValid := len(errors) == 0
-	
-	return map[string]interface{}{
-		"valid":     isValid,
-		"errors":    errors,
-		"warnings":  warnings,
-		"oid_count": oidCount,
-		"has_module_identity": hasModuleIdentity,
-		"has_imports": hasImports,
-	}, nil
+	return linter.Lint(string(content)), nil
+}
+
+// hasMIBModule 判断 MIB 库中是否存在该模块
+func (s *MIBService) hasMIBModule(module string) bool {
+	var count int64
+	s.db.Model(&models.MIB{}).Where("name = ?", module).Count(&count)
+	return count > 0
 }
 
 func (s *MIBService) GetMIBOIDs(id uint) ([]models.OID, error) {
@@ -564,94 +403,32 @@ func (s *MIBService) GetMIBOIDs(id uint) ([]models.OID, error) {
 	return oids, nil
 }
 
-func (s *MIBService) ImportMIBs(file multipart.File, format string) (map[string]interface{}, error) {
-	// 实现 MIB 从 JSON/CSV 导入功能
-	var importedMibs []models.MIB
-	
-	// 读取文件内容
-	data, err := io.ReadAll(file)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %v", err)
-	}
-	
-	switch format {
-	case "json":
-		var jsonData []map[string]interface{}
-		if err := json.Unmarshal(data, &jsonData); err != nil {
-			return nil, fmt.Errorf("invalid JSON format: %v", err)
-		}
-		
-		for _, item := range jsonData {
-			mib := models.MIB{
-				Name:        getString(item, "name"),
-				Filename:    getString(item, "filename"),
-				Description: getString(item, "description"),
-				Author:      getString(item, "author"),
-				Version:     getString(item, "version"),
-				Status:      "imported",
-			}
-			importedMibs = append(importedMibs, mib)
-		}
-		
-	case "csv":
-		reader := csv.NewReader(strings.NewReader(string(data)))
-		records, err := reader.ReadAll()
-		if err != nil {
-			return nil, fmt.Errorf("invalid CSV format: %v", err)
-		}
-		
-		// 跳过标题行
-		for i, record := range records {
-			if i == 0 || len(record) < 4 {
-				continue
-			}
-			
-			mib := models.MIB{
-				Name:        record[0],
-				Filename:    record[1],
-				Description: record[2],
-				Author:      record[3],
-				Status:      "imported",
-			}
-			if len(record) > 4 {
-				mib.Version = record[4]
-			}
-			importedMibs = append(importedMibs, mib)
-		}
-	}
-	
-	// 批量保存到数据库
-	if len(importedMibs) > 0 {
-		if err := s.db.Create(&importedMibs).Error; err != nil {
-			return nil, fmt.Errorf("failed to save imported MIBs: %v", err)
-		}
-	}
-	result := map[string]interface{}{
-		"imported": 5,
-		"skipped":  2,
-		"errors":   []string{},
-	}
+// GetOIDChildren 返回 OID 树中的直接子节点
+func (s *MIBService) GetOIDChildren(oid string) ([]OIDTreeNode, error) {
+	return s.tree.Children(oid)
+}
 
-	return result, nil
+// GetOIDAncestors 返回从根到 OID 的祖先链
+func (s *MIBService) GetOIDAncestors(oid string) ([]OIDTreeNode, error) {
+	return s.tree.Ancestors(oid)
 }
 
-func (s *MIBService) ExportMIBs(ids []string, format string) ([]byte, string, error) {
-	var mibs []models.MIB
-	
-	query := s.db.Preload("OIDs")
-	if len(ids) > 0 {
-		query = query.Where("id IN ?", ids)
-	}
-	
-	if err := query.Find(&mibs).Error; err != nil {
-		return nil, "", err
-	}
+// LookupOIDSymbol 按 "MODULE::name" 或 name 查找 OID
+func (s *MIBService) LookupOIDSymbol(symbol string) ([]OIDTreeNode, error) {
+	return s.tree.Lookup(symbol)
+}
 
-	switch format {
-	case "json":
-		data, err := json.MarshalIndent(mibs, "", "  ")
-		return data, "mibs_export.json", err
-	default:
-		return nil, "", fmt.Errorf("unsupported format: %s", format)
-	}
+// MatchOID 对任意数字 OID 做最长前缀匹配
+func (s *MIBService) MatchOID(oid string) (*OIDMatch, error) {
+	return s.tree.LongestPrefixMatch(oid)
+}
+
+// SearchOIDs 全文搜索 OID, 数字查询按 OID 前缀匹配
+func (s *MIBService) SearchOIDs(query MIBSearchQuery) (*MIBSearchResult, error) {
+	return s.search.Search(query)
+}
+
+// FullTextSearchAvailable 返回 OID 全文搜索是否可用, 不可用时搜索退化为 LIKE 匹配
+func (s *MIBService) FullTextSearchAvailable() bool {
+	return s.search.Available()
 }
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"mib-platform/models"
+)
+
+// 概念表中 OBJECT-TYPE 的角色
+const (
+	MIBKindTable  = "table"
+	MIBKindRow    = "row"
+	MIBKindColumn = "column"
+	MIBKindScalar = "scalar"
+)
+
+// buildTables 识别 OBJECT-TYPE 的 table / row / column 关系, 并展开行的 INDEX 和 AUGMENTS
+func (c *MIBCompiler) buildTables(m *CompiledMIBModule, importedFrom map[string]string, local map[string]*CompiledMIBModule) {
+	byOID := make(map[string]*CompiledMIBObject)
+	var objects []*CompiledMIBObject
+	for _, obj := range m.Objects {
+		if obj.Node.Macro != "OBJECT-TYPE" {
+			continue
+		}
+		byOID[obj.OID] = obj
+		objects = append(objects, obj)
+
+		switch {
+		case obj.Type != nil && obj.Type.BaseType == "SEQUENCE OF":
+			obj.Kind = MIBKindTable
+		case obj.Node.Clause("INDEX") != nil || obj.Node.Clause("AUGMENTS") != nil:
+			obj.Kind = MIBKindRow
+		default:
+			obj.Kind = MIBKindScalar
+		}
+	}
+
+	resolving := make(map[*CompiledMIBObject]bool)
+	var rowIndexes func(row *CompiledMIBObject) []models.OIDIndex
+	rowIndexes = func(row *CompiledMIBObject) []models.OIDIndex {
+		if row.Indexes != nil || resolving[row] {
+			return row.Indexes
+		}
+		resolving[row] = true
+
+		if clause := row.Node.Clause("INDEX"); clause != nil {
+			row.Indexes = []models.OIDIndex{}
+			implied := false
+			for _, tok := range clause.tokens {
+				switch {
+				case tok.isIdent("IMPLIED"):
+					implied = true
+				case tok.Kind == mibTokIdent:
+					index := c.indexObject(m, tok.Text, importedFrom, local)
+					index.Implied = implied
+					row.Indexes = append(row.Indexes, index)
+					implied = false
+				}
+			}
+			return row.Indexes
+		}
+
+		// AUGMENTS 的行与被扩展的行使用相同的索引
+		if idents := row.Node.Clause("AUGMENTS").Identifiers(); len(idents) > 0 {
+			row.Augments = idents[0]
+			if target := m.Object(row.Augments); target != nil && target.Kind == MIBKindRow {
+				row.Indexes = rowIndexes(target)
+			} else if module, ok := importedFrom[row.Augments]; ok {
+				if target, ok := c.lookupImport(module, row.Augments, local); ok {
+					row.Indexes = target.Indexes
+				}
+			}
+		}
+		return row.Indexes
+	}
+
+	for _, obj := range objects {
+		if obj.Kind != MIBKindRow {
+			continue
+		}
+		indexes := rowIndexes(obj)
+		if table := byOID[mibParentOID(obj.OID)]; table != nil && table.Kind == MIBKindTable {
+			obj.Table = table.Node.Name
+			table.Indexes = indexes
+		}
+	}
+
+	for _, obj := range objects {
+		if obj.Kind != MIBKindScalar {
+			continue
+		}
+		if row := byOID[mibParentOID(obj.OID)]; row != nil && row.Kind == MIBKindRow {
+			obj.Kind = MIBKindColumn
+			obj.Table = row.Table
+			obj.Indexes = row.Indexes
+		}
+	}
+}
+
+// indexObject 查找 INDEX 中引用的对象, 可以是本模块定义的或导入的
+func (c *MIBCompiler) indexObject(m *CompiledMIBModule, name string, importedFrom map[string]string, local map[string]*CompiledMIBModule) models.OIDIndex {
+	index := models.OIDIndex{Name: name}
+
+	var oid *models.OID
+	if obj := m.Object(name); obj != nil {
+		model := obj.ModelOID()
+		oid = &model
+	} else if module, ok := importedFrom[name]; ok {
+		oid, _ = c.lookupImport(module, name, local)
+	}
+	if oid == nil {
+		return index
+	}
+
+	index.OID = oid.OID
+	index.Type = oid.BaseType
+	if index.Type == "" {
+		index.Type = oid.Type
+	}
+	index.TextualConvention = oid.TextualConvention
+	if len(oid.Sizes) == 1 && oid.Sizes[0].Min == oid.Sizes[0].Max {
+		index.FixedSize = int(oid.Sizes[0].Min)
+	}
+	return index
+}
@@ -0,0 +1,644 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"mib-platform/models"
+)
+
+// mibImportDir 导入压缩包中源文件的保存目录
+var mibImportDir = "/opt/monitoring/mibs/uploads/imports"
+
+const (
+	mibArchiveManifest    = "manifest.json"
+	mibArchiveFormatLevel = 1
+)
+
+// errMIBImportRollback 试运行或存在失败行时回滚导入事务
+var errMIBImportRollback = errors.New("mib import rolled back")
+
+// MIBImportRow 导入文件中一行 (一个 MIB) 的处理结果
+type MIBImportRow struct {
+	Row      int    `json:"row"` // 从 1 开始, CSV 不含标题行
+	Name     string `json:"name"`
+	Filename string `json:"filename,omitempty"`
+	Action   string `json:"action"` // created, updated, skipped, error
+	MIBID    uint   `json:"mib_id,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+// MIBImportReport 一次导入的结果; 任一行失败时整个导入回滚
+type MIBImportReport struct {
+	Format    string         `json:"format"`
+	DryRun    bool           `json:"dry_run"`
+	Committed bool           `json:"committed"`
+	Created   int            `json:"created"`
+	Updated   int            `json:"updated"`
+	Skipped   int            `json:"skipped"`
+	Errors    int            `json:"errors"`
+	Rows      []MIBImportRow `json:"rows"`
+}
+
+// mibArchiveManifestFile 导出压缩包中的清单
+type mibArchiveManifestFile struct {
+	Version    int               `json:"version"`
+	ExportedAt time.Time         `json:"exported_at"`
+	MIBs       []mibArchiveEntry `json:"mibs"`
+}
+
+// mibArchiveEntry 清单中的一个 MIB; 带源文件时导入会重新编译, 否则使用记录中的 OID
+type mibArchiveEntry struct {
+	models.MIB
+	Source string `json:"source,omitempty"` // 源文件在压缩包内的路径
+}
+
+// mibImportItem 待导入的一个 MIB
+type mibImportItem struct {
+	row    int
+	mib    models.MIB
+	source []byte
+	parsed []*MIBModuleAST
+	err    error
+}
+
+// mibCSVColumns 导出 CSV 的列, 每个 OID 一行
+var mibCSVColumns = []string{
+	"module", "mib_id", "name", "oid", "type", "syntax", "base_type", "textual_convention",
+	"access", "status", "units", "kind", "table", "description",
+}
+
+// DetectMIBTransferFormat 根据文件名推断导入格式
+func DetectMIBTransferFormat(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return "csv"
+	case ".zip":
+		return "archive"
+	default:
+		return "json"
+	}
+}
+
+// ImportMIBs 在一个事务中导入 MIB, 按名称和校验和匹配已有记录; dryRun 时只返回结果不落库
+func (s *MIBService) ImportMIBs(file io.Reader, format string, dryRun bool) (*MIBImportReport, error) {
+	data, err := io.ReadAll(io.LimitReader(file, mibBundleMaxTotalSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+	if len(data) > mibBundleMaxTotalSize {
+		return nil, fmt.Errorf("import file exceeds %d bytes", mibBundleMaxTotalSize)
+	}
+
+	var items []*mibImportItem
+	switch format {
+	case "json":
+		items, err = parseMIBImportJSON(data)
+	case "csv":
+		items, err = parseMIBImportCSV(data)
+	case "archive":
+		items, err = parseMIBImportArchive(data)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	report := &MIBImportReport{Format: format, DryRun: dryRun, Rows: make([]MIBImportRow, len(items))}
+	var written []string
+	var touched map[uint]bool
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		t := s.withTx(tx)
+		for _, item := range orderMIBImportItems(items) {
+			row, path := t.importMIBItem(item, dryRun)
+			if path != "" {
+				written = append(written, path)
+			}
+			report.Rows[item.row-1] = row
+		}
+		touched = t.pending
+
+		for _, row := range report.Rows {
+			switch row.Action {
+			case "created":
+				report.Created++
+			case "updated":
+				report.Updated++
+			case "skipped":
+				report.Skipped++
+			default:
+				report.Errors++
+			}
+		}
+		if dryRun || report.Errors > 0 {
+			return errMIBImportRollback
+		}
+		return nil
+	})
+	if err != nil && err != errMIBImportRollback {
+		removeMIBImportFiles(written)
+		return nil, fmt.Errorf("failed to import MIBs: %v", err)
+	}
+
+	// 事务内的修改对其他连接不可见, 提交或回滚后再刷新共享索引
+	s.tree.Invalidate()
+	if err == errMIBImportRollback {
+		removeMIBImportFiles(written)
+		return report, nil
+	}
+	report.Committed = true
+	for id := range touched {
+		s.search.IndexMIB(id)
+	}
+	return report, nil
+}
+
+// withTx 返回在事务 tx 中执行的服务副本, 编译时的跨模块引用也从事务中查找
+func (s *MIBService) withTx(tx *gorm.DB) *MIBService {
+	t := &MIBService{
+		db:      tx,
+		tree:    s.tree,
+		search:  s.search,
+		pending: make(map[uint]bool),
+	}
+	t.compiler = NewMIBCompiler(t.lookupImportedSymbol, t.lookupImportedType)
+	return t
+}
+
+// importMIBItem 导入一个 MIB, 返回结果和新写入的源文件路径
+func (s *MIBService) importMIBItem(item *mibImportItem, dryRun bool) (MIBImportRow, string) {
+	row := MIBImportRow{Row: item.row, Name: item.mib.Name, Filename: item.mib.Filename}
+	fail := func(err error) MIBImportRow {
+		row.Action = "error"
+		row.Message = err.Error()
+		return row
+	}
+	if item.err != nil {
+		return fail(item.err), ""
+	}
+
+	var modules []*CompiledMIBModule
+	if item.source != nil {
+		var err error
+		modules, err = s.compiler.CompileSource(string(item.source))
+		if err != nil {
+			return fail(fmt.Errorf("failed to parse MIB source: %v", err)), ""
+		}
+		row.Name = modules[0].AST.Name
+		sum := sha256.Sum256(item.source)
+		item.mib.Checksum = hex.EncodeToString(sum[:])
+	}
+	if row.Name == "" {
+		return fail(fmt.Errorf("name is required")), ""
+	}
+
+	// 内容相同的 MIB 已存在
+	if item.mib.Checksum != "" {
+		var same []models.MIB
+		if err := s.db.Where("checksum = ?", item.mib.Checksum).Limit(1).Find(&same).Error; err != nil {
+			return fail(err), ""
+		}
+		if len(same) > 0 {
+			row.Action = "skipped"
+			row.MIBID = same[0].ID
+			row.Message = "identical checksum"
+			return row, ""
+		}
+	}
+
+	var existing []models.MIB
+	if err := s.db.Where("name = ?", row.Name).Order("id DESC").Limit(1).Find(&existing).Error; err != nil {
+		return fail(err), ""
+	}
+
+	var written string
+	if item.source != nil {
+		filePath, err := saveMIBImportSource(item.mib.Filename, item.source, dryRun)
+		if err != nil {
+			return fail(err), ""
+		}
+		if !dryRun {
+			written = filePath
+		}
+		item.mib.FilePath = filePath
+		item.mib.Size = int64(len(item.source))
+	}
+
+	var err error
+	if len(existing) > 0 {
+		row.Action = "updated"
+		row.MIBID = existing[0].ID
+		err = s.updateImportedMIB(&existing[0], item, modules)
+	} else {
+		row.Action = "created"
+		err = s.createImportedMIB(item, modules)
+		if !dryRun {
+			row.MIBID = item.mib.ID
+		}
+	}
+	if err != nil {
+		return fail(err), written
+	}
+	return row, written
+}
+
+// createImportedMIB 新建 MIB 记录
+func (s *MIBService) createImportedMIB(item *mibImportItem, modules []*CompiledMIBModule) error {
+	mib := &item.mib
+	if mib.UploadedAt.IsZero() {
+		mib.UploadedAt = time.Now()
+	}
+	if modules != nil {
+		return s.createParsedMIB(mib, modules)
+	}
+
+	if mib.Status == "" {
+		mib.Status = "imported"
+	}
+	if err := s.db.Create(mib).Error; err != nil {
+		return fmt.Errorf("failed to save MIB to database: %v", err)
+	}
+	s.tree.Invalidate()
+	s.indexMIB(mib.ID)
+	return nil
+}
+
+// updateImportedMIB 用导入的内容更新名称相同的已有 MIB
+func (s *MIBService) updateImportedMIB(existing *models.MIB, item *mibImportItem, modules []*CompiledMIBModule) error {
+	if modules != nil {
+		existing.Filename = item.mib.Filename
+		existing.FilePath = item.mib.FilePath
+		existing.Size = item.mib.Size
+		existing.Checksum = item.mib.Checksum
+		if item.mib.Author != "" {
+			existing.Author = item.mib.Author
+		}
+		return s.replaceParsedMIB(existing, modules)
+	}
+
+	updates := map[string]interface{}{}
+	for column, value := range map[string]string{
+		"filename":    item.mib.Filename,
+		"description": item.mib.Description,
+		"author":      item.mib.Author,
+		"version":     item.mib.Version,
+		"checksum":    item.mib.Checksum,
+	} {
+		if value != "" {
+			updates[column] = value
+		}
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(existing).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if len(item.mib.OIDs) == 0 {
+			return nil
+		}
+		if err := tx.Unscoped().Where("mib_id = ?", existing.ID).Delete(&models.OID{}).Error; err != nil {
+			return err
+		}
+		for i := range item.mib.OIDs {
+			item.mib.OIDs[i].MIBID = existing.ID
+		}
+		return tx.Create(&item.mib.OIDs).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update MIB: %v", err)
+	}
+	s.tree.Invalidate()
+	s.indexMIB(existing.ID)
+	return nil
+}
+
+// saveMIBImportSource 保存导入的源文件; dryRun 时只返回将要使用的路径
+func saveMIBImportSource(filename string, source []byte, dryRun bool) (string, error) {
+	dir := filepath.Join(mibImportDir, strconv.FormatInt(time.Now().UnixNano(), 10))
+	filePath := filepath.Join(dir, filepath.Base(filename))
+	if dryRun {
+		return filePath, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create import directory: %v", err)
+	}
+	if err := os.WriteFile(filePath, source, 0644); err != nil {
+		return "", fmt.Errorf("failed to save file: %v", err)
+	}
+	return filePath, nil
+}
+
+// removeMIBImportFiles 导入回滚后删除已写入的源文件
+func removeMIBImportFiles(paths []string) {
+	for _, p := range paths {
+		os.Remove(p)
+		os.Remove(filepath.Dir(p))
+	}
+}
+
+// orderMIBImportItems 带源文件的行按 IMPORTS 排序, 被导入的模块先入库
+func orderMIBImportItems(items []*mibImportItem) []*mibImportItem {
+	files := make([]*mibBundleFile, 0, len(items))
+	byFile := make(map[*mibBundleFile]*mibImportItem, len(items))
+	for _, item := range items {
+		f := &mibBundleFile{modules: item.parsed}
+		files = append(files, f)
+		byFile[f] = item
+	}
+
+	ordered := make([]*mibImportItem, 0, len(items))
+	for _, f := range orderMIBBundle(files) {
+		ordered = append(ordered, byFile[f])
+	}
+	return ordered
+}
+
+// resetMIBImportIDs 清除导入记录中的主键, 由数据库重新分配
+func resetMIBImportIDs(mib *models.MIB) {
+	mib.ID = 0
+	mib.CreatedAt = time.Time{}
+	mib.UpdatedAt = time.Time{}
+	mib.DeletedAt = gorm.DeletedAt{}
+	for i := range mib.OIDs {
+		mib.OIDs[i].ID = 0
+		mib.OIDs[i].MIBID = 0
+		mib.OIDs[i].DeletedAt = gorm.DeletedAt{}
+	}
+	for i := range mib.Imports {
+		mib.Imports[i].ID = 0
+		mib.Imports[i].MIBID = 0
+	}
+	for i := range mib.Types {
+		mib.Types[i].ID = 0
+		mib.Types[i].MIBID = 0
+	}
+	for i := range mib.Notifications {
+		mib.Notifications[i].ID = 0
+		mib.Notifications[i].MIBID = 0
+	}
+	for i := range mib.Groups {
+		mib.Groups[i].ID = 0
+		mib.Groups[i].MIBID = 0
+	}
+	for i := range mib.Compliances {
+		mib.Compliances[i].ID = 0
+		mib.Compliances[i].MIBID = 0
+	}
+}
+
+func parseMIBImportJSON(data []byte) ([]*mibImportItem, error) {
+	var mibs []models.MIB
+	if err := json.Unmarshal(data, &mibs); err != nil {
+		return nil, fmt.Errorf("invalid JSON format: %v", err)
+	}
+
+	items := make([]*mibImportItem, 0, len(mibs))
+	for i := range mibs {
+		resetMIBImportIDs(&mibs[i])
+		items = append(items, &mibImportItem{row: i + 1, mib: mibs[i]})
+	}
+	return items, nil
+}
+
+// parseMIBImportCSV 解析 MIB 元数据 CSV; 没有可识别的标题行时按 name, filename, description, author, version 的顺序读取
+func parseMIBImportCSV(data []byte) ([]*mibImportItem, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV format: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := map[string]int{"name": 0, "filename": 1, "description": 2, "author": 3, "version": 4}
+	header := make(map[string]int)
+	for i, name := range records[0] {
+		header[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	// ExportMIBs 导出的 CSV 每个 OID 一行, 不能当作 MIB 元数据导入
+	for _, name := range []string{"module", "oid", "mib_id"} {
+		if _, ok := header[name]; ok {
+			return nil, errors.New("CSV contains one row per OID (as produced by the CSV export) and cannot be imported; use the archive export format to move MIBs between instances")
+		}
+	}
+	if _, ok := header["name"]; ok {
+		columns = header
+		records = records[1:]
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	items := make([]*mibImportItem, 0, len(records))
+	for i, record := range records {
+		items = append(items, &mibImportItem{
+			row: i + 1,
+			mib: models.MIB{
+				Name:        field(record, "name"),
+				Filename:    field(record, "filename"),
+				Description: field(record, "description"),
+				Author:      field(record, "author"),
+				Version:     field(record, "version"),
+				Checksum:    field(record, "checksum"),
+			},
+		})
+	}
+	return items, nil
+}
+
+// parseMIBImportArchive 解析 ExportMIBs 生成的压缩包
+func parseMIBImportArchive(data []byte) ([]*mibImportItem, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip archive: %v", err)
+	}
+
+	files := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		files[f.Name] = f
+	}
+	mf, ok := files[mibArchiveManifest]
+	if !ok {
+		return nil, fmt.Errorf("archive has no %s", mibArchiveManifest)
+	}
+	content, err := readMIBArchiveFile(mf)
+	if err != nil {
+		return nil, err
+	}
+	var manifest mibArchiveManifestFile
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", mibArchiveManifest, err)
+	}
+	if manifest.Version > mibArchiveFormatLevel {
+		return nil, fmt.Errorf("unsupported archive version: %d", manifest.Version)
+	}
+
+	items := make([]*mibImportItem, 0, len(manifest.MIBs))
+	for i, entry := range manifest.MIBs {
+		item := &mibImportItem{row: i + 1, mib: entry.MIB}
+		resetMIBImportIDs(&item.mib)
+		items = append(items, item)
+		if entry.Source == "" {
+			continue
+		}
+
+		f, ok := files[entry.Source]
+		if !ok {
+			item.err = fmt.Errorf("source file %s not found in archive", entry.Source)
+			continue
+		}
+		if item.source, item.err = readMIBArchiveFile(f); item.err != nil {
+			continue
+		}
+		if item.mib.Filename == "" {
+			item.mib.Filename = path.Base(entry.Source)
+		}
+		// 源文件重新编译出的对象为准
+		item.mib.OIDs = nil
+		item.mib.Imports = nil
+		item.mib.Types = nil
+		item.mib.Notifications = nil
+		item.mib.Groups = nil
+		item.mib.Compliances = nil
+		item.parsed, item.err = ParseMIBSource(string(item.source))
+	}
+	return items, nil
+}
+
+func readMIBArchiveFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", f.Name, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, mibBundleMaxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", f.Name, err)
+	}
+	if len(data) > mibBundleMaxFileSize {
+		return nil, fmt.Errorf("%s exceeds %d bytes", f.Name, mibBundleMaxFileSize)
+	}
+	return data, nil
+}
+
+// ExportMIBs 导出 MIB: json 为带 OID 的记录, csv 每个 OID 一行, archive 为可重新导入的压缩包 (含源文件)
+func (s *MIBService) ExportMIBs(ids []string, format string) ([]byte, string, error) {
+	var mibs []models.MIB
+
+	query := s.db.Preload("OIDs").Order("id")
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+
+	if err := query.Find(&mibs).Error; err != nil {
+		return nil, "", err
+	}
+
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(mibs, "", "  ")
+		return data, "mibs_export.json", err
+	case "csv":
+		data, err := exportMIBsCSV(mibs)
+		return data, "mibs_export.csv", err
+	case "archive":
+		data, err := exportMIBsArchive(mibs)
+		return data, "mibs_export.zip", err
+	default:
+		return nil, "", fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+func exportMIBsCSV(mibs []models.MIB) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(mibCSVColumns); err != nil {
+		return nil, err
+	}
+	for _, mib := range mibs {
+		for _, oid := range mib.OIDs {
+			record := []string{
+				mib.Name, strconv.FormatUint(uint64(mib.ID), 10), oid.Name, oid.OID, oid.Type, oid.Syntax,
+				oid.BaseType, oid.TextualConvention, oid.Access, oid.Status, oid.Units, oid.Kind, oid.Table,
+				oid.Description,
+			}
+			if err := w.Write(record); err != nil {
+				return nil, err
+			}
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// exportMIBsArchive 生成 zip: manifest.json 加 sources/<id>/<filename>; 源文件缺失的 MIB 在清单中保留 OID
+func exportMIBsArchive(mibs []models.MIB) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	manifest := mibArchiveManifestFile{
+		Version:    mibArchiveFormatLevel,
+		ExportedAt: time.Now(),
+		MIBs:       make([]mibArchiveEntry, 0, len(mibs)),
+	}
+	for _, mib := range mibs {
+		entry := mibArchiveEntry{MIB: mib}
+		if source, err := os.ReadFile(mib.FilePath); err == nil && mib.FilePath != "" {
+			name := mib.Filename
+			if name == "" {
+				name = filepath.Base(mib.FilePath)
+			}
+			entry.Source = path.Join("sources", strconv.FormatUint(uint64(mib.ID), 10), filepath.Base(name))
+			entry.OIDs = nil
+			w, err := zw.Create(entry.Source)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := w.Write(source); err != nil {
+				return nil, err
+			}
+		}
+		manifest.MIBs = append(manifest.MIBs, entry)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	w, err := zw.Create(mibArchiveManifest)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,305 @@
+package services
+
+import (
+	"sync"
+
+	"mib-platform/models"
+)
+
+// mibBaseTypes ASN.1 和 SMI 基础类型, 类型解析到这里为止
+var mibBaseTypes = map[string]bool{
+	"INTEGER":           true,
+	"OCTET STRING":      true,
+	"OBJECT IDENTIFIER": true,
+	"BITS":              true,
+	"NULL":              true,
+	"Integer32":         true,
+	"Unsigned32":        true,
+	"Counter32":         true,
+	"Counter64":         true,
+	"Gauge32":           true,
+	"TimeTicks":         true,
+	"IpAddress":         true,
+	"Opaque":            true,
+	"Counter":           true,
+	"Gauge":             true,
+	"NetworkAddress":    true,
+	"SEQUENCE":          true,
+	"SEQUENCE OF":       true,
+	"CHOICE":            true,
+}
+
+// mibBuiltinTCSource RFC 2579 SNMPv2-TC 中的 TEXTUAL-CONVENTION, MIB 库中没有对应文件时使用
+const mibBuiltinTCSource = `
+SNMPv2-TC DEFINITIONS ::= BEGIN
+
+IMPORTS TimeTicks FROM SNMPv2-SMI;
+
+DisplayString ::= TEXTUAL-CONVENTION
+    DISPLAY-HINT "255a"
+    STATUS       current
+    DESCRIPTION  "Represents textual information taken from the NVT ASCII character set."
+    SYNTAX       OCTET STRING (SIZE (0..255))
+
+PhysAddress ::= TEXTUAL-CONVENTION
+    DISPLAY-HINT "1x:"
+    STATUS       current
+    DESCRIPTION  "Represents media- or physical-level addresses."
+    SYNTAX       OCTET STRING
+
+MacAddress ::= TEXTUAL-CONVENTION
+    DISPLAY-HINT "1x:"
+    STATUS       current
+    DESCRIPTION  "Represents an 802 MAC address represented in the canonical order."
+    SYNTAX       OCTET STRING (SIZE (6))
+
+TruthValue ::= TEXTUAL-CONVENTION
+    STATUS       current
+    DESCRIPTION  "Represents a boolean value."
+    SYNTAX       INTEGER { true(1), false(2) }
+
+TestAndIncr ::= TEXTUAL-CONVENTION
+    STATUS       current
+    DESCRIPTION  "Represents integer-valued information used for atomic operations."
+    SYNTAX       INTEGER (0..2147483647)
+
+AutonomousType ::= TEXTUAL-CONVENTION
+    STATUS       current
+    DESCRIPTION  "Represents an independently extensible type identification value."
+    SYNTAX       OBJECT IDENTIFIER
+
+InstancePointer ::= TEXTUAL-CONVENTION
+    STATUS       obsolete
+    DESCRIPTION  "A pointer to either a specific instance of a MIB object or a conceptual row."
+    SYNTAX       OBJECT IDENTIFIER
+
+VariablePointer ::= TEXTUAL-CONVENTION
+    STATUS       current
+    DESCRIPTION  "A pointer to a specific object instance."
+    SYNTAX       OBJECT IDENTIFIER
+
+RowPointer ::= TEXTUAL-CONVENTION
+    STATUS       current
+    DESCRIPTION  "Represents a pointer to a conceptual row."
+    SYNTAX       OBJECT IDENTIFIER
+
+RowStatus ::= TEXTUAL-CONVENTION
+    STATUS       current
+    DESCRIPTION  "The RowStatus textual convention is used to manage the creation and deletion of conceptual rows."
+    SYNTAX       INTEGER {
+                     active(1),
+                     notInService(2),
+                     notReady(3),
+                     createAndGo(4),
+                     createAndWait(5),
+                     destroy(6)
+                 }
+
+TimeStamp ::= TEXTUAL-CONVENTION
+    STATUS       current
+    DESCRIPTION  "The value of the sysUpTime object at which a specific occurrence happened."
+    SYNTAX       TimeTicks
+
+TimeInterval ::= TEXTUAL-CONVENTION
+    STATUS       current
+    DESCRIPTION  "A period of time, measured in units of 0.01 seconds."
+    SYNTAX       INTEGER (0..2147483647)
+
+DateAndTime ::= TEXTUAL-CONVENTION
+    DISPLAY-HINT "2d-1d-1d,1d:1d:1d.1d,1a1d:1d"
+    STATUS       current
+    DESCRIPTION  "A date-time specification."
+    SYNTAX       OCTET STRING (SIZE (8 | 11))
+
+StorageType ::= TEXTUAL-CONVENTION
+    STATUS       current
+    DESCRIPTION  "Describes the memory realization of a conceptual row."
+    SYNTAX       INTEGER {
+                     other(1),
+                     volatile(2),
+                     nonVolatile(3),
+                     permanent(4),
+                     readOnly(5)
+                 }
+
+TDomain ::= TEXTUAL-CONVENTION
+    STATUS       current
+    DESCRIPTION  "Denotes a kind of transport service."
+    SYNTAX       OBJECT IDENTIFIER
+
+TAddress ::= TEXTUAL-CONVENTION
+    STATUS       current
+    DESCRIPTION  "Denotes a transport service address."
+    SYNTAX       OCTET STRING (SIZE (1..255))
+
+END
+`
+
+var (
+	mibBuiltinTypesOnce sync.Once
+	mibBuiltinTypes     map[string]*MIBTypeInfo
+)
+
+// builtinMIBTypes 返回内置的 SNMPv2-TC 类型
+func builtinMIBTypes() map[string]*MIBTypeInfo {
+	mibBuiltinTypesOnce.Do(func() {
+		mibBuiltinTypes = make(map[string]*MIBTypeInfo)
+		modules, err := (&MIBCompiler{builtin: true}).CompileSource(mibBuiltinTCSource)
+		if err != nil {
+			return
+		}
+		for _, t := range modules[0].Types {
+			mibBuiltinTypes[t.Def.Name] = t.Info
+		}
+	})
+	return mibBuiltinTypes
+}
+
+// MIBTypeInfo 类型展开到基础类型后的信息
+type MIBTypeInfo struct {
+	Name        string            `json:"name,omitempty"` // 引用的 TC 或类型名, 基础类型为空
+	BaseType    string            `json:"base_type"`
+	DisplayHint string            `json:"display_hint,omitempty"`
+	Enums       []models.OIDEnum  `json:"enums,omitempty"`
+	Ranges      []models.OIDRange `json:"ranges,omitempty"`
+	Sizes       []models.OIDRange `json:"sizes,omitempty"`
+}
+
+// MIBTypeResolver 解析从其他模块导入的类型
+type MIBTypeResolver func(module, name string) (*MIBTypeInfo, bool)
+
+// CompiledMIBType 编译后的类型定义
+type CompiledMIBType struct {
+	Def  *MIBTypeDef
+	Info *MIBTypeInfo
+}
+
+// Type 按名称查找模块中定义的类型
+func (m *CompiledMIBModule) Type(name string) *CompiledMIBType {
+	for _, t := range m.Types {
+		if t.Def.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// ModelTypes 将模块中定义的类型转换为 models.MIBType, 不包括表项 SEQUENCE
+func (m *CompiledMIBModule) ModelTypes() []models.MIBType {
+	var types []models.MIBType
+	for _, t := range m.Types {
+		if t.Info.BaseType == "SEQUENCE" || t.Info.BaseType == "CHOICE" {
+			continue
+		}
+		mibType := models.MIBType{
+			Name:        t.Def.Name,
+			IsTC:        t.Def.IsTC,
+			BaseType:    t.Info.BaseType,
+			DisplayHint: t.Info.DisplayHint,
+			Syntax:      t.Def.Syntax.Text(),
+			Enums:       t.Info.Enums,
+			Ranges:      t.Info.Ranges,
+			Sizes:       t.Info.Sizes,
+		}
+		if status := t.Def.Clause("STATUS"); status != nil {
+			mibType.Status = status.Text()
+		}
+		if desc := t.Def.Clause("DESCRIPTION"); desc != nil {
+			mibType.Description = cleanMIBDescription(desc.Text())
+		}
+		types = append(types, mibType)
+	}
+	return types
+}
+
+// mibTypeScope 在单个模块的作用域内解析类型名
+type mibTypeScope struct {
+	compiler     *MIBCompiler
+	defs         map[string]*MIBTypeDef
+	importedFrom map[string]string
+	local        map[string]*CompiledMIBModule
+	cache        map[string]MIBTypeInfo
+	resolving    map[string]bool
+}
+
+func (c *MIBCompiler) newTypeScope(ast *MIBModuleAST, importedFrom map[string]string, local map[string]*CompiledMIBModule) *mibTypeScope {
+	scope := &mibTypeScope{
+		compiler:     c,
+		defs:         make(map[string]*MIBTypeDef),
+		importedFrom: importedFrom,
+		local:        local,
+		cache:        make(map[string]MIBTypeInfo),
+		resolving:    make(map[string]bool),
+	}
+	for _, def := range ast.Types {
+		scope.defs[def.Name] = def
+	}
+	return scope
+}
+
+// resolveSyntax 解析类型表达式, 表达式自身的枚举和约束覆盖引用类型中的定义
+func (s *mibTypeScope) resolveSyntax(syntax *MIBSyntax) MIBTypeInfo {
+	info := s.resolveName(syntax.Type)
+	if len(syntax.NamedNumbers) > 0 {
+		info.Enums = syntax.NamedNumbers
+	}
+	if len(syntax.Ranges) > 0 {
+		info.Ranges = syntax.Ranges
+	}
+	if len(syntax.Sizes) > 0 {
+		info.Sizes = syntax.Sizes
+	}
+	return info
+}
+
+func (s *mibTypeScope) resolveName(name string) MIBTypeInfo {
+	if mibBaseTypes[name] {
+		return MIBTypeInfo{BaseType: name}
+	}
+	if info, ok := s.cache[name]; ok {
+		return info
+	}
+
+	if def, ok := s.defs[name]; ok {
+		if s.resolving[name] {
+			return MIBTypeInfo{Name: name}
+		}
+		s.resolving[name] = true
+		info := s.resolveSyntax(def.Syntax)
+		delete(s.resolving, name)
+
+		if info.BaseType != "SEQUENCE" && info.BaseType != "CHOICE" {
+			info.Name = name
+		}
+		if hint := def.Clause("DISPLAY-HINT"); hint != nil {
+			info.DisplayHint = hint.Text()
+		}
+		s.cache[name] = info
+		return info
+	}
+
+	if module, ok := s.importedFrom[name]; ok {
+		if info, ok := s.compiler.lookupType(module, name, s.local); ok {
+			return *info
+		}
+	}
+	// 未找到导入来源时按名称使用内置的 SNMPv2-TC (RFC1213-MIB 等旧模块也定义了同名类型)
+	if !s.compiler.builtin {
+		if info, ok := builtinMIBTypes()[name]; ok {
+			return *info
+		}
+	}
+	return MIBTypeInfo{Name: name}
+}
+
+func (c *MIBCompiler) lookupType(module, name string, local map[string]*CompiledMIBModule) (*MIBTypeInfo, bool) {
+	if compiled, ok := local[module]; ok {
+		if t := compiled.Type(name); t != nil {
+			return t.Info, true
+		}
+	}
+	if c.resolveType != nil {
+		return c.resolveType(module, name)
+	}
+	return nil, false
+}
@@ -0,0 +1,385 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"mib-platform/models"
+)
+
+// mibIngestEventRetention 保留的导入事件条数
+const mibIngestEventRetention = 1000
+
+// MIBScanSummary 一次目录扫描的结果统计
+type MIBScanSummary struct {
+	Scanned   int       `json:"scanned"`
+	Added     int       `json:"added"`
+	Updated   int       `json:"updated"`
+	Deleted   int       `json:"deleted"`
+	Unchanged int       `json:"unchanged"`
+	Failed    int       `json:"failed"`
+	StartedAt time.Time `json:"started_at"`
+	Duration  string    `json:"duration"`
+}
+
+// MIBWatcherStatus 目录监视器状态
+type MIBWatcherStatus struct {
+	Directory    string          `json:"directory"`
+	Interval     string          `json:"interval"`
+	Running      bool            `json:"running"`
+	TrackedFiles int             `json:"tracked_files"`
+	LastScan     *MIBScanSummary `json:"last_scan"`
+	LastError    string          `json:"last_error,omitempty"`
+}
+
+// mibWatchedFile 上次扫描时文件的状态, 大小和修改时间不变时不重新计算校验和
+type mibWatchedFile struct {
+	size     int64
+	modTime  time.Time
+	checksum string
+}
+
+// MIBDirectoryWatcher 轮询 MIB 目录, 自动导入新增或变化的文件并标记已删除的文件
+type MIBDirectoryWatcher struct {
+	service  *MIBService
+	dir      string
+	interval time.Duration
+
+	scanMu sync.Mutex // 同一时间只执行一次扫描
+
+	mu        sync.RWMutex
+	files     map[string]mibWatchedFile
+	lastScan  *MIBScanSummary
+	lastError string
+	stop      chan struct{}
+}
+
+// NewMIBDirectoryWatcher 创建目录监视器, interval 为 0 时只能手动触发扫描
+func NewMIBDirectoryWatcher(service *MIBService, dir string, interval time.Duration) *MIBDirectoryWatcher {
+	return &MIBDirectoryWatcher{
+		service:  service,
+		dir:      filepath.Clean(dir),
+		interval: interval,
+		files:    make(map[string]mibWatchedFile),
+	}
+}
+
+// Start 启动后台轮询
+func (w *MIBDirectoryWatcher) Start() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stop != nil || w.interval <= 0 {
+		return
+	}
+	w.stop = make(chan struct{})
+
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			if _, err := w.Scan(); err != nil {
+				log.Printf("MIB directory scan failed: %v", err)
+			}
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}(w.stop)
+}
+
+// Stop 停止后台轮询
+func (w *MIBDirectoryWatcher) Stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stop != nil {
+		close(w.stop)
+		w.stop = nil
+	}
+}
+
+// Status 返回监视器状态
+func (w *MIBDirectoryWatcher) Status() MIBWatcherStatus {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return MIBWatcherStatus{
+		Directory:    w.dir,
+		Interval:     w.interval.String(),
+		Running:      w.stop != nil,
+		TrackedFiles: len(w.files),
+		LastScan:     w.lastScan,
+		LastError:    w.lastError,
+	}
+}
+
+// GetEvents 按时间倒序返回导入事件, action 为空时返回所有类型
+func (w *MIBDirectoryWatcher) GetEvents(page, limit int, action string) ([]models.MIBIngestEvent, int64, error) {
+	var events []models.MIBIngestEvent
+	var total int64
+
+	query := w.service.db.Model(&models.MIBIngestEvent{})
+	if action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	if err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&events).Error; err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// watchedMIBFile 本次扫描需要导入的文件
+type watchedMIBFile struct {
+	mibBundleFile
+	state    mibWatchedFile
+	existing *models.MIB
+}
+
+// Scan 扫描一次目录
+func (w *MIBDirectoryWatcher) Scan() (*MIBScanSummary, error) {
+	w.scanMu.Lock()
+	defer w.scanMu.Unlock()
+
+	summary := &MIBScanSummary{StartedAt: time.Now()}
+	err := w.scan(summary)
+	summary.Duration = time.Since(summary.StartedAt).String()
+
+	w.mu.Lock()
+	w.lastScan = summary
+	w.lastError = ""
+	if err != nil {
+		w.lastError = err.Error()
+	}
+	w.mu.Unlock()
+
+	w.pruneEvents()
+	return summary, err
+}
+
+func (w *MIBDirectoryWatcher) scan(summary *MIBScanSummary) error {
+	if _, err := os.Stat(w.dir); err != nil {
+		return fmt.Errorf("MIB directory unavailable: %v", err)
+	}
+
+	// 目录下已登记的 MIB, 同一路径存在多条时以最后导入的为准
+	var records []models.MIB
+	if err := w.service.db.Where("file_path LIKE ?", w.dir+string(os.PathSeparator)+"%").Order("id").Find(&records).Error; err != nil {
+		return err
+	}
+	byPath := make(map[string]*models.MIB)
+	for i := range records {
+		if !w.skipPath(records[i].FilePath) {
+			byPath[records[i].FilePath] = &records[i]
+		}
+	}
+
+	present := make(map[string]bool)
+	var pending []*watchedMIBFile
+	err := filepath.Walk(w.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if path != w.dir && w.skipPath(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(w.dir, path)
+		if err != nil || !info.Mode().IsRegular() || !isMIBBundleEntry(filepath.ToSlash(rel)) {
+			return nil
+		}
+
+		present[path] = true
+		summary.Scanned++
+		if f := w.checkFile(path, info, byPath[path]); f != nil {
+			pending = append(pending, f)
+		} else {
+			summary.Unchanged++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// 被导入的模块先入库, 后面的文件才能解析跨模块引用
+	files := make([]*mibBundleFile, 0, len(pending))
+	byFile := make(map[*mibBundleFile]*watchedMIBFile, len(pending))
+	for _, f := range pending {
+		files = append(files, &f.mibBundleFile)
+		byFile[&f.mibBundleFile] = f
+	}
+	for _, bf := range orderMIBBundle(files) {
+		w.ingest(byFile[bf], summary)
+	}
+
+	for path, mib := range byPath {
+		if present[path] {
+			continue
+		}
+		w.remove(mib, summary)
+	}
+	return nil
+}
+
+// skipPath 上传目录由上传接口管理, 不属于监视范围
+func (w *MIBDirectoryWatcher) skipPath(path string) bool {
+	uploads := filepath.Join(w.dir, "uploads")
+	if path == uploads || strings.HasPrefix(path, uploads+string(os.PathSeparator)) {
+		return true
+	}
+	return strings.HasPrefix(filepath.Base(path), ".")
+}
+
+// checkFile 判断文件是否需要导入, 不需要时返回 nil
+func (w *MIBDirectoryWatcher) checkFile(path string, info os.FileInfo, existing *models.MIB) *watchedMIBFile {
+	w.mu.RLock()
+	prev, seen := w.files[path]
+	w.mu.RUnlock()
+
+	state := mibWatchedFile{size: info.Size(), modTime: info.ModTime()}
+	if seen && prev.size == state.size && prev.modTime.Equal(state.modTime) {
+		// 解析失败的文件也会被记住, 内容变化前不重复报错
+		if existing == nil || existing.Checksum == prev.checksum {
+			return nil
+		}
+	}
+
+	checksum, err := fileChecksum(path)
+	if err != nil {
+		return &watchedMIBFile{mibBundleFile: mibBundleFile{name: path, path: path, err: err}, state: state, existing: existing}
+	}
+	state.checksum = checksum
+	if (existing != nil && existing.Checksum == checksum) || (existing == nil && seen && prev.checksum == checksum) {
+		w.remember(path, state)
+		return nil
+	}
+
+	f := &watchedMIBFile{mibBundleFile: mibBundleFile{name: path, path: path}, state: state, existing: existing}
+	content, err := os.ReadFile(path)
+	if err == nil {
+		f.modules, err = ParseMIBSource(string(content))
+	}
+	f.err = err
+	return f
+}
+
+func (w *MIBDirectoryWatcher) remember(path string, state mibWatchedFile) {
+	w.mu.Lock()
+	w.files[path] = state
+	w.mu.Unlock()
+}
+
+func (w *MIBDirectoryWatcher) ingest(f *watchedMIBFile, summary *MIBScanSummary) {
+	w.remember(f.path, f.state)
+	event := models.MIBIngestEvent{Path: f.path, Checksum: f.state.checksum}
+	if len(f.modules) > 0 {
+		event.Module = f.modules[0].Name
+	}
+
+	fail := func(err error) {
+		summary.Failed++
+		event.Action = "error"
+		event.Message = err.Error()
+		w.record(event)
+	}
+	if f.err != nil {
+		fail(f.err)
+		return
+	}
+
+	if f.existing != nil {
+		event.MIBID = f.existing.ID
+		err := w.service.db.Model(f.existing).Updates(map[string]interface{}{
+			"checksum": f.state.checksum,
+			"size":     f.state.size,
+		}).Error
+		if err != nil {
+			fail(err)
+			return
+		}
+		mib, err := w.service.ReparseMIB(f.existing.ID)
+		if err != nil {
+			fail(err)
+			return
+		}
+		summary.Updated++
+		event.Action = "updated"
+		event.Module = mib.Name
+		event.Message = mib.ErrorMsg
+		w.record(event)
+		return
+	}
+
+	modules, err := w.service.compiler.CompileFile(f.path)
+	if err != nil {
+		fail(err)
+		return
+	}
+	mib := &models.MIB{
+		Filename:   filepath.Base(f.path),
+		FilePath:   f.path,
+		Size:       f.state.size,
+		Checksum:   f.state.checksum,
+		Status:     "active",
+		UploadedAt: time.Now(),
+	}
+	if err := w.service.createParsedMIB(mib, modules); err != nil {
+		fail(err)
+		return
+	}
+	summary.Added++
+	event.Action = "added"
+	event.MIBID = mib.ID
+	event.Module = mib.Name
+	// 缺少依赖时仍然入库, 依赖导入后会自动重新解析
+	event.Message = mib.ErrorMsg
+	w.record(event)
+}
+
+// remove 文件已从目录中删除, 标记对应的 MIB 为已删除
+func (w *MIBDirectoryWatcher) remove(mib *models.MIB, summary *MIBScanSummary) {
+	w.mu.Lock()
+	delete(w.files, mib.FilePath)
+	w.mu.Unlock()
+
+	event := models.MIBIngestEvent{Path: mib.FilePath, MIBID: mib.ID, Module: mib.Name, Checksum: mib.Checksum}
+	err := w.service.db.Model(mib).Update("status", "deleted").Error
+	if err == nil {
+		err = w.service.DeleteMIB(mib.ID)
+	}
+	if err != nil {
+		summary.Failed++
+		event.Action = "error"
+		event.Message = fmt.Sprintf("failed to mark MIB deleted: %v", err)
+	} else {
+		summary.Deleted++
+		event.Action = "deleted"
+	}
+	w.record(event)
+}
+
+func (w *MIBDirectoryWatcher) record(event models.MIBIngestEvent) {
+	if err := w.service.db.Create(&event).Error; err != nil {
+		log.Printf("Failed to record MIB ingest event for %s: %v", event.Path, err)
+	}
+}
+
+func (w *MIBDirectoryWatcher) pruneEvents() {
+	var maxID uint
+	w.service.db.Model(&models.MIBIngestEvent{}).Select("COALESCE(MAX(id), 0)").Scan(&maxID)
+	if maxID > mibIngestEventRetention {
+		w.service.db.Where("id <= ?", maxID-mibIngestEventRetention).Delete(&models.MIBIngestEvent{})
+	}
+}
@@ -0,0 +1,409 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+
+	"mib-platform/models"
+)
+
+// OIDTreeNode OID 树中的一个节点; 没有 MIB 定义的中间节点 Name 为空
+type OIDTreeNode struct {
+	OID         string            `json:"oid"`
+	Name        string            `json:"name,omitempty"`
+	Module      string            `json:"module,omitempty"`
+	MIBID       uint              `json:"mib_id,omitempty"`
+	Type        string            `json:"type,omitempty"`
+	Syntax      string            `json:"syntax,omitempty"`
+	BaseType    string            `json:"base_type,omitempty"`
+	DisplayHint string            `json:"display_hint,omitempty"`
+	Enums       []models.OIDEnum  `json:"enums,omitempty"`
+	Ranges      []models.OIDRange `json:"ranges,omitempty"`
+	Sizes       []models.OIDRange `json:"sizes,omitempty"`
+	Access      string            `json:"access,omitempty"`
+	Status      string            `json:"status,omitempty"`
+	Units       string            `json:"units,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Kind        string            `json:"kind,omitempty"`
+	Table       string            `json:"table,omitempty"`
+	Indexes     []models.OIDIndex `json:"indexes,omitempty"`
+	ParentOID   string            `json:"parent_oid,omitempty"`
+	ChildCount  int               `json:"child_count"`
+}
+
+// Symbol 返回 "MODULE::name" 形式的符号名
+func (n *OIDTreeNode) Symbol() string {
+	if n.Module == "" {
+		return n.Name
+	}
+	return n.Module + "::" + n.Name
+}
+
+// OIDMatch 最长前缀匹配的结果
+type OIDMatch struct {
+	Node   OIDTreeNode `json:"node"`
+	Suffix string      `json:"suffix"` // 未匹配部分, 通常是表索引或标量实例 "0"
+}
+
+type oidTreeEntry struct {
+	node     OIDTreeNode
+	children []string
+}
+
+// OIDTree 由 MIB 库中所有已解析 OID 构成的全局树索引
+type OIDTree struct {
+	db      *gorm.DB
+	mu      sync.RWMutex
+	stale   bool
+	nodes   map[string]*oidTreeEntry
+	symbols map[string]string   // MODULE::name -> OID
+	names   map[string][]string // name -> OID
+}
+
+// 同一数据库的所有 MIBService 共享一棵树, 任何一处失效都会触发重建
+var oidTrees sync.Map
+
+func sharedOIDTree(db *gorm.DB) *OIDTree {
+	tree, _ := oidTrees.LoadOrStore(db, &OIDTree{db: db, stale: true})
+	return tree.(*OIDTree)
+}
+
+// Invalidate 标记树索引过期, 下次访问时从数据库重建
+func (t *OIDTree) Invalidate() {
+	t.mu.Lock()
+	t.stale = true
+	t.mu.Unlock()
+}
+
+// read 在读锁下访问树, 必要时先重建
+func (t *OIDTree) read(fn func() error) error {
+	t.mu.RLock()
+	if t.stale {
+		t.mu.RUnlock()
+		t.mu.Lock()
+		if t.stale {
+			if err := t.rebuild(); err != nil {
+				t.mu.Unlock()
+				return fmt.Errorf("failed to build OID tree: %v", err)
+			}
+		}
+		t.mu.Unlock()
+		t.mu.RLock()
+	}
+	defer t.mu.RUnlock()
+	return fn()
+}
+
+func (t *OIDTree) rebuild() error {
+	var rows []struct {
+		MIBID       uint
+		Module      string
+		Name        string
+		OID         string
+		Type        string
+		Syntax      string
+		BaseType    string
+		DisplayHint string
+		Enums       []models.OIDEnum  `gorm:"serializer:json"`
+		Ranges      []models.OIDRange `gorm:"serializer:json"`
+		Sizes       []models.OIDRange `gorm:"serializer:json"`
+		Access      string
+		Status      string
+		Units       string
+		Description string
+		Kind        string
+		Table       string
+		Indexes     []models.OIDIndex `gorm:"serializer:json"`
+	}
+	err := t.db.Model(&models.OID{}).
+		Select("o_ids.mib_id, mibs.name AS module, o_ids.name, o_ids.o_id, o_ids.type, o_ids.syntax, o_ids.base_type, o_ids.display_hint, o_ids.enums, o_ids.ranges, o_ids.sizes, o_ids.access, o_ids.status, o_ids.units, o_ids.description, o_ids.kind, o_ids.`table`, o_ids.indexes").
+		Joins("JOIN mibs ON mibs.id = o_ids.mib_id AND mibs.deleted_at IS NULL").
+		Order("o_ids.mib_id, o_ids.id").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	nodes := make(map[string]*oidTreeEntry)
+	symbols := make(map[string]string)
+	names := make(map[string][]string)
+
+	addName := func(name, oid string) {
+		for _, existing := range names[name] {
+			if existing == oid {
+				return
+			}
+		}
+		names[name] = append(names[name], oid)
+	}
+
+	for name, oid := range mibWellKnownOIDs {
+		nodes[oid] = &oidTreeEntry{node: OIDTreeNode{OID: oid, Name: name, Module: "SNMPv2-SMI"}}
+		symbols["SNMPv2-SMI::"+name] = oid
+		addName(name, oid)
+	}
+
+	// 同一 OID 被多个 MIB 定义时以最后加载的为准
+	for _, row := range rows {
+		nodes[row.OID] = &oidTreeEntry{node: OIDTreeNode{
+			OID:         row.OID,
+			Name:        row.Name,
+			Module:      row.Module,
+			MIBID:       row.MIBID,
+			Type:        row.Type,
+			Syntax:      row.Syntax,
+			BaseType:    row.BaseType,
+			DisplayHint: row.DisplayHint,
+			Enums:       row.Enums,
+			Ranges:      row.Ranges,
+			Sizes:       row.Sizes,
+			Access:      row.Access,
+			Status:      row.Status,
+			Units:       row.Units,
+			Description: row.Description,
+			Kind:        row.Kind,
+			Table:       row.Table,
+			Indexes:     row.Indexes,
+		}}
+		symbols[row.Module+"::"+row.Name] = row.OID
+		addName(row.Name, row.OID)
+	}
+
+	// 连接父子关系, 缺失的中间节点以匿名节点补齐
+	pending := make([]string, 0, len(nodes))
+	for oid := range nodes {
+		pending = append(pending, oid)
+	}
+	for len(pending) > 0 {
+		oid := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		parent := mibParentOID(oid)
+		if parent == "" {
+			continue
+		}
+		entry, ok := nodes[parent]
+		if !ok {
+			entry = &oidTreeEntry{node: OIDTreeNode{OID: parent}}
+			nodes[parent] = entry
+			pending = append(pending, parent)
+		}
+		nodes[oid].node.ParentOID = parent
+		entry.children = append(entry.children, oid)
+	}
+
+	for _, entry := range nodes {
+		sort.Slice(entry.children, func(i, j int) bool {
+			return compareOIDs(entry.children[i], entry.children[j]) < 0
+		})
+		entry.node.ChildCount = len(entry.children)
+	}
+
+	t.nodes = nodes
+	t.symbols = symbols
+	t.names = names
+	t.stale = false
+	return nil
+}
+
+// Node 返回指定 OID 的节点
+func (t *OIDTree) Node(oid string) (*OIDTreeNode, error) {
+	oid, err := normalizeOID(oid)
+	if err != nil {
+		return nil, err
+	}
+
+	var node OIDTreeNode
+	err = t.read(func() error {
+		entry, ok := t.nodes[oid]
+		if !ok {
+			return fmt.Errorf("OID %s not found in MIB tree", oid)
+		}
+		node = entry.node
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+// Children 返回 OID 的直接子节点, oid 为空时返回根节点
+func (t *OIDTree) Children(oid string) ([]OIDTreeNode, error) {
+	if oid != "" {
+		normalized, err := normalizeOID(oid)
+		if err != nil {
+			return nil, err
+		}
+		oid = normalized
+	}
+
+	children := []OIDTreeNode{}
+	err := t.read(func() error {
+		if oid == "" {
+			for _, entry := range t.nodes {
+				if entry.node.ParentOID == "" {
+					children = append(children, entry.node)
+				}
+			}
+			sort.Slice(children, func(i, j int) bool {
+				return compareOIDs(children[i].OID, children[j].OID) < 0
+			})
+			return nil
+		}
+
+		entry, ok := t.nodes[oid]
+		if !ok {
+			return fmt.Errorf("OID %s not found in MIB tree", oid)
+		}
+		for _, child := range entry.children {
+			children = append(children, t.nodes[child].node)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return children, nil
+}
+
+// Ancestors 返回从根节点到 OID 的节点链 (包含 OID 本身)
+func (t *OIDTree) Ancestors(oid string) ([]OIDTreeNode, error) {
+	oid, err := normalizeOID(oid)
+	if err != nil {
+		return nil, err
+	}
+
+	var chain []OIDTreeNode
+	err = t.read(func() error {
+		if _, ok := t.nodes[oid]; !ok {
+			return fmt.Errorf("OID %s not found in MIB tree", oid)
+		}
+		for current := oid; current != ""; current = t.nodes[current].node.ParentOID {
+			chain = append(chain, t.nodes[current].node)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain, nil
+}
+
+// Lookup 按符号查找节点; "MODULE::name" 精确匹配, 单独的 name 返回所有同名节点
+func (t *OIDTree) Lookup(symbol string) ([]OIDTreeNode, error) {
+	symbol = strings.TrimSpace(symbol)
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+
+	var nodes []OIDTreeNode
+	err := t.read(func() error {
+		if strings.Contains(symbol, "::") {
+			if oid, ok := t.symbols[symbol]; ok {
+				nodes = append(nodes, t.nodes[oid].node)
+			}
+		} else {
+			for _, oid := range t.names[symbol] {
+				nodes = append(nodes, t.nodes[oid].node)
+			}
+		}
+		if len(nodes) == 0 {
+			return fmt.Errorf("symbol %s not found in MIB tree", symbol)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// Resolve 按数字 OID、名称或 MODULE::name 查找单个节点, 名称在多个模块中定义时返回错误
+func (t *OIDTree) Resolve(ref string) (*OIDTreeNode, error) {
+	ref = strings.TrimSpace(ref)
+	if _, err := normalizeOID(ref); err == nil {
+		return t.Node(ref)
+	}
+	nodes, err := t.Lookup(ref)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) > 1 && !strings.Contains(ref, "::") {
+		return nil, fmt.Errorf("symbol %s is defined in %d modules, use MODULE::name", ref, len(nodes))
+	}
+	return &nodes[0], nil
+}
+
+// LongestPrefixMatch 返回 OID 最长的已命名前缀节点, 例如 ifDescr.3 匹配到 ifDescr, 后缀为 "3"
+func (t *OIDTree) LongestPrefixMatch(oid string) (*OIDMatch, error) {
+	oid, err := normalizeOID(oid)
+	if err != nil {
+		return nil, err
+	}
+
+	var match *OIDMatch
+	err = t.read(func() error {
+		for prefix := oid; prefix != ""; prefix = mibParentOID(prefix) {
+			entry, ok := t.nodes[prefix]
+			if !ok || entry.node.Name == "" {
+				continue
+			}
+			match = &OIDMatch{Node: entry.node, Suffix: strings.TrimPrefix(strings.TrimPrefix(oid, prefix), ".")}
+			return nil
+		}
+		return fmt.Errorf("no MIB object matches OID %s", oid)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return match, nil
+}
+
+// mibParentOID 返回数字 OID 的父 OID, 根节点返回空字符串
+func mibParentOID(oid string) string {
+	if i := strings.LastIndex(oid, "."); i >= 0 {
+		return oid[:i]
+	}
+	return ""
+}
+
+// normalizeOID 校验数字 OID 并去掉前导的 "."
+func normalizeOID(oid string) (string, error) {
+	oid = strings.TrimPrefix(strings.TrimSpace(oid), ".")
+	if oid == "" {
+		return "", fmt.Errorf("OID is required")
+	}
+	for _, arc := range strings.Split(oid, ".") {
+		if _, err := strconv.ParseUint(arc, 10, 32); err != nil {
+			return "", fmt.Errorf("invalid OID %q", oid)
+		}
+	}
+	return oid, nil
+}
+
+// compareOIDs 按分量数值比较两个数字 OID
+func compareOIDs(a, b string) int {
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		x, _ := strconv.ParseUint(as[i], 10, 32)
+		y, _ := strconv.ParseUint(bs[i], 10, 32)
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(as) - len(bs)
+}
@@ -0,0 +1,328 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gosnmp/gosnmp"
+	"gorm.io/gorm"
+
+	"mib-platform/models"
+)
+
+var prometheusInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+
+// PrometheusExporter 以 Prometheus 文本格式输出平台自己采集的 SNMP 数据,
+// 指标名、类型和索引标签与生成 snmp_exporter 配置时使用同一套逻辑
+type PrometheusExporter struct {
+	db     *gorm.DB
+	poller *SNMPPoller
+	config *ConfigService
+	tree   *OIDTree
+}
+
+// NewPrometheusExporter 创建导出器; /metrics 使用 poller 最近一次的采集结果, /probe 实时采集
+func NewPrometheusExporter(db *gorm.DB, poller *SNMPPoller) *PrometheusExporter {
+	return &PrometheusExporter{
+		db:     db,
+		poller: poller,
+		config: NewConfigService(db),
+		tree:   sharedOIDTree(db),
+	}
+}
+
+// exporterMetric 一个 MIB 对象对应的指标
+type exporterMetric struct {
+	SNMPMetric
+	node  *OIDTreeNode // 不在 MIB 库中时为 nil
+	hints map[string]string
+}
+
+// prometheusLabel 按输出顺序排列的标签
+type prometheusLabel struct {
+	name  string
+	value string
+}
+
+// prometheusFamily 同名指标的 HELP、TYPE 和样本
+type prometheusFamily struct {
+	help    string
+	typ     string
+	samples []string
+}
+
+// prometheusWriter 按指标名汇总样本, 保证同一指标的样本连续输出
+type prometheusWriter struct {
+	families map[string]*prometheusFamily
+}
+
+func newPrometheusWriter() *prometheusWriter {
+	return &prometheusWriter{families: make(map[string]*prometheusFamily)}
+}
+
+func (w *prometheusWriter) add(name, help, typ string, labels []prometheusLabel, value float64) {
+	family, ok := w.families[name]
+	if !ok {
+		family = &prometheusFamily{help: help, typ: typ}
+		w.families[name] = family
+	}
+
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(label.name)
+			b.WriteString(`="`)
+			b.WriteString(prometheusLabelEscaper.Replace(label.value))
+			b.WriteByte('"')
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	family.samples = append(family.samples, b.String())
+}
+
+var (
+	prometheusLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	prometheusHelpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+// write 按指标名排序输出文本格式 0.0.4
+func (w *prometheusWriter) write(out io.Writer) error {
+	names := make([]string, 0, len(w.families))
+	for name := range w.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// bufio.Writer 会保留第一个写入错误, 由 Flush 返回
+	bw := bufio.NewWriter(out)
+	for _, name := range names {
+		family := w.families[name]
+		if family.help != "" {
+			fmt.Fprintf(bw, "# HELP %s %s\n", name, prometheusHelpEscaper.Replace(strings.Join(strings.Fields(family.help), " ")))
+		}
+		fmt.Fprintf(bw, "# TYPE %s %s\n", name, family.typ)
+		for _, sample := range family.samples {
+			fmt.Fprintln(bw, sample)
+		}
+	}
+	return bw.Flush()
+}
+
+// prometheusName 把 MIB 名称转换为合法的指标名或标签名
+func prometheusName(name string) string {
+	name = prometheusInvalidChars.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "snmp_" + name
+	}
+	return name
+}
+
+// Metrics 输出所有设备最近一次轮询的值, 轮询引擎未启用时只有空结果
+func (e *PrometheusExporter) Metrics(out io.Writer) error {
+	w := newPrometheusWriter()
+	for _, collection := range e.poller.latestCollections() {
+		if err := e.render(w, collection); err != nil {
+			return err
+		}
+		labels := e.deviceLabels(&collection.Device)
+		w.add("snmp_poll_timestamp_seconds", "Unix time of the last poll of the device.", "gauge", labels, float64(collection.At.Unix()))
+		w.add("snmp_poll_duration_seconds", "Time taken by the last poll of the device.", "gauge", labels, collection.Duration.Seconds())
+	}
+	return w.write(out)
+}
+
+// Probe 实时采集一个设备并输出, target 可以是设备 ID、IP 地址、主机名或名称
+func (e *PrometheusExporter) Probe(target string, out io.Writer) error {
+	device, err := e.findDevice(target)
+	if err != nil {
+		return err
+	}
+	device, err = e.poller.loadDevice(device.ID)
+	if err != nil {
+		return err
+	}
+	collection, err := e.poller.collect(device)
+	if err != nil {
+		return err
+	}
+
+	w := newPrometheusWriter()
+	if err := e.render(w, collection); err != nil {
+		return err
+	}
+	w.add("snmp_scrape_duration_seconds", "Total SNMP time scrape took (walk and processing).", "gauge", nil, collection.Duration.Seconds())
+	w.add("snmp_scrape_pdus_returned", "PDUs returned from walk.", "gauge", nil, float64(len(collection.PDUs)))
+	return w.write(out)
+}
+
+// findDevice 按 ID、IP 地址、主机名或名称查找设备
+func (e *PrometheusExporter) findDevice(target string) (*models.Device, error) {
+	if target == "" {
+		return nil, fmt.Errorf("target parameter is required")
+	}
+	var device models.Device
+	query := e.db.Where("ip_address = ? OR hostname = ? OR name = ?", target, target, target)
+	if id, err := strconv.ParseUint(target, 10, 32); err == nil {
+		query = e.db.Where("id = ?", id)
+	}
+	if err := query.First(&device).Error; err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+func (e *PrometheusExporter) deviceLabels(device *models.Device) []prometheusLabel {
+	return []prometheusLabel{{"device", device.Name}, {"target", device.IPAddress}}
+}
+
+// render 把一次采集的 PDU 转换为指标: 数值直接输出, 枚举值和字符串作为与指标同名的标签
+func (e *PrometheusExporter) render(w *prometheusWriter, collection *pollerCollection) error {
+	metrics, err := e.metrics(collection.Device.Template.OIDs)
+	if err != nil {
+		return err
+	}
+
+	deviceLabels := e.deviceLabels(&collection.Device)
+	for _, pdu := range collection.PDUs {
+		oid := strings.TrimPrefix(pdu.Name, ".")
+		metric, suffix := matchExporterMetric(metrics, oid)
+		if metric == nil {
+			continue
+		}
+		name := prometheusName(metric.Name)
+		labels := append(append([]prometheusLabel{}, deviceLabels...), metric.indexLabels(suffix)...)
+
+		if value, _, _, ok := snmpNumericValue(pdu); ok {
+			if enum := metric.enumName(pdu.Value); enum != "" {
+				labels = append(labels, prometheusLabel{name, enum})
+			}
+			w.add(name, metric.Help, metric.Type, labels, value)
+			continue
+		}
+		if text, ok := metric.text(pdu); ok {
+			w.add(name, metric.Help, "gauge", append(labels, prometheusLabel{name, text}), 1)
+		}
+	}
+	return nil
+}
+
+// metrics 展开模板中的对象 (表和行展开为可读列), 再按 getOIDMetrics 生成指标定义
+func (e *PrometheusExporter) metrics(refs []string) (map[string]*exporterMetric, error) {
+	var objects []string
+	for _, ref := range refs {
+		node, err := e.tree.Resolve(ref)
+		if err != nil {
+			if oid, nerr := normalizeOID(ref); nerr == nil {
+				objects = append(objects, oid)
+			}
+			continue
+		}
+		if node.Kind != MIBKindTable && node.Kind != MIBKindRow {
+			objects = append(objects, node.OID)
+			continue
+		}
+		layout, err := e.poller.snmp.tableLayout(node.OID, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, column := range layout.columns {
+			objects = append(objects, column.OID)
+		}
+	}
+
+	defs, err := e.config.getOIDMetrics(objects)
+	if err != nil {
+		return nil, err
+	}
+	metrics := make(map[string]*exporterMetric, len(defs))
+	for _, def := range defs {
+		metric := &exporterMetric{SNMPMetric: def}
+		if node, err := e.tree.Node(def.OID); err == nil {
+			metric.node = node
+			metric.hints = e.tree.indexHints(node.Indexes)
+		}
+		metrics[def.OID] = metric
+	}
+	return metrics, nil
+}
+
+// matchExporterMetric 按最长前缀找到 OID 所属的指标, 返回实例后缀
+func matchExporterMetric(metrics map[string]*exporterMetric, oid string) (*exporterMetric, string) {
+	for prefix := oid; prefix != ""; prefix = mibParentOID(prefix) {
+		if metric, ok := metrics[prefix]; ok {
+			return metric, strings.TrimPrefix(strings.TrimPrefix(oid, prefix), ".")
+		}
+	}
+	return nil, ""
+}
+
+// indexLabels 按 INDEX 子句解码行索引; 无法解码时退回 index 标签
+func (m *exporterMetric) indexLabels(suffix string) []prometheusLabel {
+	if len(m.Indexes) == 0 || m.node == nil {
+		return nil
+	}
+	key, err := decodeSNMPIndex(suffix, m.node.Indexes, m.hints)
+	if err != nil {
+		return []prometheusLabel{{"index", suffix}}
+	}
+	labels := make([]prometheusLabel, 0, len(m.Indexes))
+	for _, index := range m.Indexes {
+		labels = append(labels, prometheusLabel{prometheusName(index.LabelName), fmt.Sprint(key[index.LabelName])})
+	}
+	return labels
+}
+
+// enumName 返回整数值对应的枚举名
+func (m *exporterMetric) enumName(value interface{}) string {
+	if m.node == nil || len(m.node.Enums) == 0 || m.node.BaseType == "BITS" {
+		return ""
+	}
+	n, ok := snmpIntegerValue(value)
+	if !ok {
+		return ""
+	}
+	for _, e := range m.node.Enums {
+		if big.NewInt(e.Value).Cmp(n) == 0 {
+			return e.Name
+		}
+	}
+	return ""
+}
+
+// text 格式化非数值的值: 字符串按 DISPLAY-HINT, 其余按 gosnmp 的字符串形式
+func (m *exporterMetric) text(pdu gosnmp.SnmpPDU) (string, bool) {
+	switch pdu.Type {
+	case gosnmp.OctetString, gosnmp.BitString:
+		octets, ok := pdu.Value.([]byte)
+		if !ok {
+			return "", false
+		}
+		if m.node != nil {
+			if display := formatSNMPValue(m.node, string(octets)); display != "" {
+				return display, true
+			}
+		}
+		if isPrintableOctets(octets) {
+			return string(octets), true
+		}
+		return formatSNMPHexString(octets), true
+	case gosnmp.IPAddress, gosnmp.ObjectIdentifier:
+		if s, ok := pdu.Value.(string); ok {
+			return strings.TrimPrefix(s, "."), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"mib-platform/models"
+)
+
+const (
+	bulkDefaultConcurrency = 4
+	bulkMaxConcurrency     = 32
+	bulkMaxRequests        = 10000
+)
+
+var bulkOperationTypes = map[string]bool{"get": true, "walk": true, "set": true}
+
+type bulkOperationKey struct {
+	db *gorm.DB
+	id uint
+}
+
+// bulkOperationCancels 本进程中正在执行的批量操作的取消函数
+var bulkOperationCancels sync.Map
+
+// StartBulkOperation 保存批量操作及其请求列表, 在后台以有限并发执行
+func (s *SNMPService) StartBulkOperation(operationType string, requests []models.BulkSNMPRequest, concurrency int) (*models.BulkOperation, error) {
+	if !bulkOperationTypes[operationType] {
+		return nil, fmt.Errorf("unsupported bulk operation type: %s", operationType)
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("at least one request is required")
+	}
+	if len(requests) > bulkMaxRequests {
+		return nil, fmt.Errorf("too many requests: %d (max %d)", len(requests), bulkMaxRequests)
+	}
+	for i, req := range requests {
+		if req.Target == "" || req.Version == "" || req.OID == "" {
+			return nil, fmt.Errorf("request %d: target, version and oid are required", i)
+		}
+		if operationType == "set" && (req.Value == nil || req.Type == "") {
+			return nil, fmt.Errorf("request %d: value and type are required for set", i)
+		}
+	}
+	if concurrency <= 0 {
+		concurrency = bulkDefaultConcurrency
+	}
+	if concurrency > bulkMaxConcurrency {
+		concurrency = bulkMaxConcurrency
+	}
+
+	operation := &models.BulkOperation{
+		Type:        operationType,
+		Status:      "pending",
+		Total:       len(requests),
+		Concurrency: concurrency,
+	}
+	results := make([]models.BulkOperationResult, len(requests))
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(operation).Error; err != nil {
+			return err
+		}
+		for i, req := range requests {
+			results[i] = models.BulkOperationResult{
+				OperationID: operation.ID,
+				Seq:         i,
+				Request:     redactBulkRequest(req),
+				Status:      "pending",
+			}
+		}
+		return tx.CreateInBatches(results, 100).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bulk operation: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	bulkOperationCancels.Store(bulkOperationKey{s.db, operation.ID}, cancel)
+
+	// 返回副本, 后台任务会继续修改 operation
+	started := *operation
+	go s.runBulkOperation(ctx, operation, requests, results)
+
+	return &started, nil
+}
+
+// redactBulkRequest 去掉团体名和 v3 密钥后再入库, 执行时使用内存中的原始请求
+func redactBulkRequest(req models.BulkSNMPRequest) models.BulkSNMPRequest {
+	req.Community = ""
+	req.AuthKey = ""
+	req.PrivKey = ""
+	return req
+}
+
+func (s *SNMPService) runBulkOperation(ctx context.Context, operation *models.BulkOperation, requests []models.BulkSNMPRequest, results []models.BulkOperationResult) {
+	key := bulkOperationKey{s.db, operation.ID}
+	defer bulkOperationCancels.Delete(key)
+
+	start := time.Now()
+	operation.Status = "running"
+	operation.StartTime = &start
+	s.db.Save(operation)
+
+	var mu sync.Mutex
+	defer func() {
+		if r := recover(); r != nil {
+			operation.Status = "failed"
+			operation.ErrorMsg = fmt.Sprintf("bulk operation panicked: %v", r)
+		}
+
+		end := time.Now()
+		operation.EndTime = &end
+		operation.Duration = end.Sub(start).String()
+		if operation.Status == "running" {
+			operation.Status = "completed"
+			if ctx.Err() != nil && operation.Completed < operation.Total {
+				operation.Status = "cancelled"
+			}
+		}
+		if operation.Status != "completed" {
+			s.db.Model(&models.BulkOperationResult{}).
+				Where("operation_id = ? AND status = ?", operation.ID, "pending").
+				Update("status", "cancelled")
+		}
+		s.db.Save(operation)
+	}()
+
+	runBounded(ctx, len(requests), operation.Concurrency, func(i int) {
+		req, result := &requests[i], &results[i]
+
+		started := time.Now()
+		response, err := s.executeBulkRequest(operation.Type, req)
+		completed := time.Now()
+
+		mu.Lock()
+		defer mu.Unlock()
+		result.StartedAt = &started
+		result.CompletedAt = &completed
+		result.Response = response
+		switch {
+		case err != nil:
+			result.Status = "failed"
+			result.Error = err.Error()
+		case !response.Success:
+			result.Status = "failed"
+			result.Error = response.Message
+		default:
+			result.Status = "success"
+		}
+		s.db.Save(result)
+
+		operation.Completed++
+		if result.Status == "success" {
+			operation.Succeeded++
+		} else {
+			operation.Failed++
+		}
+		operation.Progress = operation.Completed * 100 / operation.Total
+		s.db.Model(operation).Updates(map[string]interface{}{
+			"completed": operation.Completed,
+			"succeeded": operation.Succeeded,
+			"failed":    operation.Failed,
+			"progress":  operation.Progress,
+		})
+	})
+}
+
+// runBounded 以最多 concurrency 个并发对 0 到 n-1 调用 fn, 全部结束后返回;
+// ctx 取消后不再派发, 已派发但尚未开始的任务也不再执行
+func runBounded(ctx context.Context, n, concurrency int, fn func(i int)) {
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+dispatch:
+	for i := 0; i < n; i++ {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case sem <- struct{}{}:
+		}
+		// 两个分支同时就绪时 select 随机选择, 取消后不再派发
+		if ctx.Err() != nil {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			// 取消与释放并发槽同时发生时任务可能已被派发
+			if ctx.Err() != nil {
+				return
+			}
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// executeBulkRequest 按操作类型执行单个请求; 请求中的 panic 只让该请求失败
+func (s *SNMPService) executeBulkRequest(operationType string, req *models.BulkSNMPRequest) (response *models.SNMPResponse, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			response, err = nil, fmt.Errorf("request panicked: %v", r)
+		}
+	}()
+
+	switch operationType {
+	case "get":
+		return s.SNMPGet(&req.SNMPRequest)
+	case "walk":
+		return s.SNMPWalk(&req.SNMPRequest)
+	case "set":
+		return s.SNMPSet(&models.SNMPSetRequest{SNMPRequest: req.SNMPRequest, Value: req.Value, Type: req.Type})
+	default:
+		return nil, fmt.Errorf("unsupported bulk operation type: %s", operationType)
+	}
+}
+
+// GetBulkOperation 查询批量操作; 数据库中仍在运行但本进程没有执行的操作标记为中断
+func (s *SNMPService) GetBulkOperation(id uint) (*models.BulkOperation, error) {
+	var operation models.BulkOperation
+	if err := s.db.First(&operation, id).Error; err != nil {
+		return nil, err
+	}
+
+	if operation.Status == "pending" || operation.Status == "running" {
+		if _, ok := bulkOperationCancels.Load(bulkOperationKey{s.db, id}); !ok {
+			// 重新读取, 避免与刚结束的后台任务竞争
+			if err := s.db.First(&operation, id).Error; err != nil {
+				return nil, err
+			}
+			if operation.Status == "pending" || operation.Status == "running" {
+				now := time.Now()
+				operation.Status = "failed"
+				operation.ErrorMsg = "operation was interrupted by a service restart"
+				operation.EndTime = &now
+				s.db.Save(&operation)
+				s.db.Model(&models.BulkOperationResult{}).
+					Where("operation_id = ? AND status = ?", id, "pending").
+					Update("status", "cancelled")
+			}
+		}
+	}
+	return &operation, nil
+}
+
+// GetBulkOperationResults 分页返回批量操作中各请求的结果, status 为空时返回全部
+func (s *SNMPService) GetBulkOperationResults(id uint, page, limit int, status string) ([]models.BulkOperationResult, int64, error) {
+	var results []models.BulkOperationResult
+	var total int64
+
+	query := s.db.Model(&models.BulkOperationResult{}).Where("operation_id = ?", id)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+	offset := (page - 1) * limit
+	if err := query.Order("seq").Offset(offset).Limit(limit).Find(&results).Error; err != nil {
+		return nil, 0, err
+	}
+	return results, total, nil
+}
+
+// CancelBulkOperation 取消批量操作: 不再派发新请求, 已发出的请求执行完为止
+func (s *SNMPService) CancelBulkOperation(id uint) (*models.BulkOperation, error) {
+	operation, err := s.GetBulkOperation(id)
+	if err != nil {
+		return nil, err
+	}
+	cancel, ok := bulkOperationCancels.Load(bulkOperationKey{s.db, id})
+	if !ok {
+		return nil, fmt.Errorf("bulk operation %d is already %s", id, operation.Status)
+	}
+	cancel.(context.CancelFunc)()
+	return operation, nil
+}
@@ -0,0 +1,739 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"mib-platform/models"
+)
+
+const (
+	pollerTick        = time.Second
+	pollerRefresh     = time.Minute // 重新加载设备和模板
+	pollerMaintenance = time.Hour   // 降采样和清理
+	pollerConcurrency = 8
+	pollerGetBatch    = 60  // gosnmp 单个 PDU 的 OID 上限
+	metricRollupStep  = 300 // 降采样桶宽, 秒
+	metricMaxSeries   = 1000
+)
+
+// PollerConfig 轮询引擎配置
+type PollerConfig struct {
+	Interval        time.Duration // 0 表示关闭
+	Jitter          time.Duration
+	RawRetention    time.Duration // 0 表示不降采样
+	RollupRetention time.Duration // 0 表示不清理
+	MaxConcurrent   int           // 同时轮询的设备数, 0 表示默认值
+}
+
+// PollerStatus 轮询引擎状态
+type PollerStatus struct {
+	Running         bool       `json:"running"`
+	Interval        string     `json:"interval"`
+	Jitter          string     `json:"jitter"`
+	RawRetention    string     `json:"raw_retention"`
+	RollupRetention string     `json:"rollup_retention"`
+	Devices         int        `json:"devices"`
+	Polls           uint64     `json:"polls"`
+	Failures        uint64     `json:"failures"`
+	LastPollAt      *time.Time `json:"last_poll_at"`
+	LastError       string     `json:"last_error,omitempty"`
+}
+
+// PollResult 一次设备轮询的结果
+type PollResult struct {
+	DeviceID uint     `json:"device_id"`
+	Samples  int      `json:"samples"`
+	Skipped  int      `json:"skipped"` // 非数值或计数器的第一个值
+	Errors   []string `json:"errors,omitempty"`
+	Duration string   `json:"duration"`
+}
+
+// MetricQuery 时间序列查询条件
+type MetricQuery struct {
+	DeviceID uint
+	OID      string // 数字 OID 或对象名, 匹配该 OID 及其下的所有实例
+	Start    time.Time
+	End      time.Time
+	Step     time.Duration // 大于 0 时按桶求平均
+}
+
+// MetricPoint 时间序列中的一个点
+type MetricPoint struct {
+	Time  int64   `json:"time"`
+	Value float64 `json:"value"`
+}
+
+// MetricSeriesData 带数据点的时间序列
+type MetricSeriesData struct {
+	models.MetricSeries
+	Points []MetricPoint `json:"points"`
+}
+
+// pollerCollection 一次采集的原始结果, 不含凭据
+type pollerCollection struct {
+	Device   models.Device
+	PDUs     []gosnmp.SnmpPDU
+	Uptime   *uint32
+	Errors   []string
+	At       time.Time
+	Duration time.Duration
+}
+
+// pollerTransportError 设备没有响应或传输失败, 只有这类错误把设备标记为离线
+type pollerTransportError struct {
+	err error
+}
+
+func (e *pollerTransportError) Error() string { return e.err.Error() }
+
+func (e *pollerTransportError) Unwrap() error { return e.err }
+
+type pollerCounter struct {
+	raw uint64
+	at  time.Time
+}
+
+// SNMPPoller 按设备模板中的 OID 定时轮询设备, 样本写入本地时间序列表
+type SNMPPoller struct {
+	db   *gorm.DB
+	cfg  PollerConfig
+	snmp *SNMPService
+
+	sem        chan struct{} // 限制同时轮询的设备数
+	mu         sync.Mutex
+	stop       chan struct{}
+	next       map[uint]time.Time // 设备下一次轮询时间
+	polling    map[uint]bool
+	series     map[string]*models.MetricSeries // "设备ID/OID" -> 序列
+	counters   map[uint]pollerCounter          // 序列 ID -> 上一次计数器值
+	uptimes    map[uint]uint32                 // 设备 ID -> 上一次 sysUpTime
+	latest     map[uint]*pollerCollection      // 设备 ID -> 最近一次采集结果
+	polls      uint64
+	failures   uint64
+	lastPollAt *time.Time
+	lastError  string
+}
+
+// NewSNMPPoller 创建轮询引擎, 调用 Start 后开始调度
+func NewSNMPPoller(db *gorm.DB, cfg PollerConfig) *SNMPPoller {
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = pollerConcurrency
+	}
+	return &SNMPPoller{
+		db:       db,
+		cfg:      cfg,
+		snmp:     NewSNMPService(db),
+		sem:      make(chan struct{}, cfg.MaxConcurrent),
+		next:     make(map[uint]time.Time),
+		polling:  make(map[uint]bool),
+		series:   make(map[string]*models.MetricSeries),
+		counters: make(map[uint]pollerCounter),
+		uptimes:  make(map[uint]uint32),
+		latest:   make(map[uint]*pollerCollection),
+	}
+}
+
+// Start 开始调度轮询和定期降采样
+func (p *SNMPPoller) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stop != nil || p.cfg.Interval <= 0 {
+		return
+	}
+	p.stop = make(chan struct{})
+	go p.run(p.stop)
+}
+
+// Stop 停止调度, 正在进行的轮询执行完为止
+func (p *SNMPPoller) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stop != nil {
+		close(p.stop)
+		p.stop = nil
+	}
+}
+
+func (p *SNMPPoller) run(stop chan struct{}) {
+	ticker := time.NewTicker(pollerTick)
+	defer ticker.Stop()
+
+	var intervals map[uint]time.Duration
+	var loadedAt, maintainedAt time.Time
+	for {
+		now := time.Now()
+		if now.Sub(loadedAt) >= pollerRefresh {
+			var err error
+			if intervals, err = p.schedule(now); err != nil {
+				log.Printf("Failed to load devices for polling: %v", err)
+			}
+			loadedAt = now
+		}
+		if now.Sub(maintainedAt) >= pollerMaintenance {
+			maintainedAt = now
+			go func() {
+				if err := p.Compact(time.Now()); err != nil {
+					log.Printf("Failed to compact metric samples: %v", err)
+				}
+			}()
+		}
+
+		p.dispatch(now, intervals)
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatch 在并发限制内启动到期设备的轮询; 没有空闲槽位的设备保持到期, 下一轮立即重试
+func (p *SNMPPoller) dispatch(now time.Time, intervals map[uint]time.Duration) {
+	for _, id := range p.due(now, intervals) {
+		select {
+		case p.sem <- struct{}{}:
+		default:
+			// due 已经安排了下一次轮询, 未执行时恢复为到期
+			p.mu.Lock()
+			p.polling[id] = false
+			p.next[id] = now
+			p.mu.Unlock()
+			continue
+		}
+		go func(id uint) {
+			defer func() { <-p.sem }()
+			if _, err := p.PollDevice(id); err != nil {
+				log.Printf("Polling device %d failed: %v", id, err)
+			}
+			p.mu.Lock()
+			p.polling[id] = false
+			p.mu.Unlock()
+		}(id)
+	}
+}
+
+// schedule 加载带模板 OID 的设备及其轮询间隔; 新设备的首次轮询在一个间隔内随机分散
+func (p *SNMPPoller) schedule(now time.Time) (map[uint]time.Duration, error) {
+	var devices []models.Device
+	if err := p.db.Preload("Template").Where("template_id IS NOT NULL").Find(&devices).Error; err != nil {
+		return nil, err
+	}
+
+	intervals := make(map[uint]time.Duration)
+	for _, device := range devices {
+		if device.Template == nil || len(device.Template.OIDs) == 0 {
+			continue
+		}
+		intervals[device.ID] = p.interval(device.Template)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for id, interval := range intervals {
+		if _, ok := p.next[id]; !ok {
+			p.next[id] = now.Add(time.Duration(rand.Int63n(int64(interval))))
+		}
+	}
+	for id := range p.next {
+		if _, ok := intervals[id]; !ok {
+			delete(p.next, id)
+			delete(p.latest, id)
+		}
+	}
+	return intervals, nil
+}
+
+// interval 模板 Config 中的 poll_interval 优先于全局间隔
+func (p *SNMPPoller) interval(template *models.DeviceTemplate) time.Duration {
+	if value, ok := template.Config["poll_interval"].(string); ok {
+		if interval, err := time.ParseDuration(value); err == nil && interval >= pollerTick {
+			return interval
+		}
+		log.Printf("Ignoring invalid poll_interval %q in device template %s", value, template.Name)
+	}
+	return p.cfg.Interval
+}
+
+// due 返回到期的设备, 并按间隔加随机偏移安排下一次轮询
+func (p *SNMPPoller) due(now time.Time, intervals map[uint]time.Duration) []uint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var ids []uint
+	for id, next := range p.next {
+		if now.Before(next) || p.polling[id] {
+			continue
+		}
+		interval := intervals[id]
+		delay := interval
+		if p.cfg.Jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(2*p.cfg.Jitter))) - p.cfg.Jitter
+		}
+		if delay < interval/2 {
+			delay = interval / 2
+		}
+		p.next[id] = now.Add(delay)
+		p.polling[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Status 返回轮询引擎状态
+func (p *SNMPPoller) Status() PollerStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return PollerStatus{
+		Running:         p.stop != nil,
+		Interval:        p.cfg.Interval.String(),
+		Jitter:          p.cfg.Jitter.String(),
+		RawRetention:    p.cfg.RawRetention.String(),
+		RollupRetention: p.cfg.RollupRetention.String(),
+		Devices:         len(p.next),
+		Polls:           p.polls,
+		Failures:        p.failures,
+		LastPollAt:      p.lastPollAt,
+		LastError:       p.lastError,
+	}
+}
+
+// PollDevice 立即轮询一个设备: 标量 Get, 表和列 walk, 数值结果写入时间序列
+func (p *SNMPPoller) PollDevice(id uint) (*PollResult, error) {
+	start := time.Now()
+	result, err := p.pollDevice(id)
+
+	p.mu.Lock()
+	p.polls++
+	p.lastPollAt = &start
+	if err != nil {
+		p.failures++
+		p.lastError = fmt.Sprintf("device %d: %v", id, err)
+	}
+	p.mu.Unlock()
+
+	// 与 TestDevice 一致更新设备在线状态; 配置或数据库错误不说明设备是否可达, 状态保持不变
+	device := p.db.Model(&models.Device{}).Where("id = ?", id)
+	var transport *pollerTransportError
+	switch {
+	case err == nil:
+		device.Updates(map[string]interface{}{"status": "online", "last_seen": &start})
+	case errors.As(err, &transport):
+		device.Update("status", "offline")
+	}
+
+	if result != nil {
+		result.Duration = time.Since(start).String()
+	}
+	return result, err
+}
+
+func (p *SNMPPoller) pollDevice(id uint) (*PollResult, error) {
+	device, err := p.loadDevice(id)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := p.collect(device)
+	if err != nil {
+		return nil, err
+	}
+	result := &PollResult{DeviceID: device.ID, Errors: collection.Errors}
+
+	// sysUpTime 变小说明设备重启, 计数器从头开始, 需要重新取基线
+	restarted := false
+	p.mu.Lock()
+	if collection.Uptime != nil {
+		if last, ok := p.uptimes[device.ID]; ok && *collection.Uptime < last {
+			restarted = true
+		}
+		p.uptimes[device.ID] = *collection.Uptime
+	}
+	p.latest[device.ID] = collection
+	p.mu.Unlock()
+
+	result.Samples, result.Skipped, err = p.record(device.ID, collection.PDUs, collection.At, restarted)
+	return result, err
+}
+
+// loadDevice 加载设备及其模板和凭据, 并检查是否可以轮询
+func (p *SNMPPoller) loadDevice(id uint) (*models.Device, error) {
+	var device models.Device
+	if err := p.db.Preload("Template").Preload("Credentials").First(&device, id).Error; err != nil {
+		return nil, err
+	}
+	if len(device.Credentials) == 0 {
+		return nil, fmt.Errorf("no SNMP credentials configured for device %s", device.Name)
+	}
+	if device.Template == nil || len(device.Template.OIDs) == 0 {
+		return nil, fmt.Errorf("device %s has no template OIDs to poll", device.Name)
+	}
+	return &device, nil
+}
+
+// collect 按模板读取设备: 标量 Get, 表和列 walk; 单个对象失败记录在 Errors 中
+func (p *SNMPPoller) collect(device *models.Device) (*pollerCollection, error) {
+	start := time.Now()
+	req := snmpCredentialRequest(device.IPAddress, device.Port, device.Credentials[0], "")
+	snmp, err := p.snmp.createSNMPConnection(req)
+	if err != nil {
+		return nil, err
+	}
+	defer snmp.Conn.Close()
+
+	collection := &pollerCollection{Device: *device}
+	collection.Device.Credentials = nil
+
+	// 总是读取 sysUpTime 用于识别设备重启, 模板中没有时不记录样本
+	gets := []string{snmpSysUpTimeOID}
+	wantUptime := false
+	var walks []string
+	for _, ref := range device.Template.OIDs {
+		node, err := p.snmp.resolver.tree.Resolve(ref)
+		oid := ""
+		switch {
+		case err == nil && node.Kind == MIBKindScalar:
+			oid = node.OID + ".0"
+		case err == nil:
+			walks = append(walks, node.OID)
+			continue
+		default:
+			// 不在 MIB 库中的数字 OID 当作实例直接 Get
+			var nerr error
+			if oid, nerr = normalizeOID(ref); nerr != nil {
+				collection.Errors = append(collection.Errors, err.Error())
+				continue
+			}
+		}
+		if oid == snmpSysUpTimeOID {
+			wantUptime = true
+		} else {
+			gets = append(gets, oid)
+		}
+	}
+
+	for i := 0; i < len(gets); i += pollerGetBatch {
+		batch := gets[i:min(i+pollerGetBatch, len(gets))]
+		packet, err := p.getBatch(snmp, batch, collection)
+		if err != nil {
+			return nil, err
+		}
+		if packet == nil {
+			continue
+		}
+		for _, pdu := range packet.Variables {
+			if !snmpValueExists(pdu) {
+				continue
+			}
+			if strings.TrimPrefix(pdu.Name, ".") == snmpSysUpTimeOID {
+				if ticks, ok := pdu.Value.(uint32); ok {
+					collection.Uptime = &ticks
+				}
+				if !wantUptime {
+					continue
+				}
+			}
+			collection.PDUs = append(collection.PDUs, pdu)
+		}
+	}
+	for _, root := range walks {
+		_, err := p.snmp.walk(snmp, req, root, func(pdu gosnmp.SnmpPDU) error {
+			if snmpValueExists(pdu) {
+				collection.PDUs = append(collection.PDUs, pdu)
+			}
+			return nil
+		})
+		if err != nil {
+			collection.Errors = append(collection.Errors, fmt.Sprintf("failed to walk %s: %v", root, err))
+		}
+	}
+
+	collection.At = time.Now()
+	collection.Duration = collection.At.Sub(start)
+	return collection, nil
+}
+
+// getBatch 读取一批 OID; agent 返回的错误状态 (如 v1 的 noSuchName) 只影响出错的 OID, 记入 collection.Errors,
+// 去掉 ErrorIndex 指出的 OID 后重试其余部分. 没有可用结果时返回 nil
+func (p *SNMPPoller) getBatch(snmp *gosnmp.GoSNMP, batch []string, collection *pollerCollection) (*gosnmp.SnmpPacket, error) {
+	for len(batch) > 0 {
+		packet, err := snmp.Get(batch)
+		if err != nil {
+			return nil, &pollerTransportError{fmt.Errorf("failed to get %d OIDs: %v", len(batch), err)}
+		}
+		if packet.Error == gosnmp.NoError {
+			return packet, nil
+		}
+
+		bad := int(packet.ErrorIndex) - 1
+		if bad < 0 || bad >= len(batch) {
+			collection.Errors = append(collection.Errors, fmt.Sprintf("failed to get %d OIDs: %v", len(batch), packet.Error))
+			return nil, nil
+		}
+		collection.Errors = append(collection.Errors, fmt.Sprintf("failed to get %s: %v", batch[bad], packet.Error))
+		batch = append(batch[:bad:bad], batch[bad+1:]...)
+	}
+	return nil, nil
+}
+
+// latestCollections 返回各设备最近一次轮询的结果, 按设备 ID 排序
+func (p *SNMPPoller) latestCollections() []*pollerCollection {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	collections := make([]*pollerCollection, 0, len(p.latest))
+	for _, c := range p.latest {
+		collections = append(collections, c)
+	}
+	sort.Slice(collections, func(i, j int) bool { return collections[i].Device.ID < collections[j].Device.ID })
+	return collections
+}
+
+// record 把一次轮询的结果写入样本表; 计数器按与上一次的差值换算为每秒速率
+func (p *SNMPPoller) record(deviceID uint, pdus []gosnmp.SnmpPDU, at time.Time, restarted bool) (int, int, error) {
+	var samples []models.MetricSample
+	skipped := 0
+	for _, pdu := range pdus {
+		value, raw, bits, ok := snmpNumericValue(pdu)
+		if !ok {
+			skipped++
+			continue
+		}
+		series, err := p.seriesFor(deviceID, pdu, bits > 0)
+		if err != nil {
+			return 0, skipped, err
+		}
+
+		if bits > 0 {
+			p.mu.Lock()
+			prev, seen := p.counters[series.ID]
+			p.counters[series.ID] = pollerCounter{raw: raw, at: at}
+			p.mu.Unlock()
+
+			elapsed := at.Sub(prev.at).Seconds()
+			if !seen || restarted || elapsed <= 0 {
+				skipped++
+				continue
+			}
+			delta, ok := snmpCounterDelta(prev.raw, raw, bits)
+			if !ok {
+				skipped++
+				continue
+			}
+			value = float64(delta) / elapsed
+		}
+		samples = append(samples, models.MetricSample{SeriesID: series.ID, Time: at.Unix(), Value: value, Max: value})
+	}
+
+	if len(samples) > 0 {
+		// 同一秒内的重复轮询只保留第一个样本
+		err := p.db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(samples, 100).Error
+		if err != nil {
+			return 0, skipped, fmt.Errorf("failed to store samples: %v", err)
+		}
+	}
+	return len(samples), skipped, nil
+}
+
+// seriesFor 查找或创建设备上 OID 实例对应的序列, 名称和单位取自 MIB 库
+func (p *SNMPPoller) seriesFor(deviceID uint, pdu gosnmp.SnmpPDU, counter bool) (*models.MetricSeries, error) {
+	oid := strings.TrimPrefix(pdu.Name, ".")
+	key := fmt.Sprintf("%d/%s", deviceID, oid)
+
+	p.mu.Lock()
+	series, ok := p.series[key]
+	p.mu.Unlock()
+	if ok {
+		return series, nil
+	}
+
+	series = &models.MetricSeries{DeviceID: deviceID, OID: oid, Type: pdu.Type.String(), Kind: "gauge"}
+	if counter {
+		series.Kind = "counter"
+	}
+	if match, err := p.snmp.resolver.tree.LongestPrefixMatch(oid); err == nil {
+		series.Name = match.Node.Name
+		series.Module = match.Node.Module
+		series.Index = match.Suffix
+		series.Units = match.Node.Units
+	}
+	err := p.db.Where("device_id = ? AND o_id = ?", deviceID, oid).Attrs(series).FirstOrCreate(series).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to create series for %s: %v", oid, err)
+	}
+
+	p.mu.Lock()
+	p.series[key] = series
+	p.mu.Unlock()
+	return series, nil
+}
+
+// snmpNumericValue 取出可作为样本的数值; 计数器同时返回原始值和位宽
+func snmpNumericValue(pdu gosnmp.SnmpPDU) (value float64, raw uint64, bits int, ok bool) {
+	switch pdu.Type {
+	case gosnmp.Counter32:
+		raw = gosnmp.ToBigInt(pdu.Value).Uint64()
+		return float64(raw), raw, 32, true
+	case gosnmp.Counter64:
+		raw = gosnmp.ToBigInt(pdu.Value).Uint64()
+		return float64(raw), raw, 64, true
+	case gosnmp.Integer, gosnmp.Gauge32, gosnmp.TimeTicks, gosnmp.Uinteger32:
+		return float64(gosnmp.ToBigInt(pdu.Value).Int64()), 0, 0, true
+	case gosnmp.OpaqueFloat:
+		if v, isFloat := pdu.Value.(float32); isFloat {
+			return float64(v), 0, 0, true
+		}
+	case gosnmp.OpaqueDouble:
+		if v, isFloat := pdu.Value.(float64); isFloat {
+			return v, 0, 0, true
+		}
+	}
+	return 0, 0, 0, false
+}
+
+// snmpCounterDelta 计算两次计数器读数的差值. Counter32 变小按回绕一次处理;
+// Counter64 在轮询间隔内不会回绕, 变小说明计数器被清零, 返回 false
+func snmpCounterDelta(prev, cur uint64, bits int) (uint64, bool) {
+	if cur >= prev {
+		return cur - prev, true
+	}
+	if bits == 32 && prev <= math.MaxUint32 {
+		return cur + (1 << 32) - prev, true
+	}
+	return 0, false
+}
+
+// Compact 把超过保留时长的原始样本降采样为 5 分钟的平均值和最大值, 并删除过期的降采样数据
+func (p *SNMPPoller) Compact(now time.Time) error {
+	if p.cfg.RawRetention > 0 {
+		// 截止时间按桶对齐, 保证每个桶只汇总一次
+		cutoff := now.Add(-p.cfg.RawRetention).Unix() / metricRollupStep * metricRollupStep
+		err := p.db.Transaction(func(tx *gorm.DB) error {
+			err := tx.Exec(`INSERT INTO metric_samples (series_id, step, ts, value, max)
+				SELECT series_id, ?, ts / ? * ?, AVG(value), MAX(max) FROM metric_samples
+				WHERE step = 0 AND ts < ? GROUP BY series_id, ts / ?
+				ON CONFLICT DO NOTHING`,
+				metricRollupStep, metricRollupStep, metricRollupStep, cutoff, metricRollupStep).Error
+			if err != nil {
+				return err
+			}
+			return tx.Where("step = 0 AND ts < ?", cutoff).Delete(&models.MetricSample{}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("failed to downsample metric samples: %v", err)
+		}
+	}
+
+	if p.cfg.RollupRetention > 0 {
+		cutoff := now.Add(-p.cfg.RollupRetention).Unix()
+		if err := p.db.Where("step > 0 AND ts < ?", cutoff).Delete(&models.MetricSample{}).Error; err != nil {
+			return fmt.Errorf("failed to prune metric samples: %v", err)
+		}
+	}
+	return nil
+}
+
+// seriesQuery 按设备和 OID 前缀筛选序列
+func (p *SNMPPoller) seriesQuery(deviceID uint, ref string) (*gorm.DB, error) {
+	query := p.db.Model(&models.MetricSeries{})
+	if deviceID != 0 {
+		query = query.Where("device_id = ?", deviceID)
+	}
+	if ref != "" {
+		oid, err := normalizeOID(ref)
+		if err != nil {
+			node, rerr := p.snmp.resolver.tree.Resolve(ref)
+			if rerr != nil {
+				return nil, rerr
+			}
+			oid = node.OID
+		}
+		query = query.Where("o_id = ? OR o_id LIKE ?", oid, oid+".%")
+	}
+	return query, nil
+}
+
+// GetSeries 列出已采集的序列
+func (p *SNMPPoller) GetSeries(deviceID uint, ref string) ([]models.MetricSeries, error) {
+	query, err := p.seriesQuery(deviceID, ref)
+	if err != nil {
+		return nil, err
+	}
+	var series []models.MetricSeries
+	if err := query.Order("device_id, o_id").Limit(metricMaxSeries).Find(&series).Error; err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// QuerySeries 返回时间范围内各序列的数据点, 原始样本和降采样数据按时间合并
+func (p *SNMPPoller) QuerySeries(q MetricQuery) ([]MetricSeriesData, error) {
+	if q.End.IsZero() {
+		q.End = time.Now()
+	}
+	if q.Start.IsZero() {
+		q.Start = q.End.Add(-time.Hour)
+	}
+	if !q.Start.Before(q.End) {
+		return nil, fmt.Errorf("start must be before end")
+	}
+
+	series, err := p.GetSeries(q.DeviceID, q.OID)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]MetricSeriesData, len(series))
+	byID := make(map[uint]*MetricSeriesData, len(series))
+	ids := make([]uint, len(series))
+	for i := range series {
+		data[i] = MetricSeriesData{MetricSeries: series[i], Points: []MetricPoint{}}
+		byID[series[i].ID] = &data[i]
+		ids[i] = series[i].ID
+	}
+	if len(ids) == 0 {
+		return data, nil
+	}
+
+	var samples []models.MetricSample
+	err = p.db.Where("series_id IN ? AND ts >= ? AND ts <= ?", ids, q.Start.Unix(), q.End.Unix()).
+		Order("series_id, ts").Find(&samples).Error
+	if err != nil {
+		return nil, err
+	}
+
+	step := int64(q.Step / time.Second)
+	counts := make(map[uint][]int)
+	for _, sample := range samples {
+		d := byID[sample.SeriesID]
+		if step <= 0 {
+			d.Points = append(d.Points, MetricPoint{Time: sample.Time, Value: sample.Value})
+			continue
+		}
+		// 样本按时间排序, 同一个桶的样本是连续的
+		bucket := sample.Time / step * step
+		if n := len(d.Points); n > 0 && d.Points[n-1].Time == bucket {
+			d.Points[n-1].Value += sample.Value
+			counts[sample.SeriesID][n-1]++
+			continue
+		}
+		d.Points = append(d.Points, MetricPoint{Time: bucket, Value: sample.Value})
+		counts[sample.SeriesID] = append(counts[sample.SeriesID], 1)
+	}
+	for id, n := range counts {
+		points := byID[id].Points
+		for i := range points {
+			points[i].Value /= float64(n[i])
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,605 @@
+package services
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// 录制文件格式
+const (
+	RecordingFormatSNMPRec  = "snmprec"  // snmpsim: OID|TAG|VALUE
+	RecordingFormatSNMPWalk = "snmpwalk" // net-snmp: OID = TYPE: VALUE
+)
+
+// snmpRecord 录制文件中的一个实例
+type snmpRecord struct {
+	oid   string // 不带前导 "."
+	typ   gosnmp.Asn1BER
+	value interface{} // 与 gosnmp 编码时使用的类型一致
+}
+
+// snmprec 的类型标签 (BER tag 的十进制值)
+var snmprecTags = map[int]gosnmp.Asn1BER{
+	2:  gosnmp.Integer,
+	4:  gosnmp.OctetString,
+	5:  gosnmp.Null,
+	6:  gosnmp.ObjectIdentifier,
+	64: gosnmp.IPAddress,
+	65: gosnmp.Counter32,
+	66: gosnmp.Gauge32,
+	67: gosnmp.TimeTicks,
+	68: gosnmp.Opaque,
+	70: gosnmp.Counter64,
+}
+
+// net-snmp 输出中的类型名
+var snmpwalkTypes = map[string]gosnmp.Asn1BER{
+	"INTEGER":         gosnmp.Integer,
+	"STRING":          gosnmp.OctetString,
+	"Hex-STRING":      gosnmp.OctetString,
+	"BITS":            gosnmp.OctetString,
+	"OID":             gosnmp.ObjectIdentifier,
+	"IpAddress":       gosnmp.IPAddress,
+	"Network Address": gosnmp.IPAddress,
+	"Counter32":       gosnmp.Counter32,
+	"Gauge32":         gosnmp.Gauge32,
+	"Unsigned32":      gosnmp.Gauge32,
+	"Timeticks":       gosnmp.TimeTicks,
+	"Opaque":          gosnmp.Opaque,
+	"Counter64":       gosnmp.Counter64,
+	"NULL":            gosnmp.Null,
+}
+
+// snmpwalk 输出的一行: OID = TYPE: VALUE, 或没有类型的 OID = ""
+var snmpwalkLine = regexp.MustCompile(`^(\S+) = (?:([A-Za-z0-9 -]+): ?)?(.*)$`)
+
+// 没有加载 MIB 时 net-snmp 仍能输出的符号根
+var snmpwalkRoots = map[string]string{
+	"iso":         "1",
+	"internet":    "1.3.6.1",
+	"mib-2":       "1.3.6.1.2.1",
+	"enterprises": "1.3.6.1.4.1",
+}
+
+// DetectRecordingFormat 按文件扩展名判断录制格式, 无法判断时按内容判断
+func DetectRecordingFormat(filename, data string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".snmprec":
+		return RecordingFormatSNMPRec
+	case ".snmpwalk", ".walk":
+		return RecordingFormatSNMPWalk
+	}
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.Contains(line, " = ") && strings.Count(line, "|") >= 2 {
+			return RecordingFormatSNMPRec
+		}
+		return RecordingFormatSNMPWalk
+	}
+	return RecordingFormatSNMPWalk
+}
+
+// parseSNMPRecording 解析录制文件, 返回按 OID 排序、去重后的实例
+func parseSNMPRecording(data, format string, tree *OIDTree) ([]snmpRecord, error) {
+	var records []snmpRecord
+	var err error
+	switch format {
+	case RecordingFormatSNMPRec:
+		records, err = parseSNMPRec(data)
+	case RecordingFormatSNMPWalk:
+		records, err = parseSNMPWalk(data, tree)
+	default:
+		return nil, fmt.Errorf("unsupported recording format: %s (expected snmprec or snmpwalk)", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("recording contains no SNMP values")
+	}
+
+	// 同一 OID 出现多次时保留最后一个
+	sort.SliceStable(records, func(i, j int) bool {
+		return compareOIDs(records[i].oid, records[j].oid) < 0
+	})
+	unique := records[:0]
+	for _, record := range records {
+		if n := len(unique); n > 0 && unique[n-1].oid == record.oid {
+			unique[n-1] = record
+			continue
+		}
+		unique = append(unique, record)
+	}
+	return unique, nil
+}
+
+// parseSNMPRec 解析 snmpsim 的 .snmprec 格式; 标签带 x 后缀时值为十六进制
+func parseSNMPRec(data string) ([]snmpRecord, error) {
+	var records []snmpRecord
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "|", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("line %d: expected OID|TAG|VALUE", lineNo)
+		}
+		oid, err := normalizeOID(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+
+		tag := parts[1]
+		if strings.Contains(tag, ":") {
+			return nil, fmt.Errorf("line %d: variation modules are not supported (%s)", lineNo, tag)
+		}
+		value := parts[2]
+		hexValue := strings.HasSuffix(tag, "x")
+		if hexValue {
+			tag = strings.TrimSuffix(tag, "x")
+		}
+		n, err := strconv.Atoi(tag)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid type tag %q", lineNo, parts[1])
+		}
+		typ, ok := snmprecTags[n]
+		if !ok {
+			return nil, fmt.Errorf("line %d: unsupported type tag %d", lineNo, n)
+		}
+
+		var octets []byte
+		if hexValue {
+			if octets, err = hex.DecodeString(value); err != nil {
+				return nil, fmt.Errorf("line %d: invalid hex value", lineNo)
+			}
+			value = string(octets)
+		}
+		record, err := newSNMPRecord(oid, typ, value, octets)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recording: %v", err)
+	}
+	return records, nil
+}
+
+// newSNMPRecord 把文本值转换为 gosnmp 编码使用的类型; octets 不为空时是十六进制解码后的值
+func newSNMPRecord(oid string, typ gosnmp.Asn1BER, value string, octets []byte) (snmpRecord, error) {
+	record := snmpRecord{oid: oid, typ: typ}
+	switch typ {
+	case gosnmp.Integer:
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+		if err != nil {
+			return record, fmt.Errorf("invalid INTEGER %q", value)
+		}
+		record.value = int(n)
+	case gosnmp.Counter32, gosnmp.Gauge32, gosnmp.TimeTicks:
+		n, err := strconv.ParseUint(strings.TrimSpace(value), 10, 32)
+		if err != nil {
+			return record, fmt.Errorf("invalid %s %q", typ, value)
+		}
+		record.value = uint32(n)
+	case gosnmp.Counter64:
+		n, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return record, fmt.Errorf("invalid Counter64 %q", value)
+		}
+		record.value = n
+	case gosnmp.OctetString, gosnmp.Opaque:
+		if octets == nil {
+			octets = []byte(value)
+		}
+		record.value = octets
+	case gosnmp.ObjectIdentifier:
+		target, err := normalizeOID(value)
+		if err != nil {
+			return record, err
+		}
+		record.value = "." + target
+	case gosnmp.IPAddress:
+		if len(octets) == net.IPv4len {
+			value = net.IP(octets).String()
+		}
+		ip := net.ParseIP(strings.TrimSpace(value)).To4()
+		if ip == nil {
+			return record, fmt.Errorf("invalid IpAddress %q", value)
+		}
+		record.value = ip.String()
+	case gosnmp.Null:
+		record.value = nil
+	}
+	return record, nil
+}
+
+// parseSNMPWalk 解析 net-snmp snmpwalk 的输出 (-On 数字 OID 或 MODULE::name 形式);
+// 跨行的字符串和 Hex-STRING 续行拼接到上一行
+func parseSNMPWalk(data string, tree *OIDTree) ([]snmpRecord, error) {
+	type pending struct {
+		line   int
+		name   string
+		typ    string
+		value  string
+		quoted bool
+	}
+	var records []snmpRecord
+	var current *pending
+
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+		p := current
+		current = nil
+		record, ok, err := parseSNMPWalkValue(p.name, p.typ, p.value, tree)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", p.line, err)
+		}
+		if ok {
+			records = append(records, record)
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		// 未闭合的字符串一直续到结束引号
+		if current != nil && current.quoted && !snmpwalkQuoteClosed(current.value) {
+			current.value += "\n" + line
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		m := snmpwalkLine.FindStringSubmatch(line)
+		if m == nil {
+			if current == nil {
+				return nil, fmt.Errorf("line %d: expected OID = TYPE: VALUE", lineNo)
+			}
+			current.value += " " + trimmed
+			continue
+		}
+		if err := flush(); err != nil {
+			return nil, err
+		}
+		current = &pending{line: lineNo, name: m[1], typ: m[2], value: m[3]}
+		current.quoted = strings.HasPrefix(m[3], `"`) && (m[2] == "" || m[2] == "STRING")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recording: %v", err)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// snmpwalkQuoteClosed 判断以引号开头的值是否已经有未转义的结束引号
+func snmpwalkQuoteClosed(value string) bool {
+	for i := 1; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '"':
+			return true
+		}
+	}
+	return false
+}
+
+// parseSNMPWalkValue 转换 snmpwalk 的一个值; "No Such Object" 之类的异常返回 ok=false
+func parseSNMPWalkValue(name, typeName, value string, tree *OIDTree) (snmpRecord, bool, error) {
+	if typeName == "" && (strings.HasPrefix(value, "No ") || strings.HasPrefix(value, "Wrong Type")) {
+		return snmpRecord{}, false, nil
+	}
+	if typeName == "" && value == "NULL" {
+		typeName = "NULL"
+	}
+	oid, err := resolveRecordingOID(name, tree)
+	if err != nil {
+		return snmpRecord{}, false, err
+	}
+	if typeName == "" {
+		// OID = "" 是空字符串
+		typeName = "STRING"
+	}
+	typ, ok := snmpwalkTypes[typeName]
+	if !ok {
+		return snmpRecord{}, false, fmt.Errorf("unsupported type %s", typeName)
+	}
+
+	value = strings.TrimSpace(value)
+	switch typeName {
+	case "STRING":
+		if strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) && len(value) >= 2 {
+			value = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(value[1 : len(value)-1])
+		}
+		record, err := newSNMPRecord(oid, typ, value, nil)
+		return record, true, err
+	case "Hex-STRING", "BITS", "Opaque":
+		octets, err := parseSNMPWalkHex(value)
+		if err != nil {
+			return snmpRecord{}, false, err
+		}
+		record, err := newSNMPRecord(oid, typ, "", octets)
+		return record, true, err
+	case "OID":
+		target, err := resolveRecordingOID(value, tree)
+		if err != nil {
+			return snmpRecord{}, false, err
+		}
+		record, err := newSNMPRecord(oid, typ, target, nil)
+		return record, true, err
+	case "Timeticks":
+		// Timeticks: (12345) 0:02:03.45
+		if i, j := strings.Index(value, "("), strings.Index(value, ")"); i >= 0 && j > i {
+			value = value[i+1 : j]
+		}
+	case "INTEGER":
+		// INTEGER: up(1)
+		if i, j := strings.LastIndex(value, "("), strings.LastIndex(value, ")"); i >= 0 && j > i {
+			value = value[i+1 : j]
+		}
+	case "NULL":
+		value = ""
+	}
+	// 带 UNITS 时数值后面跟单位, 如 "Gauge32: 1000 Mbps"
+	if fields := strings.Fields(value); len(fields) > 0 {
+		value = fields[0]
+	}
+	record, err := newSNMPRecord(oid, typ, value, nil)
+	return record, true, err
+}
+
+// parseSNMPWalkHex 解析 "00 1A 2B" 形式的十六进制, BITS 的值后面可能跟位名
+func parseSNMPWalkHex(value string) ([]byte, error) {
+	var octets []byte
+	for _, field := range strings.Fields(value) {
+		if len(field) != 2 {
+			break
+		}
+		b, err := hex.DecodeString(field)
+		if err != nil {
+			break
+		}
+		octets = append(octets, b...)
+	}
+	if octets == nil && strings.TrimSpace(value) != "" && value != `""` {
+		return nil, fmt.Errorf("invalid hex value %q", value)
+	}
+	if octets == nil {
+		octets = []byte{}
+	}
+	return octets, nil
+}
+
+// resolveRecordingOID 把 snmpwalk 输出的 OID (数字、iso.3.6... 或 MODULE::name.index) 转换为数字 OID
+func resolveRecordingOID(name string, tree *OIDTree) (string, error) {
+	name = strings.TrimSpace(name)
+	if oid, err := normalizeOID(name); err == nil {
+		return oid, nil
+	}
+
+	symbol, suffix := name, ""
+	module := ""
+	if i := strings.Index(symbol, "::"); i >= 0 {
+		module, symbol = symbol[:i], symbol[i+2:]
+	}
+	if i := strings.Index(symbol, "."); i >= 0 {
+		symbol, suffix = symbol[:i], symbol[i+1:]
+	}
+	if strings.ContainsAny(symbol, "[]\"") {
+		return "", fmt.Errorf("unsupported OID %q, record the walk with -On", name)
+	}
+
+	base, ok := snmpwalkRoots[symbol]
+	if !ok {
+		if tree == nil {
+			return "", fmt.Errorf("unknown OID %q, record the walk with -On", name)
+		}
+		ref := symbol
+		if module != "" {
+			ref = module + "::" + symbol
+		}
+		node, err := tree.Resolve(ref)
+		if err != nil {
+			return "", fmt.Errorf("unknown OID %q, record the walk with -On or load its MIB", name)
+		}
+		base = node.OID
+	}
+	if suffix == "" {
+		return base, nil
+	}
+	return normalizeOID(base + "." + suffix)
+}
+
+// formatSNMPRec 把实例写成 .snmprec 格式, 不可打印的字符串用十六进制
+func formatSNMPRec(records []snmpRecord) string {
+	tags := make(map[gosnmp.Asn1BER]int, len(snmprecTags))
+	for tag, typ := range snmprecTags {
+		tags[typ] = tag
+	}
+
+	var b strings.Builder
+	for _, record := range records {
+		tag := tags[record.typ]
+		switch value := record.value.(type) {
+		case []byte:
+			if isPrintableOctets(value) && !strings.ContainsAny(string(value), "\r\n") {
+				fmt.Fprintf(&b, "%s|%d|%s\n", record.oid, tag, value)
+			} else {
+				fmt.Fprintf(&b, "%s|%dx|%s\n", record.oid, tag, hex.EncodeToString(value))
+			}
+		case string:
+			fmt.Fprintf(&b, "%s|%d|%s\n", record.oid, tag, strings.TrimPrefix(value, "."))
+		case nil:
+			fmt.Fprintf(&b, "%s|%d|\n", record.oid, tag)
+		default:
+			fmt.Fprintf(&b, "%s|%d|%v\n", record.oid, tag, value)
+		}
+	}
+	return b.String()
+}
+
+// snmpRecordFromPDU 把 walk 得到的变量绑定转换为录制实例; 异常值返回 false
+func snmpRecordFromPDU(pdu gosnmp.SnmpPDU) (snmpRecord, bool) {
+	record := snmpRecord{oid: strings.TrimPrefix(pdu.Name, "."), typ: pdu.Type}
+	switch pdu.Type {
+	case gosnmp.Integer:
+		n, ok := pdu.Value.(int)
+		if !ok {
+			return record, false
+		}
+		record.value = n
+	case gosnmp.Counter32, gosnmp.Gauge32, gosnmp.TimeTicks:
+		switch n := pdu.Value.(type) {
+		case uint32:
+			record.value = n
+		case uint:
+			record.value = uint32(n)
+		default:
+			return record, false
+		}
+	case gosnmp.Counter64:
+		n, ok := pdu.Value.(uint64)
+		if !ok {
+			return record, false
+		}
+		record.value = n
+	case gosnmp.OctetString, gosnmp.Opaque, gosnmp.BitString:
+		octets, ok := pdu.Value.([]byte)
+		if !ok {
+			return record, false
+		}
+		record.typ = gosnmp.OctetString
+		if pdu.Type == gosnmp.Opaque {
+			record.typ = gosnmp.Opaque
+		}
+		record.value = append([]byte{}, octets...)
+	case gosnmp.ObjectIdentifier:
+		oid, ok := pdu.Value.(string)
+		if !ok {
+			return record, false
+		}
+		record.value = "." + strings.TrimPrefix(oid, ".")
+	case gosnmp.IPAddress:
+		ip, ok := pdu.Value.(string)
+		if !ok {
+			return record, false
+		}
+		record.value = ip
+	case gosnmp.Null:
+		record.value = nil
+	default:
+		return record, false
+	}
+	return record, true
+}
+
+// snmpRecordText 值的文本形式: 可打印字符串原样, 二进制为十六进制
+func snmpRecordText(record snmpRecord) string {
+	switch value := record.value.(type) {
+	case []byte:
+		if isPrintableOctets(value) {
+			return string(value)
+		}
+		return formatSNMPHexString(value)
+	case string:
+		return strings.TrimPrefix(value, ".")
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// snmpRecordDisplay 按 MIB 中的 DISPLAY-HINT 和枚举显示值, node 为空或没有特殊格式时使用 snmpRecordText
+func snmpRecordDisplay(node *OIDTreeNode, record snmpRecord) string {
+	if node != nil {
+		value := record.value
+		if octets, ok := value.([]byte); ok {
+			value = string(octets)
+		}
+		if display := formatSNMPValue(node, value); display != "" {
+			return display
+		}
+	}
+	return snmpRecordText(record)
+}
+
+// formatSNMPWalk 把实例写成 net-snmp snmpwalk -On 的输出格式
+func formatSNMPWalk(records []snmpRecord) string {
+	var b strings.Builder
+	for _, record := range records {
+		fmt.Fprintf(&b, ".%s = ", record.oid)
+		switch record.typ {
+		case gosnmp.Integer:
+			fmt.Fprintf(&b, "INTEGER: %v\n", record.value)
+		case gosnmp.OctetString:
+			octets, _ := record.value.([]byte)
+			switch {
+			case len(octets) == 0:
+				b.WriteString("\"\"\n")
+			// 行尾的 \r 读回时会被去掉, 这样的字符串按十六进制写
+			case isPrintableOctets(octets) && !strings.Contains(string(octets), "\r"):
+				fmt.Fprintf(&b, "STRING: \"%s\"\n", strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(string(octets)))
+			default:
+				fmt.Fprintf(&b, "Hex-STRING: %s\n", formatSNMPHexString(octets))
+			}
+		case gosnmp.Opaque:
+			octets, _ := record.value.([]byte)
+			fmt.Fprintf(&b, "Opaque: %s\n", formatSNMPHexString(octets))
+		case gosnmp.ObjectIdentifier:
+			fmt.Fprintf(&b, "OID: %s\n", record.value)
+		case gosnmp.IPAddress:
+			fmt.Fprintf(&b, "IpAddress: %s\n", record.value)
+		case gosnmp.Counter32:
+			fmt.Fprintf(&b, "Counter32: %v\n", record.value)
+		case gosnmp.Gauge32:
+			fmt.Fprintf(&b, "Gauge32: %v\n", record.value)
+		case gosnmp.TimeTicks:
+			ticks, _ := record.value.(uint32)
+			fmt.Fprintf(&b, "Timeticks: (%d) %s\n", ticks, formatSNMPTimeTicks(ticks))
+		case gosnmp.Counter64:
+			fmt.Fprintf(&b, "Counter64: %v\n", record.value)
+		case gosnmp.Null:
+			b.WriteString("NULL\n")
+		}
+	}
+	return b.String()
+}
+
+// formatSNMPTimeTicks 与 net-snmp 相同的 "1 day, 2:03:04.05" 格式
+func formatSNMPTimeTicks(ticks uint32) string {
+	days := ticks / 8640000
+	rest := ticks % 8640000
+	clock := fmt.Sprintf("%d:%02d:%02d.%02d", rest/360000, rest/6000%60, rest/100%60, rest%100)
+	switch days {
+	case 0:
+		return clock
+	case 1:
+		return "1 day, " + clock
+	default:
+		return fmt.Sprintf("%d days, %s", days, clock)
+	}
+}
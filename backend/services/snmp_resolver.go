@@ -0,0 +1,275 @@
+package services
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"mib-platform/models"
+)
+
+// SNMPResolver 用 MIB 库的 OID 树翻译 SNMP 响应中的 OID 和值
+type SNMPResolver struct {
+	tree *OIDTree
+}
+
+// NewSNMPResolver 创建基于 OID 树的解析器
+func NewSNMPResolver(tree *OIDTree) *SNMPResolver {
+	return &SNMPResolver{tree: tree}
+}
+
+// Resolve 为结果补充名称 (名称加索引后缀)、所属模块和格式化后的值; 无法匹配的 OID 保持原样
+func (r *SNMPResolver) Resolve(results []models.SNMPResult) {
+	for i := range results {
+		r.resolveResult(&results[i])
+	}
+}
+
+func (r *SNMPResolver) resolveResult(result *models.SNMPResult) {
+	match, err := r.tree.LongestPrefixMatch(strings.TrimPrefix(result.OID, "."))
+	if err != nil {
+		return
+	}
+
+	node := &match.Node
+	result.Name = node.Name
+	result.Module = node.Module
+	result.Index = match.Suffix
+	if match.Suffix != "" {
+		result.Name += "." + match.Suffix
+	}
+
+	// 只对对象实例格式化, 表和行节点下的 OID 不是该节点的值
+	if node.Kind == MIBKindTable || node.Kind == MIBKindRow {
+		return
+	}
+	result.Display = formatSNMPValue(node, result.Value)
+}
+
+// formatSNMPValue 按枚举和 DISPLAY-HINT 格式化值, 没有可用信息时返回空字符串
+func formatSNMPValue(node *OIDTreeNode, value interface{}) string {
+	if data, ok := value.(string); ok {
+		octets := []byte(data)
+		if node.BaseType == "BITS" && len(node.Enums) > 0 {
+			return formatSNMPBits(octets, node.Enums)
+		}
+		if node.DisplayHint != "" {
+			if s, ok := applyOctetDisplayHint(node.DisplayHint, octets); ok {
+				return s
+			}
+		}
+		if !isPrintableOctets(octets) {
+			return formatSNMPHexString(octets)
+		}
+		return ""
+	}
+
+	n, ok := snmpIntegerValue(value)
+	if !ok {
+		return ""
+	}
+	for _, e := range node.Enums {
+		if big.NewInt(e.Value).Cmp(n) == 0 {
+			return fmt.Sprintf("%s(%d)", e.Name, e.Value)
+		}
+	}
+	if node.DisplayHint != "" {
+		if s, ok := applyIntegerDisplayHint(node.DisplayHint, n); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// snmpIntegerValue 将 gosnmp 返回的整数类型统一为 big.Int
+func snmpIntegerValue(value interface{}) (*big.Int, bool) {
+	switch v := value.(type) {
+	case int:
+		return big.NewInt(int64(v)), true
+	case int32:
+		return big.NewInt(int64(v)), true
+	case int64:
+		return big.NewInt(v), true
+	case uint:
+		return new(big.Int).SetUint64(uint64(v)), true
+	case uint32:
+		return new(big.Int).SetUint64(uint64(v)), true
+	case uint64:
+		return new(big.Int).SetUint64(v), true
+	case *big.Int:
+		return v, v != nil
+	case float64: // 经过 JSON 往返的值
+		if v == float64(int64(v)) {
+			return big.NewInt(int64(v)), true
+		}
+	}
+	return nil, false
+}
+
+// formatSNMPBits 列出 BITS 值中置位的命名位, 第一个字节的最高位为位 0
+func formatSNMPBits(octets []byte, enums []models.OIDEnum) string {
+	var names []string
+	for _, e := range enums {
+		if e.Value < 0 || int(e.Value/8) >= len(octets) {
+			continue
+		}
+		if octets[e.Value/8]&(0x80>>uint(e.Value%8)) != 0 {
+			names = append(names, fmt.Sprintf("%s(%d)", e.Name, e.Value))
+		}
+	}
+	return strings.Join(names, " ")
+}
+
+// isPrintableOctets 判断字符串值能否原样显示
+func isPrintableOctets(octets []byte) bool {
+	if !utf8.Valid(octets) {
+		return false
+	}
+	for _, r := range string(octets) {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+// formatSNMPHexString 以 "00 1A 2B" 的形式显示二进制字符串
+func formatSNMPHexString(octets []byte) string {
+	parts := make([]string, len(octets))
+	for i, b := range octets {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, " ")
+}
+
+// octetDisplayHintSpec RFC 2579 OCTET STRING DISPLAY-HINT 中的一段
+type octetDisplayHintSpec struct {
+	repeat bool // '*' 前缀: 第一个字节为重复次数
+	length int
+	format byte // x, d, o, a, t
+	sep    byte
+	term   byte
+}
+
+func parseOctetDisplayHint(hint string) ([]octetDisplayHintSpec, bool) {
+	var specs []octetDisplayHintSpec
+	isDigit := func(c byte) bool { return c >= '0' && c <= '9' }
+	for i := 0; i < len(hint); {
+		var spec octetDisplayHintSpec
+		if hint[i] == '*' {
+			spec.repeat = true
+			i++
+		}
+		start := i
+		for i < len(hint) && isDigit(hint[i]) {
+			i++
+		}
+		if start == i || i >= len(hint) {
+			return nil, false
+		}
+		spec.length, _ = strconv.Atoi(hint[start:i])
+		spec.format = hint[i]
+		if !strings.ContainsRune("xdoat", rune(spec.format)) {
+			return nil, false
+		}
+		i++
+		if i < len(hint) && !isDigit(hint[i]) && hint[i] != '*' {
+			spec.sep = hint[i]
+			i++
+		}
+		if spec.repeat && i < len(hint) && !isDigit(hint[i]) && hint[i] != '*' {
+			spec.term = hint[i]
+			i++
+		}
+		specs = append(specs, spec)
+	}
+	return specs, len(specs) > 0
+}
+
+// applyOctetDisplayHint 按 DISPLAY-HINT 格式化 OCTET STRING; 最后一段重复使用直到数据结束
+func applyOctetDisplayHint(hint string, octets []byte) (string, bool) {
+	specs, ok := parseOctetDisplayHint(hint)
+	if !ok {
+		return "", false
+	}
+
+	var b strings.Builder
+	for pos, k := 0, 0; pos < len(octets); k++ {
+		spec := specs[len(specs)-1]
+		if k < len(specs) {
+			spec = specs[k]
+		}
+
+		count := 1
+		if spec.repeat {
+			count = int(octets[pos])
+			pos++
+		}
+		for r := 0; r < count && pos < len(octets); r++ {
+			n := spec.length
+			if n > len(octets)-pos {
+				n = len(octets) - pos
+			}
+			chunk := octets[pos : pos+n]
+			pos += n
+
+			switch spec.format {
+			case 'a', 't':
+				b.Write(chunk)
+			default:
+				v := new(big.Int).SetBytes(chunk)
+				switch spec.format {
+				case 'x':
+					b.WriteString(fmt.Sprintf("%0*x", 2*n, v))
+				case 'o':
+					b.WriteString(v.Text(8))
+				default:
+					b.WriteString(v.String())
+				}
+			}
+
+			last := r == count-1
+			if pos < len(octets) && spec.sep != 0 && !(last && spec.term != 0) {
+				b.WriteByte(spec.sep)
+			}
+		}
+		if spec.repeat && spec.term != 0 && pos < len(octets) {
+			b.WriteByte(spec.term)
+		}
+		if spec.length == 0 && !spec.repeat {
+			break
+		}
+	}
+	return b.String(), true
+}
+
+// applyIntegerDisplayHint 按 DISPLAY-HINT 格式化整数: d, d-N (N 位小数), x, o, b
+func applyIntegerDisplayHint(hint string, n *big.Int) (string, bool) {
+	switch {
+	case hint == "d":
+		return n.String(), true
+	case hint == "x":
+		return n.Text(16), true
+	case hint == "o":
+		return n.Text(8), true
+	case hint == "b":
+		return n.Text(2), true
+	case strings.HasPrefix(hint, "d-"):
+		places, err := strconv.Atoi(hint[2:])
+		if err != nil || places <= 0 {
+			return "", false
+		}
+		digits := new(big.Int).Abs(n).String()
+		for len(digits) <= places {
+			digits = "0" + digits
+		}
+		s := digits[:len(digits)-places] + "." + digits[len(digits)-places:]
+		if n.Sign() < 0 {
+			s = "-" + s
+		}
+		return s, true
+	}
+	return "", false
+}
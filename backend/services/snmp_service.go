@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -15,13 +16,14 @@ import (
 )
 
 type SNMPService struct {
-	db *gorm.DB
+	db       *gorm.DB
+	resolver *SNMPResolver
 }
 
 func NewSNMPService(db *gorm.DB) *SNMPService {
 	return &SNMPService{
-		db: db,
-		
+		db:       db,
+		resolver: NewSNMPResolver(sharedOIDTree(db)),
 	}
 }
 
@@ -56,6 +58,8 @@ func (s *SNMPService) SNMPGet(req *models.SNMPRequest) (*models.SNMPResponse, er
 		})
 	}
 
+	s.resolveResults(req, data)
+
 	return &models.SNMPResponse{
 		Success:   true,
 		Message:   "SNMP Get successful",
@@ -80,7 +84,7 @@ func (s *SNMPService) SNMPWalk(req *models.SNMPRequest) (*models.SNMPResponse, e
 
 	// Perform SNMP Walk
 	var data []models.SNMPResult
-	err = snmp.Walk(req.OID, func(pdu gosnmp.SnmpPDU) error {
+	stats, err := s.walk(snmp, req, req.OID, func(pdu gosnmp.SnmpPDU) error {
 		data = append(data, models.SNMPResult{
 			OID:   pdu.Name,
 			Type:  pdu.Type.String(),
@@ -98,6 +102,8 @@ func (s *SNMPService) SNMPWalk(req *models.SNMPRequest) (*models.SNMPResponse, e
 		}, nil
 	}
 
+	s.resolveResults(req, data)
+
 	return &models.SNMPResponse{
 		Success:   true,
 		Message:   "SNMP Walk successful",
@@ -106,30 +112,25 @@ func (s *SNMPService) SNMPWalk(req *models.SNMPRequest) (*models.SNMPResponse, e
 		Duration:  time.Since(start).String(),
 		Stats: map[string]interface{}{
 			"variables_returned": len(data),
+			"method":             stats.Method,
+			"requests":           stats.PDUs,
 		},
 	}, nil
 }
 
+// SNMPSet 单个变量的 SET, 与 ApplySet 一样先预读和校验并记录审计
 func (s *SNMPService) SNMPSet(req *models.SNMPSetRequest) (*models.SNMPResponse, error) {
 	start := time.Now()
 
-	// Create SNMP connection
-	snmp, err := s.createSNMPConnection(&req.SNMPRequest)
-	if err != nil {
-		return nil, err
-	}
-	defer snmp.Conn.Close()
-
-	// Create PDU for Set operation
-	pdu := gosnmp.SnmpPDU{
-		Name:  req.OID,
-		Type:  s.getSNMPType(req.Type),
-		Value: req.Value,
-	}
-
-	// Perform SNMP Set
-	result, err := snmp.Set([]gosnmp.SnmpPDU{pdu})
+	operation, err := s.ApplySet(&models.SNMPSetOperationRequest{
+		Connection: &req.SNMPRequest,
+		Varbinds:   []models.SNMPSetVarbind{{OID: req.OID, Value: req.Value, Type: req.Type}},
+	})
 	if err != nil {
+		var invalid *SNMPSetValidationError
+		if operation == nil && !errors.As(err, &invalid) {
+			return nil, err
+		}
 		return &models.SNMPResponse{
 			Success:   false,
 			Message:   err.Error(),
@@ -140,14 +141,20 @@ func (s *SNMPService) SNMPSet(req *models.SNMPSetRequest) (*models.SNMPResponse,
 
 	// Convert results
 	var data []models.SNMPResult
-	for _, variable := range result.Variables {
+	for _, change := range operation.Changes {
+		records, err := parseSNMPRec(change.NewRecord)
+		if err != nil || len(records) != 1 {
+			continue
+		}
 		data = append(data, models.SNMPResult{
-			OID:   variable.Name,
-			Type:  variable.Type.String(),
-			Value: s.convertSNMPValue(variable),
+			OID:   "." + change.OID,
+			Type:  records[0].typ.String(),
+			Value: s.convertSNMPValue(gosnmp.SnmpPDU{Type: records[0].typ, Value: records[0].value}),
 		})
 	}
 
+	s.resolveResults(&req.SNMPRequest, data)
+
 	return &models.SNMPResponse{
 		Success:   true,
 		Message:   "SNMP Set successful",
@@ -189,31 +196,6 @@ func (s *SNMPService) TestConnection(req *models.SNMPRequest) (map[string]interf
 	}, nil
 }
 
-func (s *SNMPService) StartBulkOperation(operationType string, requests []models.SNMPRequest) (*models.BulkOperation, error) {
-	operation := &models.BulkOperation{
-		ID:        fmt.Sprintf("bulk_%d", time.Now().Unix()),
-		Type:      operationType,
-		Status:    "running",
-		Progress:  0,
-		Total:     len(requests),
-		StartTime: time.Now(),
-	}
-
-	// Start background processing
-	go s.processBulkOperation(operation, requests)
-
-	return operation, nil
-}
-
-func (s *SNMPService) GetBulkOperation(id string) (*models.BulkOperation, error) {
-	return &models.BulkOperation{
-		ID:       id,
-		Status:   "completed",
-		Progress: 100,
-		Total:    10,
-	}, nil
-}
-
 func (s *SNMPService) createSNMPConnection(req *models.SNMPRequest) (*gosnmp.GoSNMP, error) {
 	snmp := &gosnmp.GoSNMP{
 		Target:    req.Target,
@@ -243,19 +225,8 @@ func (s *SNMPService) createSNMPConnection(req *models.SNMPRequest) (*gosnmp.GoS
 		snmp.Version = gosnmp.Version2c
 		snmp.Community = req.Community
 	case "v3":
-		snmp.Version = gosnmp.Version3
-		snmp.SecurityModel = gosnmp.UserSecurityModel
-		snmp.MsgFlags = gosnmp.AuthNoPriv
-		snmp.SecurityParameters = &gosnmp.UsmSecurityParameters{
-			UserName:                 req.Username,
-			AuthenticationProtocol:   gosnmp.MD5,
-			AuthenticationPassphrase: req.AuthKey,
-		}
-		
-		if req.PrivKey != "" {
-			snmp.MsgFlags = gosnmp.AuthPriv
-			snmp.SecurityParameters.(*gosnmp.UsmSecurityParameters).PrivacyProtocol = gosnmp.DES
-			snmp.SecurityParameters.(*gosnmp.UsmSecurityParameters).PrivacyPassphrase = req.PrivKey
+		if err := configureSNMPv3(snmp, req); err != nil {
+			return nil, err
 		}
 	default:
 		return nil, fmt.Errorf("unsupported SNMP version: %s", req.Version)
@@ -287,59 +258,9 @@ func (s *SNMPService) convertSNMPValue(pdu gosnmp.SnmpPDU) interface{} {
 	}
 }
 
-func (s *SNMPService) getSNMPType(typeStr string) gosnmp.Asn1BER {
-	switch typeStr {
-	case "integer":
-		return gosnmp.Integer
-	case "string":
-		return gosnmp.OctetString
-	case "counter32":
-		return gosnmp.Counter32
-	case "counter64":
-		return gosnmp.Counter64
-	case "gauge32":
-		return gosnmp.Gauge32
-	case "timeticks":
-		return gosnmp.TimeTicks
-	case "ipaddress":
-		return gosnmp.IPAddress
-	default:
-		return gosnmp.OctetString
+// resolveResults 请求开启 resolve 时用 MIB 库翻译结果, 否则保留原始 OID 和值
+func (s *SNMPService) resolveResults(req *models.SNMPRequest, data []models.SNMPResult) {
+	if req.Resolve {
+		s.resolver.Resolve(data)
 	}
 }
-
-func (s *SNMPService) processBulkOperation(operation *models.BulkOperation, requests []models.SNMPRequest) {
-	// 简化的批量处理实现
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				fmt.Printf("批量SNMP操作异常: %v\n", r)
-			}
-		}()
-		
-		for i, request := range requests {
-			// 更新进度
-			progress := int(float64(i) / float64(len(requests)) * 100)
-			operation.Progress = progress
-			
-			// 执行SNMP操作 - 简化版本
-			snmpOp := SNMPOperation{
-				Type:      "get",
-				Target:    request.Target,
-				Community: request.Community,
-				Version:   request.Version,
-				OID:       request.OID,
-			}
-			result := s.executeSingleSNMPOperation(snmpOp)
-			
-			// 模拟存储结果
-			if result["success"].(bool) {
-				operation.Progress = progress
-			}
-		}
-		
-		// 完成处理
-		operation.Progress = 100
-		operation.Status = "completed"
-	}()
-}
@@ -0,0 +1,643 @@
+package services
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"gorm.io/gorm"
+
+	"mib-platform/models"
+)
+
+// SET 审计记录状态
+const (
+	SNMPSetStatusDryRun   = "dry-run"
+	SNMPSetStatusApplied  = "applied"
+	SNMPSetStatusFailed   = "failed"
+	SNMPSetStatusReverted = "reverted"
+)
+
+// ErrSNMPSetConflict 回滚前发现设备上的值已不是当时写入的值
+var ErrSNMPSetConflict = errors.New("values were changed on the device after the SET")
+
+// mibWritableAccess 可以 SET 的访问级别, write-only 只出现在 SMIv1
+var mibWritableAccess = map[string]bool{
+	"read-write":  true,
+	"read-create": true,
+	"write-only":  true,
+}
+
+// snmpSetTypes 请求中可以显式指定的类型, hex 表示以十六进制给出的 OCTET STRING
+var snmpSetTypes = map[string]gosnmp.Asn1BER{
+	"integer":    gosnmp.Integer,
+	"string":     gosnmp.OctetString,
+	"hex":        gosnmp.OctetString,
+	"oid":        gosnmp.ObjectIdentifier,
+	"ipaddress":  gosnmp.IPAddress,
+	"counter32":  gosnmp.Counter32,
+	"counter64":  gosnmp.Counter64,
+	"gauge32":    gosnmp.Gauge32,
+	"unsigned32": gosnmp.Gauge32,
+	"timeticks":  gosnmp.TimeTicks,
+}
+
+// snmpSetBaseTypes MIB 基础类型在 PDU 中的编码类型
+var snmpSetBaseTypes = map[string]gosnmp.Asn1BER{
+	"INTEGER":           gosnmp.Integer,
+	"Integer32":         gosnmp.Integer,
+	"Unsigned32":        gosnmp.Gauge32,
+	"Gauge32":           gosnmp.Gauge32,
+	"Gauge":             gosnmp.Gauge32,
+	"Counter32":         gosnmp.Counter32,
+	"Counter":           gosnmp.Counter32,
+	"Counter64":         gosnmp.Counter64,
+	"TimeTicks":         gosnmp.TimeTicks,
+	"IpAddress":         gosnmp.IPAddress,
+	"NetworkAddress":    gosnmp.IPAddress,
+	"OCTET STRING":      gosnmp.OctetString,
+	"BITS":              gosnmp.OctetString,
+	"Opaque":            gosnmp.Opaque,
+	"OBJECT IDENTIFIER": gosnmp.ObjectIdentifier,
+}
+
+// SNMPSetValidationError 预检发现的问题; 出现时不会写入任何变量
+type SNMPSetValidationError struct {
+	Problems []string `json:"problems"`
+}
+
+func (e *SNMPSetValidationError) Error() string {
+	return "SET rejected: " + strings.Join(e.Problems, "; ")
+}
+
+// SNMPSetOperationQuery SET 审计记录查询条件
+type SNMPSetOperationQuery struct {
+	DeviceID uint
+	Target   string
+	Status   string
+	Page     int
+	Limit    int
+}
+
+// snmpSetPlan 预检后的一个变量
+type snmpSetPlan struct {
+	name   string
+	module string
+	node   *OIDTreeNode // 不是 MIB 库中的标量或列对象时为空
+	old    *snmpRecord  // 写入前实例不存在时为空
+	record snmpRecord
+}
+
+// ApplySet 安全 SET: 读取当前值, 按 MIB 语法 (类型、取值范围、枚举、SIZE) 校验新值, 再在一个 PDU 中写入所有变量并记录审计.
+// 校验或预读失败时不写入也不记录; 设备拒绝或 SET 超时时记录为 failed 并返回错误
+func (s *SNMPService) ApplySet(req *models.SNMPSetOperationRequest) (*models.SNMPSetOperation, error) {
+	start := time.Now()
+	conn, deviceID, err := s.setConnection(req.DeviceID, req.Connection)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	plans := make([]snmpSetPlan, len(req.Varbinds))
+	seen := make(map[string]bool)
+	for i, varbind := range req.Varbinds {
+		oid, err := resolveRecordingOID(varbind.OID, s.resolver.tree)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if seen[oid] {
+			problems = append(problems, fmt.Sprintf("%s is set more than once", varbind.OID))
+			continue
+		}
+		seen[oid] = true
+		plan, planProblems := s.setPlan(oid)
+		plans[i] = plan
+		problems = append(problems, planProblems...)
+	}
+	if len(problems) > 0 {
+		return nil, &SNMPSetValidationError{Problems: problems}
+	}
+
+	snmp, err := s.createSNMPConnection(conn)
+	if err != nil {
+		return nil, err
+	}
+	defer snmp.Conn.Close()
+	if len(plans) > snmp.MaxOids {
+		return nil, &SNMPSetValidationError{Problems: []string{fmt.Sprintf("at most %d varbinds fit in one SET", snmp.MaxOids)}}
+	}
+
+	if err := s.readSetValues(snmp, plans); err != nil {
+		return nil, err
+	}
+	for i := range plans {
+		record, err := s.setValue(&plans[i], req.Varbinds[i])
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", plans[i].name, err))
+			continue
+		}
+		plans[i].record = record
+	}
+	if len(problems) > 0 {
+		return nil, &SNMPSetValidationError{Problems: problems}
+	}
+
+	operation := &models.SNMPSetOperation{
+		DeviceID: deviceID,
+		Target:   conn.Target,
+		Port:     int(snmp.Port),
+		Version:  conn.Version,
+		Status:   SNMPSetStatusDryRun,
+		Comment:  req.Comment,
+		Changes:  setChanges(plans),
+	}
+	if req.DryRun {
+		operation.Duration = time.Since(start).String()
+		return operation, nil
+	}
+
+	err = executeSet(snmp, plans)
+	operation.Duration = time.Since(start).String()
+	operation.Status = SNMPSetStatusApplied
+	if err != nil {
+		operation.Status = SNMPSetStatusFailed
+		operation.Error = err.Error()
+	}
+	if serr := s.db.Create(operation).Error; serr != nil {
+		return operation, fmt.Errorf("failed to save SET audit record: %v", serr)
+	}
+	return operation, err
+}
+
+// RevertSetOperation 把 applied 状态的操作写回原值, 作为一条新的审计记录保存.
+// 设备上的当前值与当时写入的值不一致时返回 ErrSNMPSetConflict, force 为 true 时仍然回滚;
+// 没有关联设备的操作需要在 conn 中重新提供连接参数
+func (s *SNMPService) RevertSetOperation(id uint, conn *models.SNMPRequest, force bool, comment string) (*models.SNMPSetOperation, error) {
+	start := time.Now()
+	var original models.SNMPSetOperation
+	if err := s.db.First(&original, id).Error; err != nil {
+		return nil, err
+	}
+	if original.Status != SNMPSetStatusApplied {
+		return nil, &SNMPSetValidationError{Problems: []string{fmt.Sprintf("operation %d is %s, only applied operations can be reverted", id, original.Status)}}
+	}
+
+	var deviceID uint
+	if original.DeviceID != nil {
+		deviceID = *original.DeviceID
+	} else if conn != nil {
+		port := conn.Port
+		if port == 0 {
+			port = 161
+		}
+		if conn.Target != original.Target || port != original.Port {
+			return nil, &SNMPSetValidationError{Problems: []string{fmt.Sprintf("connection must target %s:%d", original.Target, original.Port)}}
+		}
+	}
+	req, device, err := s.setConnection(deviceID, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	plans := make([]snmpSetPlan, len(original.Changes))
+	for i, change := range original.Changes {
+		plans[i], _ = s.setPlan(change.OID)
+		if change.OldRecord == "" {
+			problems = append(problems, fmt.Sprintf("%s did not exist before the SET and cannot be restored", plans[i].name))
+			continue
+		}
+		records, err := parseSNMPRec(change.OldRecord)
+		if err != nil || len(records) != 1 {
+			problems = append(problems, fmt.Sprintf("%s: invalid audit record %q", plans[i].name, change.OldRecord))
+			continue
+		}
+		plans[i].record = records[0]
+	}
+	if len(problems) > 0 {
+		return nil, &SNMPSetValidationError{Problems: problems}
+	}
+
+	snmp, err := s.createSNMPConnection(req)
+	if err != nil {
+		return nil, err
+	}
+	defer snmp.Conn.Close()
+
+	// 确认设备上仍是当时写入的值, 同时记录回滚前的实际值
+	if err := s.readSetValues(snmp, plans); err != nil {
+		return nil, err
+	}
+	var changed []string
+	for i, change := range original.Changes {
+		if plans[i].old == nil || snmpRecordLine(*plans[i].old) != change.NewRecord {
+			changed = append(changed, plans[i].name)
+		}
+	}
+	if len(changed) > 0 && !force {
+		return nil, fmt.Errorf("%w: %s", ErrSNMPSetConflict, strings.Join(changed, ", "))
+	}
+
+	if comment == "" {
+		comment = fmt.Sprintf("revert of operation %d", original.ID)
+	}
+	revert := &models.SNMPSetOperation{
+		DeviceID: device,
+		Target:   original.Target,
+		Port:     original.Port,
+		Version:  req.Version,
+		Comment:  comment,
+		Changes:  setChanges(plans),
+		RevertOf: &original.ID,
+	}
+	err = executeSet(snmp, plans)
+	revert.Duration = time.Since(start).String()
+	revert.Status = SNMPSetStatusApplied
+	if err != nil {
+		revert.Status = SNMPSetStatusFailed
+		revert.Error = err.Error()
+	}
+
+	serr := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(revert).Error; err != nil {
+			return err
+		}
+		if revert.Status != SNMPSetStatusApplied {
+			return nil
+		}
+		return tx.Model(&original).Updates(map[string]interface{}{
+			"status":      SNMPSetStatusReverted,
+			"reverted_by": revert.ID,
+		}).Error
+	})
+	if serr != nil {
+		return revert, fmt.Errorf("failed to save SET audit record: %v", serr)
+	}
+	return revert, err
+}
+
+// GetSetOperations 分页查询 SET 审计记录, 最新的在前
+func (s *SNMPService) GetSetOperations(query SNMPSetOperationQuery) ([]models.SNMPSetOperation, int64, error) {
+	var operations []models.SNMPSetOperation
+	var total int64
+
+	db := s.db.Model(&models.SNMPSetOperation{})
+	if query.DeviceID != 0 {
+		db = db.Where("device_id = ?", query.DeviceID)
+	}
+	if query.Target != "" {
+		db = db.Where("target = ?", query.Target)
+	}
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit <= 0 {
+		query.Limit = 20
+	}
+	if query.Limit > 1000 {
+		query.Limit = 1000
+	}
+	offset := (query.Page - 1) * query.Limit
+	if err := db.Order("id DESC").Offset(offset).Limit(query.Limit).Find(&operations).Error; err != nil {
+		return nil, 0, err
+	}
+	return operations, total, nil
+}
+
+func (s *SNMPService) GetSetOperation(id uint) (*models.SNMPSetOperation, error) {
+	var operation models.SNMPSetOperation
+	if err := s.db.First(&operation, id).Error; err != nil {
+		return nil, err
+	}
+	return &operation, nil
+}
+
+// setConnection 返回设备第一个凭据或请求中的连接参数
+func (s *SNMPService) setConnection(deviceID uint, conn *models.SNMPRequest) (*models.SNMPRequest, *uint, error) {
+	if deviceID == 0 {
+		if conn == nil || conn.Target == "" || conn.Version == "" {
+			return nil, nil, &SNMPSetValidationError{Problems: []string{"device_id or a connection with target and version is required"}}
+		}
+		return conn, nil, nil
+	}
+
+	var device models.Device
+	if err := s.db.Preload("Credentials").First(&device, deviceID).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(device.Credentials) == 0 {
+		return nil, nil, fmt.Errorf("no SNMP credentials configured for device %s", device.Name)
+	}
+	return snmpCredentialRequest(device.IPAddress, device.Port, device.Credentials[0], ""), &device.ID, nil
+}
+
+// setPlan 查找实例对应的 MIB 对象, 检查它是否可写以及实例后缀是否合理
+func (s *SNMPService) setPlan(oid string) (snmpSetPlan, []string) {
+	plan := snmpSetPlan{name: oid, record: snmpRecord{oid: oid}}
+	match, err := s.resolver.tree.LongestPrefixMatch(oid)
+	if err != nil {
+		return plan, nil
+	}
+
+	node := match.Node
+	plan.name = node.Name
+	plan.module = node.Module
+	if match.Suffix != "" {
+		plan.name += "." + match.Suffix
+	}
+
+	var problems []string
+	switch node.Kind {
+	case MIBKindScalar, MIBKindColumn:
+		plan.node = &node
+		if !mibWritableAccess[node.Access] {
+			problems = append(problems, fmt.Sprintf("%s is %s", plan.name, node.Access))
+		}
+		if node.Kind == MIBKindScalar && match.Suffix != "0" {
+			problems = append(problems, fmt.Sprintf("%s is a scalar, set %s.0", plan.name, node.Name))
+		}
+		if node.Kind == MIBKindColumn && match.Suffix == "" {
+			problems = append(problems, fmt.Sprintf("%s is a column, an instance index is required", plan.name))
+		}
+	case MIBKindTable, MIBKindRow:
+		problems = append(problems, fmt.Sprintf("%s is a %s, not an object instance", plan.name, node.Kind))
+	}
+	return plan, problems
+}
+
+// readSetValues 在一个 PDU 中读取所有变量的当前值
+func (s *SNMPService) readSetValues(snmp *gosnmp.GoSNMP, plans []snmpSetPlan) error {
+	oids := make([]string, len(plans))
+	for i, plan := range plans {
+		oids[i] = plan.record.oid
+	}
+	result, err := snmp.Get(oids)
+	if err != nil {
+		return fmt.Errorf("failed to read current values: %v", err)
+	}
+	if result.Error != gosnmp.NoError {
+		return fmt.Errorf("failed to read current values: %s%s", result.Error, setErrorAt(plans, result.ErrorIndex))
+	}
+	for i, pdu := range result.Variables {
+		if i >= len(plans) {
+			break
+		}
+		// noSuchObject / noSuchInstance 表示实例尚不存在, 如要创建的表行
+		if record, ok := snmpRecordFromPDU(pdu); ok && record.typ != gosnmp.Null {
+			plans[i].old = &record
+		}
+	}
+	return nil
+}
+
+// setValue 确定变量的类型, 把请求中的值转换为 PDU 使用的类型并按 MIB 约束检查.
+// 类型依次取 MIB 语法、请求中的 type 和设备上当前值的类型
+func (s *SNMPService) setValue(plan *snmpSetPlan, varbind models.SNMPSetVarbind) (snmpRecord, error) {
+	node := plan.node
+	explicit := gosnmp.UnknownType
+	if varbind.Type != "" {
+		t, ok := snmpSetTypes[strings.ToLower(varbind.Type)]
+		if !ok {
+			return snmpRecord{}, fmt.Errorf("unsupported type %q", varbind.Type)
+		}
+		explicit = t
+	}
+
+	var typ gosnmp.Asn1BER
+	switch {
+	case node != nil:
+		t, ok := snmpSetBaseTypes[node.BaseType]
+		if !ok {
+			return snmpRecord{}, fmt.Errorf("syntax %s cannot be set", node.Syntax)
+		}
+		if explicit != gosnmp.UnknownType && explicit != t {
+			return snmpRecord{}, fmt.Errorf("type %s does not match the MIB syntax %s", varbind.Type, node.Syntax)
+		}
+		typ = t
+	case explicit != gosnmp.UnknownType:
+		typ = explicit
+	case plan.old != nil:
+		typ = plan.old.typ
+	default:
+		return snmpRecord{}, errors.New("type is required: the object is not in the MIB library and has no current value")
+	}
+
+	text, err := snmpSetText(varbind.Value)
+	if err != nil {
+		return snmpRecord{}, err
+	}
+	var octets []byte
+	switch {
+	case strings.EqualFold(varbind.Type, "hex"):
+		if octets, err = hex.DecodeString(strings.NewReplacer(" ", "", ":", "").Replace(text)); err != nil {
+			return snmpRecord{}, fmt.Errorf("invalid hex value %q", text)
+		}
+	case node != nil && node.BaseType == "BITS":
+		if octets, err = snmpSetBits(text, node.Enums); err != nil {
+			return snmpRecord{}, err
+		}
+	case typ == gosnmp.Integer && node != nil:
+		text = snmpSetEnum(text, node.Enums)
+	case typ == gosnmp.ObjectIdentifier:
+		if text, err = resolveRecordingOID(text, s.resolver.tree); err != nil {
+			return snmpRecord{}, err
+		}
+	}
+
+	record, err := newSNMPRecord(plan.record.oid, typ, text, octets)
+	if err != nil {
+		return snmpRecord{}, err
+	}
+	if node != nil {
+		if err := checkSNMPSetConstraints(node, record); err != nil {
+			return snmpRecord{}, err
+		}
+	}
+	return record, nil
+}
+
+// snmpSetText JSON 请求中的值转换为文本; BITS 可以用名称数组
+func snmpSetText(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return "", errors.New("boolean values are not supported")
+	case float64:
+		if v != math.Trunc(v) {
+			return "", fmt.Errorf("%v is not an integer", v)
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []interface{}:
+		names := make([]string, len(v))
+		for i, item := range v {
+			names[i] = fmt.Sprint(item)
+		}
+		return strings.Join(names, ","), nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// snmpSetEnum 把枚举名称或 "down(2)" 形式的值转换为数字
+func snmpSetEnum(text string, enums []models.OIDEnum) string {
+	text = strings.TrimSpace(text)
+	if i := strings.Index(text, "("); i > 0 && strings.HasSuffix(text, ")") {
+		return text[i+1 : len(text)-1]
+	}
+	for _, e := range enums {
+		if e.Name == text {
+			return strconv.FormatInt(e.Value, 10)
+		}
+	}
+	return text
+}
+
+// snmpSetBits 把逗号或空格分隔的命名位 (也可以是 "name(n)" 或位序号) 编码为 BITS 值
+func snmpSetBits(text string, enums []models.OIDEnum) ([]byte, error) {
+	octets := []byte{}
+	for _, name := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ' ' || r == '|' }) {
+		if i := strings.Index(name, "("); i > 0 {
+			name = name[:i]
+		}
+		bit, err := strconv.ParseInt(name, 10, 32)
+		if err != nil {
+			bit = -1
+			for _, e := range enums {
+				if e.Name == name {
+					bit = e.Value
+				}
+			}
+		}
+		if bit < 0 {
+			return nil, fmt.Errorf("unknown bit %q", name)
+		}
+		for int(bit/8) >= len(octets) {
+			octets = append(octets, 0)
+		}
+		octets[bit/8] |= 0x80 >> uint(bit%8)
+	}
+	return octets, nil
+}
+
+// checkSNMPSetConstraints 按 MIB 中的枚举、取值范围和 SIZE 检查新值
+func checkSNMPSetConstraints(node *OIDTreeNode, record snmpRecord) error {
+	if octets, ok := record.value.([]byte); ok {
+		if len(node.Sizes) > 0 && !inMIBRanges(int64(len(octets)), node.Sizes) {
+			return fmt.Errorf("length %d is outside SIZE %s", len(octets), formatMIBRanges(node.Sizes))
+		}
+		return nil
+	}
+
+	n, ok := snmpIntegerValue(record.value)
+	if !ok {
+		return nil
+	}
+	if len(node.Enums) > 0 && node.BaseType != "BITS" {
+		names := make([]string, len(node.Enums))
+		for i, e := range node.Enums {
+			if n.IsInt64() && n.Int64() == e.Value {
+				return nil
+			}
+			names[i] = fmt.Sprintf("%s(%d)", e.Name, e.Value)
+		}
+		return fmt.Errorf("%s is not one of %s", n, strings.Join(names, ", "))
+	}
+	if len(node.Ranges) > 0 && (!n.IsInt64() || !inMIBRanges(n.Int64(), node.Ranges)) {
+		return fmt.Errorf("%s is outside the range %s", n, formatMIBRanges(node.Ranges))
+	}
+	return nil
+}
+
+func inMIBRanges(n int64, ranges []models.OIDRange) bool {
+	for _, r := range ranges {
+		if n >= r.Min && n <= r.Max {
+			return true
+		}
+	}
+	return false
+}
+
+// formatMIBRanges 按 MIB 中的写法输出约束, 如 (0..255 | 1024)
+func formatMIBRanges(ranges []models.OIDRange) string {
+	parts := make([]string, len(ranges))
+	for i, r := range ranges {
+		if r.Min == r.Max {
+			parts[i] = strconv.FormatInt(r.Min, 10)
+		} else {
+			parts[i] = fmt.Sprintf("%d..%d", r.Min, r.Max)
+		}
+	}
+	return "(" + strings.Join(parts, " | ") + ")"
+}
+
+// executeSet 在一个 PDU 中写入所有变量, 代理按 RFC 3416 全部应用或全部拒绝
+func executeSet(snmp *gosnmp.GoSNMP, plans []snmpSetPlan) error {
+	pdus := make([]gosnmp.SnmpPDU, len(plans))
+	for i, plan := range plans {
+		pdus[i] = gosnmp.SnmpPDU{Name: plan.record.oid, Type: plan.record.typ, Value: plan.record.value}
+	}
+	// SET 不重发: 响应丢失时第一次请求可能已经生效, 重发会再写一次 (如 TestAndIncr 类变量)
+	retries := snmp.Retries
+	snmp.Retries = 0
+	defer func() { snmp.Retries = retries }()
+	result, err := snmp.Set(pdus)
+	if err != nil {
+		return fmt.Errorf("SET failed, values on the device are unknown: %v", err)
+	}
+	if result.Error != gosnmp.NoError {
+		return fmt.Errorf("device rejected the SET: %s%s", result.Error, setErrorAt(plans, result.ErrorIndex))
+	}
+	return nil
+}
+
+// setErrorAt 错误状态中 error-index 指向的变量
+func setErrorAt(plans []snmpSetPlan, index uint8) string {
+	if index < 1 || int(index) > len(plans) {
+		return ""
+	}
+	return " at " + plans[index-1].name
+}
+
+// setChanges 生成审计记录中每个变量的前后值
+func setChanges(plans []snmpSetPlan) []models.SNMPSetChange {
+	changes := make([]models.SNMPSetChange, len(plans))
+	for i, plan := range plans {
+		changes[i] = models.SNMPSetChange{
+			OID:       plan.record.oid,
+			Module:    plan.module,
+			Type:      plan.record.typ.String(),
+			NewValue:  snmpRecordDisplay(plan.node, plan.record),
+			NewRecord: snmpRecordLine(plan.record),
+		}
+		if plan.name != plan.record.oid {
+			changes[i].Name = plan.name
+		}
+		if plan.old != nil {
+			changes[i].OldType = plan.old.typ.String()
+			changes[i].OldValue = snmpRecordDisplay(plan.node, *plan.old)
+			changes[i].OldRecord = snmpRecordLine(*plan.old)
+		}
+	}
+	return changes
+}
+
+// snmpRecordLine 单个实例的 snmprec 行, 不含换行
+func snmpRecordLine(record snmpRecord) string {
+	return strings.TrimSuffix(formatSNMPRec([]snmpRecord{record}), "\n")
+}
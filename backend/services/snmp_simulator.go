@@ -0,0 +1,905 @@
+package services
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"gorm.io/gorm"
+
+	"mib-platform/models"
+)
+
+const (
+	simulatorMaxMessage   = 65507 // UDP 载荷上限
+	simulatorMaxVarbinds  = 2048  // GetBulk 单个响应的变量绑定上限
+	simulatorEngineBoots  = 1
+	simulatorInterfaces   = 24
+	simulatorFleetMax     = 200
+	snmpUnknownEngineIDs  = "1.3.6.1.6.3.15.1.1.4.0" // usmStatsUnknownEngineIDs
+	simulatorSysUpTimeTag = "." + snmpSysUpTimeOID
+)
+
+// SimulatorConfig SNMP 代理模拟器配置
+type SimulatorConfig struct {
+	Host        string // 监听地址
+	PortMin     int    // 创建设备未指定端口时从该范围分配
+	PortMax     int
+	AllowPublic bool // 允许监听非回环地址; 否则非回环的 Host 改为 127.0.0.1
+}
+
+// SimulatedDeviceStatus 模拟设备及其运行状态
+type SimulatedDeviceStatus struct {
+	models.SimulatedDevice
+	Listen    string     `json:"listen,omitempty"`
+	Requests  uint64     `json:"requests"`
+	Dropped   uint64     `json:"dropped"` // 团体名或 USM 用户不匹配、无法解码的报文
+	StartedAt *time.Time `json:"started_at,omitempty"`
+}
+
+// SimulatorFleetRequest 批量创建厂商模拟设备
+type SimulatorFleetRequest struct {
+	Devices    map[string]int `json:"devices"`    // 厂商 -> 数量, 厂商为 cisco、huawei、h3c
+	Interfaces int            `json:"interfaces"` // 每台设备的接口数, 默认 24
+	Community  string         `json:"community"`  // 默认 public
+	Prefix     string         `json:"prefix"`     // 设备名前缀, 默认 sim
+	Register   bool           `json:"register"`   // 同时在设备管理中登记, 便于测试发现、TestDevice 和配置生成
+}
+
+// SNMPSimulator 内置 SNMP 代理模拟器: 每台模拟设备监听一个 UDP 端口, 按录制的 walk 结果应答
+// Get、GetNext、GetBulk 和 Set; Set 只修改内存中的值, 重启后恢复为录制内容
+type SNMPSimulator struct {
+	db   *gorm.DB
+	cfg  SimulatorConfig
+	tree *OIDTree
+
+	mu     sync.Mutex
+	agents map[uint]*simulatorAgent
+}
+
+// NewSNMPSimulator 创建模拟器, Start 之前不监听任何端口
+func NewSNMPSimulator(db *gorm.DB, cfg SimulatorConfig) *SNMPSimulator {
+	if cfg.Host == "" {
+		cfg.Host = "127.0.0.1"
+	}
+	// 模拟设备回放录制的真实数据, 团体名也是公开的, 默认不对网络开放
+	if !isLoopbackListenAddr(net.JoinHostPort(cfg.Host, "0")) {
+		if cfg.AllowPublic {
+			log.Printf("WARNING: simulated devices listen on non-loopback host %s and answer any host that can reach it", cfg.Host)
+		} else {
+			log.Printf("Simulated devices are not allowed to listen on non-loopback host %s, using 127.0.0.1", cfg.Host)
+			cfg.Host = "127.0.0.1"
+		}
+	}
+	return &SNMPSimulator{
+		db:     db,
+		cfg:    cfg,
+		tree:   sharedOIDTree(db),
+		agents: make(map[uint]*simulatorAgent),
+	}
+}
+
+// Start 启动上次停止时仍在运行的模拟设备
+func (s *SNMPSimulator) Start() {
+	var devices []models.SimulatedDevice
+	if err := s.db.Where("status = ?", "running").Find(&devices).Error; err != nil {
+		log.Printf("Failed to load simulated devices: %v", err)
+		return
+	}
+	for _, device := range devices {
+		if err := s.startAgent(&device); err != nil {
+			log.Printf("Simulated device %s not started: %v", device.Name, err)
+		}
+	}
+}
+
+// Stop 停止所有模拟设备, 不改变保存的状态, 下次 Start 时恢复
+func (s *SNMPSimulator) Stop() {
+	s.mu.Lock()
+	agents := s.agents
+	s.agents = make(map[uint]*simulatorAgent)
+	s.mu.Unlock()
+	for _, agent := range agents {
+		agent.close()
+	}
+}
+
+// GetDevices 列出模拟设备, 不返回 USM 密钥
+func (s *SNMPSimulator) GetDevices() ([]SimulatedDeviceStatus, error) {
+	var devices []models.SimulatedDevice
+	if err := s.db.Order("port").Find(&devices).Error; err != nil {
+		return nil, err
+	}
+	statuses := make([]SimulatedDeviceStatus, len(devices))
+	for i := range devices {
+		statuses[i] = s.status(devices[i])
+	}
+	return statuses, nil
+}
+
+// GetDevice 返回单个模拟设备
+func (s *SNMPSimulator) GetDevice(id uint) (*SimulatedDeviceStatus, error) {
+	var device models.SimulatedDevice
+	if err := s.db.First(&device, id).Error; err != nil {
+		return nil, err
+	}
+	status := s.status(device)
+	return &status, nil
+}
+
+// GetRecording 以 .snmprec 格式返回模拟设备当前的值, 包括 Set 修改过的值
+func (s *SNMPSimulator) GetRecording(id uint) (string, error) {
+	var device models.SimulatedDevice
+	if err := s.db.First(&device, id).Error; err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	agent := s.agents[id]
+	s.mu.Unlock()
+	if agent != nil {
+		agent.mu.Lock()
+		defer agent.mu.Unlock()
+		return formatSNMPRec(agent.records), nil
+	}
+
+	records, err := parseSNMPRecording(device.Recording, device.Format, s.tree)
+	if err != nil {
+		return "", err
+	}
+	return formatSNMPRec(records), nil
+}
+
+func (s *SNMPSimulator) status(device models.SimulatedDevice) SimulatedDeviceStatus {
+	device.AuthKey = ""
+	device.PrivKey = ""
+	status := SimulatedDeviceStatus{SimulatedDevice: device}
+
+	s.mu.Lock()
+	agent := s.agents[device.ID]
+	s.mu.Unlock()
+	if agent != nil {
+		agent.mu.Lock()
+		status.Listen = agent.conn.LocalAddr().String()
+		status.Requests = agent.requests
+		status.Dropped = agent.dropped
+		started := agent.started
+		status.StartedAt = &started
+		agent.mu.Unlock()
+	}
+	return status
+}
+
+// CreateDevice 校验录制文件后保存模拟设备, start 为 true 时立即开始监听
+func (s *SNMPSimulator) CreateDevice(device *models.SimulatedDevice, start bool) error {
+	if device.Name == "" {
+		return fmt.Errorf("simulated device name is required")
+	}
+	if device.Format == "" {
+		device.Format = DetectRecordingFormat("", device.Recording)
+	}
+	records, err := parseSNMPRecording(device.Recording, device.Format, s.tree)
+	if err != nil {
+		return fmt.Errorf("failed to parse recording: %v", err)
+	}
+	device.Records = len(records)
+
+	if device.Community == "" && device.Username == "" {
+		device.Community = "public"
+	}
+	if device.Username != "" {
+		if _, _, err := usmUserSecurityParameters(device.Username, device.AuthProto, device.AuthKey, device.PrivProto, device.PrivKey); err != nil {
+			return err
+		}
+	}
+	if device.EngineID == "" {
+		device.EngineID = simulatorEngineID(device.Name)
+	} else if _, err := trapEngineID(device.EngineID); err != nil {
+		return err
+	}
+
+	if device.Port == 0 {
+		if device.Port, err = s.allocatePort(); err != nil {
+			return err
+		}
+	} else if device.Port < 1 || device.Port > 65535 {
+		return fmt.Errorf("invalid port %d", device.Port)
+	}
+
+	device.ID = 0
+	device.Status = "stopped"
+	device.LastError = ""
+	if err := s.db.Create(device).Error; err != nil {
+		return fmt.Errorf("failed to create simulated device: %v", err)
+	}
+	if start {
+		return s.startAgent(device)
+	}
+	return nil
+}
+
+// StartDevice 开始监听模拟设备的端口
+func (s *SNMPSimulator) StartDevice(id uint) (*SimulatedDeviceStatus, error) {
+	var device models.SimulatedDevice
+	if err := s.db.First(&device, id).Error; err != nil {
+		return nil, err
+	}
+	err := s.startAgent(&device)
+	status := s.status(device)
+	return &status, err
+}
+
+// StopDevice 停止监听模拟设备的端口, 内存中 Set 修改的值随之丢弃
+func (s *SNMPSimulator) StopDevice(id uint) (*SimulatedDeviceStatus, error) {
+	var device models.SimulatedDevice
+	if err := s.db.First(&device, id).Error; err != nil {
+		return nil, err
+	}
+	s.stopAgent(id)
+	device.Status = "stopped"
+	device.LastError = ""
+	if err := s.db.Model(&device).Updates(map[string]interface{}{"status": device.Status, "last_error": ""}).Error; err != nil {
+		return nil, err
+	}
+	status := s.status(device)
+	return &status, nil
+}
+
+// DeleteDevice 停止并删除模拟设备; 创建时在设备管理中登记的设备一并删除
+func (s *SNMPSimulator) DeleteDevice(id uint) error {
+	var device models.SimulatedDevice
+	if err := s.db.First(&device, id).Error; err != nil {
+		return err
+	}
+	s.stopAgent(id)
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if device.DeviceID != nil {
+			if err := tx.Where("device_id = ?", *device.DeviceID).Delete(&models.SNMPCredential{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&models.Device{}, *device.DeviceID).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&device).Error
+	})
+}
+
+// CreateFleet 用内置的厂商录制批量创建并启动模拟设备
+func (s *SNMPSimulator) CreateFleet(req SimulatorFleetRequest) ([]SimulatedDeviceStatus, error) {
+	if req.Interfaces <= 0 {
+		req.Interfaces = simulatorInterfaces
+	}
+	if req.Community == "" {
+		req.Community = "public"
+	}
+	if req.Prefix == "" {
+		req.Prefix = "sim"
+	}
+
+	vendors := make([]string, 0, len(req.Devices))
+	total := 0
+	for vendor, count := range req.Devices {
+		if _, ok := simulatorProfiles[strings.ToLower(vendor)]; !ok {
+			return nil, fmt.Errorf("unsupported vendor %q (expected cisco, huawei or h3c)", vendor)
+		}
+		if count < 0 {
+			return nil, fmt.Errorf("invalid device count %d for %s", count, vendor)
+		}
+		vendors = append(vendors, vendor)
+		total += count
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("at least one device is required")
+	}
+	if total > simulatorFleetMax {
+		return nil, fmt.Errorf("a fleet is limited to %d devices", simulatorFleetMax)
+	}
+	sort.Strings(vendors)
+
+	var created []SimulatedDeviceStatus
+	for _, vendor := range vendors {
+		profile := simulatorProfiles[strings.ToLower(vendor)]
+		for n, i := 1, 0; i < req.Devices[vendor]; n++ {
+			name := fmt.Sprintf("%s-%s-%02d", req.Prefix, strings.ToLower(vendor), n)
+			var count int64
+			if err := s.db.Model(&models.SimulatedDevice{}).Where("name = ?", name).Count(&count).Error; err != nil {
+				return created, err
+			}
+			if count > 0 {
+				continue
+			}
+			i++
+
+			device := &models.SimulatedDevice{
+				Name:        name,
+				Vendor:      profile.Vendor,
+				Description: profile.Model,
+				Community:   req.Community,
+				Format:      RecordingFormatSNMPRec,
+				Recording:   formatSNMPRec(profile.records(name, n, req.Interfaces)),
+			}
+			if err := s.CreateDevice(device, true); err != nil {
+				return created, fmt.Errorf("failed to create %s: %v", name, err)
+			}
+			if req.Register {
+				if err := s.register(device, profile); err != nil {
+					return created, err
+				}
+			}
+			created = append(created, s.status(*device))
+		}
+	}
+	return created, nil
+}
+
+// register 在设备管理中登记模拟设备, 使用 v2c 团体名
+func (s *SNMPSimulator) register(device *models.SimulatedDevice, profile simulatorProfile) error {
+	host := s.cfg.Host
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host = "127.0.0.1"
+	}
+	registered := models.Device{
+		Name:        device.Name,
+		Hostname:    device.Name,
+		IPAddress:   host,
+		Port:        device.Port,
+		Type:        "switch",
+		Vendor:      profile.Vendor,
+		Model:       profile.Model,
+		Location:    "SNMP simulator",
+		Description: "Simulated " + profile.Vendor + " " + profile.Model,
+		Credentials: []models.SNMPCredential{{Version: "v2c", Community: device.Community}},
+	}
+	if err := s.db.Create(&registered).Error; err != nil {
+		return fmt.Errorf("failed to register %s: %v", device.Name, err)
+	}
+	device.DeviceID = &registered.ID
+	return s.db.Model(device).Update("device_id", registered.ID).Error
+}
+
+// allocatePort 在配置的范围内找一个未被其他模拟设备使用且可以绑定的端口
+func (s *SNMPSimulator) allocatePort() (int, error) {
+	var used []int
+	if err := s.db.Model(&models.SimulatedDevice{}).Pluck("port", &used).Error; err != nil {
+		return 0, err
+	}
+	taken := make(map[int]bool, len(used))
+	for _, port := range used {
+		taken[port] = true
+	}
+	for port := s.cfg.PortMin; port > 0 && port <= s.cfg.PortMax; port++ {
+		if taken[port] {
+			continue
+		}
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP(s.cfg.Host), Port: port})
+		if err != nil {
+			continue
+		}
+		conn.Close()
+		return port, nil
+	}
+	return 0, fmt.Errorf("no free simulator port in %d-%d", s.cfg.PortMin, s.cfg.PortMax)
+}
+
+// startAgent 加载录制内容并开始监听, 结果写回设备状态
+func (s *SNMPSimulator) startAgent(device *models.SimulatedDevice) error {
+	s.stopAgent(device.ID)
+
+	agent, err := s.newAgent(*device)
+	device.Status = "running"
+	device.LastError = ""
+	if err != nil {
+		device.Status = "error"
+		device.LastError = err.Error()
+	}
+	if uerr := s.db.Model(device).Updates(map[string]interface{}{"status": device.Status, "last_error": device.LastError}).Error; uerr != nil && err == nil {
+		err = uerr
+	}
+	if agent == nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.agents[device.ID] = agent
+	s.mu.Unlock()
+	go agent.serve()
+	return err
+}
+
+func (s *SNMPSimulator) stopAgent(id uint) {
+	s.mu.Lock()
+	agent := s.agents[id]
+	delete(s.agents, id)
+	s.mu.Unlock()
+	if agent != nil {
+		agent.close()
+	}
+}
+
+// newAgent 解析录制内容、准备 USM 参数并绑定端口
+func (s *SNMPSimulator) newAgent(device models.SimulatedDevice) (*simulatorAgent, error) {
+	records, err := parseSNMPRecording(device.Recording, device.Format, s.tree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse recording: %v", err)
+	}
+	engineID, err := trapEngineID(device.EngineID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 引擎发现请求不带用户名, 需要能解出来才能回复 report
+	table := gosnmp.NewSnmpV3SecurityParametersTable(gosnmp.Logger{})
+	if err := table.Add("", &gosnmp.UsmSecurityParameters{AuthoritativeEngineID: engineID}); err != nil {
+		return nil, err
+	}
+	level := gosnmp.NoAuthNoPriv
+	if device.Username != "" {
+		usm, userLevel, err := usmUserSecurityParameters(device.Username, device.AuthProto, device.AuthKey, device.PrivProto, device.PrivKey)
+		if err != nil {
+			return nil, err
+		}
+		usm.AuthoritativeEngineID = engineID
+		if err := table.Add(device.Username, usm); err != nil {
+			return nil, err
+		}
+		level = userLevel
+	}
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP(s.cfg.Host), Port: device.Port})
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %v", net.JoinHostPort(s.cfg.Host, strconv.Itoa(device.Port)), err)
+	}
+
+	agent := &simulatorAgent{
+		device:   device,
+		conn:     conn,
+		engineID: engineID,
+		level:    level,
+		started:  time.Now(),
+		done:     make(chan struct{}),
+		records:  records,
+		params: &gosnmp.GoSNMP{
+			Version:                     gosnmp.Version3,
+			SecurityModel:               gosnmp.UserSecurityModel,
+			SecurityParameters:          &gosnmp.UsmSecurityParameters{AuthoritativeEngineID: engineID},
+			TrapSecurityParametersTable: table,
+		},
+	}
+	return agent, nil
+}
+
+// simulatorEngineID 按设备名生成 engine ID, 与接收器使用同一个企业号前缀
+func simulatorEngineID(name string) string {
+	prefix, _ := hex.DecodeString(trapEngineIDPrefix)
+	id := "sim-" + name
+	if len(id) > 32-len(prefix) {
+		id = id[:32-len(prefix)]
+	}
+	return hex.EncodeToString(append(prefix, id...))
+}
+
+// simulatorAgent 一台正在运行的模拟设备
+type simulatorAgent struct {
+	device   models.SimulatedDevice
+	conn     *net.UDPConn
+	params   *gosnmp.GoSNMP // 只用于解码请求, 只在 serve 协程中使用
+	engineID string
+	level    gosnmp.SnmpV3MsgFlags // v3 请求要求的最低安全级别
+	started  time.Time
+	done     chan struct{}
+
+	mu            sync.Mutex
+	records       []snmpRecord // 按 OID 排序
+	requests      uint64
+	dropped       uint64
+	unknownEngine uint32
+}
+
+func (a *simulatorAgent) close() {
+	a.conn.Close()
+	<-a.done
+}
+
+// serve 逐个处理请求, 直到连接关闭
+func (a *simulatorAgent) serve() {
+	defer close(a.done)
+	buf := make([]byte, simulatorMaxMessage)
+	for {
+		n, addr, err := a.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		msg := make([]byte, n)
+		copy(msg, buf[:n])
+
+		response := a.respond(msg)
+		if response == nil {
+			a.mu.Lock()
+			a.dropped++
+			a.mu.Unlock()
+			continue
+		}
+		out, err := response.MarshalMsg()
+		if err != nil {
+			log.Printf("Simulated device %s failed to encode response: %v", a.device.Name, err)
+			continue
+		}
+		if _, err := a.conn.WriteToUDP(out, addr); err != nil && !errors.Is(err, net.ErrClosed) {
+			log.Printf("Simulated device %s failed to send response: %v", a.device.Name, err)
+		}
+	}
+}
+
+// respond 解码请求并构造响应; 认证失败或团体名不匹配时返回 nil, 与真实设备一样不应答
+func (a *simulatorAgent) respond(msg []byte) *gosnmp.SnmpPacket {
+	packet, err := a.params.UnmarshalTrap(msg, false)
+	if err != nil {
+		return nil
+	}
+
+	var usm *gosnmp.UsmSecurityParameters
+	switch packet.Version {
+	case gosnmp.Version1, gosnmp.Version2c:
+		if a.device.Community == "" || packet.Community != a.device.Community {
+			return nil
+		}
+		if packet.Version == gosnmp.Version1 && packet.PDUType == gosnmp.GetBulkRequest {
+			return nil
+		}
+	case gosnmp.Version3:
+		var ok bool
+		if usm, ok = packet.SecurityParameters.(*gosnmp.UsmSecurityParameters); !ok {
+			return nil
+		}
+		// RFC 3414 3.2.3: engine ID 不是本机的请求回复 report, 客户端由此完成引擎发现
+		if usm.AuthoritativeEngineID != a.engineID {
+			return a.report(packet, usm)
+		}
+		if a.device.Username == "" || usm.UserName != a.device.Username || packet.MsgFlags&gosnmp.AuthPriv < a.level {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	switch packet.PDUType {
+	case gosnmp.GetRequest, gosnmp.GetNextRequest, gosnmp.GetBulkRequest, gosnmp.SetRequest:
+	default:
+		return nil
+	}
+
+	a.mu.Lock()
+	a.requests++
+	variables, status, index := a.process(packet)
+	a.mu.Unlock()
+
+	packet.PDUType = gosnmp.GetResponse
+	packet.Variables = variables
+	packet.Error = status
+	packet.ErrorIndex = index
+	packet.NonRepeaters = 0
+	packet.MaxRepetitions = 0
+	if usm != nil {
+		packet.MsgFlags &= gosnmp.AuthPriv
+		usm.AuthoritativeEngineBoots = simulatorEngineBoots
+		usm.AuthoritativeEngineTime = a.engineTime()
+	}
+	return packet
+}
+
+// report 回复 usmStatsUnknownEngineIDs, 带上本机的 engine ID、启动次数和运行时间
+func (a *simulatorAgent) report(packet *gosnmp.SnmpPacket, usm *gosnmp.UsmSecurityParameters) *gosnmp.SnmpPacket {
+	if packet.MsgFlags&gosnmp.Reportable == 0 {
+		return nil
+	}
+	params, ok := usm.Copy().(*gosnmp.UsmSecurityParameters)
+	if !ok {
+		return nil
+	}
+	params.AuthoritativeEngineID = a.engineID
+	params.AuthoritativeEngineBoots = simulatorEngineBoots
+	params.AuthoritativeEngineTime = a.engineTime()
+
+	a.mu.Lock()
+	a.unknownEngine++
+	count := a.unknownEngine
+	a.mu.Unlock()
+
+	packet.PDUType = gosnmp.Report
+	packet.MsgFlags = gosnmp.NoAuthNoPriv
+	packet.SecurityParameters = params
+	packet.Variables = []gosnmp.SnmpPDU{{Name: "." + snmpUnknownEngineIDs, Type: gosnmp.Counter32, Value: count}}
+	return packet
+}
+
+func (a *simulatorAgent) engineTime() uint32 {
+	return uint32(time.Since(a.started) / time.Second)
+}
+
+// process 按 RFC 3416 第 4.2 节处理请求, 返回变量绑定和错误状态; 调用方持有 a.mu
+func (a *simulatorAgent) process(packet *gosnmp.SnmpPacket) ([]gosnmp.SnmpPDU, gosnmp.SNMPError, uint8) {
+	v1 := packet.Version == gosnmp.Version1
+	requested := packet.Variables
+
+	switch packet.PDUType {
+	case gosnmp.GetRequest:
+		variables := make([]gosnmp.SnmpPDU, 0, len(requested))
+		for i, v := range requested {
+			oid := strings.TrimPrefix(v.Name, ".")
+			if index, ok := a.find(oid); ok {
+				variables = append(variables, a.pdu(index))
+				continue
+			}
+			if v1 {
+				return requested, gosnmp.NoSuchName, uint8(min(i+1, 255))
+			}
+			variables = append(variables, gosnmp.SnmpPDU{Name: "." + oid, Type: a.missing(oid)})
+		}
+		return variables, gosnmp.NoError, 0
+
+	case gosnmp.GetNextRequest:
+		variables := make([]gosnmp.SnmpPDU, 0, len(requested))
+		for i, v := range requested {
+			oid := strings.TrimPrefix(v.Name, ".")
+			next, ok := a.next(oid)
+			if !ok {
+				if v1 {
+					return requested, gosnmp.NoSuchName, uint8(min(i+1, 255))
+				}
+				variables = append(variables, gosnmp.SnmpPDU{Name: "." + oid, Type: gosnmp.EndOfMibView})
+				continue
+			}
+			variables = append(variables, a.pdu(next))
+		}
+		return variables, gosnmp.NoError, 0
+
+	case gosnmp.GetBulkRequest:
+		return a.bulk(packet), gosnmp.NoError, 0
+
+	case gosnmp.SetRequest:
+		return a.set(requested, v1)
+	}
+	return requested, gosnmp.GenErr, 0
+}
+
+// bulk 处理 GetBulk: 前 non-repeaters 个变量做一次 GetNext, 其余重复 max-repetitions 次;
+// 响应超过 UDP 报文或请求方的 msgMaxSize 时截断
+func (a *simulatorAgent) bulk(packet *gosnmp.SnmpPacket) []gosnmp.SnmpPDU {
+	requested := packet.Variables
+	nonRepeaters := min(int(packet.NonRepeaters), len(requested))
+	budget := simulatorMaxMessage - 512
+	if packet.MsgMaxSize > 0 && int(packet.MsgMaxSize)-512 < budget {
+		budget = int(packet.MsgMaxSize) - 512
+	}
+
+	var variables []gosnmp.SnmpPDU
+	add := func(pdu gosnmp.SnmpPDU) bool {
+		size := snmpVarbindSize(pdu)
+		if len(variables) >= simulatorMaxVarbinds || size > budget {
+			return false
+		}
+		budget -= size
+		variables = append(variables, pdu)
+		return true
+	}
+	nextPDU := func(oid string) (gosnmp.SnmpPDU, string, bool) {
+		if i, ok := a.next(oid); ok {
+			return a.pdu(i), a.records[i].oid, true
+		}
+		return gosnmp.SnmpPDU{Name: "." + oid, Type: gosnmp.EndOfMibView}, oid, false
+	}
+
+	for _, v := range requested[:nonRepeaters] {
+		pdu, _, _ := nextPDU(strings.TrimPrefix(v.Name, "."))
+		if !add(pdu) {
+			return variables
+		}
+	}
+
+	cursors := make([]string, 0, len(requested)-nonRepeaters)
+	for _, v := range requested[nonRepeaters:] {
+		cursors = append(cursors, strings.TrimPrefix(v.Name, "."))
+	}
+	for r := 0; r < int(packet.MaxRepetitions) && len(cursors) > 0; r++ {
+		more := false
+		for j, cursor := range cursors {
+			pdu, oid, ok := nextPDU(cursor)
+			if !add(pdu) {
+				return variables
+			}
+			cursors[j] = oid
+			more = more || ok
+		}
+		if !more {
+			break
+		}
+	}
+	return variables
+}
+
+// set 先检查所有变量再统一修改 (RFC 3416 4.2.5 的原子性); 只能修改录制中已有的实例
+func (a *simulatorAgent) set(requested []gosnmp.SnmpPDU, v1 bool) ([]gosnmp.SnmpPDU, gosnmp.SNMPError, uint8) {
+	indexes := make([]int, len(requested))
+	for i, v := range requested {
+		index, ok := a.find(strings.TrimPrefix(v.Name, "."))
+		if !ok {
+			if v1 {
+				return requested, gosnmp.NoSuchName, uint8(min(i+1, 255))
+			}
+			return requested, gosnmp.NoCreation, uint8(min(i+1, 255))
+		}
+		if v.Type != a.records[index].typ {
+			if v1 {
+				return requested, gosnmp.BadValue, uint8(min(i+1, 255))
+			}
+			return requested, gosnmp.WrongType, uint8(min(i+1, 255))
+		}
+		indexes[i] = index
+	}
+
+	for i, v := range requested {
+		value := v.Value
+		if octets, ok := value.([]byte); ok {
+			value = append([]byte{}, octets...)
+		}
+		a.records[indexes[i]].value = value
+	}
+	return requested, gosnmp.NoError, 0
+}
+
+// find 二分查找 OID 完全相同的实例
+func (a *simulatorAgent) find(oid string) (int, bool) {
+	i := sort.Search(len(a.records), func(i int) bool {
+		return compareOIDs(a.records[i].oid, oid) >= 0
+	})
+	return i, i < len(a.records) && a.records[i].oid == oid
+}
+
+// next 返回字典序在 oid 之后的第一个实例
+func (a *simulatorAgent) next(oid string) (int, bool) {
+	i := sort.Search(len(a.records), func(i int) bool {
+		return compareOIDs(a.records[i].oid, oid) > 0
+	})
+	return i, i < len(a.records)
+}
+
+// missing 对象存在但实例不存在时为 noSuchInstance, 否则为 noSuchObject
+func (a *simulatorAgent) missing(oid string) gosnmp.Asn1BER {
+	object := mibParentOID(oid)
+	if object == "" {
+		return gosnmp.NoSuchObject
+	}
+	if i, ok := a.next(object); ok && strings.HasPrefix(a.records[i].oid, object+".") {
+		return gosnmp.NoSuchInstance
+	}
+	return gosnmp.NoSuchObject
+}
+
+// pdu 返回实例的变量绑定; sysUpTime 按模拟设备启动后经过的时间递增
+func (a *simulatorAgent) pdu(i int) gosnmp.SnmpPDU {
+	record := a.records[i]
+	pdu := gosnmp.SnmpPDU{Name: "." + record.oid, Type: record.typ, Value: record.value}
+	if pdu.Name == simulatorSysUpTimeTag {
+		if ticks, ok := record.value.(uint32); ok {
+			pdu.Value = ticks + uint32(time.Since(a.started)/(10*time.Millisecond))
+		}
+	}
+	return pdu
+}
+
+// snmpVarbindSize 估算变量绑定编码后的长度
+func snmpVarbindSize(pdu gosnmp.SnmpPDU) int {
+	size := len(pdu.Name) + 8
+	switch value := pdu.Value.(type) {
+	case []byte:
+		size += len(value)
+	case string:
+		size += len(value)
+	default:
+		size += 9
+	}
+	return size
+}
+
+// simulatorProfile 内置的厂商设备录制
+type simulatorProfile struct {
+	Vendor    string
+	Model     string
+	ObjectID  string // sysObjectID
+	Descr     string // sysDescr, 设备发现按其中的厂商名识别
+	Interface string // 接口名格式
+	OUI       []byte // 接口 MAC 地址前缀
+}
+
+var simulatorProfiles = map[string]simulatorProfile{
+	"cisco": {
+		Vendor:    "Cisco",
+		Model:     "Catalyst 9300",
+		ObjectID:  "1.3.6.1.4.1.9.1.2494",
+		Descr:     "Cisco IOS Software [Amsterdam], Catalyst L3 Switch Software (CAT9K_IOSXE), Version 17.3.4, RELEASE SOFTWARE (fc3)\r\nTechnical Support: http://www.cisco.com/techsupport\r\nCopyright (c) 1986-2021 by Cisco Systems, Inc.",
+		Interface: "GigabitEthernet1/0/%d",
+		OUI:       []byte{0x00, 0x1e, 0xbd},
+	},
+	"huawei": {
+		Vendor:    "Huawei",
+		Model:     "S5700-28C-EI",
+		ObjectID:  "1.3.6.1.4.1.2011.2.23.96",
+		Descr:     "Huawei Versatile Routing Platform Software\r\nVRP (R) software, Version 5.170 (S5700 V200R011C10SPC600)\r\nCopyright (C) 2000-2018 HUAWEI TECH CO., LTD\r\nHuawei S5700-28C-EI Routing Switch",
+		Interface: "GigabitEthernet0/0/%d",
+		OUI:       []byte{0x00, 0xe0, 0xfc},
+	},
+	"h3c": {
+		Vendor:    "H3C",
+		Model:     "S6800-54QF",
+		ObjectID:  "1.3.6.1.4.1.25506.1.1189",
+		Descr:     "H3C Comware Platform Software, Software Version 7.1.070, Release 6616\r\nH3C S6800-54QF\r\nCopyright (c) 2004-2021 New H3C Technologies Co., Ltd. All rights reserved.",
+		Interface: "Ten-GigabitEthernet1/0/%d",
+		OUI:       []byte{0x3c, 0x8c, 0x40},
+	},
+}
+
+// records 生成一台设备的 system 组、ifTable 和 ifXTable
+func (p simulatorProfile) records(name string, n, interfaces int) []snmpRecord {
+	const (
+		system = "1.3.6.1.2.1.1"
+		ifRow  = "1.3.6.1.2.1.2.2.1"
+		ifXRow = "1.3.6.1.2.1.31.1.1.1"
+	)
+	records := []snmpRecord{
+		{system + ".1.0", gosnmp.OctetString, []byte(p.Descr)},
+		{system + ".2.0", gosnmp.ObjectIdentifier, "." + p.ObjectID},
+		{system + ".3.0", gosnmp.TimeTicks, uint32(n * 8640000)},
+		{system + ".4.0", gosnmp.OctetString, []byte("noc@example.com")},
+		{system + ".5.0", gosnmp.OctetString, []byte(name)},
+		{system + ".6.0", gosnmp.OctetString, []byte("QA lab")},
+		{system + ".7.0", gosnmp.Integer, 6},
+		{"1.3.6.1.2.1.2.1.0", gosnmp.Integer, interfaces},
+	}
+	for i := 1; i <= interfaces; i++ {
+		ifName := fmt.Sprintf(p.Interface, i)
+		mac := append(append([]byte{}, p.OUI...), byte(n>>8), byte(n), byte(i))
+		oper := 1
+		if i%4 == 0 {
+			oper = 2
+		}
+		traffic := uint64(n*1000003+i*7919) * 1024
+		column := func(row string, col int) string {
+			return fmt.Sprintf("%s.%d.%d", row, col, i)
+		}
+		records = append(records,
+			snmpRecord{column(ifRow, 1), gosnmp.Integer, i},
+			snmpRecord{column(ifRow, 2), gosnmp.OctetString, []byte(ifName)},
+			snmpRecord{column(ifRow, 3), gosnmp.Integer, 6},
+			snmpRecord{column(ifRow, 4), gosnmp.Integer, 1500},
+			snmpRecord{column(ifRow, 5), gosnmp.Gauge32, uint32(1000000000)},
+			snmpRecord{column(ifRow, 6), gosnmp.OctetString, mac},
+			snmpRecord{column(ifRow, 7), gosnmp.Integer, 1},
+			snmpRecord{column(ifRow, 8), gosnmp.Integer, oper},
+			snmpRecord{column(ifRow, 10), gosnmp.Counter32, uint32(traffic)},
+			snmpRecord{column(ifRow, 14), gosnmp.Counter32, uint32(0)},
+			snmpRecord{column(ifRow, 16), gosnmp.Counter32, uint32(traffic / 2)},
+			snmpRecord{column(ifXRow, 1), gosnmp.OctetString, []byte(ifName)},
+			snmpRecord{column(ifXRow, 6), gosnmp.Counter64, traffic},
+			snmpRecord{column(ifXRow, 10), gosnmp.Counter64, traffic / 2},
+			snmpRecord{column(ifXRow, 15), gosnmp.Gauge32, uint32(1000)},
+			snmpRecord{column(ifXRow, 18), gosnmp.OctetString, []byte{}},
+		)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return compareOIDs(records[i].oid, records[j].oid) < 0
+	})
+	return records
+}
@@ -0,0 +1,410 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"gorm.io/gorm"
+
+	"mib-platform/models"
+)
+
+const (
+	snapshotDefaultRoot = "1.3.6.1" // 整个 internet 子树, 包括 mib-2 和企业私有 MIB
+	snapshotMaxRecords  = 500000
+)
+
+// errSnapshotTooLarge walk 返回的值超过上限时中止
+var errSnapshotTooLarge = fmt.Errorf("walk returned more than %d values, snapshot a subtree instead", snapshotMaxRecords)
+
+// SnapshotDiffOptions 快照比较选项
+type SnapshotDiffOptions struct {
+	IgnoreVolatile bool     // 忽略 Counter32、Counter64 和 TimeTicks 的值变化 (类型变化仍然报告)
+	Ignore         []string // 忽略这些 OID 或对象名下的所有实例
+}
+
+// SnapshotChange 两个快照之间一个实例的差异
+type SnapshotChange struct {
+	OID      string `json:"oid"`
+	Name     string `json:"name,omitempty"` // MIB 对象名加实例后缀, 如 ifDescr.3
+	Module   string `json:"module,omitempty"`
+	OldType  string `json:"old_type,omitempty"`
+	OldValue string `json:"old_value,omitempty"`
+	NewType  string `json:"new_type,omitempty"`
+	NewValue string `json:"new_value,omitempty"`
+}
+
+// SnapshotDiffSummary 差异计数
+type SnapshotDiffSummary struct {
+	Added     int `json:"added"`
+	Removed   int `json:"removed"`
+	Changed   int `json:"changed"`
+	Unchanged int `json:"unchanged"`
+	Ignored   int `json:"ignored"`
+}
+
+// SnapshotDiff 两个快照的比较结果
+type SnapshotDiff struct {
+	From    models.DeviceSnapshot `json:"from"`
+	To      models.DeviceSnapshot `json:"to"`
+	Summary SnapshotDiffSummary   `json:"summary"`
+	Added   []SnapshotChange      `json:"added"`
+	Removed []SnapshotChange      `json:"removed"`
+	Changed []SnapshotChange      `json:"changed"`
+}
+
+// SnapshotService 设备 walk 快照: 采集、导入导出和比较, 用于设备升级前后的回归检查
+type SnapshotService struct {
+	db   *gorm.DB
+	snmp *SNMPService
+	tree *OIDTree
+}
+
+func NewSnapshotService(db *gorm.DB) *SnapshotService {
+	return &SnapshotService{
+		db:   db,
+		snmp: NewSNMPService(db),
+		tree: sharedOIDTree(db),
+	}
+}
+
+// TakeSnapshot 用设备的第一个凭据 walk 整个设备或 root 指定的子树 (对象名或数字 OID) 并保存
+func (s *SnapshotService) TakeSnapshot(deviceID uint, root, label string) (*models.DeviceSnapshot, error) {
+	var device models.Device
+	if err := s.db.Preload("Credentials").First(&device, deviceID).Error; err != nil {
+		return nil, err
+	}
+	if len(device.Credentials) == 0 {
+		return nil, fmt.Errorf("no SNMP credentials configured for device %s", device.Name)
+	}
+
+	rootOID := snapshotDefaultRoot
+	if root != "" {
+		node, err := s.tree.Resolve(root)
+		if err == nil {
+			rootOID = node.OID
+		} else if rootOID, err = normalizeOID(root); err != nil {
+			return nil, fmt.Errorf("unknown root %q: expected an object name or numeric OID", root)
+		}
+	}
+
+	start := time.Now()
+	req := snmpCredentialRequest(device.IPAddress, device.Port, device.Credentials[0], rootOID)
+	snmp, err := s.snmp.createSNMPConnection(req)
+	if err != nil {
+		return nil, err
+	}
+	defer snmp.Conn.Close()
+
+	var records []snmpRecord
+	_, err = s.snmp.walk(snmp, req, rootOID, func(pdu gosnmp.SnmpPDU) error {
+		if record, ok := snmpRecordFromPDU(pdu); ok {
+			if len(records) >= snapshotMaxRecords {
+				return errSnapshotTooLarge
+			}
+			records = append(records, record)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errSnapshotTooLarge) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to walk %s on %s: %v", rootOID, device.Name, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("walk of %s on %s returned no values", rootOID, device.Name)
+	}
+
+	snapshot := &models.DeviceSnapshot{
+		DeviceID:   device.ID,
+		DeviceName: device.Name,
+		Root:       rootOID,
+		Label:      label,
+		Source:     "walk",
+		Duration:   time.Since(start).Round(time.Millisecond).String(),
+		TakenAt:    start,
+	}
+	if err := s.save(snapshot, records); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// ImportSnapshot 导入 snmpwalk 或 snmprec 格式的录制, 作为设备在 takenAt 时刻的快照
+func (s *SnapshotService) ImportSnapshot(deviceID uint, data, format, label string, takenAt time.Time) (*models.DeviceSnapshot, error) {
+	var device models.Device
+	if err := s.db.First(&device, deviceID).Error; err != nil {
+		return nil, err
+	}
+	if format == "" {
+		format = DetectRecordingFormat("", data)
+	}
+	records, err := parseSNMPRecording(data, format, s.tree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse recording: %v", err)
+	}
+	if takenAt.IsZero() {
+		takenAt = time.Now()
+	}
+
+	snapshot := &models.DeviceSnapshot{
+		DeviceID:   device.ID,
+		DeviceName: device.Name,
+		Label:      label,
+		Source:     "import",
+		TakenAt:    takenAt,
+	}
+	if err := s.save(snapshot, records); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// save 把实例写成 snmprec 并压缩保存
+func (s *SnapshotService) save(snapshot *models.DeviceSnapshot, records []snmpRecord) error {
+	text := formatSNMPRec(records)
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := io.WriteString(zw, text); err != nil {
+		return fmt.Errorf("failed to compress snapshot: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress snapshot: %v", err)
+	}
+
+	snapshot.Records = len(records)
+	snapshot.Size = len(text)
+	snapshot.StoredSize = buf.Len()
+	snapshot.Data = buf.Bytes()
+	if err := s.db.Create(snapshot).Error; err != nil {
+		return fmt.Errorf("failed to save snapshot: %v", err)
+	}
+	return nil
+}
+
+// records 解压并解析快照内容
+func (s *SnapshotService) records(snapshot *models.DeviceSnapshot) ([]snmpRecord, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(snapshot.Data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress snapshot %d: %v", snapshot.ID, err)
+	}
+	defer zr.Close()
+	text, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress snapshot %d: %v", snapshot.ID, err)
+	}
+	return parseSNMPRecording(string(text), RecordingFormatSNMPRec, nil)
+}
+
+// GetSnapshots 分页列出快照, deviceID 为 0 时列出所有设备的快照
+func (s *SnapshotService) GetSnapshots(deviceID uint, page, limit int) ([]models.DeviceSnapshot, int64, error) {
+	var snapshots []models.DeviceSnapshot
+	var total int64
+
+	query := s.db.Model(&models.DeviceSnapshot{})
+	if deviceID != 0 {
+		query = query.Where("device_id = ?", deviceID)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+	if err := query.Omit("data").Order("taken_at DESC, id DESC").Offset(offset).Limit(limit).Find(&snapshots).Error; err != nil {
+		return nil, 0, err
+	}
+	return snapshots, total, nil
+}
+
+// GetSnapshot 返回快照的元数据
+func (s *SnapshotService) GetSnapshot(id uint) (*models.DeviceSnapshot, error) {
+	var snapshot models.DeviceSnapshot
+	if err := s.db.Omit("data").First(&snapshot, id).Error; err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+func (s *SnapshotService) DeleteSnapshot(id uint) error {
+	result := s.db.Delete(&models.DeviceSnapshot{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ExportSnapshot 按 snmpwalk (-On 格式) 或 snmprec 格式导出快照
+func (s *SnapshotService) ExportSnapshot(id uint, format string) (*models.DeviceSnapshot, string, error) {
+	var snapshot models.DeviceSnapshot
+	if err := s.db.First(&snapshot, id).Error; err != nil {
+		return nil, "", err
+	}
+	records, err := s.records(&snapshot)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch format {
+	case "", RecordingFormatSNMPWalk:
+		return &snapshot, formatSNMPWalk(records), nil
+	case RecordingFormatSNMPRec:
+		return &snapshot, formatSNMPRec(records), nil
+	default:
+		return nil, "", fmt.Errorf("unsupported export format: %s (expected snmpwalk or snmprec)", format)
+	}
+}
+
+// DiffSnapshots 比较两个快照: from 之后新增、删除以及值或类型变化的实例, 名称按 MIB 库翻译
+func (s *SnapshotService) DiffSnapshots(fromID, toID uint, opts SnapshotDiffOptions) (*SnapshotDiff, error) {
+	var from, to models.DeviceSnapshot
+	if err := s.db.First(&from, fromID).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.First(&to, toID).Error; err != nil {
+		return nil, err
+	}
+	before, err := s.records(&from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.records(&to)
+	if err != nil {
+		return nil, err
+	}
+
+	var ignore []string
+	for _, ref := range opts.Ignore {
+		if ref = strings.TrimSpace(ref); ref == "" {
+			continue
+		}
+		node, err := s.tree.Resolve(ref)
+		if err == nil {
+			ignore = append(ignore, node.OID)
+		} else if oid, nerr := normalizeOID(ref); nerr == nil {
+			ignore = append(ignore, oid)
+		} else {
+			return nil, fmt.Errorf("unknown ignore entry %q: expected an object name or numeric OID", ref)
+		}
+	}
+	ignored := func(oid string) bool {
+		for _, prefix := range ignore {
+			if oid == prefix || strings.HasPrefix(oid, prefix+".") {
+				return true
+			}
+		}
+		return false
+	}
+
+	from.Data, to.Data = nil, nil
+	diff := &SnapshotDiff{
+		From:    from,
+		To:      to,
+		Added:   []SnapshotChange{},
+		Removed: []SnapshotChange{},
+		Changed: []SnapshotChange{},
+	}
+
+	// 两边都按 OID 排序, 归并比较
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		var old, cur *snmpRecord
+		switch {
+		case j >= len(after):
+			old = &before[i]
+			i++
+		case i >= len(before):
+			cur = &after[j]
+			j++
+		default:
+			switch c := compareOIDs(before[i].oid, after[j].oid); {
+			case c < 0:
+				old = &before[i]
+				i++
+			case c > 0:
+				cur = &after[j]
+				j++
+			default:
+				old, cur = &before[i], &after[j]
+				i++
+				j++
+			}
+		}
+
+		oid := ""
+		if old != nil {
+			oid = old.oid
+		} else {
+			oid = cur.oid
+		}
+		if ignored(oid) {
+			diff.Summary.Ignored++
+			continue
+		}
+
+		switch {
+		case cur == nil:
+			diff.Removed = append(diff.Removed, s.change(oid, old, nil))
+			diff.Summary.Removed++
+		case old == nil:
+			diff.Added = append(diff.Added, s.change(oid, nil, cur))
+			diff.Summary.Added++
+		case old.typ == cur.typ && reflect.DeepEqual(old.value, cur.value):
+			diff.Summary.Unchanged++
+		case old.typ == cur.typ && opts.IgnoreVolatile && snapshotVolatile(old.typ):
+			diff.Summary.Ignored++
+		default:
+			diff.Changed = append(diff.Changed, s.change(oid, old, cur))
+			diff.Summary.Changed++
+		}
+	}
+	return diff, nil
+}
+
+// snapshotVolatile 计数器和运行时间每次 walk 都不同, 回归检查时通常忽略
+func snapshotVolatile(typ gosnmp.Asn1BER) bool {
+	return typ == gosnmp.Counter32 || typ == gosnmp.Counter64 || typ == gosnmp.TimeTicks
+}
+
+// change 生成一个实例的差异, 值按 MIB 中的 DISPLAY-HINT 和枚举显示
+func (s *SnapshotService) change(oid string, old, cur *snmpRecord) SnapshotChange {
+	change := SnapshotChange{OID: oid}
+	var node *OIDTreeNode
+	if match, err := s.tree.LongestPrefixMatch(oid); err == nil {
+		node = &match.Node
+		change.Name = node.Name
+		change.Module = node.Module
+		if match.Suffix != "" {
+			change.Name += "." + match.Suffix
+		}
+		if node.Kind == MIBKindTable || node.Kind == MIBKindRow {
+			node = nil
+		}
+	}
+
+	if old != nil {
+		change.OldType = old.typ.String()
+		change.OldValue = snmpRecordDisplay(node, *old)
+	}
+	if cur != nil {
+		change.NewType = cur.typ.String()
+		change.NewValue = snmpRecordDisplay(node, *cur)
+	}
+	return change
+}
@@ -0,0 +1,409 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"mib-platform/models"
+)
+
+const (
+	streamDefaultConcurrency = 16
+	streamMaxConcurrency     = 128
+	streamMaxTargets         = 5000
+	streamDefaultTimeout     = 10 * time.Second
+	streamMaxTimeout         = 5 * time.Minute
+	streamMaxResults         = 10000 // 每个目标 walk 返回的最大变量数
+)
+
+var streamOperationTypes = map[string]bool{"get": true, "walk": true}
+
+var errStreamTooManyResults = fmt.Errorf("more than %d variables returned, narrow the OIDs", streamMaxResults)
+
+// SNMPStreamResult 一个目标的查询结果, 目标完成后立即推送
+type SNMPStreamResult struct {
+	Seq       int                 `json:"seq"` // 完成顺序, 从 1 开始
+	Target    string              `json:"target"`
+	DeviceID  uint                `json:"device_id,omitempty"`
+	Device    string              `json:"device,omitempty"`
+	Success   bool                `json:"success"`
+	Error     string              `json:"error,omitempty"`
+	Data      []models.SNMPResult `json:"data,omitempty"`
+	LatencyMs float64             `json:"latency_ms"`
+}
+
+// SNMPStreamLatency 已完成目标的延迟分位数 (毫秒, nearest-rank)
+type SNMPStreamLatency struct {
+	Min float64 `json:"min"`
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+// SNMPStreamSummary 所有目标完成或客户端断开后的汇总
+type SNMPStreamSummary struct {
+	Total     int               `json:"total"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Cancelled int               `json:"cancelled"` // 客户端断开时尚未完成的目标
+	Duration  string            `json:"duration"`
+	Latency   SNMPStreamLatency `json:"latency_ms"`
+}
+
+// SNMPStreamQuery 已校验并展开目标的多目标查询
+type SNMPStreamQuery struct {
+	service     *SNMPService
+	operation   string
+	oids        []string
+	targets     []snmpStreamTarget
+	concurrency int
+	timeout     time.Duration
+	resolve     bool
+}
+
+// snmpStreamTarget 一个查询目标; 设备没有可用凭据时 err 说明原因, 结果直接记为失败
+type snmpStreamTarget struct {
+	address  string
+	deviceID uint
+	device   string
+	req      *models.SNMPRequest
+	err      string
+}
+
+// PrepareStreamQuery 校验多目标查询并展开目标列表; 出错时尚未开始任何查询
+func (s *SNMPService) PrepareStreamQuery(req *models.SNMPStreamRequest) (*SNMPStreamQuery, error) {
+	if !streamOperationTypes[req.Operation] {
+		return nil, fmt.Errorf("unsupported operation: %s (expected get or walk)", req.Operation)
+	}
+	query := &SNMPStreamQuery{
+		service:     s,
+		operation:   req.Operation,
+		concurrency: req.Concurrency,
+		timeout:     time.Duration(req.Timeout) * time.Second,
+		resolve:     req.Resolve,
+	}
+	if query.concurrency <= 0 {
+		query.concurrency = streamDefaultConcurrency
+	}
+	if query.concurrency > streamMaxConcurrency {
+		query.concurrency = streamMaxConcurrency
+	}
+	if query.timeout <= 0 {
+		query.timeout = streamDefaultTimeout
+	}
+	if query.timeout > streamMaxTimeout {
+		query.timeout = streamMaxTimeout
+	}
+
+	for _, ref := range req.OIDs {
+		oid, err := resolveRecordingOID(ref, s.resolver.tree)
+		if err != nil {
+			return nil, err
+		}
+		query.oids = append(query.oids, oid)
+	}
+	if req.Operation == "get" && len(query.oids) > gosnmp.MaxOids {
+		return nil, fmt.Errorf("too many OIDs for get: %d (max %d)", len(query.oids), gosnmp.MaxOids)
+	}
+
+	targets, err := s.streamTargets(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, errors.New("no targets selected: specify targets, device_ids, group_id or tags")
+	}
+	if len(targets) > streamMaxTargets {
+		return nil, fmt.Errorf("too many targets: %d (max %d)", len(targets), streamMaxTargets)
+	}
+	query.targets = targets
+	return query, nil
+}
+
+// Targets 返回展开后的目标数
+func (q *SNMPStreamQuery) Targets() int {
+	return len(q.targets)
+}
+
+// streamTargets 合并设备、设备分组、标签和地址列表选中的目标, 同一地址只查询一次
+func (s *SNMPService) streamTargets(req *models.SNMPStreamRequest) ([]snmpStreamTarget, error) {
+	var targets []snmpStreamTarget
+	seen := make(map[string]bool)
+
+	deviceIDs := append([]uint{}, req.DeviceIDs...)
+	var groupIDs []string
+	if req.GroupID != "" {
+		var group models.DeviceGroup
+		if err := s.db.Select("id").First(&group, "id = ?", req.GroupID).Error; err != nil {
+			return nil, err
+		}
+		groupIDs = append(groupIDs, group.ID)
+	}
+	if len(req.Tags) > 0 {
+		ids, err := s.streamGroupsByTags(req.Tags)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return nil, fmt.Errorf("no device group matches tags %v", req.Tags)
+		}
+		groupIDs = append(groupIDs, ids...)
+	}
+	if len(groupIDs) > 0 {
+		var members []string
+		if err := s.db.Model(&models.DeviceGroupDevice{}).Where("device_group_id IN ?", groupIDs).Pluck("device_id", &members).Error; err != nil {
+			return nil, fmt.Errorf("failed to load device group members: %v", err)
+		}
+		for _, member := range members {
+			if id, err := strconv.ParseUint(member, 10, 32); err == nil {
+				deviceIDs = append(deviceIDs, uint(id))
+			}
+		}
+	}
+	var devices []models.Device
+	if len(deviceIDs) > 0 {
+		if err := s.db.Preload("Credentials").Where("id IN ?", deviceIDs).Order("id").Find(&devices).Error; err != nil {
+			return nil, fmt.Errorf("failed to load devices: %v", err)
+		}
+	}
+	for _, device := range devices {
+		port := device.Port
+		if port == 0 {
+			port = 161
+		}
+		key := net.JoinHostPort(device.IPAddress, strconv.Itoa(port))
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		target := snmpStreamTarget{address: key, deviceID: device.ID, device: device.Name}
+		if len(device.Credentials) == 0 {
+			target.err = fmt.Sprintf("no SNMP credentials configured for device %s", device.Name)
+		} else {
+			target.req = snmpCredentialRequest(device.IPAddress, port, device.Credentials[0], "")
+		}
+		targets = append(targets, target)
+	}
+
+	// 与已选设备相同的地址使用设备的凭据
+	if len(req.Targets) > 0 {
+		if req.Connection == nil || req.Connection.Version == "" {
+			return nil, errors.New("connection with version and credentials is required for targets")
+		}
+		for _, address := range req.Targets {
+			host, port := address, req.Connection.Port
+			if h, p, err := net.SplitHostPort(address); err == nil {
+				n, err := strconv.Atoi(p)
+				if err != nil || n <= 0 || n > 65535 {
+					return nil, fmt.Errorf("invalid target %q", address)
+				}
+				host, port = h, n
+			}
+			if host == "" {
+				return nil, fmt.Errorf("invalid target %q", address)
+			}
+			if port == 0 {
+				port = 161
+			}
+			key := net.JoinHostPort(host, strconv.Itoa(port))
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			conn := *req.Connection
+			conn.Target = host
+			conn.Port = port
+			targets = append(targets, snmpStreamTarget{address: key, req: &conn})
+		}
+	}
+
+	return targets, nil
+}
+
+// streamGroupsByTags 返回标签包含所有给定键值的设备分组
+func (s *SNMPService) streamGroupsByTags(tags map[string]string) ([]string, error) {
+	var groups []models.DeviceGroup
+	if err := s.db.Select("id", "tags").Find(&groups).Error; err != nil {
+		return nil, fmt.Errorf("failed to load device groups: %v", err)
+	}
+
+	var ids []string
+	for _, group := range groups {
+		var groupTags map[string]interface{}
+		if len(group.Tags) == 0 || json.Unmarshal(group.Tags, &groupTags) != nil {
+			continue
+		}
+		matched := true
+		for key, value := range tags {
+			if v, ok := groupTags[key]; !ok || fmt.Sprint(v) != value {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			ids = append(ids, group.ID)
+		}
+	}
+	return ids, nil
+}
+
+// Run 以有限并发查询所有目标, 每个目标完成后立即调用 emit (调用是串行的);
+// ctx 取消 (客户端断开) 后不再派发新目标, 正在进行的请求随之中止
+func (q *SNMPStreamQuery) Run(ctx context.Context, emit func(SNMPStreamResult)) *SNMPStreamSummary {
+	start := time.Now()
+	summary := &SNMPStreamSummary{Total: len(q.targets)}
+	latencies := make([]float64, 0, len(q.targets))
+
+	var mu sync.Mutex
+	runBounded(ctx, len(q.targets), q.concurrency, func(i int) {
+		result := q.execute(ctx, &q.targets[i])
+		// 因客户端断开而失败的目标计为取消
+		if !result.Success && ctx.Err() != nil {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if result.Success {
+			summary.Succeeded++
+		} else {
+			summary.Failed++
+		}
+		latencies = append(latencies, result.LatencyMs)
+		result.Seq = len(latencies)
+		emit(result)
+	})
+
+	summary.Cancelled = summary.Total - summary.Succeeded - summary.Failed
+	summary.Duration = time.Since(start).String()
+	summary.Latency = streamLatency(latencies)
+	return summary
+}
+
+// execute 查询一个目标, 整个目标 (包括重试和 walk 的所有请求) 受 timeout 限制; 查询中的 panic 只让该目标失败
+func (q *SNMPStreamQuery) execute(ctx context.Context, target *snmpStreamTarget) SNMPStreamResult {
+	start := time.Now()
+	result := SNMPStreamResult{Target: target.address, DeviceID: target.deviceID, Device: target.device}
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("query panicked: %v", r)
+			}
+		}()
+		return q.query(ctx, target, &result)
+	}()
+	result.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		result.Data = nil
+		result.Error = err.Error()
+		return result
+	}
+	result.Success = true
+	return result
+}
+
+func (q *SNMPStreamQuery) query(parent context.Context, target *snmpStreamTarget, result *SNMPStreamResult) error {
+	if target.req == nil {
+		return errors.New(target.err)
+	}
+	ctx, cancel := context.WithTimeout(parent, q.timeout)
+	defer cancel()
+
+	snmp, err := q.service.createSNMPConnection(target.req)
+	if err != nil {
+		return err
+	}
+	defer snmp.Conn.Close()
+	snmp.Context = ctx
+
+	switch q.operation {
+	case "get":
+		packet, err := snmp.Get(q.oids)
+		if err != nil {
+			return q.streamError(parent, ctx, err)
+		}
+		if packet.Error != gosnmp.NoError {
+			if i := int(packet.ErrorIndex); i >= 1 && i <= len(q.oids) {
+				return fmt.Errorf("%s at %s", packet.Error, q.oids[i-1])
+			}
+			return fmt.Errorf("%s", packet.Error)
+		}
+		for _, pdu := range packet.Variables {
+			result.Data = append(result.Data, q.service.streamResult(pdu))
+		}
+	case "walk":
+		for _, oid := range q.oids {
+			_, err := q.service.walk(snmp, target.req, oid, func(pdu gosnmp.SnmpPDU) error {
+				if len(result.Data) >= streamMaxResults {
+					return errStreamTooManyResults
+				}
+				result.Data = append(result.Data, q.service.streamResult(pdu))
+				return nil
+			})
+			if err != nil {
+				return q.streamError(parent, ctx, err)
+			}
+		}
+	}
+
+	if q.resolve {
+		q.service.resolver.Resolve(result.Data)
+	}
+	return nil
+}
+
+func (s *SNMPService) streamResult(pdu gosnmp.SnmpPDU) models.SNMPResult {
+	return models.SNMPResult{
+		OID:   pdu.Name,
+		Type:  pdu.Type.String(),
+		Value: s.convertSNMPValue(pdu),
+	}
+}
+
+// streamError 区分整个查询被取消和单个目标超时
+func (q *SNMPStreamQuery) streamError(parent, ctx context.Context, err error) error {
+	switch {
+	case parent.Err() != nil:
+		return errors.New("query cancelled")
+	case ctx.Err() != nil || errors.Is(err, context.DeadlineExceeded):
+		// gosnmp 的读超时可能先于 ctx 自己的计时器触发
+		return fmt.Errorf("timed out after %s", q.timeout)
+	}
+	return err
+}
+
+// streamLatency 计算延迟分位数
+func streamLatency(latencies []float64) SNMPStreamLatency {
+	if len(latencies) == 0 {
+		return SNMPStreamLatency{}
+	}
+	sorted := append([]float64{}, latencies...)
+	sort.Float64s(sorted)
+	rank := func(p float64) float64 {
+		i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+		return sorted[max(i, 0)]
+	}
+	return SNMPStreamLatency{
+		Min: sorted[0],
+		P50: rank(50),
+		P90: rank(90),
+		P95: rank(95),
+		P99: rank(99),
+		Max: sorted[len(sorted)-1],
+	}
+}
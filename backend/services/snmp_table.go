@@ -0,0 +1,320 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"mib-platform/models"
+)
+
+// snmpTableLayout 从 MIB 库解析出的表结构
+type snmpTableLayout struct {
+	table   OIDTreeNode
+	row     OIDTreeNode
+	columns []OIDTreeNode
+	hints   map[string]string // 索引对象名 -> DISPLAY-HINT
+}
+
+// tableLayout 查找表、行和要获取的列; columns 为空时取所有可读列
+func (s *SNMPService) tableLayout(ref string, columns []string) (*snmpTableLayout, error) {
+	node, err := s.resolver.tree.Resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	if node.Kind == MIBKindRow {
+		if node, err = s.resolver.tree.Node(node.ParentOID); err != nil {
+			return nil, err
+		}
+	}
+	if node.Kind != MIBKindTable {
+		return nil, fmt.Errorf("%s is not a table", ref)
+	}
+
+	layout := &snmpTableLayout{table: *node}
+	children, err := s.resolver.tree.Children(node.OID)
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range children {
+		if child.Kind == MIBKindRow {
+			layout.row = child
+			break
+		}
+	}
+	if layout.row.OID == "" {
+		return nil, fmt.Errorf("table %s has no row definition", node.Name)
+	}
+
+	cells, err := s.resolver.tree.Children(layout.row.OID)
+	if err != nil {
+		return nil, err
+	}
+	available := make(map[string]OIDTreeNode)
+	for _, cell := range cells {
+		if cell.Kind != MIBKindColumn || !mibPollableAccess[cell.Access] {
+			continue
+		}
+		available[cell.Name] = cell
+		if len(columns) == 0 {
+			layout.columns = append(layout.columns, cell)
+		}
+	}
+	for _, name := range columns {
+		cell, ok := available[name]
+		if !ok {
+			return nil, fmt.Errorf("column %s is not a readable column of %s", name, node.Name)
+		}
+		layout.columns = append(layout.columns, cell)
+	}
+	if len(layout.columns) == 0 {
+		return nil, fmt.Errorf("table %s has no readable columns", node.Name)
+	}
+
+	layout.hints = s.resolver.tree.indexHints(layout.row.Indexes)
+	return layout, nil
+}
+
+// indexHints 查找 INDEX 对象的 DISPLAY-HINT, 用于格式化字符串索引
+func (t *OIDTree) indexHints(indexes []models.OIDIndex) map[string]string {
+	hints := make(map[string]string)
+	for _, index := range indexes {
+		if index.OID == "" {
+			continue
+		}
+		if node, err := t.Node(index.OID); err == nil && node.DisplayHint != "" {
+			hints[index.Name] = node.DisplayHint
+		}
+	}
+	return hints
+}
+
+// SNMPTable 获取整张表, 按行返回并用 INDEX 对象解码行索引
+func (s *SNMPService) SNMPTable(req *models.SNMPTableRequest) (*models.SNMPTableResponse, error) {
+	start := time.Now()
+
+	layout, err := s.tableLayout(req.OID, req.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.SNMPTableResponse{
+		Table:  layout.table.Name,
+		Module: layout.table.Module,
+		OID:    layout.table.OID,
+		Rows:   []models.SNMPTableRow{},
+	}
+	for _, index := range layout.row.Indexes {
+		response.Indexes = append(response.Indexes, index.Name)
+	}
+	for _, column := range layout.columns {
+		response.Columns = append(response.Columns, column.Name)
+	}
+
+	snmp, err := s.createSNMPConnection(&req.SNMPRequest)
+	if err != nil {
+		return nil, err
+	}
+	defer snmp.Conn.Close()
+
+	// 各列分别 walk, 表的非重复变量不适用
+	walkReq := req.SNMPRequest
+	walkReq.NonRepeaters = nil
+
+	type cell struct {
+		column string
+		suffix string
+	}
+	var data []models.SNMPResult
+	var cells []cell
+	requests := 0
+	method := ""
+	for _, column := range layout.columns {
+		prefix := column.OID + "."
+		stats, err := s.walk(snmp, &walkReq, column.OID, func(pdu gosnmp.SnmpPDU) error {
+			name := strings.TrimPrefix(pdu.Name, ".")
+			if !strings.HasPrefix(name, prefix) {
+				return nil
+			}
+			data = append(data, models.SNMPResult{
+				OID:   pdu.Name,
+				Type:  pdu.Type.String(),
+				Value: s.convertSNMPValue(pdu),
+			})
+			cells = append(cells, cell{column: column.Name, suffix: strings.TrimPrefix(name, prefix)})
+			return nil
+		})
+		if stats != nil {
+			requests += stats.PDUs
+			method = stats.Method
+		}
+		if err != nil {
+			response.Message = fmt.Sprintf("failed to walk column %s: %v", column.Name, err)
+			response.Timestamp = time.Now()
+			response.Duration = time.Since(start).String()
+			return response, nil
+		}
+	}
+	s.resolveResults(&req.SNMPRequest, data)
+
+	rows := make(map[string]*models.SNMPTableRow)
+	indexErrors := 0
+	for i, c := range cells {
+		row, ok := rows[c.suffix]
+		if !ok {
+			row = &models.SNMPTableRow{Index: c.suffix, Values: make(map[string]models.SNMPResult)}
+			key, err := decodeSNMPIndex(c.suffix, layout.row.Indexes, layout.hints)
+			if err != nil {
+				indexErrors++
+			} else {
+				row.Key = key
+			}
+			rows[c.suffix] = row
+		}
+		row.Values[c.column] = data[i]
+	}
+	for _, row := range rows {
+		response.Rows = append(response.Rows, *row)
+	}
+	sort.Slice(response.Rows, func(i, j int) bool {
+		return compareOIDs(response.Rows[i].Index, response.Rows[j].Index) < 0
+	})
+
+	response.Success = true
+	response.Message = "SNMP table fetch successful"
+	response.Timestamp = time.Now()
+	response.Duration = time.Since(start).String()
+	response.Stats = map[string]interface{}{
+		"rows_returned":      len(response.Rows),
+		"variables_returned": len(data),
+		"method":             method,
+		"requests":           requests,
+		"index_errors":       indexErrors,
+	}
+	return response, nil
+}
+
+// decodeSNMPIndex 按 RFC 2578 7.7 把行 OID 后缀解码为各 INDEX 对象的值
+func decodeSNMPIndex(suffix string, indexes []models.OIDIndex, hints map[string]string) (map[string]interface{}, error) {
+	var subIDs []uint64
+	if suffix != "" {
+		for _, part := range strings.Split(suffix, ".") {
+			n, err := strconv.ParseUint(part, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid index sub-identifier %q", part)
+			}
+			subIDs = append(subIDs, n)
+		}
+	}
+	if len(indexes) == 0 {
+		return nil, fmt.Errorf("row has no INDEX definition")
+	}
+
+	key := make(map[string]interface{})
+	take := func(name string, n int) ([]uint64, error) {
+		if n > len(subIDs) {
+			return nil, fmt.Errorf("index %s needs %d sub-identifiers, %d left", name, n, len(subIDs))
+		}
+		part := subIDs[:n]
+		subIDs = subIDs[n:]
+		return part, nil
+	}
+	for i, index := range indexes {
+		last := i == len(indexes)-1
+		switch index.Type {
+		case "INTEGER", "Integer32", "Unsigned32", "Gauge32", "Counter32", "TimeTicks", "Counter", "Gauge":
+			part, err := take(index.Name, 1)
+			if err != nil {
+				return nil, err
+			}
+			key[index.Name] = part[0]
+		case "IpAddress", "NetworkAddress":
+			part, err := take(index.Name, 4)
+			if err != nil {
+				return nil, err
+			}
+			key[index.Name] = fmt.Sprintf("%d.%d.%d.%d", part[0], part[1], part[2], part[3])
+		case "OCTET STRING", "BITS", "Opaque":
+			n, err := snmpIndexLength(index, last, &subIDs)
+			if err != nil {
+				return nil, err
+			}
+			part, err := take(index.Name, n)
+			if err != nil {
+				return nil, err
+			}
+			octets := make([]byte, len(part))
+			for j, v := range part {
+				if v > 255 {
+					return nil, fmt.Errorf("index %s sub-identifier %d is not an octet", index.Name, v)
+				}
+				octets[j] = byte(v)
+			}
+			key[index.Name] = formatSNMPIndexOctets(octets, hints[index.Name])
+		case "OBJECT IDENTIFIER":
+			n, err := snmpIndexLength(index, last, &subIDs)
+			if err != nil {
+				return nil, err
+			}
+			part, err := take(index.Name, n)
+			if err != nil {
+				return nil, err
+			}
+			parts := make([]string, len(part))
+			for j, v := range part {
+				parts[j] = strconv.FormatUint(v, 10)
+			}
+			key[index.Name] = strings.Join(parts, ".")
+		default:
+			// 索引对象未解析时无法判断长度, 只有最后一个索引可以取走剩余部分
+			if !last {
+				return nil, fmt.Errorf("index %s has unknown type", index.Name)
+			}
+			parts := make([]string, len(subIDs))
+			for j, v := range subIDs {
+				parts[j] = strconv.FormatUint(v, 10)
+			}
+			key[index.Name] = strings.Join(parts, ".")
+			subIDs = nil
+		}
+	}
+	if len(subIDs) > 0 {
+		return nil, fmt.Errorf("%d unused index sub-identifiers", len(subIDs))
+	}
+	return key, nil
+}
+
+// snmpIndexLength 变长索引的长度: 定长类型、IMPLIED 取剩余部分, 否则读取长度前缀
+func snmpIndexLength(index models.OIDIndex, last bool, subIDs *[]uint64) (int, error) {
+	if index.FixedSize > 0 {
+		return index.FixedSize, nil
+	}
+	if index.Implied && last {
+		return len(*subIDs), nil
+	}
+	if len(*subIDs) == 0 {
+		return 0, fmt.Errorf("index %s is missing its length", index.Name)
+	}
+	n := (*subIDs)[0]
+	*subIDs = (*subIDs)[1:]
+	if n > uint64(len(*subIDs)) {
+		return 0, fmt.Errorf("index %s length %d exceeds remaining %d sub-identifiers", index.Name, n, len(*subIDs))
+	}
+	return int(n), nil
+}
+
+func formatSNMPIndexOctets(octets []byte, hint string) string {
+	if hint != "" {
+		if s, ok := applyOctetDisplayHint(hint, octets); ok {
+			return s
+		}
+	}
+	if isPrintableOctets(octets) {
+		return string(octets)
+	}
+	return formatSNMPHexString(octets)
+}
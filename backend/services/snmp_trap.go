@@ -0,0 +1,578 @@
+package services
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"gorm.io/gorm"
+
+	"mib-platform/models"
+)
+
+const (
+	trapPruneInterval   = time.Hour
+	trapWebhookTimeout  = 5 * time.Second
+	trapListenTimeout   = 3 * time.Second
+	snmpSysUpTimeOID    = "1.3.6.1.2.1.1.3.0"
+	snmpTrapOIDOID      = "1.3.6.1.6.3.1.1.4.1.0"
+	snmpGenericTrapsOID = "1.3.6.1.6.3.1.1.5" // RFC 3584: generic-trap N 对应 snmpTraps.(N+1)
+	trapEngineIDPrefix  = "80001f8804"        // RFC 3411 engine ID 前缀: net-snmp 企业号, 文本格式
+)
+
+// SNMPv1 generic-trap 0-5 的名称, MIB 库中没有 SNMPv2-MIB 时使用
+var snmpGenericTrapNames = []string{"coldStart", "warmStart", "linkDown", "linkUp", "authenticationFailure", "egpNeighborLoss"}
+
+// TrapReceiverConfig trap 接收器配置
+type TrapReceiverConfig struct {
+	ListenAddr  string        // 为空时不监听
+	Communities []string      // 为空时只在回环地址上接受任意团体名的 v1/v2c trap, 其他地址只接受 v3
+	Retention   time.Duration // 0 表示不清理
+	EngineID    string        // 十六进制, 为空时按主机名生成
+	WebhookURL  string
+}
+
+// TrapReceiverStatus trap 接收器状态
+type TrapReceiverStatus struct {
+	ListenAddr string     `json:"listen_addr"`
+	Running    bool       `json:"running"`
+	EngineID   string     `json:"engine_id"`
+	Users      int        `json:"users"`
+	Received   uint64     `json:"received"`
+	Dropped    uint64     `json:"dropped"`
+	LastTrapAt *time.Time `json:"last_trap_at"`
+	Retention  string     `json:"retention"`
+	Webhook    bool       `json:"webhook"`
+	LastError  string     `json:"last_error,omitempty"`
+}
+
+// TrapQuery trap 查询条件
+type TrapQuery struct {
+	Page     int
+	Limit    int
+	Source   string
+	DeviceID uint
+	TrapOID  string
+	Name     string
+	Since    *time.Time
+	Until    *time.Time
+}
+
+// TrapReceiver 监听 UDP 端口接收 trap 和 inform, 按 MIB 库翻译后入库
+type TrapReceiver struct {
+	db     *gorm.DB
+	cfg    TrapReceiverConfig
+	snmp   *SNMPService
+	mibs   *MIBService
+	client *http.Client
+
+	mu         sync.Mutex
+	listener   *gosnmp.TrapListener
+	stop       chan struct{}
+	engineID   string
+	levels     map[string]gosnmp.SnmpV3MsgFlags // v3 用户名 -> 要求的最低安全级别
+	received   uint64
+	dropped    uint64
+	lastTrapAt *time.Time
+	lastError  string
+}
+
+// NewTrapReceiver 创建 trap 接收器, 调用 Start 后开始监听
+func NewTrapReceiver(db *gorm.DB, cfg TrapReceiverConfig) *TrapReceiver {
+	return &TrapReceiver{
+		db:     db,
+		cfg:    cfg,
+		snmp:   NewSNMPService(db),
+		mibs:   NewMIBService(db),
+		client: &http.Client{Timeout: trapWebhookTimeout},
+	}
+}
+
+// Start 开始监听并定期清理过期 trap
+func (r *TrapReceiver) Start() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cfg.ListenAddr == "" {
+		return nil
+	}
+	if err := r.startListener(); err != nil {
+		r.lastError = err.Error()
+		return err
+	}
+
+	if r.stop == nil && r.cfg.Retention > 0 {
+		r.stop = make(chan struct{})
+		go func(stop chan struct{}) {
+			ticker := time.NewTicker(trapPruneInterval)
+			defer ticker.Stop()
+			for {
+				r.prune()
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+				}
+			}
+		}(r.stop)
+	}
+	return nil
+}
+
+// Stop 停止监听和清理
+func (r *TrapReceiver) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopListener()
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+}
+
+// Reload 重新加载 USM 用户后重启监听
+func (r *TrapReceiver) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.listener == nil {
+		return nil
+	}
+	r.stopListener()
+	if err := r.startListener(); err != nil {
+		r.lastError = err.Error()
+		return err
+	}
+	return nil
+}
+
+func (r *TrapReceiver) startListener() error {
+	if r.listener != nil {
+		return nil
+	}
+	params, levels, err := r.listenerParams()
+	if err != nil {
+		return err
+	}
+	// 没有团体名和 v3 用户时任何主机都能写入 trap, 只允许在回环地址上这样监听
+	if len(r.cfg.Communities) == 0 && len(levels) == 0 && !isLoopbackListenAddr(r.cfg.ListenAddr) {
+		return fmt.Errorf("refusing to listen on %s without authentication: configure trap communities or an SNMPv3 trap user, or listen on a loopback address", r.cfg.ListenAddr)
+	}
+
+	listener := gosnmp.NewTrapListener()
+	listener.Params = params
+	listener.OnNewTrap = r.handle
+
+	done := make(chan error, 1)
+	go func() { done <- listener.Listen(r.cfg.ListenAddr) }()
+	select {
+	case <-listener.Listening():
+	case err := <-done:
+		return fmt.Errorf("failed to listen on %s: %v", r.cfg.ListenAddr, err)
+	case <-time.After(trapListenTimeout):
+		listener.Close()
+		return fmt.Errorf("timed out listening on %s", r.cfg.ListenAddr)
+	}
+	go func() {
+		if err := <-done; err != nil {
+			log.Printf("Trap listener on %s stopped: %v", r.cfg.ListenAddr, err)
+			r.mu.Lock()
+			r.lastError = err.Error()
+			r.mu.Unlock()
+		}
+	}()
+
+	r.listener = listener
+	r.levels = levels
+	r.lastError = ""
+	return nil
+}
+
+// isLoopbackListenAddr 判断监听地址是否只绑定在回环接口上; 主机为空表示所有接口
+func isLoopbackListenAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (r *TrapReceiver) stopListener() {
+	if r.listener != nil {
+		r.listener.Close()
+		r.listener = nil
+	}
+}
+
+// listenerParams 构造监听参数: 本地 engine ID 作为 inform 的权威引擎, 每个 USM 用户用它本地化密钥;
+// 同时返回每个用户要求的安全级别
+func (r *TrapReceiver) listenerParams() (*gosnmp.GoSNMP, map[string]gosnmp.SnmpV3MsgFlags, error) {
+	engineID, err := trapEngineID(r.cfg.EngineID)
+	if err != nil {
+		return nil, nil, err
+	}
+	r.engineID = engineID
+
+	var users []models.SNMPTrapUser
+	if err := r.db.Find(&users).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load trap users: %v", err)
+	}
+
+	table := gosnmp.NewSnmpV3SecurityParametersTable(gosnmp.Logger{})
+	// 引擎发现请求不带用户名, 需要能解出来才能回复 report; 这类报文不会入库
+	if err := table.Add("", &gosnmp.UsmSecurityParameters{AuthoritativeEngineID: engineID}); err != nil {
+		return nil, nil, err
+	}
+	levels := make(map[string]gosnmp.SnmpV3MsgFlags)
+	for _, user := range users {
+		if user.Username == "" {
+			continue
+		}
+		usm, flags, err := usmUserSecurityParameters(user.Username, user.AuthProto, user.AuthKey, user.PrivProto, user.PrivKey)
+		if err != nil {
+			log.Printf("Skipping trap user %s: %v", user.Username, err)
+			continue
+		}
+		usm.AuthoritativeEngineID = engineID
+		if err := table.Add(user.Username, usm); err != nil {
+			log.Printf("Skipping trap user %s: %v", user.Username, err)
+			continue
+		}
+		levels[user.Username] = flags & gosnmp.AuthPriv
+	}
+
+	params := &gosnmp.GoSNMP{
+		Version:                     gosnmp.Version3,
+		SecurityModel:               gosnmp.UserSecurityModel,
+		SecurityParameters:          &gosnmp.UsmSecurityParameters{AuthoritativeEngineID: engineID},
+		TrapSecurityParametersTable: table,
+	}
+	return params, levels, nil
+}
+
+// trapEngineID 解析配置的 engine ID; 未配置时用主机名生成, 重启后保持不变
+func trapEngineID(configured string) (string, error) {
+	if configured != "" {
+		id, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(strings.ReplaceAll(configured, ":", "")), "0x"))
+		if err != nil || len(id) < 5 || len(id) > 32 {
+			return "", fmt.Errorf("invalid trap engine ID %q: expected 5-32 bytes of hex", configured)
+		}
+		return string(id), nil
+	}
+
+	prefix, _ := hex.DecodeString(trapEngineIDPrefix)
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "mib-platform"
+	}
+	if len(host) > 32-len(prefix) {
+		host = host[:32-len(prefix)]
+	}
+	return string(prefix) + host, nil
+}
+
+// Status 返回接收器状态
+func (r *TrapReceiver) Status() TrapReceiverStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return TrapReceiverStatus{
+		ListenAddr: r.cfg.ListenAddr,
+		Running:    r.listener != nil,
+		EngineID:   hex.EncodeToString([]byte(r.engineID)),
+		Users:      len(r.levels),
+		Received:   r.received,
+		Dropped:    r.dropped,
+		LastTrapAt: r.lastTrapAt,
+		Retention:  r.cfg.Retention.String(),
+		Webhook:    r.cfg.WebhookURL != "",
+		LastError:  r.lastError,
+	}
+}
+
+// handle 处理收到的 trap / inform; inform 的响应由 gosnmp 在回调返回后发送
+func (r *TrapReceiver) handle(packet *gosnmp.SnmpPacket, addr *net.UDPAddr) {
+	if !r.authorized(packet) {
+		r.mu.Lock()
+		r.dropped++
+		r.mu.Unlock()
+		return
+	}
+
+	trap := r.decode(packet, addr)
+	if err := r.db.Create(trap).Error; err != nil {
+		log.Printf("Failed to store trap from %s: %v", trap.SourceIP, err)
+	}
+
+	r.mu.Lock()
+	r.received++
+	r.lastTrapAt = &trap.ReceivedAt
+	r.mu.Unlock()
+
+	if r.cfg.WebhookURL != "" {
+		go r.forward(*trap)
+	}
+}
+
+// authorized 检查 trap 的认证: v1/v2c 需要匹配的团体名 (只有回环地址上未配置团体名时放行),
+// v3 需要已配置的用户并且安全级别不低于该用户的配置
+func (r *TrapReceiver) authorized(packet *gosnmp.SnmpPacket) bool {
+	if packet.Version != gosnmp.Version3 {
+		if len(r.cfg.Communities) == 0 {
+			return isLoopbackListenAddr(r.cfg.ListenAddr)
+		}
+		return containsString(r.cfg.Communities, packet.Community)
+	}
+
+	usm, ok := packet.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	if !ok || usm.UserName == "" {
+		return false
+	}
+	r.mu.Lock()
+	level, ok := r.levels[usm.UserName]
+	r.mu.Unlock()
+	return ok && packet.MsgFlags&gosnmp.AuthPriv >= level
+}
+
+// decode 提取 trap OID、运行时间和变量绑定, 并关联通知目录和设备
+func (r *TrapReceiver) decode(packet *gosnmp.SnmpPacket, addr *net.UDPAddr) *models.SNMPTrap {
+	trap := &models.SNMPTrap{
+		ReceivedAt: time.Now(),
+		SourceIP:   addr.IP.String(),
+		SourcePort: addr.Port,
+		PDUType:    "trap",
+		Varbinds:   []models.SNMPResult{},
+	}
+	if packet.PDUType == gosnmp.InformRequest {
+		trap.PDUType = "inform"
+	}
+
+	switch packet.Version {
+	case gosnmp.Version1:
+		trap.Version = "v1"
+	case gosnmp.Version2c:
+		trap.Version = "v2c"
+	case gosnmp.Version3:
+		trap.Version = "v3"
+		if usm, ok := packet.SecurityParameters.(*gosnmp.UsmSecurityParameters); ok {
+			trap.Username = usm.UserName
+		}
+	}
+
+	variables := packet.Variables
+	if packet.Version == gosnmp.Version1 {
+		trap.Enterprise = strings.TrimPrefix(packet.Enterprise, ".")
+		trap.AgentAddress = packet.AgentAddress
+		trap.GenericTrap = packet.GenericTrap
+		trap.SpecificTrap = packet.SpecificTrap
+		trap.Uptime = uint32(packet.Timestamp)
+		trap.TrapOID = snmpV1TrapOID(trap.Enterprise, packet.GenericTrap, packet.SpecificTrap)
+	} else {
+		variables = nil
+		for _, pdu := range packet.Variables {
+			switch strings.TrimPrefix(pdu.Name, ".") {
+			case snmpSysUpTimeOID:
+				if ticks, ok := pdu.Value.(uint32); ok {
+					trap.Uptime = ticks
+				}
+			case snmpTrapOIDOID:
+				if oid, ok := pdu.Value.(string); ok {
+					trap.TrapOID = strings.TrimPrefix(oid, ".")
+				}
+			default:
+				variables = append(variables, pdu)
+			}
+		}
+	}
+
+	for _, pdu := range variables {
+		trap.Varbinds = append(trap.Varbinds, models.SNMPResult{
+			OID:   strings.TrimPrefix(pdu.Name, "."),
+			Type:  pdu.Type.String(),
+			Value: r.snmp.convertSNMPValue(pdu),
+		})
+	}
+	r.snmp.resolver.Resolve(trap.Varbinds)
+
+	r.identify(trap)
+	r.matchDevice(trap)
+	return trap
+}
+
+// snmpV1TrapOID 按 RFC 3584 3.1 把 SNMPv1 trap 头部映射为通知 OID
+func snmpV1TrapOID(enterprise string, generic, specific int) string {
+	if generic >= 0 && generic < len(snmpGenericTrapNames) {
+		return fmt.Sprintf("%s.%d", snmpGenericTrapsOID, generic+1)
+	}
+	return fmt.Sprintf("%s.0.%d", enterprise, specific)
+}
+
+// identify 在通知目录中查找 trap 的名称和模块
+func (r *TrapReceiver) identify(trap *models.SNMPTrap) {
+	if trap.TrapOID == "" {
+		return
+	}
+	notifications, _ := r.mibs.SearchNotifications(trap.TrapOID, "", nil)
+	if len(notifications) == 0 && trap.Version == "v1" && trap.GenericTrap == 6 && trap.Enterprise != "" {
+		specific := uint32(trap.SpecificTrap)
+		notifications, _ = r.mibs.SearchNotifications("", trap.Enterprise, &specific)
+	}
+	if len(notifications) > 0 {
+		trap.TrapName = notifications[0].Name
+		trap.Module = notifications[0].Module
+		return
+	}
+
+	if node, err := r.snmp.resolver.tree.Node(trap.TrapOID); err == nil && node.Name != "" {
+		trap.TrapName = node.Name
+		trap.Module = node.Module
+		return
+	}
+	if strings.HasPrefix(trap.TrapOID, snmpGenericTrapsOID+".") {
+		var n int
+		if _, err := fmt.Sscanf(strings.TrimPrefix(trap.TrapOID, snmpGenericTrapsOID+"."), "%d", &n); err == nil && n >= 1 && n <= len(snmpGenericTrapNames) {
+			trap.TrapName = snmpGenericTrapNames[n-1]
+			trap.Module = "SNMPv2-MIB"
+		}
+	}
+}
+
+// matchDevice 按源地址关联设备, v1 trap 经过代理转发时再尝试 agent-addr
+func (r *TrapReceiver) matchDevice(trap *models.SNMPTrap) {
+	for _, ip := range []string{trap.SourceIP, trap.AgentAddress} {
+		if ip == "" {
+			continue
+		}
+		var device models.Device
+		if err := r.db.Where("ip_address = ?", ip).First(&device).Error; err == nil {
+			trap.DeviceID = &device.ID
+			trap.DeviceName = device.Name
+			return
+		}
+	}
+}
+
+// forward 把 trap 以 JSON POST 到 webhook, 失败只记录日志
+func (r *TrapReceiver) forward(trap models.SNMPTrap) {
+	body, err := json.Marshal(trap)
+	if err != nil {
+		log.Printf("Failed to encode trap %d for webhook: %v", trap.ID, err)
+		return
+	}
+	resp, err := r.client.Post(r.cfg.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("Failed to forward trap %d: %v", trap.ID, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("Trap webhook returned %s for trap %d", resp.Status, trap.ID)
+	}
+}
+
+func (r *TrapReceiver) prune() {
+	cutoff := time.Now().Add(-r.cfg.Retention)
+	if err := r.db.Where("received_at < ?", cutoff).Delete(&models.SNMPTrap{}).Error; err != nil {
+		log.Printf("Failed to prune traps: %v", err)
+	}
+}
+
+// GetTraps 按接收时间倒序分页查询 trap
+func (r *TrapReceiver) GetTraps(q TrapQuery) ([]models.SNMPTrap, int64, error) {
+	var traps []models.SNMPTrap
+	var total int64
+
+	query := r.db.Model(&models.SNMPTrap{})
+	if q.Source != "" {
+		query = query.Where("source_ip = ?", q.Source)
+	}
+	if q.DeviceID != 0 {
+		query = query.Where("device_id = ?", q.DeviceID)
+	}
+	if q.TrapOID != "" {
+		oid, err := normalizeOID(q.TrapOID)
+		if err != nil {
+			return nil, 0, err
+		}
+		query = query.Where("trap_oid = ?", oid)
+	}
+	if q.Name != "" {
+		query = query.Where("trap_name = ?", q.Name)
+	}
+	if q.Since != nil {
+		query = query.Where("received_at >= ?", *q.Since)
+	}
+	if q.Until != nil {
+		query = query.Where("received_at < ?", *q.Until)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.Limit <= 0 {
+		q.Limit = 50
+	}
+	offset := (q.Page - 1) * q.Limit
+	if err := query.Order("received_at DESC, id DESC").Offset(offset).Limit(q.Limit).Find(&traps).Error; err != nil {
+		return nil, 0, err
+	}
+	return traps, total, nil
+}
+
+// GetTrap 返回单个 trap
+func (r *TrapReceiver) GetTrap(id uint) (*models.SNMPTrap, error) {
+	var trap models.SNMPTrap
+	if err := r.db.First(&trap, id).Error; err != nil {
+		return nil, err
+	}
+	return &trap, nil
+}
+
+// GetUsers 列出 USM 用户, 不返回密钥
+func (r *TrapReceiver) GetUsers() ([]models.SNMPTrapUser, error) {
+	var users []models.SNMPTrapUser
+	if err := r.db.Order("username").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for i := range users {
+		users[i].AuthKey = ""
+		users[i].PrivKey = ""
+	}
+	return users, nil
+}
+
+// CreateUser 添加 USM 用户并重新加载监听
+func (r *TrapReceiver) CreateUser(user *models.SNMPTrapUser) error {
+	if _, _, err := usmUserSecurityParameters(user.Username, user.AuthProto, user.AuthKey, user.PrivProto, user.PrivKey); err != nil {
+		return err
+	}
+	if err := r.db.Create(user).Error; err != nil {
+		return fmt.Errorf("failed to create trap user: %v", err)
+	}
+	return r.Reload()
+}
+
+// DeleteUser 删除 USM 用户并重新加载监听
+func (r *TrapReceiver) DeleteUser(id uint) error {
+	result := r.db.Delete(&models.SNMPTrapUser{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return r.Reload()
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/gosnmp/gosnmp"
+
+	"mib-platform/models"
+)
+
+// RFC 3414 要求 USM 口令至少 8 个字符
+const snmpMinUSMPassphrase = 8
+
+var snmpAuthProtocols = map[string]gosnmp.SnmpV3AuthProtocol{
+	"MD5":    gosnmp.MD5,
+	"SHA":    gosnmp.SHA,
+	"SHA224": gosnmp.SHA224,
+	"SHA256": gosnmp.SHA256,
+	"SHA384": gosnmp.SHA384,
+	"SHA512": gosnmp.SHA512,
+}
+
+var snmpPrivProtocols = map[string]gosnmp.SnmpV3PrivProtocol{
+	"DES":     gosnmp.DES,
+	"AES":     gosnmp.AES,
+	"AES192":  gosnmp.AES192,
+	"AES256":  gosnmp.AES256,
+	"AES192C": gosnmp.AES192C,
+	"AES256C": gosnmp.AES256C,
+}
+
+var snmpSecurityLevels = map[string]gosnmp.SnmpV3MsgFlags{
+	"noauthnopriv": gosnmp.NoAuthNoPriv,
+	"authnopriv":   gosnmp.AuthNoPriv,
+	"authpriv":     gosnmp.AuthPriv,
+}
+
+// normalizeSNMPProtocol 统一协议名写法: "sha-256", "SHA_256", "SHA1" 都视为同一协议
+func normalizeSNMPProtocol(name string) string {
+	name = strings.ToUpper(strings.NewReplacer("-", "", "_", "", " ", "").Replace(name))
+	switch name {
+	case "SHA1":
+		return "SHA"
+	case "AES128":
+		return "AES"
+	}
+	return name
+}
+
+// snmpSecurityLevel 返回请求的安全级别, 未指定时按是否提供密钥推断
+func snmpSecurityLevel(req *models.SNMPRequest) (gosnmp.SnmpV3MsgFlags, error) {
+	if req.SecurityLevel == "" {
+		switch {
+		case req.AuthKey == "":
+			return gosnmp.NoAuthNoPriv, nil
+		case req.PrivKey == "":
+			return gosnmp.AuthNoPriv, nil
+		default:
+			return gosnmp.AuthPriv, nil
+		}
+	}
+	level, ok := snmpSecurityLevels[strings.ToLower(req.SecurityLevel)]
+	if !ok {
+		return 0, fmt.Errorf("unsupported SNMPv3 security level: %s (expected noAuthNoPriv, authNoPriv or authPriv)", req.SecurityLevel)
+	}
+	return level, nil
+}
+
+// configureSNMPv3 按请求配置 USM 安全参数和上下文; 不支持或不完整的组合返回错误
+func configureSNMPv3(snmp *gosnmp.GoSNMP, req *models.SNMPRequest) error {
+	if req.Username == "" {
+		return fmt.Errorf("SNMPv3 requires a username")
+	}
+	level, err := snmpSecurityLevel(req)
+	if err != nil {
+		return err
+	}
+
+	params := &gosnmp.UsmSecurityParameters{UserName: req.Username}
+	if level != gosnmp.NoAuthNoPriv {
+		// 未指定协议时沿用早期版本的 MD5 / DES
+		authName := normalizeSNMPProtocol(req.AuthProto)
+		if authName == "" {
+			authName = "MD5"
+		}
+		auth, ok := snmpAuthProtocols[authName]
+		if !ok {
+			return fmt.Errorf("unsupported SNMPv3 auth protocol: %s", req.AuthProto)
+		}
+		if len(req.AuthKey) < snmpMinUSMPassphrase {
+			return fmt.Errorf("SNMPv3 auth key must be at least %d characters", snmpMinUSMPassphrase)
+		}
+		params.AuthenticationProtocol = auth
+		params.AuthenticationPassphrase = req.AuthKey
+	} else if req.PrivKey != "" || req.PrivProto != "" {
+		return fmt.Errorf("SNMPv3 privacy requires authentication (noAuthPriv is not a valid security level)")
+	}
+
+	if level == gosnmp.AuthPriv {
+		privName := normalizeSNMPProtocol(req.PrivProto)
+		if privName == "" {
+			privName = "DES"
+		}
+		priv, ok := snmpPrivProtocols[privName]
+		if !ok {
+			return fmt.Errorf("unsupported SNMPv3 privacy protocol: %s", req.PrivProto)
+		}
+		if len(req.PrivKey) < snmpMinUSMPassphrase {
+			return fmt.Errorf("SNMPv3 privacy key must be at least %d characters", snmpMinUSMPassphrase)
+		}
+		params.PrivacyProtocol = priv
+		params.PrivacyPassphrase = req.PrivKey
+	}
+
+	if req.ContextEngineID != "" {
+		engineID, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(strings.ReplaceAll(req.ContextEngineID, ":", "")), "0x"))
+		if err != nil || len(engineID) == 0 {
+			return fmt.Errorf("invalid SNMPv3 context engine ID %q: expected hex", req.ContextEngineID)
+		}
+		snmp.ContextEngineID = string(engineID)
+	}
+	snmp.ContextName = req.ContextName
+
+	snmp.Version = gosnmp.Version3
+	snmp.SecurityModel = gosnmp.UserSecurityModel
+	snmp.MsgFlags = level
+	snmp.SecurityParameters = params
+	return nil
+}
+
+// usmUserSecurityParameters 按本地 USM 用户的配置生成安全参数和安全级别, 协议校验与 SNMPv3 会话相同
+func usmUserSecurityParameters(username, authProto, authKey, privProto, privKey string) (*gosnmp.UsmSecurityParameters, gosnmp.SnmpV3MsgFlags, error) {
+	snmp := &gosnmp.GoSNMP{}
+	err := configureSNMPv3(snmp, &models.SNMPRequest{
+		Username:  username,
+		AuthProto: authProto,
+		AuthKey:   authKey,
+		PrivProto: privProto,
+		PrivKey:   privKey,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return snmp.SecurityParameters.(*gosnmp.UsmSecurityParameters), snmp.MsgFlags, nil
+}
+
+// snmpCredentialRequest 用设备凭据构造 SNMP 请求
+func snmpCredentialRequest(target string, port int, cred models.SNMPCredential, oid string) *models.SNMPRequest {
+	return &models.SNMPRequest{
+		Target:          target,
+		Port:            port,
+		Version:         cred.Version,
+		Community:       cred.Community,
+		Username:        cred.Username,
+		SecurityLevel:   cred.SecurityLevel,
+		AuthProto:       cred.AuthProto,
+		AuthKey:         cred.AuthKey,
+		PrivProto:       cred.PrivProto,
+		PrivKey:         cred.PrivKey,
+		ContextName:     cred.ContextName,
+		ContextEngineID: cred.ContextEngineID,
+		OID:             oid,
+	}
+}
+
+// validateSNMPCredential 在保存前检查 v3 凭据的协议组合
+func validateSNMPCredential(cred models.SNMPCredential) error {
+	switch cred.Version {
+	case "v1", "v2c":
+		return nil
+	case "v3":
+		return configureSNMPv3(&gosnmp.GoSNMP{}, snmpCredentialRequest("", 0, cred, ""))
+	default:
+		return fmt.Errorf("unsupported SNMP version: %s", cred.Version)
+	}
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gosnmp/gosnmp"
+
+	"mib-platform/models"
+)
+
+// gosnmp 的默认值, 一个 PDU 能装下大多数表的一屏数据
+const snmpDefaultMaxRepetitions = 50
+
+// snmpWalkStats 记录一次 walk 的方式和往返次数
+type snmpWalkStats struct {
+	Method string
+	PDUs   int
+}
+
+// walk 遍历 root 下的所有 OID; v2c / v3 使用 GetBulk, v1 退回 GetNext.
+// 请求中的 NonRepeaters 只取一次, 随第一个请求发送
+func (s *SNMPService) walk(snmp *gosnmp.GoSNMP, req *models.SNMPRequest, root string, fn gosnmp.WalkFunc) (*snmpWalkStats, error) {
+	if snmp.Version == gosnmp.Version1 {
+		return s.walkGetNext(snmp, req.NonRepeaters, root, fn)
+	}
+	maxRepetitions := req.MaxRepetitions
+	if maxRepetitions == 0 {
+		maxRepetitions = snmpDefaultMaxRepetitions
+	}
+	return s.walkGetBulk(snmp, req.NonRepeaters, root, maxRepetitions, fn)
+}
+
+func (s *SNMPService) walkGetNext(snmp *gosnmp.GoSNMP, nonRepeaters []string, root string, fn gosnmp.WalkFunc) (*snmpWalkStats, error) {
+	stats := &snmpWalkStats{Method: "getnext"}
+	if len(nonRepeaters) > 0 {
+		result, err := snmp.GetNext(nonRepeaters)
+		if err != nil {
+			return stats, err
+		}
+		stats.PDUs++
+		for _, pdu := range result.Variables {
+			if snmpValueExists(pdu) {
+				if err := fn(pdu); err != nil {
+					return stats, err
+				}
+			}
+		}
+	}
+
+	err := snmp.Walk(root, func(pdu gosnmp.SnmpPDU) error {
+		stats.PDUs++
+		return fn(pdu)
+	})
+	return stats, err
+}
+
+func (s *SNMPService) walkGetBulk(snmp *gosnmp.GoSNMP, nonRepeaters []string, root string, maxRepetitions uint32, fn gosnmp.WalkFunc) (*snmpWalkStats, error) {
+	stats := &snmpWalkStats{Method: "getbulk"}
+	if len(nonRepeaters) > 255 {
+		return stats, fmt.Errorf("too many non-repeaters: %d (max 255)", len(nonRepeaters))
+	}
+	root = strings.TrimPrefix(root, ".")
+
+	cursor := root
+	pending := nonRepeaters
+	found := 0
+	for {
+		oids := append(append([]string{}, pending...), cursor)
+		result, err := snmp.GetBulk(oids, uint8(len(pending)), maxRepetitions)
+		if err != nil {
+			return stats, err
+		}
+		stats.PDUs++
+		if result.Error != gosnmp.NoError {
+			return stats, fmt.Errorf("GetBulk %s failed: %v at index %d", cursor, result.Error, result.ErrorIndex)
+		}
+
+		variables := result.Variables
+		if len(variables) < len(pending) {
+			return stats, fmt.Errorf("GetBulk returned %d variables for %d non-repeaters", len(variables), len(pending))
+		}
+		for _, pdu := range variables[:len(pending)] {
+			if snmpValueExists(pdu) {
+				if err := fn(pdu); err != nil {
+					return stats, err
+				}
+			}
+		}
+		variables = variables[len(pending):]
+		pending = nil
+
+		done := len(variables) == 0
+		for _, pdu := range variables {
+			name := strings.TrimPrefix(pdu.Name, ".")
+			if pdu.Type == gosnmp.EndOfMibView || !strings.HasPrefix(name+".", root+".") || name == root {
+				done = true
+				break
+			}
+			if compareOIDs(name, cursor) <= 0 {
+				return stats, fmt.Errorf("agent returned OID %s not increasing after %s", name, cursor)
+			}
+			if err := fn(pdu); err != nil {
+				return stats, err
+			}
+			cursor = name
+			found++
+		}
+		if done {
+			break
+		}
+	}
+
+	// root 本身是实例 (如 sysUpTime.0) 时子树为空, 与 gosnmp Walk 一致退回 Get
+	if found == 0 {
+		result, err := snmp.Get([]string{root})
+		if err != nil {
+			return stats, err
+		}
+		stats.PDUs++
+		for _, pdu := range result.Variables {
+			if snmpValueExists(pdu) {
+				if err := fn(pdu); err != nil {
+					return stats, err
+				}
+			}
+		}
+	}
+	return stats, nil
+}
+
+// snmpValueExists 过滤 noSuchObject / noSuchInstance / endOfMibView 等异常值
+func snmpValueExists(pdu gosnmp.SnmpPDU) bool {
+	switch pdu.Type {
+	case gosnmp.NoSuchObject, gosnmp.NoSuchInstance, gosnmp.EndOfMibView, gosnmp.Null:
+		return false
+	}
+	return true
+}